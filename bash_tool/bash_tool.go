@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/shell"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/textenc"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/workspace"
 	"github.com/ollama/ollama/api"
 )
 
@@ -67,12 +70,12 @@ func (a *Agent) Run(ctx context.Context) error {
 	if a.verbose {
 		log.Printf("starting conversation with model: %s", a.model)
 	}
-	fmt.Println("Chat with Ollama (type 'exit' to quit)")
+	render.Stdout.ExerciseBanner(a.model, len(a.tools))
 
 	for {
 		var userInput string
 		prompt := &survey.Input{
-			Message: "\033[32mYou:\033[0m",
+			Message: render.Stdout.Prompt("You"),
 		}
 		err := survey.AskOne(prompt, &userInput)
 		if err != nil {
@@ -108,7 +111,7 @@ func (a *Agent) Run(ctx context.Context) error {
 		for {
 			// Display text content
 			if message.Content != "" {
-				fmt.Println("\u001b[34mOllama:\u001b[0m", message.Content)
+				render.Stdout.Assistant(message.Content)
 			}
 
 			// Check for tool calls
@@ -125,7 +128,7 @@ func (a *Agent) Run(ctx context.Context) error {
 					if a.verbose {
 						log.Printf("Tool use detected: %s, arguments: %s", toolCall.Function.Name, string(argsJSON))
 					}
-					fmt.Printf("\u001b[33mTool Input:\u001b[0m %s\n", string(argsJSON))
+					render.Stdout.ToolCall(toolCall.Function.Name, toolCall.Function.Arguments)
 
 					// Find and execute the tool
 					var toolResult string
@@ -140,7 +143,7 @@ func (a *Agent) Run(ctx context.Context) error {
 								//Convert arguments to JSON for tool function
 								argsJSON, _ := json.Marshal(toolCall.Function.Arguments)
 								toolResult, toolError = tool.Function(argsJSON)
-								fmt.Printf("\u001b[32mTool Output:\u001b[0m %s\n", toolResult)
+								render.Stdout.ToolResult(toolResult)
 								if toolError != nil {
 									log.Printf("Tool Error: %v", toolError)
 								} else {
@@ -154,7 +157,7 @@ func (a *Agent) Run(ctx context.Context) error {
 
 					if !toolFound {
 						toolError = fmt.Errorf("tool '%s' not found", toolCall.Function.Name)
-						fmt.Printf("\u001b[31mTool Error:\u001b[0m %v\n", toolError)
+						render.Stdout.Error(toolError)
 						toolResult = toolError.Error()
 					}
 
@@ -272,12 +275,17 @@ func ReadFile(input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("failed to unmarshal read_file input: %w", err)
 	}
 	log.Printf("ReadFile path: %s", readFileInput.Path)
-	content, err := os.ReadFile(readFileInput.Path)
+	filePath, err := workspace.InWorkingDir(readFileInput.Path)
+	if err != nil {
+		return "", err
+	}
+	// Files in other encodings such as GBK are converted to UTF-8 for the model
+	content, enc, err := textenc.ReadFile(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
-	log.Printf("Successfully read file %s, content length: %d", readFileInput.Path, len(content))
-	return string(content), nil
+	log.Printf("Successfully read file %s (%s), content length: %d", readFileInput.Path, enc, len(content))
+	return content, nil
 }
 
 var ListFilesDefinition = ToolDefinition{
@@ -312,6 +320,9 @@ func ListFiles(input json.RawMessage) (string, error) {
 	}
 
 	log.Printf("ListFiles path: %s", dir)
+	if dir, err = workspace.InWorkingDir(dir); err != nil {
+		return "", err
+	}
 
 	var files []string
 	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -323,6 +334,8 @@ func ListFiles(input json.RawMessage) (string, error) {
 		if err != nil {
 			return err
 		}
+		// Use forward slashes on every platform, as in the paths the model passes back
+		relPath = filepath.ToSlash(relPath)
 
 		if relPath != "." {
 			if info.IsDir() {
@@ -348,14 +361,14 @@ func ListFiles(input json.RawMessage) (string, error) {
 
 var BashToolDefinition = ToolDefinition{
 	Name:        "bash",
-	Description: "Execute a bash command and return the output. Use this tool to run shell commands in the working directory.",
+	Description: "Execute a " + shell.Default().Name + " command and return the output. Use this tool to run shell commands in the working directory.",
 	InputSchema: api.ToolFunctionParameters{
 		Type:     "object",
 		Required: []string{"command"},
 		Properties: map[string]api.ToolProperty{
 			"command": {
 				Type:        api.PropertyType{"string"},
-				Description: "The bash command to execute.",
+				Description: "The " + shell.Default().Name + " command to execute.",
 			},
 		},
 	},
@@ -373,7 +386,7 @@ func Bash(input json.RawMessage) (string, error) {
 	}
 	log.Printf("Bash command: %s", bashInput.Command)
 
-	cmd := exec.Command("bash", "-c", bashInput.Command)
+	cmd := shell.Default().Command(bashInput.Command)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("failed to execute bash command: %w", err)
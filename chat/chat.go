@@ -8,6 +8,7 @@ import (
 	"os"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
 	"github.com/ollama/ollama/api"
 )
 
@@ -60,12 +61,12 @@ func (a *Agent) Run(ctx context.Context) error {
 	if a.verbose {
 		log.Printf("starting conversation with model: %s", a.model)
 	}
-	fmt.Println("Chat with Ollama (type 'exit' to quit)")
+	render.Stdout.ExerciseBanner(a.model, 0)
 
 	for {
 		var userInput string
 		prompt := &survey.Input{
-			Message: "\033[32mYou:\033[0m",
+			Message: render.Stdout.Prompt("You"),
 		}
 		err := survey.AskOne(prompt, &userInput)
 		if err != nil {
@@ -96,7 +97,7 @@ func (a *Agent) Run(ctx context.Context) error {
 		}
 		conversation = append(conversation, reply)
 
-		fmt.Println("\u001b[34mOllama:\u001b[0m", reply.Content)
+		render.Stdout.Assistant(reply.Content)
 	}
 
 	return nil
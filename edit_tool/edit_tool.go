@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
-	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/filelock"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/shell"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/textenc"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/workspace"
 	"github.com/ollama/ollama/api"
 )
 
@@ -68,12 +71,12 @@ func (a *Agent) Run(ctx context.Context) error {
 	if a.verbose {
 		log.Printf("starting conversation with model: %s", a.model)
 	}
-	fmt.Println("Chat with Ollama (type 'exit' to quit)")
+	render.Stdout.ExerciseBanner(a.model, len(a.tools))
 
 	for {
 		var userInput string
 		prompt := &survey.Input{
-			Message: "\033[32mYou:\033[0m",
+			Message: render.Stdout.Prompt("You"),
 		}
 		err := survey.AskOne(prompt, &userInput)
 		if err != nil {
@@ -109,7 +112,7 @@ func (a *Agent) Run(ctx context.Context) error {
 		for {
 			// Display text content
 			if message.Content != "" {
-				fmt.Println("\u001b[34mOllama:\u001b[0m", message.Content)
+				render.Stdout.Assistant(message.Content)
 			}
 
 			// Check for tool calls
@@ -126,7 +129,7 @@ func (a *Agent) Run(ctx context.Context) error {
 					if a.verbose {
 						log.Printf("Tool use detected: %s, arguments: %s", toolCall.Function.Name, string(argsJSON))
 					}
-					fmt.Printf("\u001b[33mTool Input:\u001b[0m %s\n", string(argsJSON))
+					render.Stdout.ToolCall(toolCall.Function.Name, toolCall.Function.Arguments)
 
 					// Find and execute the tool
 					var toolResult string
@@ -141,7 +144,7 @@ func (a *Agent) Run(ctx context.Context) error {
 								//Convert arguments to JSON for tool function
 								argsJSON, _ := json.Marshal(toolCall.Function.Arguments)
 								toolResult, toolError = tool.Function(argsJSON)
-								fmt.Printf("\u001b[32mTool Output:\u001b[0m %s\n", toolResult)
+								render.Stdout.ToolResult(toolResult)
 								if toolError != nil {
 									if a.verbose {
 										log.Printf("Tool Error: %v", toolError)
@@ -160,7 +163,7 @@ func (a *Agent) Run(ctx context.Context) error {
 
 					if !toolFound {
 						toolError = fmt.Errorf("tool '%s' not found", toolCall.Function.Name)
-						fmt.Printf("\u001b[31mTool Error:\u001b[0m %v\n", toolError)
+						render.Stdout.Error(toolError)
 						toolResult = toolError.Error()
 					}
 
@@ -278,12 +281,17 @@ func ReadFile(input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("failed to unmarshal read_file input: %w", err)
 	}
 	log.Printf("ReadFile path: %s", readFileInput.Path)
-	content, err := os.ReadFile(readFileInput.Path)
+	filePath, err := workspace.InWorkingDir(readFileInput.Path)
+	if err != nil {
+		return "", err
+	}
+	// Files in other encodings such as GBK are converted to UTF-8 for the model
+	content, enc, err := textenc.ReadFile(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
-	log.Printf("Successfully read file %s, content length: %d", readFileInput.Path, len(content))
-	return string(content), nil
+	log.Printf("Successfully read file %s (%s), content length: %d", readFileInput.Path, enc, len(content))
+	return content, nil
 }
 
 var ListFilesDefinition = ToolDefinition{
@@ -318,6 +326,9 @@ func ListFiles(input json.RawMessage) (string, error) {
 	}
 
 	log.Printf("ListFiles path: %s", dir)
+	if dir, err = workspace.InWorkingDir(dir); err != nil {
+		return "", err
+	}
 
 	var files []string
 	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -329,6 +340,8 @@ func ListFiles(input json.RawMessage) (string, error) {
 		if err != nil {
 			return err
 		}
+		// Use forward slashes on every platform, as in the paths the model passes back
+		relPath = filepath.ToSlash(relPath)
 
 		if relPath != "." {
 			if info.IsDir() {
@@ -354,14 +367,14 @@ func ListFiles(input json.RawMessage) (string, error) {
 
 var BashToolDefinition = ToolDefinition{
 	Name:        "bash",
-	Description: "Execute a bash command and return the output. Use this tool when you need to run a bash command in the working directory.",
+	Description: "Execute a " + shell.Default().Name + " command and return the output. Use this tool when you need to run a shell command in the working directory.",
 	InputSchema: api.ToolFunctionParameters{
 		Type:     "object",
 		Required: []string{"command"},
 		Properties: map[string]api.ToolProperty{
 			"command": {
 				Type:        api.PropertyType{"string"},
-				Description: "The bash command to execute.",
+				Description: "The " + shell.Default().Name + " command to execute.",
 			},
 		},
 	},
@@ -379,7 +392,7 @@ func Bash(input json.RawMessage) (string, error) {
 	}
 	log.Printf("Bash command: %s", bashInput.Command)
 
-	cmd := exec.Command("bash", "-c", bashInput.Command)
+	cmd := shell.Default().Command(bashInput.Command)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("failed to execute bash command: %w", err)
@@ -436,17 +449,35 @@ func EditFile(input json.RawMessage) (string, error) {
 	}
 
 	log.Printf("Editing file: %s (replacing %d chars with %d chars)", editFileInput.Path, len(editFileInput.OldStr), len(editFileInput.NewStr))
-	content, err := os.ReadFile(editFileInput.Path)
+	filePath, err := workspace.InWorkingDir(editFileInput.Path)
+	if err != nil {
+		log.Printf("EditFile failed: %v", err)
+		return "", err
+	}
+
+	// Hold the file lock across the read and the write so that concurrent edits cannot interleave
+	unlock, err := filelock.Lock(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to lock file: %w", err)
+	}
+	defer unlock()
+
+	// Edit the text as UTF-8 and write it back in the file's original encoding
+	oldContent, enc, err := textenc.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) && editFileInput.OldStr == "" {
 			log.Printf("File does not exist, creating new file: %s", editFileInput.Path)
-			return createNewFile(editFileInput.Path, editFileInput.NewStr)
+			return createNewFile(filePath, editFileInput.NewStr)
 		}
 		log.Printf("Failed to read file %s: %v", editFileInput.Path, err)
 		return "", err
 	}
 
-	oldContent := string(content)
+	// Match and replace with "\n" line endings, then restore the file's own
+	lineEndings := textenc.DetectLineEndings(oldContent)
+	oldContent = textenc.ToLF(oldContent)
+	editFileInput.OldStr = textenc.ToLF(editFileInput.OldStr)
+	editFileInput.NewStr = textenc.ToLF(editFileInput.NewStr)
 
 	// Special case: if old_str is empty, we're appending to the file
 	var newContent string
@@ -467,7 +498,12 @@ func EditFile(input json.RawMessage) (string, error) {
 		newContent = strings.Replace(oldContent, editFileInput.OldStr, editFileInput.NewStr, 1)
 	}
 
-	err = os.WriteFile(editFileInput.Path, []byte(newContent), 0644)
+	data, err := textenc.Encode(lineEndings.Apply(newContent), enc)
+	if err != nil {
+		log.Printf("EditFile failed: %v", err)
+		return "", err
+	}
+	err = os.WriteFile(filePath, data, 0644)
 	if err != nil {
 		log.Printf("Failed to write file %s: %v", editFileInput.Path, err)
 		return "", err
@@ -479,7 +515,7 @@ func EditFile(input json.RawMessage) (string, error) {
 
 func createNewFile(filePath, content string) (string, error) {
 	log.Printf("Creating new file: %s (%d bytes)", filePath, len(content))
-	dir := path.Dir(filePath)
+	dir := filepath.Dir(filePath)
 	if dir != "." {
 		log.Printf("Creating directory: %s", dir)
 		err := os.MkdirAll(dir, 0755)
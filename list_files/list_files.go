@@ -10,6 +10,9 @@ import (
 	"path/filepath"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/textenc"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/workspace"
 	"github.com/ollama/ollama/api"
 )
 
@@ -65,12 +68,12 @@ func (a *Agent) Run(ctx context.Context) error {
 	if a.verbose {
 		log.Printf("starting conversation with model: %s", a.model)
 	}
-	fmt.Println("Chat with Ollama (type 'exit' to quit)")
+	render.Stdout.ExerciseBanner(a.model, len(a.tools))
 
 	for {
 		var userInput string
 		prompt := &survey.Input{
-			Message: "\033[32mYou:\033[0m",
+			Message: render.Stdout.Prompt("You"),
 		}
 		err := survey.AskOne(prompt, &userInput)
 		if err != nil {
@@ -106,7 +109,7 @@ func (a *Agent) Run(ctx context.Context) error {
 		for {
 			// Display text content
 			if message.Content != "" {
-				fmt.Println("\u001b[34mOllama:\u001b[0m", message.Content)
+				render.Stdout.Assistant(message.Content)
 			}
 
 			// Check for tool calls
@@ -123,7 +126,7 @@ func (a *Agent) Run(ctx context.Context) error {
 					if a.verbose {
 						log.Printf("Tool use detected: %s, arguments: %s", toolCall.Function.Name, string(argsJSON))
 					}
-					fmt.Printf("\u001b[33mTool Input:\u001b[0m %s\n", string(argsJSON))
+					render.Stdout.ToolCall(toolCall.Function.Name, toolCall.Function.Arguments)
 
 					// Find and execute the tool
 					var toolResult string
@@ -138,7 +141,7 @@ func (a *Agent) Run(ctx context.Context) error {
 								//Convert arguments to JSON for tool function
 								argsJSON, _ := json.Marshal(toolCall.Function.Arguments)
 								toolResult, toolError = tool.Function(argsJSON)
-								fmt.Printf("\u001b[32mTool Output:\u001b[0m %s\n", toolResult)
+								render.Stdout.ToolResult(toolResult)
 								if toolError != nil {
 									log.Printf("Tool Error: %v", toolError)
 								} else {
@@ -152,7 +155,7 @@ func (a *Agent) Run(ctx context.Context) error {
 
 					if !toolFound {
 						toolError = fmt.Errorf("tool '%s' not found", toolCall.Function.Name)
-						fmt.Printf("\u001b[31mTool Error:\u001b[0m %v\n", toolError)
+						render.Stdout.Error(toolError)
 						toolResult = toolError.Error()
 					}
 
@@ -270,12 +273,17 @@ func ReadFile(input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("failed to unmarshal read_file input: %w", err)
 	}
 	log.Printf("ReadFile path: %s", readFileInput.Path)
-	content, err := os.ReadFile(readFileInput.Path)
+	filePath, err := workspace.InWorkingDir(readFileInput.Path)
+	if err != nil {
+		return "", err
+	}
+	// Files in other encodings such as GBK are converted to UTF-8 for the model
+	content, enc, err := textenc.ReadFile(filePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
-	log.Printf("Successfully read file %s, content length: %d", readFileInput.Path, len(content))
-	return string(content), nil
+	log.Printf("Successfully read file %s (%s), content length: %d", readFileInput.Path, enc, len(content))
+	return content, nil
 }
 
 var ListFilesDefinition = ToolDefinition{
@@ -310,6 +318,9 @@ func ListFiles(input json.RawMessage) (string, error) {
 	}
 
 	log.Printf("ListFiles path: %s", dir)
+	if dir, err = workspace.InWorkingDir(dir); err != nil {
+		return "", err
+	}
 
 	var files []string
 	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -321,6 +332,8 @@ func ListFiles(input json.RawMessage) (string, error) {
 		if err != nil {
 			return err
 		}
+		// Use forward slashes on every platform, as in the paths the model passes back
+		relPath = filepath.ToSlash(relPath)
 
 		if relPath != "." {
 			if info.IsDir() {
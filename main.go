@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -19,6 +20,27 @@ const (
 )
 
 func main() {
+	// 子命令: mcp init 生成 MCP 配置文件，mcp search 在索引中搜索 MCP 服务器并加入配置，
+	// build-servers 编译内置 MCP 服务器，serve-all 启动所有 SSE MCP 服务器
+	var subcommand func() error
+	switch {
+	case len(os.Args) >= 3 && os.Args[1] == "mcp" && os.Args[2] == "init":
+		subcommand = runMCPInit
+	case len(os.Args) >= 3 && os.Args[1] == "mcp" && os.Args[2] == "search":
+		subcommand = func() error { return runMCPSearch(os.Args[3:]) }
+	case len(os.Args) >= 2 && os.Args[1] == "build-servers":
+		subcommand = runBuildServers
+	case len(os.Args) >= 2 && os.Args[1] == "serve-all":
+		subcommand = func() error { return runServeAll(os.Args[2:]) }
+	}
+	if subcommand != nil {
+		if err := subcommand(); err != nil {
+			fmt.Printf("  %s❌ %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		return
+	}
+
 	models := checkOllamaEnvironment()
 	if models == nil {
 		return
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+)
+
+// defaultListenAddr 是 serve 子命令的默认监听地址
+const defaultListenAddr = "127.0.0.1:9622"
+
+// maxRequestBody 是 API 请求体的最大字节数
+const maxRequestBody = 1 << 20
+
+// apiShutdownTimeout 是停止服务时等待处理中的请求结束的最长时间
+const apiShutdownTimeout = 30 * time.Second
+
+// runServe 以 HTTP API 提供对话，每个会话由 SessionManager 创建，不同会话并发处理。
+// 配置了 users 时每个请求需要用户的 token，每个用户有独立的会话、工作区和速率限制。
+// 运行中不向终端提问，收到 SIGINT/SIGTERM 后等待处理中的请求结束再退出，返回进程退出码
+func runServe(ctx context.Context, agent *Agent, addr string, config *mcp.Config, clientOpts *mcp.ClientOptions) int {
+	agent.headless = true
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	users, err := loadAPIUsers(ctx, agent, config, clientOpts)
+	if err != nil {
+		render.Stdout.Error(err)
+		return 1
+	}
+	defer closeAPIUsers(users)
+
+	manager := NewSessionManager(agent)
+	server := &http.Server{
+		Addr:        addr,
+		Handler:     newAPIHandler(manager, users),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if len(users) > 0 {
+		render.Stdout.Success("serve", "agent API listening on http://%s for %d users", addr, len(users))
+	} else {
+		render.Stdout.Success("serve", "agent API listening on http://%s", addr)
+	}
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		render.Stdout.Error(err)
+		return 1
+	}
+	if err := manager.CloseAll(); err != nil {
+		render.Stdout.Error(err)
+	}
+	return 0
+}
+
+// apiHandler 实现会话 API：
+//
+//	POST   /sessions               创建会话，返回 {"id": ...}
+//	GET    /sessions               列出会话 ID
+//	POST   /sessions/{id}/messages 发送 {"prompt": ...}，返回 {"answer": ...}
+//	DELETE /sessions/{id}          结束会话
+//
+// 配置了用户时请求需要带 "Authorization: Bearer <token>"，用户只能看到和使用自己的会话
+type apiHandler struct {
+	manager *SessionManager
+	users   []*apiUser
+}
+
+func newAPIHandler(manager *SessionManager, users []*apiUser) http.Handler {
+	h := &apiHandler{manager: manager, users: users}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sessions", h.create)
+	mux.HandleFunc("GET /sessions", h.list)
+	mux.HandleFunc("POST /sessions/{id}/messages", h.send)
+	mux.HandleFunc("DELETE /sessions/{id}", h.close)
+	return h.authenticate(mux)
+}
+
+// apiUserKey 是请求 context 中保存已认证用户的键
+type apiUserKey struct{}
+
+// authenticate 在配置了用户时检查请求的 token，并将对应的用户保存在请求的 context 中
+func (h *apiHandler) authenticate(next http.Handler) http.Handler {
+	if len(h.users) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		user := findAPIUser(h.users, strings.TrimSpace(token))
+		if !ok || user == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp_agent"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiUserKey{}, user)))
+	})
+}
+
+// sessions 返回处理请求的会话管理器：已认证用户的，或没有配置用户时共享的
+func (h *apiHandler) sessions(r *http.Request) *SessionManager {
+	if user, ok := r.Context().Value(apiUserKey{}).(*apiUser); ok {
+		return user.manager
+	}
+	return h.manager
+}
+
+func (h *apiHandler) create(w http.ResponseWriter, r *http.Request) {
+	agent := h.sessions(r).Create(nil)
+	writeJSON(w, http.StatusCreated, map[string]string{"id": agent.ID})
+}
+
+func (h *apiHandler) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]string{"sessions": h.sessions(r).List()})
+}
+
+func (h *apiHandler) send(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&req); err != nil || req.Prompt == "" {
+		writeError(w, http.StatusBadRequest, errors.New(`expected {"prompt": "..."}`))
+		return
+	}
+	manager := h.sessions(r)
+	id := r.PathValue("id")
+	if _, ok := manager.Get(id); !ok {
+		writeError(w, http.StatusNotFound, errSessionNotFound)
+		return
+	}
+	if user, ok := r.Context().Value(apiUserKey{}).(*apiUser); ok {
+		if allowed, wait := user.limiter.allow(time.Now()); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			writeError(w, http.StatusTooManyRequests, fmt.Errorf("rate limit of %d messages per minute exceeded", user.limiter.limit))
+			return
+		}
+	}
+	answer, err := manager.Send(r.Context(), id, req.Prompt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"answer": answer})
+}
+
+func (h *apiHandler) close(w http.ResponseWriter, r *http.Request) {
+	err := h.sessions(r).Close(r.PathValue("id"))
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
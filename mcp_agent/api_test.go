@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAgent(t *testing.T) *Agent {
+	// 关闭会话时使用统计写入 ~/.mcp_agent
+	t.Setenv("HOME", t.TempDir())
+	return &Agent{agentCore: &agentCore{defaultModel: "m"}, Session: newSession("m", nil)}
+}
+
+// apiRequest 发送请求并返回状态码和解析后的 JSON
+func apiRequest(t *testing.T, handler http.Handler, method, path, token, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var result map[string]any
+	if rec.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	}
+	return rec.Code, result
+}
+
+func TestAPIWithoutUsers(t *testing.T) {
+	handler := newAPIHandler(NewSessionManager(newTestAgent(t)), nil)
+
+	status, created := apiRequest(t, handler, http.MethodPost, "/sessions", "", "")
+	require.Equal(t, http.StatusCreated, status)
+	status, listed := apiRequest(t, handler, http.MethodGet, "/sessions", "", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []any{created["id"]}, listed["sessions"])
+
+	status, _ = apiRequest(t, handler, http.MethodPost, "/sessions/nope/messages", "", `{"prompt": "hi"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = apiRequest(t, handler, http.MethodDelete, "/sessions/"+created["id"].(string), "", "")
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestAPIUsers(t *testing.T) {
+	agent := newTestAgent(t)
+	t.Setenv("BOB_TOKEN", "bob-secret")
+	users, err := loadAPIUsers(t.Context(), agent, &mcp.Config{Users: []mcp.User{
+		{Name: "alice", Token: "alice-secret"},
+		{Name: "bob", Token: "$BOB_TOKEN"},
+	}}, &mcp.ClientOptions{})
+	require.NoError(t, err)
+	handler := newAPIHandler(NewSessionManager(agent), users)
+
+	status, result := apiRequest(t, handler, http.MethodGet, "/sessions", "", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "missing or invalid token", result["error"])
+	status, _ = apiRequest(t, handler, http.MethodGet, "/sessions", "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// 每个用户只能看到和使用自己的会话
+	status, alice := apiRequest(t, handler, http.MethodPost, "/sessions", "alice-secret", "")
+	require.Equal(t, http.StatusCreated, status)
+	status, bob := apiRequest(t, handler, http.MethodPost, "/sessions", "bob-secret", "")
+	require.Equal(t, http.StatusCreated, status)
+
+	_, listed := apiRequest(t, handler, http.MethodGet, "/sessions", "alice-secret", "")
+	assert.Equal(t, []any{alice["id"]}, listed["sessions"])
+	_, listed = apiRequest(t, handler, http.MethodGet, "/sessions", "bob-secret", "")
+	assert.Equal(t, []any{bob["id"]}, listed["sessions"])
+
+	status, _ = apiRequest(t, handler, http.MethodPost, "/sessions/"+bob["id"].(string)+"/messages", "alice-secret", `{"prompt": "hi"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = apiRequest(t, handler, http.MethodDelete, "/sessions/"+bob["id"].(string), "alice-secret", "")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = apiRequest(t, handler, http.MethodDelete, "/sessions/"+bob["id"].(string), "bob-secret", "")
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestLoadAPIUsersErrors(t *testing.T) {
+	agent := newTestAgent(t)
+	tests := []struct {
+		name  string
+		users []mcp.User
+		err   string
+	}{
+		{"no name", []mcp.User{{Token: "t"}}, "needs a name"},
+		{"duplicate name", []mcp.User{{Name: "a", Token: "t1"}, {Name: "a", Token: "t2"}}, `duplicate user "a"`},
+		{"no token", []mcp.User{{Name: "a", Token: "$UNSET_TEST_TOKEN"}}, `"a" has no token`},
+		{"shared token", []mcp.User{{Name: "a", Token: "t"}, {Name: "b", Token: "t"}}, "same token"},
+		{"missing workspace", []mcp.User{{Name: "a", Token: "t", Workspace: "/does/not/exist"}}, `workspace of "a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadAPIUsers(t.Context(), agent, &mcp.Config{Users: tt.users}, &mcp.ClientOptions{})
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{limit: 2}
+	now := time.Now()
+
+	ok, _ := l.allow(now)
+	assert.True(t, ok)
+	ok, _ = l.allow(now.Add(10 * time.Second))
+	assert.True(t, ok)
+	ok, wait := l.allow(now.Add(20 * time.Second))
+	assert.False(t, ok)
+	assert.Equal(t, 40*time.Second, wait)
+
+	// 一分钟后最早的请求不再计入
+	ok, _ = l.allow(now.Add(61 * time.Second))
+	assert.True(t, ok)
+
+	unlimited := &rateLimiter{}
+	for range 100 {
+		ok, _ = unlimited.allow(now)
+		assert.True(t, ok)
+	}
+}
+
+func TestAPIRateLimit(t *testing.T) {
+	agent := newTestAgent(t)
+	users, err := loadAPIUsers(t.Context(), agent, &mcp.Config{Users: []mcp.User{{Name: "a", Token: "t", RateLimit: 1}}}, &mcp.ClientOptions{})
+	require.NoError(t, err)
+	handler := newAPIHandler(NewSessionManager(agent), users)
+	// 已用完这一分钟的配额
+	users[0].limiter.allow(time.Now())
+
+	_, created := apiRequest(t, handler, http.MethodPost, "/sessions", "t", "")
+	req := httptest.NewRequest(http.MethodPost, "/sessions/"+created["id"].(string)+"/messages", strings.NewReader(`{"prompt": "hi"}`))
+	req.Header.Set("Authorization", "Bearer t")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestAPIUserWorkspace(t *testing.T) {
+	agent := newTestAgent(t)
+	dir := t.TempDir()
+	users, err := loadAPIUsers(t.Context(), agent, &mcp.Config{
+		MCPServers: map[string]mcp.MCPServer{},
+		Users:      []mcp.User{{Name: "a", Token: "t", Workspace: dir}, {Name: "b", Token: "u"}},
+	}, &mcp.ClientOptions{})
+	require.NoError(t, err)
+	defer closeAPIUsers(users)
+
+	// 有独立工作区的用户使用自己的 MCP 客户端，文件类工具只能访问该目录
+	session := users[0].manager.Create(nil)
+	assert.Equal(t, []string{dir}, session.toolRoots())
+	require.NotNil(t, session.client())
+	_, err = session.toolPath("/etc/passwd")
+	assert.Error(t, err)
+
+	shared := users[1].manager.Create(nil)
+	assert.Nil(t, shared.userSpace)
+	assert.Nil(t, shared.client())
+}
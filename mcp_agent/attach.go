@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ollama/ollama/api"
+)
+
+// maxAttachmentSize 是单个文本附件注入对话的最大字节数，超出部分会被截断
+const maxAttachmentSize = 100 * 1024
+
+// stringList 是可重复指定的字符串参数，如 --file a.log --file b.go
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// attachment 是随一次性提问附带的上下文内容
+type attachment struct {
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+// loadAttachments 读取 --file 指定的文件，以及通过管道传入的标准输入
+func loadAttachments(files []string) ([]attachment, error) {
+	var attachments []attachment
+
+	if stdinIsPiped() {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		if len(data) > 0 {
+			attachments = append(attachments, attachment{Name: "stdin", MIMEType: detectMIMEType("", data), Data: data})
+		}
+	}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment: %w", err)
+		}
+		attachments = append(attachments, attachment{Name: path, MIMEType: detectMIMEType(path, data), Data: data})
+	}
+
+	return attachments, nil
+}
+
+// stdinPrompt 在没有 -p 时把管道输入的文本作为问题，其余附件（--file）保持不变
+func stdinPrompt(attachments []attachment) (string, []attachment, error) {
+	var data []byte
+	if len(attachments) > 0 && attachments[0].Name == "stdin" {
+		data, attachments = attachments[0].Data, attachments[1:]
+	}
+	if !isText(data) {
+		return "", nil, fmt.Errorf("no prompt: stdin is not text, pass an instruction with -p to attach it")
+	}
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		return "", nil, fmt.Errorf("no prompt: stdin is empty, pass the prompt with -p or through a pipe")
+	}
+	if len(prompt) > maxAttachmentSize {
+		prompt = prompt[:maxAttachmentSize] + fmt.Sprintf("\n... (truncated, %d bytes total)", len(data))
+	}
+	return prompt, attachments, nil
+}
+
+// stdinIsPiped 判断标准输入是否来自管道或重定向，而不是终端
+func stdinIsPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+// detectMIMEType 优先根据扩展名判断类型，否则根据内容探测
+func detectMIMEType(path string, data []byte) string {
+	if ext := filepath.Ext(path); ext != "" {
+		if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+			return mimeType
+		}
+	}
+	mimeType := http.DetectContentType(data)
+	// 未知扩展名的 UTF-8 文本（如 .go、.log）按纯文本处理
+	if mimeType == "application/octet-stream" && isText(data) {
+		return "text/plain; charset=utf-8"
+	}
+	return mimeType
+}
+
+// isText 判断内容是否为文本：合法的 UTF-8 且不包含 NUL 字节
+func isText(data []byte) bool {
+	return utf8.Valid(data) && !bytes.ContainsRune(data, 0)
+}
+
+// attachmentMessage 将附件转换为用户消息：文本作为上下文块拼接在问题之后，图片作为多模态输入
+func attachmentMessage(prompt string, attachments []attachment) api.Message {
+	message := api.Message{Role: "user"}
+
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	for _, att := range attachments {
+		mediaType, _, _ := mime.ParseMediaType(att.MIMEType)
+		switch {
+		case strings.HasPrefix(mediaType, "image/"):
+			message.Images = append(message.Images, api.ImageData(att.Data))
+			sb.WriteString(fmt.Sprintf("\n\n<attachment name=%q type=%q>[image attached]</attachment>", att.Name, mediaType))
+		case strings.HasPrefix(mediaType, "text/") || isText(att.Data):
+			content := string(att.Data)
+			if len(content) > maxAttachmentSize {
+				content = content[:maxAttachmentSize] + fmt.Sprintf("\n... (truncated, %d bytes total)", len(att.Data))
+			}
+			sb.WriteString(fmt.Sprintf("\n\n<attachment name=%q type=%q>\n%s\n</attachment>", att.Name, mediaType, content))
+		default:
+			sb.WriteString(fmt.Sprintf("\n\n<attachment name=%q type=%q>[binary content, %d bytes]</attachment>", att.Name, mediaType, len(att.Data)))
+		}
+	}
+
+	message.Content = sb.String()
+	return message
+}
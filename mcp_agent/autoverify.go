@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/shell"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// maxVerifyAttempts 是 --auto 模式下一轮对话的修改通过校验的最大尝试次数
+	maxVerifyAttempts = 3
+	// maxVerifyOutput 是交给模型的校验命令输出的最大长度，保留末尾的部分
+	maxVerifyOutput = 4000
+	// defaultVerifyTimeout 是 --verify-timeout 的默认值
+	defaultVerifyTimeout = 10 * time.Minute
+)
+
+// errVerifyFailed 表示一轮对话的修改多次尝试后仍未通过 --verify-cmd，修改已被丢弃
+var errVerifyFailed = errors.New("verification failed")
+
+// startTurnEdits 在一轮对话开始时清空上一轮记录的编辑；使用工作区副本时让副本与工作区一致
+func (a *Agent) startTurnEdits() error {
+	a.turnEdits.reset()
+	if a.staging == nil {
+		return nil
+	}
+	if err := a.staging.sync(); err != nil {
+		return fmt.Errorf("failed to update the copy of the workspace for --verify-cmd: %w", err)
+	}
+	return nil
+}
+
+// verifyTurn 在 --auto 模式下用 --verify-cmd（如测试或 lint）检查一轮对话修改的文件。工具只修改工作区的副本，
+// 校验命令也在副本中运行：通过时将修改的文件写回工作区；失败时将副本中的这些文件恢复到这一轮开始前的内容，
+// 把命令输出交给模型重新修改，最多尝试 maxVerifyAttempts 次。未通过校验的修改不会写入工作区
+func (a *Agent) verifyTurn(ctx context.Context, conversation []api.Message, tools []api.Tool) ([]api.Message, error) {
+	for attempt := 1; ; attempt++ {
+		paths := a.turnEdits.paths()
+		if len(paths) == 0 {
+			return conversation, nil
+		}
+		changed := make([]string, len(paths))
+		for i, path := range paths {
+			changed[i] = a.staging.workspacePath(path)
+		}
+
+		render.Stdout.Note("verify", "running %s", a.verifyCmd)
+		output, err := runVerifyCommand(ctx, a.verifyCmd, a.staging.commandDir(), a.verifyTimeout)
+		if err == nil {
+			if err := a.staging.apply(paths); err != nil {
+				return conversation, fmt.Errorf("%s passed, but the changes could not be written to the workspace: %w", a.verifyCmd, err)
+			}
+			a.turnEdits.reset()
+			render.Stdout.Success("verify", "%s passed, applied the changes to %s", a.verifyCmd, strings.Join(changed, ", "))
+			return conversation, nil
+		}
+		if ctx.Err() != nil {
+			return conversation, ctx.Err()
+		}
+
+		if restoreErr := a.turnEdits.restore(); restoreErr != nil {
+			return conversation, fmt.Errorf("failed to discard the changes after %s failed: %w", a.verifyCmd, restoreErr)
+		}
+		render.Stdout.Warning("verify", "%s failed (%v), discarded the changes to %s", a.verifyCmd, err, strings.Join(changed, ", "))
+		if attempt == maxVerifyAttempts {
+			return conversation, fmt.Errorf("%w: %s still fails after %d attempts, the changes were discarded", errVerifyFailed, a.verifyCmd, attempt)
+		}
+
+		conversation = append(conversation, api.Message{
+			Role: "user",
+			Content: fmt.Sprintf("The verification command `%s` failed, so your changes to %s were discarded and the files are back to their previous content.\n\nOutput:\n%s\n\nFix the problem and make the changes again.",
+				a.verifyCmd, strings.Join(paths, ", "), tail(output, maxVerifyOutput)),
+		})
+		if conversation, err = a.processTurn(ctx, conversation, tools); err != nil {
+			return conversation, err
+		}
+	}
+}
+
+// runVerifyCommand 在 dir 中用平台的 shell 运行校验命令，返回合并的标准输出和标准错误。
+// 超过 timeout 的命令被终止，按校验失败处理
+func runVerifyCommand(ctx context.Context, command, dir string, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := shell.Default().CommandContext(ctx, command)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+		output = append(output, fmt.Sprintf("\n(the command was stopped after %s)", timeout)...)
+	}
+	return string(output), err
+}
+
+// tail 返回 s 末尾不超过 n 个字节的部分，从完整的一行开始；最后一行就超过 n 个字节时从完整的字符开始
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	if s[start-1] != '\n' {
+		if i := strings.IndexByte(s[start:], '\n'); i >= 0 && i+1 < n {
+			start += i + 1
+		} else {
+			for start < len(s) && !utf8.RuneStart(s[start]) {
+				start++
+			}
+		}
+	}
+	return "... (truncated)\n" + s[start:]
+}
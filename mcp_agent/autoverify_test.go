@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyTurnAppliesPassingChanges(t *testing.T) {
+	root, s := newTestStaging(t)
+	writeFile(t, filepath.Join(root, "main.txt"), "broken\n")
+	agent := newTestAgent(t)
+	agent.auto = true
+	agent.verifyCmd = "grep -q fixed main.txt"
+	agent.staging = s
+	t.Chdir(root)
+
+	require.NoError(t, agent.startTurnEdits())
+	edited := filepath.Join(s.copies[0], "main.txt")
+	agent.turnEdits.record(edited)
+	writeFile(t, edited, "fixed\n")
+
+	_, err := agent.verifyTurn(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fixed\n", readFile(t, filepath.Join(root, "main.txt")))
+	assert.Empty(t, agent.turnEdits.paths())
+}
+
+func TestRunVerifyCommand(t *testing.T) {
+	dir := t.TempDir()
+	output, err := runVerifyCommand(context.Background(), "pwd", dir, time.Minute)
+	require.NoError(t, err)
+	resolved, _ := filepath.EvalSymlinks(dir)
+	assert.Equal(t, resolved, strings.TrimSpace(output))
+
+	_, err = runVerifyCommand(context.Background(), "exit 1", dir, time.Minute)
+	assert.Error(t, err)
+
+	output, err = runVerifyCommand(context.Background(), "echo started; sleep 30", dir, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out after 100ms")
+	assert.Contains(t, output, "started")
+}
+
+func TestTail(t *testing.T) {
+	assert.Equal(t, "short", tail("short", 10))
+	// 从完整的一行开始
+	assert.Equal(t, "... (truncated)\nthird\n", tail("first\nsecond\nthird\n", 10))
+	assert.Equal(t, "... (truncated)\nsecond\nthird\n", tail("first\nsecond\nthird\n", 13))
+	// 一行就超过 n 个字节时不截断多字节字符
+	assert.Equal(t, "... (truncated)\n界界", tail("世界界界", 7))
+}
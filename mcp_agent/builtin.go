@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/ollama/ollama/api"
+)
+
+// 内置工具名不带服务器前缀，调用时优先于 MCP 工具 (默认为 server__tool) 分发
+const (
+	toolListResources = "list_resources"
+	toolReadResource  = "read_resource"
+	toolSearchTools   = "search_tools"
+)
+
+// checkAliases 拒绝与内置工具同名的工具别名：内置工具优先分发，这样的别名指向的工具永远不会被调用
+func checkAliases(config *mcp.Config) error {
+	for name, server := range config.MCPServers {
+		for tool, alias := range server.Aliases {
+			switch alias {
+			case toolListResources, toolReadResource, toolSearchTools:
+				return fmt.Errorf("alias %q of %s on server %s is the name of a built-in tool", alias, tool, name)
+			}
+		}
+	}
+	return nil
+}
+
+// builtinTools 返回由 Agent 自身实现的工具定义
+func builtinTools() []api.Tool {
+	return []api.Tool{
+		{
+			Type: mcp.ToolTypeFunction,
+			Function: api.ToolFunction{
+				Name:        toolListResources,
+				Description: "List the resources (files, documents, etc.) published by the connected MCP servers. Returns the server name and URI of each resource.",
+				Parameters: api.ToolFunctionParameters{
+					Type:       "object",
+					Properties: map[string]api.ToolProperty{},
+				},
+			},
+		},
+		{
+			Type: mcp.ToolTypeFunction,
+			Function: api.ToolFunction{
+				Name:        toolReadResource,
+				Description: "Read the contents of a resource published by an MCP server. Use list_resources first to discover the server name and URI.",
+				Parameters: api.ToolFunctionParameters{
+					Type:     "object",
+					Required: []string{"server", "uri"},
+					Properties: map[string]api.ToolProperty{
+						"server": {
+							Type:        api.PropertyType{"string"},
+							Description: "The name of the MCP server that publishes the resource.",
+						},
+						"uri": {
+							Type:        api.PropertyType{"string"},
+							Description: "The URI of the resource to read.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// callTool 执行工具调用，内置工具由 Agent 处理，其余转发给 MCP 客户端
+func (a *Agent) callTool(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+	if a.offline {
+		if err := a.checkOffline(name, args); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.checkURLPolicy(ctx, name, args); err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case toolSearchTools:
+		query, _ := args["query"].(string)
+		return a.searchTools(ctx, query)
+	case toolListResources:
+		return a.listResources(ctx)
+	case toolReadResource:
+		server, _ := args["server"].(string)
+		uri, _ := args["uri"].(string)
+		if server == "" || uri == "" {
+			return nil, fmt.Errorf("both server and uri are required")
+		}
+		return a.readResource(ctx, server, uri)
+	default:
+		if err := a.approveToolCall(name, args); err != nil {
+			return nil, err
+		}
+
+		// Ctrl-C 只取消正在执行的工具调用，而不是退出程序，错误会反馈给模型
+		callCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+
+		opts := []mcp.CallOption{mcp.WithProgress(printProgress)}
+		if a.toolTimeout > 0 {
+			opts = append(opts, mcp.WithTimeout(a.toolTimeout))
+		}
+		result, err := a.client().CallTool(callCtx, name, args, opts...)
+		if err != nil {
+			if callCtx.Err() != nil && ctx.Err() == nil {
+				return nil, fmt.Errorf("tool call %s was cancelled by the user", name)
+			}
+			return nil, err
+		}
+		// 工具自身报告的错误按调用失败处理
+		if result.IsError {
+			return nil, errors.New(result.String())
+		}
+		return result, nil
+	}
+}
+
+// listResources 列出所有 MCP 服务器发布的资源
+func (a *Agent) listResources(ctx context.Context) (string, error) {
+	resources, err := a.client().ListResources(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(resources) == 0 {
+		return "No resources available", nil
+	}
+
+	var sb strings.Builder
+	for _, r := range resources {
+		sb.WriteString(fmt.Sprintf("- server: %s, uri: %s, name: %s", r.Server, r.URI, r.Name))
+		if r.MIMEType != "" {
+			sb.WriteString(fmt.Sprintf(", type: %s", r.MIMEType))
+		}
+		if r.Description != "" {
+			sb.WriteString(fmt.Sprintf(" - %s", r.Description))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// readResource 读取指定资源的内容
+func (a *Agent) readResource(ctx context.Context, server, uri string) (string, error) {
+	contents, err := a.client().ReadResource(ctx, server, uri)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, c := range contents {
+		if c.Blob != nil {
+			sb.WriteString(fmt.Sprintf("[binary resource %s, type: %s, %d bytes]\n", c.URI, c.MIMEType, len(c.Blob)))
+			continue
+		}
+		sb.WriteString(c.Text)
+	}
+	return sb.String(), nil
+}
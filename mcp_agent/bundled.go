@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/servers"
+)
+
+// useBundledBinaries 将指向 mcp_tool/ 的服务器命令（如 "go run ./mcp_tool/..."）替换为
+// ./bin 下编译好的二进制，二进制缺失或源码更新时按需重新编译。编译失败时保留原命令。
+func useBundledBinaries(config *mcp.Config, debug debugChannels) {
+	binDir, err := filepath.Abs(servers.DefaultBinDir)
+	if err != nil {
+		return
+	}
+
+	for name, server := range config.MCPServers {
+		if server.Remote() || server.Type == "docker" {
+			continue
+		}
+		bundled, args, ok := servers.Resolve(server.Command, server.Args)
+		if !ok {
+			continue
+		}
+
+		if servers.Stale(bundled, binDir) {
+			render.Stdout.Note("build", "building bundled MCP server %s", bundled.Name)
+			if _, err := servers.Build(bundled, binDir); err != nil {
+				render.Stdout.Error(fmt.Errorf("%w, falling back to %s", err, server.Command))
+				continue
+			}
+		}
+
+		debug.logf(debugMCP, "Using bundled binary for MCP server %s", name)
+		server.Command = servers.Binary(bundled, binDir)
+		server.Args = args
+		config.MCPServers[name] = server
+	}
+}
@@ -2,24 +2,23 @@ package main
 
 import (
 	"context"
-	"log"
 
 	"github.com/ollama/ollama/api"
 )
 
 // runInference 调用 Ollama 进行推理
 func (a *Agent) runInference(ctx context.Context, conversation []api.Message, tools []api.Tool) (api.Message, error) {
-	if a.verbose {
-		log.Printf("Making API call to Ollama with model: %s and %d tools", a.model, len(tools))
-	}
+	return a.runInferenceWithModel(ctx, a.model, conversation, tools)
+}
 
-	a.InputLock()
-	defer a.InputUnLock()
+// runInferenceWithModel 使用指定模型调用 Ollama 进行推理
+func (a *Agent) runInferenceWithModel(ctx context.Context, model string, conversation []api.Message, tools []api.Tool) (api.Message, error) {
+	a.debug.logf(debugLLM, "Making API call to Ollama with model: %s and %d tools", model, len(tools))
 
 	// 禁用流式传输以简化响应处理
 	stream := false
 	req := &api.ChatRequest{
-		Model:    a.model,
+		Model:    model,
 		Messages: conversation,
 		Tools:    tools,
 		Stream:   &stream,
@@ -30,21 +29,20 @@ func (a *Agent) runInference(ctx context.Context, conversation []api.Message, to
 	// 响应回调函数
 	respFunc := func(resp api.ChatResponse) error {
 		responseMessage = resp.Message
+		if resp.Done {
+			a.usage.recordInference(resp.Metrics)
+		}
 		return nil
 	}
 
 	// 执行聊天请求
-	err := a.ollamaClient.Chat(ctx, req, respFunc)
+	err := a.chat(ctx, req, respFunc)
 	if err != nil {
-		if a.verbose {
-			log.Printf("API call failed: %v", err)
-		}
+		a.debug.logf(debugLLM, "API call failed: %v", err)
 		return api.Message{}, err
 	}
 
-	if a.verbose {
-		log.Printf("API call successful, response received")
-	}
+	a.debug.logf(debugLLM, "API call successful, response received")
 
 	return responseMessage, nil
 }
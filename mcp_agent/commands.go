@@ -0,0 +1,295 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/ollama/ollama/api"
+)
+
+// commandHelp 是 /help 显示的命令列表
+var commandHelp = []struct{ usage, description string }{
+	{"/help", "show this list"},
+	{"/tools", "list the tools available to the model"},
+	{"/servers", "show MCP server details"},
+	{"/status", "check the MCP servers"},
+	{"/model [name] [--resend]", "list the models or switch, optionally sending the system prompt again"},
+	{"/lang [en|zh|auto]", "show or set the reply language of this conversation"},
+	{"/clear", "start a new conversation"},
+	{"/save [file]", "save the session now, or write the transcript to a file"},
+	{"/verbose [on|off]", "toggle all debug logging"},
+	{"/debug <channels>|on|off", "enable debug logging for channels, e.g. llm,tools"},
+	{"/trace [on|off]", "trace MCP JSON-RPC traffic"},
+	{"/prompts", "list the prompt templates of the MCP servers"},
+	{"/prompt <server> <name> [key=value ...]", "send a rendered prompt template"},
+	{"/history search <query>", "search saved sessions and resume one"},
+	{"/last-result [file]", "show or save the full result of the last tool call"},
+	{"/timeline", "show the tool calls of the last turn with their time and output size"},
+	{"/share [file]", "write a session bundle to share"},
+}
+
+// handleCommand 处理以 "/" 开头的命令，返回更新后的对话，以及是否需要将对话发送给模型
+// （如注入了渲染后的提示词模板）
+func (a *Agent) handleCommand(ctx context.Context, input string, conversation []api.Message, tools []api.Tool) ([]api.Message, bool, error) {
+	fields := strings.Fields(input)
+	switch fields[0] {
+	case "/help":
+		for _, c := range commandHelp {
+			fmt.Printf("  %-42s %s\n", render.Stdout.Paint(render.Cyan, c.usage), c.description)
+		}
+		return conversation, false, nil
+	case "/tools":
+		a.printTools(tools)
+		return conversation, false, nil
+	case "/model":
+		updated, err := a.modelCommand(ctx, fields[1:], conversation)
+		return updated, false, err
+	case "/lang":
+		if len(fields) == 1 {
+			fmt.Printf("reply language: %s\n", cmp.Or(a.language(), langAuto))
+			return conversation, false, nil
+		}
+		lang, err := parseReplyLang(fields[1])
+		if err != nil {
+			return conversation, false, err
+		}
+		a.replyLang = cmp.Or(lang, langAuto)
+		fmt.Printf("reply language: %s\n", a.replyLang)
+		return conversation, false, nil
+	case "/clear":
+		// 之后的对话保存为新的会话
+		a.saved = nil
+		a.lastToolName, a.lastToolResult = "", ""
+		fmt.Println("conversation cleared")
+		return nil, false, nil
+	case "/save":
+		if len(fields) > 1 {
+			if err := os.WriteFile(fields[1], []byte(renderTranscript(conversation)), 0o644); err != nil {
+				return conversation, false, err
+			}
+			fmt.Printf("transcript written to %s\n", fields[1])
+			return conversation, false, nil
+		}
+		if err := a.saveSession(ctx, conversation); err != nil {
+			return conversation, false, err
+		}
+		if a.saved == nil {
+			return conversation, false, fmt.Errorf("nothing to save yet")
+		}
+		fmt.Printf("session saved as %s (resume with --resume %s)\n", a.saved.ID, a.saved.ID)
+		return conversation, false, nil
+	case "/verbose":
+		on, err := parseToggle(fields, a.debug.any())
+		if err != nil {
+			return conversation, false, err
+		}
+		debug := make(debugChannels)
+		if on {
+			debug, _ = parseDebugChannels("all")
+		}
+		a.setDebug(debug)
+		fmt.Printf("verbose logging: %s\n", onOff(on))
+		return conversation, false, nil
+	case "/share":
+		path := fmt.Sprintf("mcp_agent_share_%s.tar.gz", time.Now().Format("20060102_150405"))
+		if len(fields) > 1 {
+			path = fields[1]
+		}
+		if err := a.writeShareBundle(path, conversation, tools); err != nil {
+			return conversation, false, err
+		}
+		fmt.Printf("session bundle written to %s (import with --import %s)\n", path, path)
+		return conversation, false, nil
+	case "/prompts":
+		return conversation, false, a.printPrompts(ctx)
+	case "/history":
+		if len(fields) < 3 || fields[1] != "search" {
+			return conversation, false, fmt.Errorf("usage: /history search <query>")
+		}
+		query := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/history"))
+		query = strings.TrimSpace(strings.TrimPrefix(query, "search"))
+		resumed, err := a.searchHistory(query)
+		if err != nil || resumed == nil {
+			return conversation, false, err
+		}
+		return resumed, false, nil
+	case "/status":
+		a.printStatus(ctx)
+		return conversation, false, nil
+	case "/servers":
+		a.printServers(ctx)
+		return conversation, false, nil
+	case "/timeline":
+		fmt.Println(a.timeline.render())
+		return conversation, false, nil
+	case "/last-result":
+		if a.lastToolName == "" {
+			return conversation, false, fmt.Errorf("no tool result yet")
+		}
+		if len(fields) > 1 {
+			if err := os.WriteFile(fields[1], []byte(a.lastToolResult), 0o644); err != nil {
+				return conversation, false, err
+			}
+			fmt.Printf("result of %s written to %s (%d chars)\n", a.lastToolName, fields[1], len(a.lastToolResult))
+			return conversation, false, nil
+		}
+		fmt.Printf("%s of %s (%d chars):\n%s\n", render.Stdout.Paint(render.Green, "result"), a.lastToolName, len(a.lastToolResult), a.lastToolResult)
+		return conversation, false, nil
+	case "/prompt":
+		if len(fields) < 3 {
+			return conversation, false, fmt.Errorf("usage: /prompt <server> <name> [key=value ...]")
+		}
+		messages, err := a.renderPrompt(ctx, fields[1], fields[2], fields[3:])
+		if err != nil {
+			return conversation, false, err
+		}
+		return append(conversation, messages...), true, nil
+	case "/debug":
+		// /debug on|off 切换全部通道，/debug llm,tools 只启用指定通道
+		debug := a.debug
+		if len(fields) > 1 && fields[1] != "on" && fields[1] != "off" {
+			channels, err := parseDebugChannels(fields[1])
+			if err != nil {
+				return conversation, false, err
+			}
+			debug = channels
+		} else {
+			on, err := parseToggle(fields, a.debug.any())
+			if err != nil {
+				return conversation, false, err
+			}
+			debug = make(debugChannels)
+			if on {
+				debug, _ = parseDebugChannels("all")
+			}
+		}
+		a.setDebug(debug)
+		fmt.Printf("debug logging: %s\n", debug)
+		return conversation, false, nil
+	case "/trace":
+		on, err := parseToggle(fields, a.client().Tracing())
+		if err != nil {
+			return conversation, false, err
+		}
+		if on {
+			a.client().SetTrace(os.Stderr)
+		} else {
+			a.client().SetTrace(nil)
+		}
+		fmt.Printf("MCP traffic tracing: %s\n", onOff(on))
+		return conversation, false, nil
+	default:
+		return conversation, false, fmt.Errorf("unknown command: %s (see /help)", fields[0])
+	}
+}
+
+// printTools 按服务器显示模型可用的工具及其描述的第一行
+func (a *Agent) printTools(tools []api.Tool) {
+	if len(tools) == 0 {
+		fmt.Println("No tools available")
+		return
+	}
+	for _, tool := range tools {
+		description, _, _ := strings.Cut(tool.Function.Description, "\n")
+		fmt.Printf("  %s %s %s\n", render.Stdout.Paint(render.Cyan, tool.Function.Name),
+			render.Stdout.Paint(render.Gray, "("+a.serverOf(tool.Function.Name)+")"), truncateString(description, 80))
+	}
+	fmt.Printf("%d tools\n", len(tools))
+}
+
+// printPrompts 显示所有 MCP 服务器提供的提示词模板
+func (a *Agent) printPrompts(ctx context.Context) error {
+	prompts, err := a.client().ListPrompts(ctx)
+	if err != nil {
+		return err
+	}
+	if len(prompts) == 0 {
+		fmt.Println("No prompts available")
+		return nil
+	}
+
+	for _, p := range prompts {
+		fmt.Print(render.Stdout.Paint(render.Cyan, p.Server+" "+p.Name))
+		for _, arg := range p.Arguments {
+			if arg.Required {
+				fmt.Printf(" %s=<required>", arg.Name)
+			} else {
+				fmt.Printf(" [%s=...]", arg.Name)
+			}
+		}
+		fmt.Println()
+		if p.Description != "" {
+			fmt.Printf("    %s\n", p.Description)
+		}
+	}
+	return nil
+}
+
+// renderPrompt 渲染提示词模板并转换为对话消息
+func (a *Agent) renderPrompt(ctx context.Context, server, name string, rawArgs []string) ([]api.Message, error) {
+	args := make(map[string]string)
+	for _, raw := range rawArgs {
+		key, value, ok := strings.Cut(raw, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid prompt argument %q, expected key=value", raw)
+		}
+		args[key] = value
+	}
+
+	result, err := a.client().GetPrompt(ctx, server, name, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []api.Message
+	for _, m := range result.Messages {
+		var content string
+		switch c := m.Content.(type) {
+		case *mcp.TextContent:
+			content = c.Text
+		case *mcp.EmbeddedResource:
+			if c.Resource != nil {
+				content = c.Resource.Text
+			}
+		}
+		if content == "" {
+			continue
+		}
+		messages = append(messages, api.Message{Role: string(m.Role), Content: content})
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("prompt %s returned no text messages", name)
+	}
+
+	a.debug.logf(debugMCP, "Rendered prompt %s from %s into %d messages", name, server, len(messages))
+	return messages, nil
+}
+
+// parseToggle 解析 "/cmd on|off" 形式的开关参数，省略参数时切换当前状态
+func parseToggle(fields []string, current bool) (bool, error) {
+	if len(fields) < 2 {
+		return !current, nil
+	}
+	switch fields[1] {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		return current, fmt.Errorf("usage: %s [on|off]", fields[0])
+	}
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
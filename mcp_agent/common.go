@@ -3,23 +3,17 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-)
-
-func (a *Agent) InputUnLock() {
-	a.inputLock.Lock()
-	defer a.inputLock.Unlock()
-	a.isProcessing = false
-}
+	"unicode/utf8"
 
-func (a *Agent) InputLock() {
-	a.inputLock.Lock()
-	defer a.inputLock.Unlock()
-	a.isProcessing = true
-}
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+)
 
-// formatToolResult 将工具返回结果格式化为字符串
+// formatToolResult 将工具返回结果格式化为字符串，MCP 工具的结构化结果以 JSON 形式返回给模型
 func formatToolResult(result interface{}) string {
 	switch v := result.(type) {
+	case *mcp.ToolResult:
+		return v.String()
 	case string:
 		return v
 	case []byte:
@@ -34,10 +28,41 @@ func formatToolResult(result interface{}) string {
 	}
 }
 
-// truncateString 截断字符串用于显示
+// printProgress 显示长时间运行的工具调用的进度
+func printProgress(progress, total float64, message string) {
+	render.Stdout.Progress(progress, total, message)
+}
+
+// defaultResultDisplay 是终端默认显示的工具结果长度
+const defaultResultDisplay = 500
+
+// printToolResult 显示工具结果，超出部分可以通过 /last-result 查看
+func (a *Agent) printToolResult(name, result string) {
+	shown, truncated := truncateRunes(result, a.resultDisplay)
+	if a.resultDisplay <= 0 || !truncated {
+		render.Stdout.ToolResult(result)
+		return
+	}
+	render.Stdout.ToolResult(shown)
+	render.Stdout.Hint("(%d of %d chars shown, /last-result shows the full result)", a.resultDisplay, utf8.RuneCountInString(result))
+}
+
+// truncateString 截断字符串用于显示，maxLen 按字符计
 func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+	if truncated, ok := truncateRunes(s, maxLen); ok {
+		return truncated + "... (truncated)"
+	}
+	return s
+}
+
+// truncateRunes 返回 s 的前 n 个字符，不会切断多字节字符；第二个返回值表示是否被截断
+func truncateRunes(s string, n int) (string, bool) {
+	count := 0
+	for i := range s {
+		if count == n {
+			return s[:i], true
+		}
+		count++
 	}
-	return s[:maxLen] + "... (truncated)"
+	return s, false
 }
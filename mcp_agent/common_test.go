@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s         string
+		n         int
+		want      string
+		truncated bool
+	}{
+		{"hello", 10, "hello", false},
+		{"hello", 5, "hello", false},
+		{"hello", 3, "hel", true},
+		{"你好世界", 2, "你好", true},
+		{"a你b好", 3, "a你b", true},
+		{"你好", 0, "", true},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		got, truncated := truncateRunes(tt.s, tt.n)
+		assert.Equal(t, tt.want, got, "%q[:%d]", tt.s, tt.n)
+		assert.Equal(t, tt.truncated, truncated, "%q[:%d]", tt.s, tt.n)
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "short", truncateString("short", 10))
+	assert.Equal(t, "修复会话... (truncated)", truncateString("修复会话保存的问题", 4))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/contextwindow"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// fitContext 将发送给模型的消息裁剪到上下文窗口内：保留系统提示和最近一轮的工具结果，
+// 先截短较早的工具结果，再省略最早的几轮对话。窗口的四分之一留给模型的回答，对话本身不被修改
+func (a *Agent) fitContext(messages []api.Message, tools []api.Tool) []api.Message {
+	window := a.numCtx
+	if window <= 0 {
+		window = contextwindow.DefaultSize
+	}
+	fitted, report := contextwindow.Fit(messages, tools, window*3/4)
+	if !report.Changed() {
+		a.contextOmitted = 0
+		return messages
+	}
+
+	a.debug.logf(debugLLM, "Context trimmed from ~%d to ~%d tokens: %d tool results shortened, %d messages omitted",
+		report.Tokens, report.Fitted, report.TruncatedResults, report.OmittedMessages)
+	if report.OmittedMessages > a.contextOmitted {
+		render.Stdout.Note("context", "the conversation (~%d tokens) exceeds the context window of %d tokens, %d earlier messages are no longer sent to the model (raise it with --num-ctx or /clear)",
+			report.Tokens, window, report.OmittedMessages)
+	}
+	a.contextOmitted = report.OmittedMessages
+	return fitted
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// processCPUTime 在不支持 getrusage 的平台上不测量 CPU 时间
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
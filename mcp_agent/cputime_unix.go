@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime 返回本进程及其已结束的子进程（如工具运行的命令）使用的 CPU 时间（用户态加内核态）
+func processCPUTime() (time.Duration, bool) {
+	var total time.Duration
+	for _, who := range []int{syscall.RUSAGE_SELF, syscall.RUSAGE_CHILDREN} {
+		var usage syscall.Rusage
+		if err := syscall.Getrusage(who, &usage); err != nil {
+			return 0, false
+		}
+		total += time.Duration(usage.Utime.Nano()) + time.Duration(usage.Stime.Nano())
+	}
+	return total, true
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 调试日志通道，通过 --debug=llm,tools 选择需要输出的日志
+const (
+	debugLLM   = "llm"   // 推理请求、响应和评审
+	debugTools = "tools" // 工具调用、结果和工具列表
+	debugMCP   = "mcp"   // MCP 服务器、配置、通知和 sampling 请求
+	debugUI    = "ui"    // 用户输入、命令和会话生命周期
+)
+
+var allDebugChannels = []string{debugLLM, debugTools, debugMCP, debugUI}
+
+// debugChannels 是启用的调试日志通道集合
+type debugChannels map[string]bool
+
+// parseDebugChannels 解析逗号分隔的通道列表，"all" 表示全部通道
+func parseDebugChannels(spec string) (debugChannels, error) {
+	channels := make(debugChannels)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+			continue
+		case name == "all":
+			for _, ch := range allDebugChannels {
+				channels[ch] = true
+			}
+		case isDebugChannel(name):
+			channels[name] = true
+		default:
+			return nil, fmt.Errorf("unknown debug channel %q, expected one of: %s, all", name, strings.Join(allDebugChannels, ", "))
+		}
+	}
+	return channels, nil
+}
+
+func isDebugChannel(name string) bool {
+	for _, ch := range allDebugChannels {
+		if ch == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (d debugChannels) enabled(channel string) bool {
+	return d[channel]
+}
+
+// any 判断是否启用了任意通道
+func (d debugChannels) any() bool {
+	return len(d) > 0
+}
+
+// logf 在通道启用时通过结构化日志输出一条调试日志
+func (d debugChannels) logf(channel, format string, args ...interface{}) {
+	if d.enabled(channel) {
+		slog.Debug(fmt.Sprintf(format, args...), "channel", channel)
+	}
+}
+
+func (d debugChannels) String() string {
+	if !d.any() {
+		return "off"
+	}
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// setDebug 在运行中切换调试通道。MCP 服务器日志在其他 goroutine 中到达，
+// 是否显示单独保存在 serverLogs 中
+func (a *agentCore) setDebug(debug debugChannels) {
+	a.debug = debug
+	a.serverLogs.Store(debug.enabled(debugMCP))
+	configureLogging(debug)
+}
+
+// configureLogging 根据启用的调试通道设置日志输出：启用时使用输出到 stderr 的结构化日志，
+// log 包的输出也会经过该 logger
+func configureLogging(debug debugChannels) {
+	if debug.any() {
+		handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})
+		slog.SetDefault(slog.New(handler))
+		slog.Debug("Debug logging enabled", "channels", debug.String())
+	} else {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+		log.SetOutput(os.Stdout)
+		log.SetFlags(0)
+		log.SetPrefix("")
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// diffContextLines 是 diff 中每个修改块前后保留的上下文行数
+const diffContextLines = 3
+
+// maxDiffCells 限制逐行比较的计算量 (行数乘积)，超出时将整个文件视为替换
+const maxDiffCells = 4_000_000
+
+// fileSnapshots 记录会话中被编辑文件在第一次修改前的内容，用于生成修改的 diff
+type fileSnapshots struct {
+	mu    sync.Mutex
+	files map[string]snapshot
+}
+
+type snapshot struct {
+	content string
+	existed bool
+}
+
+// record 在编辑前保存文件的原始内容，同一文件只保存第一次
+func (s *fileSnapshots) record(path string) {
+	path = expandPath(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = make(map[string]snapshot)
+	}
+	if _, ok := s.files[path]; ok {
+		return
+	}
+	content, err := os.ReadFile(path)
+	s.files[path] = snapshot{content: string(content), existed: err == nil}
+}
+
+// paths 返回记录的文件，按路径排序
+func (s *fileSnapshots) paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := make([]string, 0, len(s.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// restore 将记录的文件恢复到原始内容（原来不存在的文件被删除），然后清空记录
+func (s *fileSnapshots) restore() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for path, before := range s.files {
+		var err error
+		if before.existed {
+			err = os.WriteFile(path, []byte(before.content), 0644)
+		} else if err = os.Remove(path); errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.files = nil
+	return errors.Join(errs...)
+}
+
+// reset 清空记录
+func (s *fileSnapshots) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files = nil
+}
+
+// diffs 返回所有被编辑文件从原始内容到当前内容的 unified diff
+func (s *fileSnapshots) diffs() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths := make([]string, 0, len(s.files))
+	for path := range s.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		before := s.files[path]
+		after, err := os.ReadFile(path)
+		sb.WriteString(unifiedDiff(path, before.content, string(after), before.existed, err == nil))
+	}
+	return sb.String()
+}
+
+// unifiedDiff 生成两个版本之间的 unified diff，内容相同时返回空字符串
+func unifiedDiff(path, before, after string, beforeExists, afterExists bool) string {
+	if before == after && beforeExists == afterExists {
+		return ""
+	}
+
+	oldName, newName := "a/"+strings.TrimPrefix(path, "/"), "b/"+strings.TrimPrefix(path, "/")
+	if !beforeExists {
+		oldName = "/dev/null"
+	}
+	if !afterExists {
+		newName = "/dev/null"
+	}
+
+	oldLines, newLines := splitLines(before), splitLines(after)
+	ops := diffLines(oldLines, newLines)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops) {
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", h.oldStart, h.oldCount, h.newStart, h.newCount)
+		for _, op := range h.ops {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffOp 是一行的比较结果，kind 为 ' '、'-' 或 '+'
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines 基于最长公共子序列逐行比较
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+type hunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	ops                []diffOp
+}
+
+// hunks 将比较结果按修改位置分组，每组保留前后若干行上下文
+func hunks(ops []diffOp) []hunk {
+	var result []hunk
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// 向前包含上下文
+		start := max(i-diffContextLines, 0)
+		for k := start; k < i; k++ {
+			if ops[k].kind != ' ' {
+				start = k + 1
+			}
+		}
+		h := hunk{oldStart: oldLine - (i - start), newStart: newLine - (i - start)}
+
+		// 向后扩展，直到连续的未修改行超过两倍上下文
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := 0
+			for end+run < len(ops) && ops[end+run].kind == ' ' {
+				run++
+			}
+			if end+run == len(ops) || run > 2*diffContextLines {
+				end += min(run, diffContextLines)
+				break
+			}
+			end += run
+		}
+
+		h.ops = ops[start:end]
+		for _, op := range h.ops {
+			if op.kind != '+' {
+				h.oldCount++
+			}
+			if op.kind != '-' {
+				h.newCount++
+			}
+		}
+		// unified diff 中空的一侧起始行号为修改位置的前一行
+		if h.oldCount == 0 {
+			h.oldStart--
+		}
+		if h.newCount == 0 {
+			h.newStart--
+		}
+		result = append(result, h)
+
+		for _, op := range ops[i:end] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return result
+}
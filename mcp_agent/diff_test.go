@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lines 返回 n 行互不相同的文本
+func lines(n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	return sb.String()
+}
+
+func TestUnifiedDiffUnchanged(t *testing.T) {
+	assert.Empty(t, unifiedDiff("a.go", "x\n", "x\n", true, true))
+}
+
+func TestUnifiedDiffSingleChange(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\n"
+	after := "a\nb\nc\nD\ne\nf\ng\n"
+
+	want := "--- a/a.go\n+++ b/a.go\n" +
+		"@@ -1,7 +1,7 @@\n" +
+		" a\n b\n c\n-d\n+D\n e\n f\n g\n"
+	assert.Equal(t, want, unifiedDiff("a.go", before, after, true, true))
+}
+
+func TestUnifiedDiffSeparateHunks(t *testing.T) {
+	before := lines(20)
+	old := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	changed := append([]string(nil), old...)
+	changed[1] = "second"
+	changed[17] = "eighteenth"
+	after := strings.Join(changed, "\n") + "\n"
+
+	diff := unifiedDiff("/tmp/f.txt", before, after, true, true)
+	assert.Contains(t, diff, "--- a/tmp/f.txt\n+++ b/tmp/f.txt\n")
+	assert.Contains(t, diff, "@@ -1,5 +1,5 @@\n "+old[0]+"\n-"+old[1]+"\n+second\n")
+	assert.Contains(t, diff, "@@ -15,6 +15,6 @@\n "+old[14]+"\n")
+	assert.Contains(t, diff, "-"+old[17]+"\n+eighteenth\n")
+	assert.Equal(t, 2, strings.Count(diff, "@@ -"))
+}
+
+func TestUnifiedDiffNearbyChangesMerge(t *testing.T) {
+	before := lines(12)
+	old := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	changed := append([]string(nil), old...)
+	changed[2] = "three"
+	changed[8] = "nine"
+	after := strings.Join(changed, "\n") + "\n"
+
+	diff := unifiedDiff("f", before, after, true, true)
+	assert.Equal(t, 1, strings.Count(diff, "@@ -"))
+	assert.Contains(t, diff, "@@ -1,12 +1,12 @@\n")
+}
+
+func TestUnifiedDiffInsertAndDelete(t *testing.T) {
+	diff := unifiedDiff("f", "a\nb\n", "a\nx\nb\n", true, true)
+	assert.Equal(t, "--- a/f\n+++ b/f\n@@ -1,2 +1,3 @@\n a\n+x\n b\n", diff)
+
+	diff = unifiedDiff("f", "a\nb\nc\n", "a\nc\n", true, true)
+	assert.Equal(t, "--- a/f\n+++ b/f\n@@ -1,3 +1,2 @@\n a\n-b\n c\n", diff)
+}
+
+func TestUnifiedDiffCreatedAndDeleted(t *testing.T) {
+	diff := unifiedDiff("f", "", "a\nb\n", false, true)
+	assert.Equal(t, "--- /dev/null\n+++ b/f\n@@ -0,0 +1,2 @@\n+a\n+b\n", diff)
+
+	diff = unifiedDiff("f", "a\n", "", true, false)
+	assert.Equal(t, "--- a/f\n+++ /dev/null\n@@ -1,1 +0,0 @@\n-a\n", diff)
+
+	// 创建一个空文件也要体现在 diff 中
+	diff = unifiedDiff("f", "", "", false, true)
+	assert.Equal(t, "--- /dev/null\n+++ b/f\n", diff)
+}
+
+func TestDiffLinesLargeInputReplacesWholeFile(t *testing.T) {
+	a := strings.Split(strings.TrimSuffix(lines(2001), "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(lines(2001), "\n"), "\n")
+	ops := diffLines(a, b)
+	require.Len(t, ops, len(a)+len(b))
+	assert.Equal(t, byte('-'), ops[0].kind)
+	assert.Equal(t, byte('+'), ops[len(ops)-1].kind)
+}
+
+func TestFileSnapshotsDiffs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(path, []byte("one\ntwo\n"), 0644))
+
+	var s fileSnapshots
+	s.record(path)
+	require.NoError(t, os.WriteFile(path, []byte("one\n2\n"), 0644))
+	// 只保存第一次修改前的内容
+	s.record(path)
+
+	diff := s.diffs()
+	assert.Contains(t, diff, "-two\n+2\n")
+	assert.Equal(t, []string{path}, s.paths())
+}
+
+func TestFileSnapshotsRestore(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.txt")
+	created := filepath.Join(dir, "created.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("original\n"), 0644))
+
+	var s fileSnapshots
+	s.record(existing)
+	s.record(created)
+	require.NoError(t, os.WriteFile(existing, []byte("edited\n"), 0644))
+	require.NoError(t, os.WriteFile(created, []byte("new\n"), 0644))
+	assert.Contains(t, s.diffs(), "--- /dev/null\n+++ b/"+strings.TrimPrefix(created, "/"))
+
+	require.NoError(t, s.restore())
+
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "original\n", string(content))
+	_, err = os.Stat(created)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Empty(t, s.paths())
+}
+
+func TestFileSnapshotsRestoreMissingFile(t *testing.T) {
+	// 记录后从未创建的文件，恢复时不应报错
+	var s fileSnapshots
+	s.record(filepath.Join(t.TempDir(), "never.txt"))
+	assert.NoError(t, s.restore())
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/contextwindow"
+	"github.com/ollama/ollama/api"
+)
+
+// runDumpPrompt 执行 dump-prompt 子命令：以 JSON 输出当前工作区第一次推理时发送给 Ollama 的完整请求
+// （仓库地图等系统消息、工具定义和选项），用于检查和调整模型实际看到的内容。
+// prompt 不为空时作为用户消息加入，并按它选择工具；各部分的大小估算输出到标准错误。返回进程退出码
+func runDumpPrompt(ctx context.Context, agent *Agent, prompt string) int {
+	agent.headless = true
+
+	tools, err := agent.loadTools(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitToolError
+	}
+
+	var conversation []api.Message
+	if prompt != "" {
+		conversation = append(conversation, api.Message{Role: "user", Content: prompt})
+		agent.retrieveContext(ctx, conversation)
+		agent.toolSelect.startTurn()
+		tools = agent.selectTools(ctx, conversation, tools)
+	}
+	messages := agent.fitContext(agent.withContext(conversation), tools)
+
+	req := &api.ChatRequest{
+		Model:    agent.model,
+		Messages: messages,
+		Tools:    tools,
+		Stream:   &agent.stream,
+	}
+	agent.prepareRequest(req)
+
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitModelError
+	}
+	fmt.Println(string(data))
+
+	fmt.Fprintf(os.Stderr, "%d messages (~%d tokens), %d tools (~%d tokens)\n",
+		len(messages), contextwindow.Estimate(messages, nil), len(tools), contextwindow.Estimate(nil, tools))
+	return exitSuccess
+}
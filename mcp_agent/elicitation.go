@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// elicitField 是 elicitation 请求中需要用户填写的一个字段
+type elicitField struct {
+	name        string
+	title       string
+	description string
+	kind        string // string, number, integer, boolean
+	enum        []string
+	def         any
+	required    bool
+}
+
+// handleElicitation 处理 MCP 服务器在工具调用过程中发起的 elicitation/create 请求，
+// 通过终端向用户询问服务器需要的信息
+func (a *Agent) handleElicitation(ctx context.Context, server string, params *mcp.ElicitParams) (*mcp.ElicitResult, error) {
+	a.debug.logf(debugMCP, "Elicitation request from %s: %s", server, params.Message)
+
+	// 非交互模式下无法询问用户
+	if a.headless {
+		return &mcp.ElicitResult{Action: "cancel"}, nil
+	}
+
+	render.Stdout.Line(render.Magenta, "input", "server %s asks: %s", server, params.Message)
+
+	fields, err := parseElicitSchema(params.RequestedSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	defer a.input.pause()()
+
+	answer := false
+	if err := survey.AskOne(&survey.Confirm{Message: "Provide this information?", Default: true}, &answer); err != nil {
+		return &mcp.ElicitResult{Action: "cancel"}, nil
+	}
+	if !answer {
+		return &mcp.ElicitResult{Action: "decline"}, nil
+	}
+
+	content := make(map[string]any, len(fields))
+	for _, field := range fields {
+		value, err := askElicitField(field)
+		if errors.Is(err, terminal.InterruptErr) {
+			return &mcp.ElicitResult{Action: "cancel"}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			content[field.name] = value
+		}
+	}
+	return &mcp.ElicitResult{Action: "accept", Content: content}, nil
+}
+
+// parseElicitSchema 解析请求的 JSON schema，elicitation 只允许一层简单类型的属性
+func parseElicitSchema(schema any) ([]elicitField, error) {
+	object, ok := schema.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unsupported elicitation schema %T", schema)
+	}
+	properties, _ := object["properties"].(map[string]any)
+
+	required := make(map[string]bool)
+	if list, ok := object["required"].([]any); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	fields := make([]elicitField, 0, len(properties))
+	for name, raw := range properties {
+		property, _ := raw.(map[string]any)
+		field := elicitField{name: name, title: name, kind: "string", required: required[name], def: property["default"]}
+		if title, ok := property["title"].(string); ok && title != "" {
+			field.title = title
+		}
+		field.description, _ = property["description"].(string)
+		if kind, ok := property["type"].(string); ok {
+			field.kind = kind
+		}
+		if enum, ok := property["enum"].([]any); ok {
+			for _, v := range enum {
+				field.enum = append(field.enum, fmt.Sprint(v))
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	// 必填字段在前，其余按名称排序
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].required != fields[j].required {
+			return fields[i].required
+		}
+		return fields[i].name < fields[j].name
+	})
+	return fields, nil
+}
+
+// askElicitField 询问单个字段的值，未填写的可选字段返回 nil
+func askElicitField(field elicitField) (any, error) {
+	message := field.title
+	if !field.required {
+		message += " (optional)"
+	}
+	message += ":"
+
+	switch {
+	case field.kind == "boolean":
+		value, _ := field.def.(bool)
+		err := survey.AskOne(&survey.Confirm{Message: message, Default: value, Help: field.description}, &value)
+		return value, err
+
+	case len(field.enum) > 0:
+		options := field.enum
+		if !field.required {
+			options = append([]string{""}, options...)
+		}
+		var value string
+		prompt := &survey.Select{Message: message, Options: options, Help: field.description}
+		if def := fmt.Sprint(field.def); field.def != nil && slices.Contains(options, def) {
+			prompt.Default = def
+		}
+		if err := survey.AskOne(prompt, &value); err != nil || value == "" {
+			return nil, err
+		}
+		return value, nil
+
+	default:
+		var value string
+		prompt := &survey.Input{Message: message, Help: field.description}
+		if field.def != nil {
+			prompt.Default = fmt.Sprint(field.def)
+		}
+		var opts []survey.AskOpt
+		if field.required {
+			opts = append(opts, survey.WithValidator(survey.Required))
+		}
+		if field.kind == "number" || field.kind == "integer" {
+			opts = append(opts, survey.WithValidator(numberValidator(field.kind)))
+		}
+		if err := survey.AskOne(prompt, &value, opts...); err != nil || value == "" {
+			return nil, err
+		}
+		switch field.kind {
+		case "integer":
+			return strconv.ParseInt(value, 10, 64)
+		case "number":
+			return strconv.ParseFloat(value, 64)
+		}
+		return value, nil
+	}
+}
+
+// numberValidator 校验输入是否为数字，空值交给 Required 校验
+func numberValidator(kind string) survey.Validator {
+	return func(ans interface{}) error {
+		s, _ := ans.(string)
+		if s == "" {
+			return nil
+		}
+		var err error
+		if kind == "integer" {
+			_, err = strconv.ParseInt(s, 10, 64)
+		} else {
+			_, err = strconv.ParseFloat(s, 64)
+		}
+		if err != nil {
+			return fmt.Errorf("please enter a valid %s", kind)
+		}
+		return nil
+	}
+}
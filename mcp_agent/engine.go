@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// turnState 是对话状态机的状态。一轮对话的流转为：
+//
+//	AwaitingUser → Inferring ⇄ ExecutingTools
+//	                  ↓
+//	             Summarizing → AwaitingUser
+//
+// 评审不通过时从 Summarizing 回到 Inferring 继续修改
+type turnState int
+
+const (
+	stateAwaitingUser   turnState = iota // 等待用户输入
+	stateInferring                       // 等待模型回答
+	stateExecutingTools                  // 执行模型请求的工具调用
+	stateSummarizing                     // 模型已给出最终回答，进行评审和保存
+)
+
+// defaultParallelTools 是同时执行的只读工具调用的默认上限
+const defaultParallelTools = 4
+
+// defaultMaxToolIterations 是一轮对话中连续工具调用轮数的默认上限，避免模型无休止地调用工具
+const defaultMaxToolIterations = 25
+
+func (s turnState) String() string {
+	switch s {
+	case stateAwaitingUser:
+		return "awaiting_user"
+	case stateInferring:
+		return "inferring"
+	case stateExecutingTools:
+		return "executing_tools"
+	case stateSummarizing:
+		return "summarizing"
+	}
+	return fmt.Sprintf("state(%d)", int(s))
+}
+
+// turnEvent 是对话引擎发出的事件。终端、TUI、Web 界面和 JSONL 输出订阅同一组事件，
+// 共用同一个引擎
+type turnEvent interface {
+	turnEvent()
+}
+
+// stateEvent 在状态变化时发出
+type stateEvent struct {
+	from, to turnState
+}
+
+// messageEvent 在收到模型的完整回答后发出，streamed 表示内容已经以流式输出
+type messageEvent struct {
+	message  api.Message
+	streamed bool
+}
+
+// toolCallEvent 在执行工具调用之前发出
+type toolCallEvent struct {
+	call api.ToolCall
+}
+
+// toolResultEvent 在工具调用结束后发出，result 是发送给模型的结果文本
+type toolResultEvent struct {
+	call   api.ToolCall
+	server string
+	result string
+	err    error
+	usage  toolUsage
+}
+
+func (stateEvent) turnEvent()      {}
+func (messageEvent) turnEvent()    {}
+func (toolCallEvent) turnEvent()   {}
+func (toolResultEvent) turnEvent() {}
+
+// subscribe 注册会话事件的处理函数，事件在引擎所在的 goroutine 中依次传递
+func (s *Session) subscribe(fn func(turnEvent)) {
+	s.observers = append(s.observers, fn)
+}
+
+func (s *Session) emit(event turnEvent) {
+	for _, fn := range s.observers {
+		fn(event)
+	}
+}
+
+// setState 切换状态机的状态并发出 stateEvent
+func (s *Session) setState(to turnState) {
+	if s.state == to {
+		return
+	}
+	from := s.state
+	s.state = to
+	s.emit(stateEvent{from: from, to: to})
+}
+
+// printEvent 在终端显示对话事件
+func (a *Agent) printEvent(event turnEvent) {
+	switch ev := event.(type) {
+	case stateEvent:
+		a.debug.logf(debugUI, "State %s -> %s", ev.from, ev.to)
+	case messageEvent:
+		if !ev.streamed && ev.message.Content != "" {
+			render.Stdout.Assistant(ev.message.Content)
+		}
+	case toolCallEvent:
+		argsJSON, _ := json.Marshal(ev.call.Function.Arguments)
+		a.debug.logf(debugTools, "Tool use detected: %s with input: %s", ev.call.Function.Name, string(argsJSON))
+		render.Stdout.ToolCall(ev.call.Function.Name, ev.call.Function.Arguments)
+	case toolResultEvent:
+		if ev.err != nil {
+			render.Stdout.Error(ev.err)
+			a.debug.logf(debugTools, "Tool execution failed: %v", ev.err)
+			return
+		}
+		a.printToolResult(ev.call.Function.Name, ev.result)
+		a.debug.logf(debugTools, "Tool execution successful, result length: %d chars", len(ev.result))
+	}
+}
+
+// processTurn 运行状态机：推理，执行模型请求的工具调用，再次推理，直到模型不再使用工具，返回更新后的对话
+func (a *Agent) processTurn(ctx context.Context, conversation []api.Message, tools []api.Tool) ([]api.Message, error) {
+	a.timeline.reset()
+	a.repeats.reset()
+
+	a.retrieveContext(ctx, conversation)
+
+	// 工具较多或模型较小时只发送与问题最相关的工具
+	a.toolSelect.startTurn()
+	allTools := tools
+
+	var message api.Message
+	first := true
+	iterations := 0
+	a.toolLimitReached = false
+	a.setState(stateInferring)
+	for {
+		switch a.state {
+		case stateInferring:
+			if a.toolLimitReached {
+				// 达到工具调用轮数上限后不再提供工具，让模型总结后把控制权交还给用户
+				tools = nil
+			} else {
+				if !first {
+					allTools = a.refreshTools(ctx, allTools)
+				}
+				tools = a.selectTools(ctx, conversation, allTools)
+			}
+
+			// --stream 时每次推理都流式输出，包括工具调用之后的回答
+			streamed := a.stream
+			var err error
+			if message, err = a.infer(ctx, conversation, tools, streamed); err != nil {
+				if errors.Is(err, errInterrupted) {
+					conversation = a.keepPartial(conversation, message)
+				}
+				return conversation, err
+			}
+			first = false
+			if a.toolLimitReached {
+				message.ToolCalls = nil
+			}
+			conversation = append(conversation, message)
+			a.emit(messageEvent{message: message, streamed: streamed})
+
+			if len(message.ToolCalls) > 0 {
+				a.setState(stateExecutingTools)
+			} else {
+				a.setState(stateSummarizing)
+			}
+
+		case stateExecutingTools:
+			a.debug.logf(debugTools, "Processing %d tool calls from Ollama", len(message.ToolCalls))
+			conversation = a.executeToolCalls(ctx, conversation, message.ToolCalls)
+			iterations++
+			if a.maxRepeatedCalls > 0 && a.repeats.worst >= a.maxRepeatedCalls {
+				render.Stdout.Warning("tools", "the model repeated the same tool call %d times (--max-repeated-calls), stopping", a.repeats.worst)
+				return conversation, errToolLoop
+			}
+			if a.maxToolIterations > 0 && iterations >= a.maxToolIterations {
+				a.stopToolLoop()
+			}
+			a.debug.logf(debugLLM, "Sending tool results back to Ollama")
+			a.setState(stateInferring)
+
+		default:
+			return conversation, nil
+		}
+	}
+}
+
+// infer 将对话发送给模型并返回模型的回答。Ctrl-C 取消请求时返回 errInterrupted，
+// 流式输出时同时返回已经收到的部分回答
+func (a *Agent) infer(ctx context.Context, conversation []api.Message, tools []api.Tool, streamed bool) (api.Message, error) {
+	inferCtx, stop := interruptible(ctx)
+	var message api.Message
+	var err error
+	if streamed {
+		message, err = a.runInferenceStreaming(inferCtx, a.fitContext(a.withContext(conversation), tools), tools)
+		if err != nil {
+			a.debug.logf(debugLLM, "Error during streaming inference: %v", err)
+		}
+	} else {
+		message, err = a.runInference(inferCtx, a.fitContext(a.withContext(conversation), tools), tools)
+		if err != nil {
+			a.debug.logf(debugLLM, "Error during inference: %v", err)
+		}
+	}
+	if stop() && err != nil {
+		return message, errInterrupted
+	}
+	return message, err
+}
+
+// keepPartial 将被中断的回答中已经收到的文本加入对话，未完成的工具调用被丢弃
+func (a *Agent) keepPartial(conversation []api.Message, message api.Message) []api.Message {
+	if message.Content == "" {
+		render.Stdout.Note("cancelled", "request cancelled")
+		return conversation
+	}
+	render.Stdout.Note("cancelled", "request cancelled, the partial response is kept in the conversation")
+	return append(conversation, api.Message{Role: "assistant", Content: message.Content})
+}
+
+// toolOutcome 是一个工具调用的结果和资源使用
+type toolOutcome struct {
+	message api.Message
+	err     error
+	start   time.Time
+	usage   toolUsage
+}
+
+// executeToolCalls 执行一次回答中的所有工具调用（内置工具或 MCP 工具），结果按调用顺序加入对话。
+// 连续的只读调用最多 parallelTools 个同时执行，其余调用可能需要确认或会修改文件，依次执行。
+// 这一轮中已经执行过的相同调用不再执行（--max-repeated-calls）
+func (a *Agent) executeToolCalls(ctx context.Context, conversation []api.Message, toolCalls []api.ToolCall) []api.Message {
+	for i := 0; i < len(toolCalls); {
+		if a.maxRepeatedCalls > 0 {
+			if previous, ok := a.repeats.lookup(toolCalls[i]); ok {
+				conversation = a.repeatToolCall(conversation, toolCalls[i], previous)
+				i++
+				continue
+			}
+		}
+		j := i + 1
+		if a.parallelTools > 1 && a.readOnlyTool(toolCalls[i].Function.Name) {
+			for j < len(toolCalls) && a.readOnlyTool(toolCalls[j].Function.Name) && !a.repeats.seen(toolCalls[j]) {
+				j++
+			}
+		}
+		conversation = a.executeBatch(ctx, conversation, toolCalls[i:j])
+		i = j
+	}
+	return conversation
+}
+
+// readOnlyTool 判断工具是否可以与其他调用同时执行：内置工具，以及配置中 autoApprove 的非编辑工具，
+// 它们不需要确认，也不修改文件
+func (a *Agent) readOnlyTool(name string) bool {
+	switch name {
+	case toolListResources, toolReadResource, toolSearchTools:
+		return true
+	}
+	return a.client() != nil && a.client().AutoApproved(name) && !a.isEditTool(name)
+}
+
+// executeBatch 同时执行一组工具调用，并发数不超过 parallelTools，全部结束后按顺序处理结果
+func (a *Agent) executeBatch(ctx context.Context, conversation []api.Message, toolCalls []api.ToolCall) []api.Message {
+	for _, toolCall := range toolCalls {
+		a.startToolCall(toolCall)
+	}
+	if len(toolCalls) > 1 {
+		a.debug.logf(debugTools, "Running %d tool calls in parallel", len(toolCalls))
+	}
+
+	outcomes := make([]toolOutcome, len(toolCalls))
+	slots := make(chan struct{}, max(1, a.parallelTools))
+	var wg sync.WaitGroup
+	for i, toolCall := range toolCalls {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			outcomes[i] = a.runMeasured(ctx, toolCall)
+		}()
+	}
+	wg.Wait()
+
+	for i, toolCall := range toolCalls {
+		conversation = a.finishToolCall(ctx, conversation, toolCall, outcomes[i])
+	}
+	return conversation
+}
+
+// startToolCall 在执行工具调用之前发出事件，编辑类工具记录文件的原始内容
+func (a *Agent) startToolCall(toolCall api.ToolCall) {
+	a.emit(toolCallEvent{call: toolCall})
+
+	// 编辑前记录文件原始内容，用于 /share 生成 diff（使用副本时记录工作区中的文件，通过校验后才会变化）
+	// --auto 模式下同时记录这一轮开始前的内容，校验失败时恢复
+	if a.isEditTool(toolCall.Function.Name) {
+		path, _ := toolCall.Function.Arguments["path"].(string)
+		if path, err := a.toolPath(path); err == nil {
+			if a.staging != nil {
+				a.snapshots.record(a.staging.workspacePath(path))
+			} else {
+				a.snapshots.record(path)
+			}
+			if a.auto {
+				a.turnEdits.record(path)
+			}
+		}
+	}
+}
+
+// runMeasured 执行工具调用并测量资源使用，可以在多个 goroutine 中同时调用
+func (a *Agent) runMeasured(ctx context.Context, toolCall api.ToolCall) toolOutcome {
+	start := time.Now()
+	measure := measureTool()
+	message, err := a.runToolCall(ctx, toolCall)
+	return toolOutcome{message: message, err: err, start: start, usage: measure(message)}
+}
+
+// finishToolCall 记录工具调用的结果并将其加入对话，编辑类工具执行后校验文件
+func (a *Agent) finishToolCall(ctx context.Context, conversation []api.Message, toolCall api.ToolCall, outcome toolOutcome) []api.Message {
+	toolMessage, err, usage := outcome.message, outcome.err, outcome.usage
+	server := a.serverOf(toolCall.Function.Name)
+	a.timeline.record(toolCall.Function.Name, server, outcome.start, usage, err)
+	a.usage.recordToolCall(toolCall.Function.Name, err != nil)
+	a.lastToolErr = err
+
+	if err != nil {
+		toolMessage = api.Message{Role: "tool", Content: fmt.Sprintf("Error: %v", err), ToolName: toolCall.Function.Name, ToolCallID: toolCall.ID}
+	} else {
+		// 完整结果供 /last-result 查看
+		a.lastToolName, a.lastToolResult = toolCall.Function.Name, toolMessage.Content
+	}
+	a.emit(toolResultEvent{call: toolCall, server: server, result: toolMessage.Content, err: err, usage: usage})
+
+	// 编辑类工具执行后做语法校验，错误附加在工具结果之后反馈给模型
+	var note string
+	if err == nil && a.isEditTool(toolCall.Function.Name) {
+		if path, ok := toolCall.Function.Arguments["path"].(string); ok {
+			if resolved, pathErr := a.toolPath(path); pathErr != nil {
+				a.debug.logf(debugTools, "Not verifying %s: %v", path, pathErr)
+			} else if verifyErr := verifyEditedFile(resolved); verifyErr != nil {
+				a.lastToolErr = verifyErr
+				render.Stdout.Errorf("verify", "%s", verifyErr.Error())
+				toolMessage.Content += fmt.Sprintf("\n\nVerification failed after editing %s, please fix the syntax errors:\n%v", path, verifyErr)
+			} else {
+				a.debug.logf(debugTools, "Verification passed for %s", resolved)
+				// 小模型经常忘记 import，语法正确的 Go 文件自动整理导入并告知模型
+				if a.autoImports && strings.EqualFold(filepath.Ext(resolved), ".go") {
+					note = a.fixImports(ctx, resolved)
+				}
+			}
+		}
+	}
+
+	conversation = append(conversation, toolMessage)
+	// 失败的调用不记录，之后相同的调用重新执行，不会重复返回错误
+	if a.maxRepeatedCalls > 0 && err == nil {
+		a.repeats.record(toolCall, toolMessage, !a.readOnlyTool(toolCall.Function.Name))
+	}
+	if note != "" {
+		conversation = append(conversation, api.Message{Role: "system", Content: note})
+	}
+	return conversation
+}
+
+// runToolCall 执行工具调用并将结果转换为工具消息，工具返回的图片作为多模态输入附加。
+// 工具或结果处理中的 panic 被转换为错误，会话继续进行
+func (a *Agent) runToolCall(ctx context.Context, toolCall api.ToolCall) (message api.Message, err error) {
+	name := toolCall.Function.Name
+	defer func() {
+		if r := recover(); r != nil {
+			message, err = api.Message{}, a.toolPanic(name, r)
+		}
+	}()
+
+	result, err := a.callTool(ctx, name, toolCall.Function.Arguments)
+	if err != nil {
+		return api.Message{}, err
+	}
+	message = api.Message{
+		Role:       "tool",
+		Content:    a.guardToolResult(name, formatToolResult(result)),
+		ToolName:   name,
+		ToolCallID: toolCall.ID,
+	}
+	if mcpResult, ok := result.(*mcp.ToolResult); ok {
+		message.Images = a.toolImages(ctx, mcpResult)
+	}
+	return message, nil
+}
+
+// stopToolLoop 在工具调用轮数达到 --max-tool-iterations 时调用：提示用户，之后的推理不再提供工具，
+// 并由 withToolLimit 告诉模型只能总结已完成的工作和剩余的步骤
+func (a *Agent) stopToolLoop() {
+	a.toolLimitReached = true
+	render.Stdout.Warning("tools", "stopped after %d rounds of tool calls (--max-tool-iterations), asking the model to summarize", a.maxToolIterations)
+}
+
+// withToolLimit 在达到工具调用轮数上限后，在发送给模型的消息末尾加上要求总结的系统消息，不修改对话本身
+func (a *Agent) withToolLimit(conversation []api.Message) []api.Message {
+	if !a.toolLimitReached {
+		return conversation
+	}
+	messages := make([]api.Message, 0, len(conversation)+1)
+	messages = append(messages, conversation...)
+	return append(messages, api.Message{
+		Role: "system",
+		Content: fmt.Sprintf("You have used tools %d times in a row, which is the limit for one request. Do not call any more tools. "+
+			"Summarize what you have done so far and what is left to do, so that the user can decide how to continue.", a.maxToolIterations),
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopToolLoopDoesNotChangeConversation(t *testing.T) {
+	agent := newTestAgent(t)
+	agent.maxToolIterations = 3
+	conversation := []api.Message{
+		{Role: "user", Content: "fix the build"},
+		{Role: "tool", Content: "ok", ToolName: "bash"},
+	}
+
+	assert.Equal(t, conversation, agent.withContext(conversation))
+
+	agent.stopToolLoop()
+	messages := agent.withContext(conversation)
+	require.Len(t, messages, len(conversation)+1)
+	last := messages[len(messages)-1]
+	assert.Equal(t, "system", last.Role)
+	assert.Contains(t, last.Content, "Do not call any more tools")
+	// 总结要求只在发送时加上，不保存到对话中
+	assert.Len(t, conversation, 2)
+}
+
+func TestFinishToolCallRecordsOnlySuccessfulCalls(t *testing.T) {
+	agent := newTestAgent(t)
+	client, err := mcp.NewClient(t.Context(), &mcp.Config{MCPServers: map[string]mcp.MCPServer{}}, &mcp.ClientOptions{})
+	require.NoError(t, err)
+	defer closeMCPClient(client)
+	agent.mcpClient = client
+	agent.maxRepeatedCalls = defaultMaxRepeatedCalls
+	call := api.ToolCall{Function: api.ToolCallFunction{Name: toolSearchTools, Arguments: api.ToolCallFunctionArguments{"query": "files"}}}
+
+	agent.finishToolCall(t.Context(), nil, call, toolOutcome{err: errors.New("server not connected")})
+	assert.False(t, agent.repeats.seen(call))
+
+	agent.finishToolCall(t.Context(), nil, call, toolOutcome{message: api.Message{Role: "tool", Content: "read_file"}})
+	assert.True(t, agent.repeats.seen(call))
+
+	// 关闭重复检测时不记录
+	agent.repeats.reset()
+	agent.maxRepeatedCalls = 0
+	agent.finishToolCall(t.Context(), nil, call, toolOutcome{message: api.Message{Role: "tool", Content: "read_file"}})
+	assert.False(t, agent.repeats.seen(call))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	EnsembleModeSideBySide = "side"
+	EnsembleModeCritique   = "critique"
+)
+
+// ensembleAnswer 是单个模型在 ensemble 模式下的回答
+type ensembleAnswer struct {
+	model    string
+	message  api.Message
+	duration time.Duration
+	err      error
+}
+
+// parseModelList 解析逗号分隔的模型列表
+func parseModelList(s string) []string {
+	var models []string
+	for _, m := range strings.Split(s, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// runEnsemble 将同一段对话并行发送给多个模型并展示所有回答（实验性功能）
+// 返回第一个模型的回答作为对话的延续。为避免重复执行工具，ensemble 模式下不提供工具。
+func (a *Agent) runEnsemble(ctx context.Context, conversation []api.Message) (api.Message, error) {
+	answers := make([]ensembleAnswer, len(a.ensembleModels))
+
+	var wg sync.WaitGroup
+	for i, model := range a.ensembleModels {
+		wg.Add(1)
+		go func(i int, model string) {
+			defer wg.Done()
+			start := time.Now()
+			message, err := a.runInferenceWithModel(ctx, model, conversation, nil)
+			answers[i] = ensembleAnswer{model: model, message: message, duration: time.Since(start), err: err}
+		}(i, model)
+	}
+	wg.Wait()
+
+	for _, answer := range answers {
+		printEnsembleAnswer(answer)
+	}
+
+	primary := answers[0]
+	if primary.err != nil {
+		return api.Message{}, fmt.Errorf("model %s failed: %w", primary.model, primary.err)
+	}
+
+	if a.ensembleMode == EnsembleModeCritique && len(answers) > 1 && answers[1].err == nil {
+		a.critiqueAnswer(ctx, conversation, primary, answers[1].model)
+	}
+
+	return primary.message, nil
+}
+
+// critiqueAnswer 让另一个模型评审主模型的回答
+func (a *Agent) critiqueAnswer(ctx context.Context, conversation []api.Message, answer ensembleAnswer, critic string) {
+	var question string
+	for i := len(conversation) - 1; i >= 0; i-- {
+		if conversation[i].Role == "user" {
+			question = conversation[i].Content
+			break
+		}
+	}
+
+	critiqueConversation := []api.Message{
+		{
+			Role: "user",
+			Content: fmt.Sprintf("Critique the following answer to the question. Point out mistakes, missing details and give a score from 1 to 10.\n\nQuestion:\n%s\n\nAnswer from %s:\n%s",
+				question, answer.model, answer.message.Content),
+		},
+	}
+
+	start := time.Now()
+	message, err := a.runInferenceWithModel(ctx, critic, critiqueConversation, nil)
+	a.debug.logf(debugLLM, "Critique by %s finished in %s", critic, time.Since(start))
+	printEnsembleAnswer(ensembleAnswer{model: critic + " (critique)", message: message, duration: time.Since(start), err: err})
+}
+
+// printEnsembleAnswer 显示单个模型的回答
+func printEnsembleAnswer(answer ensembleAnswer) {
+	fmt.Printf("%s (%s)\n", render.Stdout.Paint(render.Yellow, "── "+answer.model), answer.duration.Round(time.Millisecond))
+	if answer.err != nil {
+		render.Stdout.Error(answer.err)
+		fmt.Println()
+		return
+	}
+	fmt.Printf("%s\n\n", strings.TrimSpace(answer.message.Content))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// eventRecord 是 --events 输出的一行 JSON，每个对话事件一行，字段按事件类型填写
+type eventRecord struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"` // state, message, tool_call, tool_result
+
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+
+	Content   string   `json:"content,omitempty"`
+	ToolCalls []string `json:"tool_calls,omitempty"`
+
+	Tool      string         `json:"tool,omitempty"`
+	Server    string         `json:"server,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+
+	// 工具调用使用的资源，cpu_ms 只在支持测量的平台上输出
+	DurationMS *int64 `json:"duration_ms,omitempty"`
+	CPUMS      *int64 `json:"cpu_ms,omitempty"`
+	Bytes      *int   `json:"bytes,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// eventWriter 将对话事件以 JSONL 格式写出，供脚本分析（如找出耗时较长的工具）
+type eventWriter struct {
+	enc *json.Encoder
+}
+
+// openEventLog 打开 --events 指定的文件（追加写入），path 为 "-" 时写到标准输出
+func openEventLog(path string) (*eventWriter, func() error, error) {
+	if path == "-" {
+		return newEventWriter(os.Stdout), func() error { return nil }, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return newEventWriter(f), f.Close, nil
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{enc: json.NewEncoder(w)}
+}
+
+// write 是会话事件的订阅函数
+func (e *eventWriter) write(event turnEvent) {
+	record := eventRecord{Time: time.Now()}
+	switch ev := event.(type) {
+	case stateEvent:
+		record.Event, record.From, record.To = "state", ev.from.String(), ev.to.String()
+	case messageEvent:
+		record.Event, record.Content = "message", ev.message.Content
+		for _, call := range ev.message.ToolCalls {
+			record.ToolCalls = append(record.ToolCalls, call.Function.Name)
+		}
+	case toolCallEvent:
+		record.Event, record.Tool, record.Arguments = "tool_call", ev.call.Function.Name, ev.call.Function.Arguments
+	case toolResultEvent:
+		record.Event, record.Tool, record.Server = "tool_result", ev.call.Function.Name, ev.server
+		duration, bytes := ev.usage.duration.Milliseconds(), ev.usage.bytes
+		record.DurationMS, record.Bytes = &duration, &bytes
+		if ev.usage.cpuKnown {
+			cpu := ev.usage.cpu.Milliseconds()
+			record.CPUMS = &cpu
+		}
+		if ev.err != nil {
+			record.Error = ev.err.Error()
+		}
+	default:
+		return
+	}
+	// 写入失败不影响对话
+	_ = e.enc.Encode(record)
+}
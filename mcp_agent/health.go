@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+)
+
+// printStatus 探测已连接的 MCP 服务器并显示各服务器的状态
+func (a *Agent) printStatus(ctx context.Context) {
+	for _, status := range a.client().Status() {
+		if status.Alive() {
+			pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			_, _ = a.client().Ping(pingCtx, status.Name)
+			cancel()
+		}
+	}
+
+	for _, status := range a.client().Status() {
+		style := render.Green
+		if !status.Alive() {
+			style = render.Yellow
+		}
+		if status.LastError != nil || status.State == mcp.StateDead {
+			style = render.Red
+		}
+		fmt.Printf("%s %s (%s)", render.Stdout.Paint(style, fmt.Sprintf("%-10s", status.State)), status.Name, status.Type)
+		if !status.LastCall.IsZero() {
+			fmt.Printf(", last call %s ago took %s", time.Since(status.LastCall).Round(time.Second), status.LastLatency.Round(time.Millisecond))
+		}
+		if status.Restarts > 0 {
+			fmt.Printf(", restarted %d times", status.Restarts)
+		}
+		fmt.Println()
+		if status.LastError != nil {
+			fmt.Printf("    last error: %v\n", status.LastError)
+		}
+	}
+}
+
+// printServers 显示各 MCP 服务器的实现名称、版本、声明的能力和工具数
+func (a *Agent) printServers(ctx context.Context) {
+	servers := a.client().ListServers(ctx)
+	if len(servers) == 0 {
+		fmt.Println("no MCP servers configured")
+		return
+	}
+	for _, server := range servers {
+		style := render.Green
+		if server.State != mcp.StateConnected {
+			style = render.Yellow
+		}
+		fmt.Printf("%s (%s, %s)\n", render.Stdout.Paint(style, server.Name), server.Type, server.State)
+		if server.Implementation != "" {
+			fmt.Printf("    implementation: %s %s (protocol %s)\n", server.Implementation, server.Version, server.ProtocolVersion)
+		}
+		if len(server.Capabilities) > 0 {
+			fmt.Printf("    capabilities:   %s\n", strings.Join(server.Capabilities, ", "))
+		}
+		if server.ToolCount >= 0 {
+			fmt.Printf("    tools:          %d\n", server.ToolCount)
+		}
+		if server.Instructions != "" {
+			fmt.Printf("    instructions:   %s\n", truncateString(server.Instructions, 200))
+		}
+	}
+}
+
+// printToolMetrics 在会话结束时显示各 MCP 工具的调用次数、延迟和错误率
+func (a *Agent) printToolMetrics() {
+	metrics := a.client().Metrics()
+	if len(metrics) == 0 {
+		return
+	}
+	fmt.Println()
+	render.Stdout.Heading("Tool usage")
+	fmt.Printf("%-32s %6s %8s %8s %8s %7s\n", "TOOL", "CALLS", "P50", "P90", "P99", "ERRORS")
+	for _, m := range metrics {
+		fmt.Printf("%-32s %6d %8s %8s %8s %6.0f%%\n",
+			truncateString(m.Server+"/"+m.Tool, 32), m.Calls,
+			m.P50.Round(time.Millisecond), m.P90.Round(time.Millisecond), m.P99.Round(time.Millisecond),
+			m.ErrorRate()*100)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// historyMatch 是会话中匹配查询的一处内容
+type historyMatch struct {
+	session *savedSession
+	snippet string
+	count   int
+}
+
+// searchSessions 在所有保存的会话的标题和消息中搜索 query（不区分大小写）
+func searchSessions(sessions []*savedSession, query string) []historyMatch {
+	query = strings.ToLower(query)
+
+	var matches []historyMatch
+	for _, session := range sessions {
+		match := historyMatch{session: session}
+		if strings.Contains(strings.ToLower(session.Title), query) {
+			match.count++
+		}
+		for _, message := range session.Messages {
+			content := strings.ToLower(message.Content)
+			n := strings.Count(content, query)
+			if n == 0 {
+				continue
+			}
+			if match.snippet == "" {
+				match.snippet = snippet(message.Content, strings.Index(content, query), len(query))
+			}
+			match.count += n
+		}
+		if match.count > 0 {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// snippet 截取匹配位置前后的一段文本用于展示
+func snippet(content string, index, length int) string {
+	const window = 40
+	index = min(index, len(content))
+	start := max(index-window, 0)
+	end := min(index+length+window, len(content))
+	// 避免截断多字节字符
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start--
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end++
+	}
+
+	s := strings.Join(strings.Fields(content[start:end]), " ")
+	if start > 0 {
+		s = "..." + s
+	}
+	if end < len(content) {
+		s += "..."
+	}
+	return s
+}
+
+// searchHistory 处理 /history search 命令，用户可以查看或恢复匹配的会话，
+// 返回恢复后的对话，未恢复时返回 nil
+func (a *Agent) searchHistory(query string) ([]api.Message, error) {
+	sessions, err := a.sessions.list()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+	matches := searchSessions(sessions, query)
+	if len(matches) == 0 {
+		fmt.Printf("No sessions match %q\n", query)
+		return nil, nil
+	}
+
+	options := make([]string, 0, len(matches))
+	for _, m := range matches {
+		options = append(options, fmt.Sprintf("%s  %s (%d matches)", m.session.UpdatedAt.Format("2006-01-02 15:04"), m.session.Title, m.count))
+	}
+	for i, m := range matches {
+		fmt.Printf("%s  %s\n", render.Stdout.Paint(render.Cyan, fmt.Sprintf("%d. %s", i+1, m.session.Title)), m.session.UpdatedAt.Format("2006-01-02 15:04"))
+		if m.snippet != "" {
+			fmt.Printf("    %s\n", m.snippet)
+		}
+	}
+
+	choice := pickSession("Select a session:", options)
+	if choice < 0 || !chooseResume(matches[choice].session) {
+		return nil, nil
+	}
+	return a.resumeSession(matches[choice].session), nil
+}
+
+// resumeSession 切换到保存的会话，之后的对话继续保存到该会话
+func (a *Agent) resumeSession(session *savedSession) []api.Message {
+	a.saved = session
+	fmt.Printf("Resumed session %s: %s (%d messages)\n", session.ID, session.Title, len(session.Messages))
+	return session.Messages
+}
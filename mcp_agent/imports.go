@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+)
+
+// importsTimeout 是整理一个文件的 import 的最长时间，gopls 第一次加载包可能较慢
+const importsTimeout = 30 * time.Second
+
+// importsTool 是整理 import 的命令：优先使用 goimports，否则使用 gopls 的 organize imports 操作
+var importsTool = sync.OnceValue(func() []string {
+	if path, ok := lookTool("goimports"); ok {
+		return []string{path, "-w"}
+	}
+	if path, ok := lookTool("gopls"); ok {
+		return []string{path, "codeaction", "-kind=source.organizeImports", "-w"}
+	}
+	return nil
+})
+
+// lookTool 在 PATH 和 $GOPATH/bin 中查找 Go 工具，go install 安装的工具常常不在 PATH 中
+func lookTool(name string) (string, bool) {
+	if path, err := exec.LookPath(name); err == nil {
+		return path, true
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			gopath = filepath.Join(home, "go")
+		}
+	}
+	for _, dir := range filepath.SplitList(gopath) {
+		if path, err := exec.LookPath(filepath.Join(dir, "bin", name)); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// fixImports 在 Go 文件编辑后整理 import，补上模型忘记的导入并删除未使用的导入。
+// 有变化时返回告知模型的说明，没有可用的工具或没有变化时返回空字符串。取消这一轮对话时整理也会停止
+func (a *Agent) fixImports(ctx context.Context, path string) string {
+	tool := importsTool()
+	if tool == nil {
+		a.debug.logf(debugTools, "Neither goimports nor gopls found, not fixing imports of %s", path)
+		return ""
+	}
+	path = expandPath(path)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, importsTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, tool[0], append(tool[1:], filepath.Base(path))...)
+	cmd.Dir = filepath.Dir(path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		a.debug.logf(debugTools, "%s failed on %s: %v: %s", filepath.Base(tool[0]), path, err, strings.TrimSpace(string(output)))
+		return ""
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil || bytes.Equal(before, after) {
+		return ""
+	}
+	added, removed := diffImports(before, after)
+	if len(added) == 0 && len(removed) == 0 {
+		return ""
+	}
+
+	var changes []string
+	if len(added) > 0 {
+		changes = append(changes, "added "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, "removed "+strings.Join(removed, ", "))
+	}
+	summary := strings.Join(changes, "; ")
+	render.Stdout.Note("imports", "%s: %s", path, summary)
+	return fmt.Sprintf("The imports of %s were fixed automatically after your edit (%s). The file on disk already contains this change, take it into account in later edits of the file.", path, summary)
+}
+
+// diffImports 返回两个版本的 Go 文件之间新增和删除的导入路径
+func diffImports(before, after []byte) (added, removed []string) {
+	old, current := fileImports(before), fileImports(after)
+	for _, path := range current {
+		if !slices.Contains(old, path) {
+			added = append(added, strconv.Quote(path))
+		}
+	}
+	for _, path := range old {
+		if !slices.Contains(current, path) {
+			removed = append(removed, strconv.Quote(path))
+		}
+	}
+	return added, removed
+}
+
+func fileImports(src []byte) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(file.Imports))
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
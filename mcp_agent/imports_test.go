@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileImports(t *testing.T) {
+	src := []byte(`package main
+
+import (
+	"fmt"
+	str "strings"
+	_ "embed"
+)
+
+import "os"
+
+func main() {}
+`)
+	assert.Equal(t, []string{"fmt", "strings", "embed", "os"}, fileImports(src))
+	assert.Empty(t, fileImports([]byte("package main\n")))
+	// 无法解析的文件没有导入
+	assert.Nil(t, fileImports([]byte("not go")))
+}
+
+func TestDiffImports(t *testing.T) {
+	before := []byte("package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n")
+	after := []byte("package main\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n")
+
+	added, removed := diffImports(before, after)
+	assert.Equal(t, []string{`"strings"`}, added)
+	assert.Equal(t, []string{`"os"`}, removed)
+
+	added, removed = diffImports(before, before)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+}
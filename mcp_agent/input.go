@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// inputGate 在处理一轮对话期间接管终端输入：关闭回显，避免提前输入的字符与模型输出混在一起，
+// 输入的内容被缓存下来，完整的行在这一轮结束后依次作为下一条输入，未输入完的行作为下一次提示的默认值
+type inputGate struct {
+	fd      int
+	enabled bool // 标准输入是终端时才接管
+
+	mu      sync.Mutex
+	locked  bool   // 正在处理一轮对话
+	restore func() // 恢复终端状态，nil 表示没有在读取输入
+	stop    chan struct{}
+	done    chan struct{}
+
+	typed []byte   // 读取到但尚未解析的输入
+	lines []string // 等待作为输入的完整行
+}
+
+func newInputGate(f *os.File) *inputGate {
+	fd := int(f.Fd())
+	return &inputGate{fd: fd, enabled: term.IsTerminal(fd)}
+}
+
+// lock 在一轮对话开始时调用，之后的键盘输入不再回显，而是被缓存
+func (g *inputGate) lock() {
+	if g == nil || !g.enabled {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.locked = true
+	g.startLocked()
+}
+
+// unlock 在一轮对话结束时调用，恢复终端并解析缓存的输入
+func (g *inputGate) unlock() {
+	if g == nil || !g.enabled {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.locked = false
+	g.stopLocked()
+	g.lines = append(g.lines, splitTyped(&g.typed)...)
+}
+
+// pause 在对话过程中向用户提问前调用（如 sampling 确认），暂时交还终端，返回恢复接管的函数
+func (g *inputGate) pause() func() {
+	if g == nil || !g.enabled {
+		return func() {}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.restore == nil {
+		return func() {}
+	}
+	g.stopLocked()
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.locked {
+			g.startLocked()
+		}
+	}
+}
+
+// next 返回下一条提前输入的完整行
+func (g *inputGate) next() (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.lines) == 0 {
+		return "", false
+	}
+	line := g.lines[0]
+	g.lines = g.lines[1:]
+	return line, true
+}
+
+// pending 返回并清空提前输入但没有按回车的内容
+func (g *inputGate) pending() string {
+	if g == nil {
+		return ""
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	text := string(g.typed)
+	g.typed = nil
+	return text
+}
+
+func (g *inputGate) startLocked() {
+	if g.restore != nil {
+		return
+	}
+	restore, err := quietTerminal(g.fd)
+	if err != nil {
+		return
+	}
+	g.restore = restore
+	g.stop = make(chan struct{})
+	g.done = make(chan struct{})
+	go g.read(g.stop, g.done)
+}
+
+func (g *inputGate) stopLocked() {
+	if g.restore == nil {
+		return
+	}
+	restore, done := g.restore, g.done
+	g.restore = nil
+	close(g.stop)
+	// 读取在没有输入时很快返回；等待期间释放锁，读取的 goroutine 需要它保存输入
+	g.mu.Unlock()
+	<-done
+	g.mu.Lock()
+	restore()
+}
+
+// read 读取终端输入直到 stop 被关闭
+func (g *inputGate) read(stop, done chan struct{}) {
+	defer close(done)
+	buf := make([]byte, 256)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		n, err := readTerminal(g.fd, buf)
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			g.mu.Lock()
+			g.typed = append(g.typed, buf[:n]...)
+			g.mu.Unlock()
+		}
+	}
+}
+
+// splitTyped 按终端的行编辑规则解析输入：处理退格、Ctrl-U 和方向键等转义序列，
+// 返回完整的行，剩余未完成的行保留在 typed 中
+func splitTyped(typed *[]byte) []string {
+	var lines []string
+	var line []byte
+	data := *typed
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '\r' || c == '\n':
+			if s := strings.TrimSpace(string(line)); s != "" {
+				lines = append(lines, s)
+			}
+			line = line[:0]
+		case c == 0x7f || c == '\b':
+			if len(line) > 0 {
+				_, size := utf8.DecodeLastRune(line)
+				line = line[:len(line)-size]
+			}
+		case c == 0x15: // Ctrl-U 清空当前行
+			line = line[:0]
+		case c == 0x1b: // 跳过 ESC [ ... 字母 形式的转义序列
+			if i+1 < len(data) && (data[i+1] == '[' || data[i+1] == 'O') {
+				i += 2
+				for i < len(data) && (data[i] < 0x40 || data[i] > 0x7e) {
+					i++
+				}
+			}
+		case c == '\t' || c >= 0x20:
+			line = append(line, c)
+		}
+	}
+	*typed = append([]byte(nil), line...)
+	return lines
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// 其他平台不接管终端输入，提前输入的内容照常回显
+func quietTerminal(fd int) (func(), error) {
+	return nil, errors.ErrUnsupported
+}
+
+func readTerminal(fd int, p []byte) (int, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,37 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// quietTerminal 关闭终端的回显和行缓冲，保留 Ctrl-C 等信号；没有输入时读取在 100ms 后返回，
+// 以便读取输入的 goroutine 能及时停止。返回恢复终端原状态的函数
+func quietTerminal(fd int) (func(), error) {
+	old, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+	quiet := *old
+	quiet.Lflag &^= unix.ECHO | unix.ICANON
+	quiet.Cc[unix.VMIN] = 0
+	quiet.Cc[unix.VTIME] = 1
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &quiet); err != nil {
+		return nil, err
+	}
+	return func() { _ = unix.IoctlSetTermios(fd, ioctlSetTermios, old) }, nil
+}
+
+// readTerminal 读取终端输入，没有输入时返回 0
+func readTerminal(fd int, p []byte) (int, error) {
+	for {
+		n, err := unix.Read(fd, p)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		return n, err
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+)
+
+// interruptible 返回用户按下 Ctrl-C 时被取消的 context，用于推理请求：第一次 Ctrl-C 只取消当前请求，
+// 取消完成之前再按一次 Ctrl-C 退出程序。stop 停止监听信号并返回请求是否被中断
+func interruptible(ctx context.Context) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt)
+
+	var interrupted atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			interrupted.Store(true)
+			cancel()
+		case <-done:
+			return
+		}
+		select {
+		case <-signals:
+			fmt.Println()
+			os.Exit(exitUserAbort)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() bool {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+		return interrupted.Load()
+	}
+}
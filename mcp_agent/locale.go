@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/permissions"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// glossaryFile 是项目术语表的文件名，位于项目的 .coding-agent 目录中
+	glossaryFile = "glossary.md"
+	// maxGlossarySize 是放入系统提示的术语表的最大字节数
+	maxGlossarySize = 8 * 1024
+	// langAuto 表示由模型按用户使用的语言回答
+	langAuto = "auto"
+)
+
+// replyInstructions 是要求模型使用指定语言回答的系统提示，用目标语言书写，小模型更容易遵守
+var replyInstructions = map[string]string{
+	"en": "Always reply in English, even when the user, the files or the tool results use another language. Keep code, commands and identifiers unchanged.",
+	"zh": "请始终使用简体中文回答，即使用户、文件或工具结果使用其他语言。代码、命令和标识符保持原样。",
+}
+
+// toolDescriptions 按语言提供工具描述，用于将发送给模型的工具描述统一为同一种语言。
+// 键为完整工具名 (server__tool，服务器名与 map.json 中一致) 或内置工具名。
+var toolDescriptions = map[string]map[string]string{
+	"en": {
+		"filesystem__read_file":      "Read the contents of the given file. Supports text files and returns the full content.",
+		"filesystem__list_directory": "List all files and subdirectories in the given directory.",
+		"filesystem__write_file":     "Write content to a file. Creates the file if it does not exist and overwrites it otherwise.",
+		"filesystem__edit_file":      "Edit the content of the given file. Creates the file if it does not exist, otherwise edits the existing content.",
+		"filesystem__get_file_info":  "Get detailed information about a file or directory, including size, modification time and permissions.",
+		"filesystem__search_files":   "Search the given directory for files whose names match a pattern.",
+
+		"code_search__grep_search":   "Search code files with a regular expression. Supports file type filters, case-insensitive matching and context lines. Useful for finding code patterns, strings and function calls.",
+		"code_search__find_files":    "Find files by name pattern. Supports the * and ? wildcards. Useful for locating a specific file or a kind of file.",
+		"code_search__read_file":     "Read the contents of a file. Supports a start line and a number of lines. Large files are truncated.",
+		"code_search__list_dir":      "List files and subdirectories of a directory. Supports recursive listing with a depth limit and shows file sizes and modification times.",
+		"code_search__search_symbol": "Search for symbol definitions (functions, classes, structs, interfaces, etc.) in the code. Useful for quickly locating definitions.",
+
+		"web_browser__fetch_page": "Fetch the full HTML of a web page. Useful when the page structure needs to be analyzed.",
+		"web_browser__get_text":   "Get the plain text of a web page (HTML tags removed). Useful for reading page content. Use the selector parameter to only get the text of specific elements.",
+		"web_browser__get_links":  "Get all links of a web page with their text and URL, useful for analyzing the page navigation.",
+		"web_browser__screenshot": "Take a screenshot of a web page and return it as a base64 encoded PNG image.",
+	},
+	"zh": {
+		toolListResources: "列出已连接的 MCP 服务器发布的资源（文件、文档等），返回每个资源的服务器名和 URI。",
+		toolReadResource:  "读取 MCP 服务器发布的资源内容。请先使用 list_resources 获取服务器名和 URI。",
+		toolSearchTools:   "按用途搜索所有可用的工具。当前只列出了与问题最相关的工具，没有合适的工具时调用它，找到的工具可以直接调用。",
+	},
+}
+
+// localizeTools 将工具描述替换为指定语言的版本，lang 为空时保持原样
+func (a *Agent) localizeTools(tools []api.Tool, lang string) []api.Tool {
+	if lang == "" {
+		return tools
+	}
+
+	descriptions := toolDescriptions[lang]
+	localized := make([]api.Tool, len(tools))
+	for i, tool := range tools {
+		// 别名按原始的 server__tool 名查找
+		key := tool.Function.Name
+		if server, name, err := a.client().ResolveToolName(key); err == nil {
+			key = server + "__" + name
+		}
+		if description, ok := descriptions[key]; ok {
+			tool.Function.Description = description
+		} else if !matchesLanguage(tool.Function.Description, lang) {
+			a.debug.logf(debugTools, "No %s description for tool %s, keeping the original", lang, tool.Function.Name)
+		}
+		localized[i] = tool
+	}
+	return localized
+}
+
+// matchesLanguage 粗略判断文本是否已经是目标语言（根据是否包含汉字）
+func matchesLanguage(text, lang string) bool {
+	hasHan := false
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			hasHan = true
+			break
+		}
+	}
+	if lang == "zh" {
+		return hasHan
+	}
+	return !hasHan
+}
+
+// parseReplyLang 检查回答语言的设置，auto 表示不限制
+func parseReplyLang(lang string) (string, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" || lang == langAuto {
+		return "", nil
+	}
+	if _, ok := replyInstructions[lang]; !ok {
+		return "", fmt.Errorf("unsupported language %q, expected en, zh or auto", lang)
+	}
+	return lang, nil
+}
+
+// glossaryPath 返回项目术语表的默认路径
+func glossaryPath(dir string) string {
+	return filepath.Join(dir, permissions.DirName, glossaryFile)
+}
+
+// loadGlossary 读取项目术语表（领域术语、命名约定等），required 为 false 时文件不存在不算错误。
+// 超过 maxGlossarySize 的部分被截掉
+func loadGlossary(path string, required bool) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read glossary: %w", err)
+	}
+	glossary := strings.TrimSpace(string(data))
+	if len(glossary) > maxGlossarySize {
+		glossary = truncateString(glossary, maxGlossarySize)
+	}
+	return glossary, nil
+}
+
+// language 返回当前会话的回答语言，/lang 的设置优先于 --lang，为空表示不限制
+func (a *Agent) language() string {
+	if a.replyLang != "" {
+		if a.replyLang == langAuto {
+			return ""
+		}
+		return a.replyLang
+	}
+	return a.defaultLang
+}
+
+// withInstructions 在发送给模型的消息前加上回答语言和项目术语表，不修改对话本身
+func (a *Agent) withInstructions(conversation []api.Message) []api.Message {
+	var parts []string
+	if instruction, ok := replyInstructions[a.language()]; ok {
+		parts = append(parts, instruction)
+	}
+	if a.glossary != "" {
+		parts = append(parts, "Project glossary. Use these terms and naming conventions in answers and code:\n\n"+a.glossary)
+	}
+	if len(parts) == 0 {
+		return conversation
+	}
+	messages := make([]api.Message, 0, len(conversation)+1)
+	messages = append(messages, api.Message{Role: "system", Content: strings.Join(parts, "\n\n")})
+	return append(messages, conversation...)
+}
+
+// withContext 加上所有不保存在对话中的系统消息：对话前的回答语言和术语表、仓库地图以及检索到的代码，
+// 和达到工具调用轮数上限后对话末尾的总结要求
+func (a *Agent) withContext(conversation []api.Message) []api.Message {
+	return a.withInstructions(a.withRepoMap(a.withRetrieved(a.withToolLimit(conversation))))
+}
@@ -2,277 +2,801 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/permissions"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/stream"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/vectorindex"
 	"github.com/ollama/ollama/api"
 )
 
 func main() {
-	verbose := flag.Bool("verbose", false, "enable verbose logging")
-	model := flag.String("model", "qwen3:1.7b", "Ollama model name")
-	stream := flag.Bool("stream", false, "Enable streaming mode")
-	configPath := flag.String("config", "", "MCP config file path (default: ./mcp_agent/mcp.json)")
+	verbose := flag.Bool("verbose", false, "enable verbose logging (same as --debug=all)")
+	debugSpec := flag.String("debug", "", "Comma-separated debug log channels: llm, tools, mcp, ui or all")
+	model := flag.String("model", "qwen3:1.7b", "Model name, e.g. qwen3:1.7b for Ollama or gpt-4o-mini for --provider openai")
+	provider := flag.String("provider", providerOllama, "Inference backend: ollama, or openai for servers speaking the OpenAI chat/completions API (OpenAI, DeepSeek, vLLM, LM Studio, ...)")
+	var openaiOpts openaiOptions
+	flag.StringVar(&openaiOpts.baseURL, "openai-base-url", "", "Base URL of the OpenAI-compatible server, e.g. http://localhost:8000/v1 for vLLM (default: $OPENAI_BASE_URL or the OpenAI API)")
+	flag.StringVar(&openaiOpts.apiKey, "openai-api-key", "", "API key for the OpenAI-compatible server, may reference an environment variable like ${DEEPSEEK_API_KEY} (default: $OPENAI_API_KEY)")
+	streamMode := flag.Bool("stream", false, "Enable streaming mode")
+	lang := flag.String("lang", "", "Language the assistant always replies in: en or zh (default: the language of the user)")
+	glossary := flag.String("glossary", "", "Project glossary (domain terms, naming conventions) added to the system prompt (default: .coding-agent/glossary.md of the project, if present)")
+	toolLang := flag.String("tool-lang", "", "Normalize tool descriptions sent to the model to one language: en or zh (default: keep as published)")
+	offline := flag.Bool("offline", false, "Offline mode: disable web tools and block shell commands that access the network (curl, wget, pip install, ...)")
+	trace := flag.Bool("trace", false, "Trace all MCP JSON-RPC traffic to stderr (toggle at runtime with /trace on|off)")
+	ensemble := flag.String("ensemble", "", "Experimental: comma-separated models that all answer each prompt, e.g. qwen3:1.7b,llama3.2:3b")
+	ensembleMode := flag.String("ensemble-mode", EnsembleModeSideBySide, "Ensemble display mode: side (show all answers) or critique (second model reviews the first)")
+	review := flag.Bool("review", false, "Run a reviewer pass after each task to check the changes against the request")
+	reviewModel := flag.String("review-model", "", "Model used for the reviewer pass (default: same as --model)")
+	auto := flag.Bool("auto", false, "Autonomous mode: run tools without asking; with --verify-cmd, keep the file changes of a turn only if the command passes, otherwise discard them and let the model retry")
+	verifyCmd := flag.String("verify-cmd", "", "In --auto mode, command that checks the changes of each turn, e.g. \"go build ./... && go test ./...\"; the model edits a copy of the workspace and only changes that pass are written back")
+	verifyTimeout := flag.Duration("verify-timeout", defaultVerifyTimeout, "Time after which the --verify-cmd command is stopped and the changes count as failed, 0 means no limit")
+	autoApproveTools := flag.Bool("auto-approve-tools", false, "Run MCP tools without asking, also those not listed in the autoApprove rules of the MCP config")
+	autoImports := flag.Bool("auto-imports", true, "Fix the imports of Go files after edits with goimports, or gopls if goimports is not installed")
+	autoApproveSampling := flag.Bool("auto-approve-sampling", false, "Approve sampling requests from MCP servers without asking")
+	prompt := flag.String("p", "", "One-shot mode: answer this prompt and exit (piped stdin and --file contents are attached). Without -p, piped stdin is the prompt")
+	statusFile := flag.String("status-file", "", "In -p mode, write the final status as JSON to this file (\"-\" for stdout)")
+	stallTimeout := flag.Duration("stall-timeout", DefaultStallTimeout, "Probe Ollama and ask whether to wait, retry or abort when the model sends nothing for this long (0 disables)")
+	injectionGuard := flag.Bool("injection-guard", true, "Remove instruction-like text (\"ignore previous instructions...\") from web tool results before they reach the model")
+	parallelTools := flag.Int("parallel-tools", defaultParallelTools, "Run up to this many read-only tool calls of one response at the same time (1 runs them one by one)")
+	maxRepeatedCalls := flag.Int("max-repeated-calls", defaultMaxRepeatedCalls, "Abort a turn after the model repeats an identical tool call (same name and arguments) this many times; repeated calls reuse the previous result (0 disables the detection)")
+	maxToolIterations := flag.Int("max-tool-iterations", defaultMaxToolIterations, "Stop after this many consecutive rounds of tool calls in one turn, let the model summarize and return to the user (0 disables the limit)")
+	toolTimeout := flag.Duration("tool-timeout", 0, "Cancel MCP tool calls that run longer than this (default: the server's callTimeout, or none)")
+	resultDisplay := flag.Int("result-display", defaultResultDisplay, "Show at most this many characters of each tool result, use /last-result for the rest (0 shows everything)")
+	maxTools := flag.Int("max-tools", 0, "Send at most this many tools, chosen by relevance to the question (0 picks a limit from the model size, -1 sends all)")
+	embedModel := flag.String("embed-model", DefaultEmbedModel, "Embedding model used to choose the most relevant tools for small models and to retrieve relevant code")
+	retrieve := flag.Int("retrieve", defaultRetrieveChunks, "Add this many workspace code chunks most similar to each request to the context, found with --embed-model (0 disables; needs --repo-map)")
+	keepAlive := flag.Duration("keep-alive", DefaultKeepAlive, "How long Ollama keeps the model and its prompt cache loaded between requests (0 uses the server default)")
+	maxOutput := flag.Int("max-output", stream.DefaultLimit, "Stop streamed responses longer than this many bytes (0 disables)")
+	eventLog := flag.String("events", "", "Append the conversation events (state changes, messages, tool calls and results with their duration, CPU time and output size) as JSON lines to this file (\"-\" for stdout)")
+	numCtx := flag.Int("num-ctx", 0, "Context window of the model in tokens, sent to Ollama as num_ctx; longer conversations are trimmed to fit (default: Ollama's default of 4096)")
+	serverLogDir := flag.String("server-log-dir", "", "Append the log notifications of each MCP server to <dir>/<server>.log")
+	serverStderrDir := flag.String("server-stderr-dir", defaultServerStderrDir(), "Append the stderr output of each stdio MCP server to <dir>/<server>.log instead of the terminal (empty prints it to the terminal; --debug=mcp prints it to both)")
+	serverLogLevel := flag.String("server-log-level", mcp.DefaultLogLevel, "Minimum level of MCP server log notifications (debug, info, notice, warning, error, ...)")
+	repoMap := flag.Bool("repo-map", true, "Index the workspace in the background and give the model a map of its files and symbols")
+	var ollama ollamaOptions
+	flag.StringVar(&ollama.host, "ollama-host", "", "Ollama server URL, e.g. https://ollama.example.com (default: $OLLAMA_HOST or 127.0.0.1:11434)")
+	flag.StringVar(&ollama.token, "ollama-token", "", "Bearer token sent to the Ollama server, may reference an environment variable like ${MY_TOKEN} (default: $OLLAMA_TOKEN)")
+	flag.StringVar(&ollama.user, "ollama-user", "", "Basic auth credentials user:password for the Ollama server, the password may reference an environment variable")
+	flag.StringVar(&ollama.caFile, "ollama-ca", "", "CA certificate (PEM) used to verify the Ollama server")
+	flag.StringVar(&ollama.certFile, "ollama-cert", "", "Client certificate (PEM) for mutual TLS with the Ollama server")
+	flag.StringVar(&ollama.keyFile, "ollama-key", "", "Client key (PEM) for mutual TLS with the Ollama server")
+	flag.BoolVar(&ollama.insecure, "ollama-insecure", false, "Do not verify the certificate of the Ollama server")
+	importPath := flag.String("import", "", "Continue a conversation from a session bundle created with /share")
+	resumeID := flag.String("resume", "", "Resume the saved session with this ID (run the sessions subcommand to list them)")
+	continueLast := flag.Bool("continue", false, "Resume the most recent session of the current directory")
+	var files stringList
+	flag.Var(&files, "file", "File to attach as context in -p mode (repeatable)")
+	flag.Var((*stringList)(&ollama.headers), "ollama-header", "Extra header \"Name: Value\" sent to the Ollama server (repeatable)")
+	var workspaces stringList
+	flag.Var(&workspaces, "workspace", "Workspace directory advertised to MCP servers as a root, repeatable (default: current directory)")
+	var configPaths stringList
+	listen := flag.String("listen", defaultListenAddr, "Address the agent API of the serve subcommand listens on")
+	sessionStoreSpec := flag.String("session-store", "", "Where sessions are saved: dir:<path> for one JSON file per session, or sqlite:<path> for one SQLite database (default: ~/.agent/sessions)")
+	profile := flag.String("profile", os.Getenv("MCP_PROFILE"), "Config profile to use, e.g. dev or prod, from the \"profiles\" section of the MCP config (default: $MCP_PROFILE)")
+	flag.Var(&configPaths, "config", "MCP config file, repeatable; later files override servers of earlier ones (default: ~/.claude.json merged with ./.mcp.json, ./mcp.json, ./map.json or ./mcp_agent/map.json)")
 	flag.Parse()
 
-	if *verbose {
-		log.SetOutput(os.Stderr)
-		log.SetFlags(log.LstdFlags | log.Lshortfile)
-		log.Println("Verbose logging enabled")
-	} else {
-		log.SetOutput(os.Stdout)
-		log.SetFlags(0)
-		log.SetPrefix("")
+	// 子命令和非交互模式通过 exitCode 设置退出码后返回，先执行其他 defer（关闭会话存储、删除工作区副本等）再退出
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	// 子命令: stats 显示历史使用统计
+	if flag.Arg(0) == "stats" {
+		sessions, err := loadUsage()
+		if err != nil {
+			log.Fatalf("Failed to load usage: %v", err)
+		}
+		printStats(sessions)
+		return
 	}
 
-	// 确定配置文件路径
-	cfgPath := *configPath
-	if cfgPath == "" {
-		// 优先使用当前目录下的 map.json
-		if _, err := os.Stat("map.json"); err == nil {
-			cfgPath = "map.json"
-		} else {
-			// 打印当前工作目录
-			cwd, _ := os.Getwd()
-			mcpAgentDir := fmt.Sprintf("%s/mcp_agent", cwd)
-			cfgPath = filepath.Join(mcpAgentDir, "map.json")
+	// 子命令: permissions list|revoke 管理项目中"总是允许"的确认规则
+	if flag.Arg(0) == "permissions" {
+		if err := runPermissionsCommand(projectDir(workspaces), flag.Args()[1:]); err != nil {
+			log.Fatal(err)
 		}
+		return
+	}
+
+	store, err := openSessionStore(*sessionStoreSpec)
+	if err != nil {
+		log.Fatalf("Failed to open session store: %v", err)
+	}
+	defer store.close()
+
+	// 恢复的会话在交互模式中继续，标准输入不能是管道
+	if (*resumeID != "" || *continueLast) && *prompt == "" && stdinIsPiped() {
+		log.Fatalf("--resume and --continue need a terminal, but stdin is piped (use docker run -it in a container)")
 	}
 
-	// 加载 MCP 配置
+	// 子命令: sessions 选择并恢复保存的会话；--resume 和 --continue 直接恢复会话，
+	// 每轮对话后保存的会话可以在退出或崩溃后继续
+	var resumed *savedSession
+	switch {
+	case flag.Arg(0) == "sessions":
+		if resumed, err = selectSession(store); err != nil {
+			log.Fatalf("Failed to select session: %v", err)
+		}
+		if resumed == nil {
+			return
+		}
+	case *resumeID != "":
+		if resumed, err = findSession(store, *resumeID); err != nil {
+			log.Fatalf("Failed to resume session: %v", err)
+		}
+	case *continueLast:
+		cwd, _ := os.Getwd()
+		if resumed, err = latestSession(store, cwd); err != nil {
+			log.Fatalf("Failed to continue session: %v", err)
+		}
+	}
+	// 未指定 --model 时沿用会话的模型
+	if resumed != nil && !flagPassed("model") && resumed.Model != "" {
+		*model = resumed.Model
+	}
+
+	debug, err := parseDebugChannels(*debugSpec)
+	if err != nil {
+		log.Fatalf("Invalid --debug: %v", err)
+	}
 	if *verbose {
-		log.Printf("Loading MCP config from: %s", cfgPath)
+		debug, _ = parseDebugChannels("all")
+	}
+	configureLogging(debug)
+	// 没有 -p 时，通过管道传入的标准输入本身就是问题，如 echo "explain main.go" | mcp_agent。
+	// 子命令以及恢复、导入会话时仍是交互模式
+	restoring := *resumeID != "" || *continueLast || *importPath != ""
+	headless := *prompt != "" || (flag.NArg() == 0 && !restoring && stdinIsPiped())
+	if len(files) > 0 && !headless {
+		log.Fatalf("--file can only be used together with -p")
+	}
+
+	// 确定配置文件路径
+	if len(configPaths) == 0 {
+		configPaths = defaultConfigPaths()
 	}
-	config, err := mcp.LoadConfig(cfgPath)
+
+	// 加载并合并 MCP 配置
+	debug.logf(debugMCP, "Loading MCP config from: %s", strings.Join(configPaths, ", "))
+	config, err := loadConfig(configPaths, *profile)
 	if err != nil {
 		log.Fatalf("Failed to load MCP config: %v", err)
 	}
+	useBundledBinaries(config, debug)
 
-	// 创建 MCP 客户端
+	// 初始化推理客户端
+	llm, err := newModelClient(*provider, ollama, openaiOpts)
+	if err != nil {
+		log.Fatalf("Failed to initialize %s client: %v", *provider, err)
+	}
+	debug.logf(debugLLM, "%s client initialized", *provider)
+
+	// 创建 Agent
+	agent := NewAgent(llm, nil, *model, debug, *streamMode)
+	agent.provider = *provider
+	agent.endpoint = modelEndpoint(*provider, ollama, openaiOpts)
+	agent.profile = *profile
+	agent.workspace = projectDir(workspaces)
+	agent.autoApproveSampling = *autoApproveSampling
+	agent.autoApproveTools = *autoApproveTools || *auto
+	agent.auto = *auto
+	agent.autoImports = *autoImports
+	agent.sessions = store
+	agent.verifyCmd = *verifyCmd
+	agent.verifyTimeout = *verifyTimeout
+	if *verifyCmd != "" && !*auto {
+		log.Fatalf("--verify-cmd can only be used together with --auto")
+	}
+	if *verifyCmd != "" && flag.Arg(0) == "serve" {
+		// 副本属于一个会话，serve 模式的多个会话会同时修改同一个工作区
+		log.Fatalf("--verify-cmd cannot be used with serve")
+	}
+	agent.ensembleModels = parseModelList(*ensemble)
+	agent.ensembleMode = *ensembleMode
+	agent.review = *review
+	agent.toolLang = *toolLang
+	if agent.defaultLang, err = parseReplyLang(*lang); err != nil {
+		log.Fatalf("Invalid --lang: %v", err)
+	}
+	if *glossary != "" {
+		agent.glossary, err = loadGlossary(*glossary, true)
+	} else {
+		agent.glossary, err = loadGlossary(glossaryPath(projectDir(workspaces)), false)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	agent.offline = *offline
+	agent.reviewModel = *reviewModel
+	agent.stallTimeout = *stallTimeout
+	agent.toolTimeout = *toolTimeout
+	agent.maxToolIterations = *maxToolIterations
+	agent.maxRepeatedCalls = *maxRepeatedCalls
+	agent.parallelTools = *parallelTools
+	agent.injectionGuard = *injectionGuard
+	agent.maxOutput = *maxOutput
+	agent.numCtx = *numCtx
+	agent.keepAlive = *keepAlive
+	agent.maxTools = *maxTools
+	agent.embedModel = *embedModel
+	agent.retrieveChunks = *retrieve
+	agent.resultDisplay = *resultDisplay
+	agent.urlPolicy.Store(config.URLPolicy)
+	agent.useURLProxy(config)
+	if agent.permissions, err = permissions.Load(projectDir(workspaces)); err != nil {
+		render.Stdout.Warning("permissions", "%v, saved approvals are ignored", err)
+	}
+	if *eventLog != "" {
+		events, closeEvents, err := openEventLog(*eventLog)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer closeEvents()
+		agent.subscribe(events.write)
+	}
+
+	// 创建 MCP 客户端，服务器发起的请求和通知交给 Agent 处理
 	ctx := context.Background()
-	mcpClient, err := mcp.NewClient(ctx, config)
+	clientOpts := agent.clientOptions()
+	// 工作区目录通过 roots 能力告知服务器，filesystem、code_search 等服务器只在工作区内操作
+	clientOpts.Roots = workspaces
+	if len(clientOpts.Roots) == 0 {
+		if cwd, err := os.Getwd(); err == nil {
+			clientOpts.Roots = []string{cwd}
+		}
+	}
+	agent.roots = clientOpts.Roots
+	// 在后台建立仓库地图，不阻塞第一个问题，完成后自动加入之后的推理请求；
+	// dump-prompt 需要完整的请求，等待索引完成
+	dumpPrompt := flag.Arg(0) == "dump-prompt"
+	if *repoMap && len(clientOpts.Roots) > 0 {
+		if dumpPrompt {
+			agent.indexWorkspace(ctx, clientOpts.Roots[0])
+		} else {
+			go agent.indexWorkspace(ctx, clientOpts.Roots[0])
+		}
+	}
+	clientOpts.LogDir = *serverLogDir
+	clientOpts.LogLevel = *serverLogLevel
+	clientOpts.StderrDir = *serverStderrDir
+	if debug.enabled(debugMCP) {
+		clientOpts.StderrTee = os.Stderr
+	}
+	if *trace {
+		clientOpts.Trace = os.Stderr
+	}
+	mcpClient, err := mcp.NewClient(ctx, config, clientOpts)
 	if err != nil {
 		log.Fatalf("Failed to create MCP client: %v", err)
 	}
-	defer mcpClient.Close()
+	defer closeMCPClient(mcpClient)
+	agent.mcpClient = mcpClient
 
-	if *verbose {
-		log.Println("MCP client initialized")
+	debug.logf(debugMCP, "MCP client initialized")
+
+	// 子命令: dump-prompt [问题] 输出发送给模型的请求
+	if dumpPrompt {
+		exitCode = runDumpPrompt(ctx, agent, strings.Join(flag.Args()[1:], " "))
+		return
 	}
 
-	// 初始化 Ollama 客户端
-	ollamaClient, err := api.ClientFromEnvironment()
-	if err != nil {
-		log.Fatalf("Failed to initialize Ollama client: %v", err)
+	// --verify-cmd 时工具操作工作区的副本，通过校验的修改才写回工作区
+	if *verifyCmd != "" {
+		render.Stdout.Note("verify", "copying the workspace, edits are applied to it after %s passes", *verifyCmd)
+		if agent.staging, err = newStagingWorkspace(ctx, clientOpts.Roots, config, clientOpts); err != nil {
+			log.Fatalf("Failed to prepare a copy of the workspace for --verify-cmd: %v", err)
+		}
+		defer agent.staging.close()
 	}
-	if *verbose {
-		log.Println("Ollama client initialized")
+	if headless {
+		exitCode = runHeadless(ctx, agent, *prompt, files, *statusFile)
+		return
+	}
+
+	// 配置文件变化时自动重新加载 MCP 服务器，无需重启会话
+	go mcp.WatchConfig(ctx, configPaths, mcp.DefaultWatchInterval, func() {
+		agent.reloadConfig(ctx, configPaths, *profile)
+	})
+
+	// 子命令: serve 以 HTTP API 提供多个并发的会话
+	if flag.Arg(0) == "serve" {
+		exitCode = runServe(ctx, agent, *listen, config, clientOpts)
+		return
+	}
+
+	if resumed != nil {
+		agent.conversation = agent.resumeSession(resumed)
+	}
+	if *importPath != "" {
+		manifest, imported, err := readShareBundle(*importPath)
+		if err != nil {
+			log.Fatalf("Failed to import session bundle: %v", err)
+		}
+		agent.conversation = imported
+		fmt.Printf("Imported %d messages from %s (model %s, shared %s)\n", len(imported), *importPath, manifest.Model, manifest.CreatedAt.Format(time.DateTime))
 	}
 
-	// 创建 Agent
-	agent := NewAgent(ollamaClient, mcpClient, *model, *verbose, *stream)
 	err = agent.Run(ctx)
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 	}
+	if agent.saved != nil {
+		render.Stdout.Hint("Session saved, continue it with --resume %s", agent.saved.ID)
+	}
+	agent.printToolMetrics()
+
+	// 保存本次会话的使用统计
+	if saveErr := saveUsage(agent.usage.finish(err == nil)); saveErr != nil {
+		debug.logf(debugUI, "Failed to save usage: %v", saveErr)
+	}
+}
+
+// defaultConfigPaths 返回默认的配置文件列表：全局的 ~/.claude.json，以及项目级配置。
+// 项目级配置优先使用 .mcp.json 或由 "mcp init" 生成的 mcp.json，其次是 map.json
+func defaultConfigPaths() []string {
+	var paths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".claude.json"))
+	}
+
+	for _, path := range []string{".mcp.json", "mcp.json", "map.json"} {
+		if _, err := os.Stat(path); err == nil {
+			return append(paths, path)
+		}
+	}
+	cwd, _ := os.Getwd()
+	return append(paths, filepath.Join(cwd, "mcp_agent", "map.json"))
+}
+
+// defaultServerStderrDir 返回 MCP 服务器 stderr 日志的默认目录 ~/.mcp_agent/logs
+func defaultServerStderrDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mcp_agent", "logs")
+}
+
+// loadConfig 加载并合并 MCP 配置，再应用选定的配置方案（profile）
+func loadConfig(paths []string, profile string) (*mcp.Config, error) {
+	config, err := mcp.LoadConfig(paths...)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ApplyProfile(profile); err != nil {
+		return nil, err
+	}
+	if err := checkAliases(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// closeMCPClient 关闭所有 MCP 服务器，并提示没有正常退出的服务器
+func closeMCPClient(client *mcp.Client) {
+	err := client.Close()
+	var shutdownErr *mcp.ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		if err != nil {
+			render.Stdout.Error(err)
+		}
+		return
+	}
+	for _, name := range shutdownErr.Servers() {
+		render.Stdout.Warning("mcp", "server %s did not shut down cleanly: %v", name, shutdownErr.Failed[name])
+	}
+}
+
+// flagPassed 判断命令行是否显式设置了指定参数
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// runHeadless 执行非交互模式，返回进程退出码
+func runHeadless(ctx context.Context, agent *Agent, prompt string, files []string, statusFile string) int {
+	agent.headless = true
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var answer string
+	attachments, err := loadAttachments(files)
+	if err == nil && prompt == "" {
+		prompt, attachments, err = stdinPrompt(attachments)
+	}
+	if err == nil {
+		answer, err = agent.RunOnce(ctx, prompt, attachments)
+	}
+	if err != nil {
+		// 错误输出到标准错误，脚本捕获标准输出时不会混入回答
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+	}
+
+	usage := agent.usage.finish(err == nil)
+	if saveErr := saveUsage(usage); saveErr != nil {
+		agent.debug.logf(debugUI, "Failed to save usage: %v", saveErr)
+	}
+
+	status := newRunStatus(ctx, answer, err, usage)
+	if statusFile != "" {
+		if err := writeStatus(statusFile, status); err != nil {
+			render.Stdout.Error(err)
+		}
+	}
+	return status.ExitCode
 }
 
-// Agent 是基于 MCP 的智能代理
+// Agent 是基于 MCP 的智能代理。agentCore 是所有会话共享的客户端和配置，Session 是当前会话的状态，
+// SessionManager 为每个会话创建共享同一 agentCore 的 Agent，使多个对话可以在同一进程中并发运行
 type Agent struct {
-	ollamaClient *api.Client
-	mcpClient    *mcp.Client
-	model        string
-	verbose      bool
-	stream       bool
-	inputLock    sync.Mutex
-	isProcessing bool
+	*agentCore
+	*Session
+}
+
+// agentCore 是所有会话共享的客户端、配置和缓存
+type agentCore struct {
+	llm       modelClient
+	mcpClient *mcp.Client
+	debug     debugChannels
+	stream    bool
+
+	// --model 指定的模型，新会话使用的默认模型
+	defaultModel string
+
+	// 是否显示 MCP 服务器发送的日志（调试 mcp 通道时），/debug 可以在运行中切换
+	serverLogs atomic.Bool
+
+	// 推理后端（ollama 或 openai）及其地址
+	provider string
+	endpoint string
+
+	// 使用的配置方案和工作区目录，显示在启动信息中
+	profile   string
+	workspace string
+
+	// 告知 MCP 服务器的工作区目录，文件工具参数中的相对路径相对于第一个目录
+	roots []string
+
+	// 交互模式下处理对话期间接管终端输入
+	input *inputGate
+
+	// ensemble 模式下同时回答的模型列表，第一个模型的回答作为对话延续
+	ensembleModels []string
+	ensembleMode   string
+
+	// 是否无需确认直接响应 MCP 服务器的 sampling 请求
+	autoApproveSampling bool
+
+	// 是否无需确认直接执行所有 MCP 工具
+	autoApproveTools bool
+
+	// 编辑 Go 文件后是否用 goimports 或 gopls 整理 import
+	autoImports bool
+
+	// 自主模式，verifyCmd 不为空时每轮对话的修改通过校验才写回工作区，校验命令最多运行 verifyTimeout
+	auto          bool
+	verifyCmd     string
+	verifyTimeout time.Duration
+
+	// 项目中保存的"总是允许"规则，加载失败时为 nil
+	permissions *permissions.Store
+
+	// MCP 服务器通知工具列表变化后置为 true，下次推理前刷新工具
+	toolsChanged atomic.Bool
+
+	// 发送给模型的工具描述语言，为空时保持原样
+	toolLang string
+
+	// 会话的保存位置（--session-store）
+	sessions sessionStore
+
+	// 模型回答使用的语言（为空时不限制），以及加入系统提示的项目术语表
+	defaultLang string
+	glossary    string
+
+	// 离线模式下禁用网络工具并拦截访问网络的命令
+	offline bool
+
+	// 各模型是否支持图片输入
+	visionMu      sync.Mutex
+	visionSupport map[string]bool
+
+	// 推理无响应超过该时间后探测模型状态并询问用户，0 表示不监视
+	stallTimeout time.Duration
+
+	// MCP 工具调用的超时时间，0 表示使用服务器配置
+	toolTimeout time.Duration
+
+	// 一轮对话中连续工具调用的最大轮数，0 表示不限制
+	maxToolIterations int
+
+	// 一轮对话中同一个工具调用的最大重复次数，超过后中止这一轮，0 表示不检测
+	maxRepeatedCalls int
+
+	// 同时执行的只读工具调用数，1 表示依次执行
+	parallelTools int
+
+	// 是否过滤网络类工具结果中的提示词注入
+	injectionGuard bool
+
+	// 配置中的 URL 访问策略，配置热加载时更新
+	urlPolicy atomic.Pointer[mcp.URLPolicy]
+	// 检查网络类服务器连接的本机代理，配置 urlPolicy 后启动
+	urlProxy *urlProxy
+
+	// 终端显示工具结果的最大字符数，0 表示完整显示；发送给模型的结果不受影响
+	resultDisplay int
+
+	// 发送给模型的工具数上限（0 按模型大小决定，-1 不限制），以及选择工具用的嵌入模型
+	maxTools   int
+	embedModel string
+
+	// 模型在请求之间保持加载的时间，以及提示词前缀的缓存
+	keepAlive   time.Duration
+	promptCache promptCache
+
+	// 流式响应的最大字节数，0 表示不限制
+	maxOutput int
+
+	// 模型的上下文窗口（num_ctx），0 表示使用 Ollama 的默认值
+	numCtx int
+
+	// 后台建立的工作区仓库地图，索引完成前为 nil
+	repoMap atomic.Pointer[string]
+
+	// 工作区代码片段的向量索引，在仓库地图之后建立；retrieveChunks 是每轮对话前检索的片段数，0 表示不检索
+	vectorIndex    atomic.Pointer[vectorindex.Index]
+	retrieveChunks int
+
+	// 非交互模式 (-p)，不向用户提问
+	headless bool
+
+	// 任务完成后是否进行评审，reviewModel 为空时使用主模型
+	review      bool
+	reviewModel string
 }
 
-// NewAgent 创建一个新的 Agent 实例
+// NewAgent 创建一个新的 Agent 实例及其第一个会话
 func NewAgent(
-	ollamaClient *api.Client,
+	llm modelClient,
 	mcpClient *mcp.Client,
 	model string,
-	verbose bool,
+	debug debugChannels,
 	stream bool,
 ) *Agent {
-	return &Agent{
-		ollamaClient: ollamaClient,
+	core := &agentCore{
+		llm:          llm,
 		mcpClient:    mcpClient,
-		model:        model,
-		verbose:      verbose,
+		debug:        debug,
 		stream:       stream,
+		defaultModel: model,
+		input:        newInputGate(os.Stdin),
 	}
+	core.serverLogs.Store(debug.enabled(debugMCP))
+	agent := &Agent{agentCore: core, Session: newSession(model, nil)}
+	agent.subscribe(agent.printEvent)
+	return agent
 }
 
-// Run 启动 Agent 的交互循环
-func (a *Agent) Run(ctx context.Context) error {
-	var conversation []api.Message
+// printBanner 显示启动信息：模型、推理服务、工作区、已连接的 MCP 服务器和工具数
+func (a *Agent) printBanner(tools []api.Tool) {
+	var servers []string
+	for _, status := range a.client().Status() {
+		if status.Alive() {
+			servers = append(servers, status.Name)
+		} else {
+			servers = append(servers, fmt.Sprintf("%s (%s)", status.Name, status.State))
+		}
+	}
+	render.Stdout.Banner(render.Banner{
+		Title:     "Chat with MCP tools",
+		Model:     a.model,
+		Provider:  a.provider,
+		Endpoint:  a.endpoint,
+		Workspace: a.workspace,
+		Profile:   a.profile,
+		Servers:   servers,
+		Tools:     len(tools),
+		Hint:      "use 'ctrl-c' to quit, '/help' to list commands",
+	})
+}
 
+// Run 启动当前会话的交互循环，会话中可以已有对话（如从分享包导入）
+func (a *Agent) Run(ctx context.Context) error {
 	// 获取 MCP 工具列表
-	tools, err := a.mcpClient.GetTools(ctx)
+	tools, err := a.loadTools(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get MCP tools: %w", err)
+		return err
 	}
 
-	if a.verbose {
-		log.Printf("Loaded %d MCP tools", len(tools))
-		for _, tool := range tools {
-			log.Printf("  - %s: %s", tool.Function.Name, tool.Function.Description)
-		}
+	a.debug.logf(debugTools, "Loaded %d MCP tools", len(tools))
+	for _, tool := range tools {
+		a.debug.logf(debugTools, "  - %s: %s", tool.Function.Name, tool.Function.Description)
 	}
 
-	fmt.Println("Chat with Ollama + MCP (use 'ctrl-c' to quit)")
-	fmt.Printf("Available tools: %d\n", len(tools))
+	a.printBanner(tools)
 
 	for {
-		var userInput string
-		prompt := &survey.Input{
-			Message: "\033[94mYou\033[0m:",
-		}
-		err := survey.AskOne(prompt, &userInput)
+		userInput, err := a.readInput()
 		if err != nil {
-			if a.verbose {
-				log.Printf("User input ended: %v", err)
-			}
+			a.debug.logf(debugUI, "User input ended: %v", err)
 			break
 		}
 
 		// 跳过空消息
 		if userInput == "" {
-			if a.verbose {
-				log.Println("Skipping empty message")
-			}
+			a.debug.logf(debugUI, "Skipping empty message")
 			continue
 		}
 
-		if a.verbose {
-			log.Printf("User input received: %q", userInput)
+		a.debug.logf(debugUI, "User input received: %q", userInput)
+
+		if strings.HasPrefix(userInput, "/") {
+			// 处理命令，命令可能修改对话（如注入渲染后的提示词模板、恢复历史会话）
+			updated, send, err := a.handleCommand(ctx, userInput, a.conversation, tools)
+			if err != nil {
+				render.Stdout.Error(err)
+				continue
+			}
+			a.conversation = updated
+			if !send {
+				continue
+			}
+		} else {
+			userMessage := api.Message{Role: "user", Content: userInput}
+			a.conversation = append(a.conversation, userMessage)
 		}
 
-		userMessage := api.Message{Role: "user", Content: userInput}
-		conversation = append(conversation, userMessage)
+		a.debug.logf(debugLLM, "Sending message to Ollama, conversation length: %d", len(a.conversation))
 
-		if a.verbose {
-			log.Printf("Sending message to Ollama, conversation length: %d", len(conversation))
+		// 处理期间输入的内容不回显，在这一轮结束后作为下一条输入
+		a.input.lock()
+		tools, err = a.runTurn(ctx, tools)
+		a.input.unlock()
+		if err != nil {
+			return err
 		}
+	}
 
-		// 禁止用户输入
-		//oldState, termErr := term.MakeRaw(int(os.Stdin.Fd()))
-		//if termErr != nil && a.verbose {
-		//	log.Printf("Warning: failed to set terminal raw mode: %v", termErr)
-		//}
-
-		var message api.Message
-		if a.stream {
-			fmt.Print("\u001b[93mOllama\u001b[0m:")
-			if message, err = a.runInferenceStreaming(ctx, conversation, tools); err != nil {
-				if a.verbose {
-					log.Printf("Error during streaming inference: %v", err)
-				}
-				return err
-			}
-		} else {
-			if message, err = a.runInference(ctx, conversation, tools); err != nil {
-				if a.verbose {
-					log.Printf("Error during inference: %v", err)
-				}
-				return err
-			}
-		}
+	a.debug.logf(debugUI, "Chat session ended")
+	return nil
+}
 
-		conversation = append(conversation, message)
+// readInput 读取下一条用户输入，优先使用处理上一轮时提前输入的内容
+func (a *Agent) readInput() (string, error) {
+	if line, ok := a.input.next(); ok {
+		fmt.Printf("%s %s\n", render.Stdout.Prompt("You"), line)
+		return line, nil
+	}
+	var userInput string
+	prompt := &survey.Input{
+		Message: render.Stdout.Prompt("You"),
+		Default: a.input.pending(),
+	}
+	err := survey.AskOne(prompt, &userInput)
+	return userInput, err
+}
 
-		// 持续处理直到没有工具调用
-		for {
-			// 显示文本内容
-			if !a.stream && message.Content != "" {
-				fmt.Printf("\u001b[93mOllama\u001b[0m: %s\n", message.Content)
-			}
+// runTurn 回答最新的用户消息，返回刷新后的工具列表
+func (a *Agent) runTurn(ctx context.Context, tools []api.Tool) ([]api.Tool, error) {
+	if len(a.ensembleModels) > 0 {
+		message, err := a.runEnsemble(ctx, a.conversation)
+		if err != nil {
+			render.Stdout.Error(err)
+			return tools, nil
+		}
+		a.conversation = append(a.conversation, message)
+		a.persistSession(ctx, a.conversation)
+		return tools, nil
+	}
 
-			// 检查工具调用
-			var hasToolUse bool
-			if len(message.ToolCalls) > 0 {
-				hasToolUse = true
-				if a.verbose {
-					log.Printf("Processing %d tool calls from Ollama", len(message.ToolCalls))
-				}
-
-				// 处理每个工具调用
-				for _, toolCall := range message.ToolCalls {
-					if a.verbose {
-						argsJSON, _ := json.Marshal(toolCall.Function.Arguments)
-						log.Printf("Tool use detected: %s with input: %s", toolCall.Function.Name, string(argsJSON))
-					}
-					argsJSON, _ := json.Marshal(toolCall.Function.Arguments)
-					fmt.Printf("\u001b[96mtool\u001b[0m: %s(%s)\n", toolCall.Function.Name, string(argsJSON))
-
-					// 通过 MCP 客户端调用工具
-					result, err := a.mcpClient.CallTool(ctx, toolCall.Function.Name, toolCall.Function.Arguments)
-
-					var toolResult string
-					if err != nil {
-						toolResult = fmt.Sprintf("Error: %v", err)
-						fmt.Printf("\u001b[91merror\u001b[0m: %s\n", err.Error())
-						if a.verbose {
-							log.Printf("Tool execution failed: %v", err)
-						}
-					} else {
-						// 将结果转换为字符串
-						toolResult = formatToolResult(result)
-						fmt.Printf("\u001b[92mresult\u001b[0m: %s\n", truncateString(toolResult, 500))
-						if a.verbose {
-							log.Printf("Tool execution successful, result length: %d chars", len(toolResult))
-						}
-					}
-
-					// 将工具结果添加到对话中
-					conversation = append(conversation, api.Message{
-						Role:     "tool",
-						Content:  toolResult,
-						ToolName: toolCall.Function.Name,
-					})
-				}
-			}
+	tools = a.refreshTools(ctx, tools)
+	return tools, a.completeTurn(ctx, tools)
+}
 
-			// 如果没有工具调用，结束循环
-			if !hasToolUse {
-				break
-			}
+// completeTurn 回答会话中最新的用户消息：处理工具调用、可选的评审，然后保存会话
+func (a *Agent) completeTurn(ctx context.Context, tools []api.Tool) error {
+	defer a.setState(stateAwaitingUser)
 
-			// 获取工具执行后的响应
-			if a.verbose {
-				log.Printf("Sending tool results back to Ollama")
-			}
-			message, err = a.runInference(ctx, conversation, tools)
-			if err != nil {
-				if a.verbose {
-					log.Printf("Error during followup inference: %v", err)
-				}
-				return err
-			}
-			conversation = append(conversation, message)
+	// 这一轮从调用方加入的用户消息开始
+	turnStart := len(a.conversation) - 1
+	if err := a.startTurnEdits(); err != nil {
+		return err
+	}
+	conversation, err := a.processTurn(ctx, a.conversation, tools)
+	if err == nil && a.verifyCmd != "" {
+		conversation, err = a.verifyTurn(ctx, conversation, tools)
+	}
+	a.conversation = conversation
+	if err != nil {
+		// 出错时也保存已完成的部分，之后可以用 --resume 继续
+		a.persistSession(ctx, a.conversation)
+		// Ctrl-C 取消推理后回到输入提示；修改未通过校验或模型陷入重复调用时提示用户，会话继续
+		if errors.Is(err, errInterrupted) {
+			return nil
+		}
+		if errors.Is(err, errVerifyFailed) || errors.Is(err, errToolLoop) {
+			render.Stdout.Error(err)
+			return nil
+		}
+		return err
+	}
 
-			if a.verbose {
-				log.Printf("Received followup response")
-			}
+	// 可选的评审环节：检查本轮修改是否满足用户请求
+	if a.review {
+		conversation, err = a.reviewTurn(ctx, a.conversation, tools, turnStart)
+		a.conversation = conversation
+		if err != nil {
+			return err
 		}
+	}
+	a.persistSession(ctx, a.conversation)
+	return nil
+}
 
-		// 恢复终端状态，允许用户输入
-		//if oldState != nil {
-		//	term.Restore(int(os.Stdin.Fd()), oldState)
-		//}
+// RunOnce 一次性回答 prompt（附带附件内容）后返回最终回答，用于非交互模式
+func (a *Agent) RunOnce(ctx context.Context, prompt string, attachments []attachment) (string, error) {
+	tools, err := a.loadTools(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errToolFailed, err)
 	}
 
-	if a.verbose {
-		log.Println("Chat session ended")
+	defer a.setState(stateAwaitingUser)
+
+	conversation := []api.Message{attachmentMessage(prompt, attachments)}
+	a.debug.logf(debugUI, "One-shot prompt with %d attachments, %d chars", len(attachments), len(conversation[0].Content))
+
+	if err := a.startTurnEdits(); err != nil {
+		return "", fmt.Errorf("%w: %v", errToolFailed, err)
 	}
-	return nil
+	if conversation, err = a.processTurn(ctx, conversation, tools); err != nil {
+		return "", err
+	}
+	if a.verifyCmd != "" {
+		if conversation, err = a.verifyTurn(ctx, conversation, tools); errors.Is(err, errVerifyFailed) {
+			return "", fmt.Errorf("%w: %v", errToolFailed, err)
+		} else if err != nil {
+			return "", err
+		}
+	}
+	if a.review {
+		if conversation, err = a.reviewTurn(ctx, conversation, tools, 0); err != nil {
+			return "", err
+		}
+	}
+
+	answer := conversation[len(conversation)-1].Content
+	if a.toolLimitReached {
+		return answer, fmt.Errorf("%w: stopped after %d rounds of tool calls", errCapExceeded, a.maxToolIterations)
+	}
+	// 按最终结果分类：最后一次工具调用失败后模型仍给出了回答，说明它已经处理了这个错误
+	if a.lastToolErr != nil && strings.TrimSpace(answer) == "" {
+		return answer, fmt.Errorf("%w: %v", errToolFailed, a.lastToolErr)
+	}
+	return answer, nil
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+	"golang.org/x/term"
+)
+
+// modelCommand 处理 /model：不带参数时列出可用的模型并选择，带参数时切换到指定模型。
+// 加上 --resend 时在对话末尾重新发送系统提示，新模型不必从很长的历史开头找到指令
+func (a *Agent) modelCommand(ctx context.Context, args []string, conversation []api.Message) ([]api.Message, error) {
+	resend := slices.Contains(args, "--resend")
+	args = slices.DeleteFunc(args, func(s string) bool { return s == "--resend" })
+
+	models, err := a.llm.List(ctx)
+	if err != nil {
+		a.debug.logf(debugLLM, "Failed to list models: %v", err)
+	}
+
+	var name string
+	switch {
+	case len(args) > 0:
+		name = args[0]
+	case err != nil:
+		fmt.Printf("model: %s (failed to list the available models: %v)\n", a.model, err)
+		return conversation, nil
+	default:
+		a.printModels(models)
+		if !term.IsTerminal(int(os.Stdin.Fd())) || len(models.Models) == 0 {
+			return conversation, nil
+		}
+		names := modelNames(models)
+		name = a.model
+		prompt := &survey.Select{Message: "Switch to model:", Options: names, Default: a.matchModel(names, a.model)}
+		if err := survey.AskOne(prompt, &name); err != nil {
+			return conversation, nil
+		}
+	}
+
+	if name == a.model && !resend {
+		fmt.Printf("model: %s\n", a.model)
+		return conversation, nil
+	}
+	if models != nil && a.matchModel(modelNames(models), name) == "" {
+		if a.provider == providerOllama {
+			render.Stdout.Warning("model", "%s is not pulled yet, you will be asked to pull it on the next request", name)
+		} else {
+			render.Stdout.Warning("model", "%s is not listed by the server", name)
+		}
+	}
+	a.model = name
+	fmt.Printf("switched to model %s\n", a.model)
+
+	if resend {
+		conversation = a.resendSystemPrompt(conversation)
+	}
+	return conversation, nil
+}
+
+// printModels 列出服务上可用的模型，当前模型以 * 标出
+func (a *Agent) printModels(models *api.ListResponse) {
+	if len(models.Models) == 0 {
+		fmt.Printf("model: %s (no models available)\n", a.model)
+		return
+	}
+	current := a.matchModel(modelNames(models), a.model)
+	for _, m := range models.Models {
+		mark := " "
+		if m.Name == current {
+			mark = render.Stdout.Paint(render.Green, "*")
+		}
+		var details []string
+		if m.Details.ParameterSize != "" {
+			details = append(details, m.Details.ParameterSize)
+		}
+		if m.Size > 0 {
+			details = append(details, formatBytes(m.Size))
+		}
+		line := fmt.Sprintf("%s %s", mark, m.Name)
+		if len(details) > 0 {
+			line += " " + render.Stdout.Paint(render.Gray, "("+strings.Join(details, ", ")+")")
+		}
+		fmt.Println(line)
+	}
+}
+
+// matchModel 返回列表中与 name 对应的模型名，Ollama 模型名省略标签时等同于 :latest；没有时返回空字符串
+func (a *Agent) matchModel(names []string, name string) string {
+	for _, n := range names {
+		if n == name || (a.provider == providerOllama && !strings.Contains(name, ":") && n == name+":latest") {
+			return n
+		}
+	}
+	return ""
+}
+
+func modelNames(models *api.ListResponse) []string {
+	names := make([]string, len(models.Models))
+	for i, m := range models.Models {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// resendSystemPrompt 在对话末尾追加一条系统消息，重述对话开头的系统提示以及回答语言和术语表
+func (a *Agent) resendSystemPrompt(conversation []api.Message) []api.Message {
+	var parts []string
+	for _, message := range a.withInstructions(conversation) {
+		if message.Role != "system" {
+			break
+		}
+		parts = append(parts, message.Content)
+	}
+	if len(parts) == 0 {
+		fmt.Println("no system prompt to resend")
+		return conversation
+	}
+	render.Stdout.Note("model", "the system prompt will be sent again with the next message")
+	return append(conversation, api.Message{
+		Role:    "system",
+		Content: "The model was switched. These instructions from the start of the conversation still apply:\n\n" + strings.Join(parts, "\n\n"),
+	})
+}
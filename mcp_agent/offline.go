@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// offlineToolPattern 匹配需要访问网络的工具名或服务器名（按 "_" 分隔的单词匹配）
+var offlineToolPattern = regexp.MustCompile(`(?i)(^|_)(web|http|https|browser|fetch|download|curl)(_|$)`)
+
+// networkCommandPattern 匹配会访问网络的 shell 命令
+var networkCommandPattern = regexp.MustCompile(`(?i)(^|[\s;&|(` + "`" + `])(curl|wget|ssh|scp|sftp|rsync|ftp|telnet|nc|ncat|ping|pip3?\s+install|npm\s+(install|i|add)|yarn\s+add|go\s+(get|install)|apt(-get)?\s+install|git\s+(clone|fetch|pull|push))(\s|$)`)
+
+// isNetworkTool 判断工具是否需要访问网络，同时检查工具名（可能是别名）和所属服务器名
+func (a *Agent) isNetworkTool(name string) bool {
+	parts := []string{name}
+	if server, tool, err := a.client().ResolveToolName(name); err == nil {
+		parts = []string{server, tool}
+	}
+	for _, part := range parts {
+		if offlineToolPattern.MatchString(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterOfflineTools 移除离线模式下不可用的工具
+func (a *Agent) filterOfflineTools(tools []api.Tool) []api.Tool {
+	filtered := make([]api.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if a.isNetworkTool(tool.Function.Name) {
+			a.debug.logf(debugTools, "Offline mode: disabled tool %s", tool.Function.Name)
+			continue
+		}
+		filtered = append(filtered, tool)
+	}
+	return filtered
+}
+
+// checkOffline 在离线模式下拒绝网络工具以及包含网络命令的 shell 调用
+func (a *Agent) checkOffline(name string, args map[string]interface{}) error {
+	if a.isNetworkTool(name) {
+		return fmt.Errorf("tool %s is disabled in offline mode", name)
+	}
+	for _, key := range []string{"command", "cmd", "script"} {
+		command, ok := args[key].(string)
+		if !ok {
+			continue
+		}
+		if match := networkCommandPattern.FindString(command); match != "" {
+			return fmt.Errorf("command blocked in offline mode: %q accesses the network", strings.TrimSpace(match))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"cmp"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// defaultOllamaPort 是 --ollama-host 未指定协议和端口时使用的端口
+const defaultOllamaPort = "11434"
+
+// ollamaOptions 配置如何连接 Ollama，用于部署在需要认证的反向代理之后的远程实例
+type ollamaOptions struct {
+	host     string   // 如 https://ollama.example.com，为空时使用 OLLAMA_HOST
+	token    string   // Bearer token，可以引用环境变量，如 ${MY_TOKEN}，为空时使用 $OLLAMA_TOKEN
+	user     string   // Basic 认证的 "用户名:密码"，密码可以引用环境变量
+	headers  []string // 额外的请求头，格式为 "Name: Value"
+	caFile   string   // 验证服务器证书的 CA 证书（PEM）
+	certFile string   // 客户端证书（PEM），用于 mTLS
+	keyFile  string   // 客户端私钥（PEM）
+	insecure bool     // 不验证服务器证书
+}
+
+// newOllamaClient 按选项创建 Ollama 客户端，未设置任何选项时与 api.ClientFromEnvironment 相同
+func newOllamaClient(opts ollamaOptions) (*api.Client, error) {
+	// 默认值不写在 flag 中，避免 -h 输出 token；使用 --ollama-user 时不读取 $OLLAMA_TOKEN
+	if opts.user == "" {
+		opts.token = cmp.Or(os.ExpandEnv(opts.token), os.Getenv("OLLAMA_TOKEN"))
+	}
+	if !opts.configured() {
+		return api.ClientFromEnvironment()
+	}
+
+	base, err := parseOllamaHost(opts.host)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.caFile != "" || opts.certFile != "" || opts.keyFile != "" || opts.insecure {
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	header := http.Header{}
+	for _, h := range opts.headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --ollama-header %q, expected \"Name: Value\"", h)
+		}
+		header.Set(strings.TrimSpace(name), os.ExpandEnv(strings.TrimSpace(value)))
+	}
+	switch {
+	case opts.token != "" && opts.user != "":
+		return nil, fmt.Errorf("--ollama-token and --ollama-user cannot be used together")
+	case opts.token != "":
+		header.Set("Authorization", "Bearer "+opts.token)
+	case opts.user != "":
+		user, password, _ := strings.Cut(opts.user, ":")
+		req := http.Request{Header: http.Header{}}
+		req.SetBasicAuth(user, os.ExpandEnv(password))
+		header.Set("Authorization", req.Header.Get("Authorization"))
+	}
+
+	return api.NewClient(base, &http.Client{Transport: &headerTransport{header: header, next: transport}}), nil
+}
+
+func (o ollamaOptions) configured() bool {
+	return o.host != "" || o.token != "" || o.user != "" || len(o.headers) > 0 ||
+		o.caFile != "" || o.certFile != "" || o.keyFile != "" || o.insecure
+}
+
+// parseOllamaHost 解析 --ollama-host，规则与 OLLAMA_HOST 相同：没有协议时使用 http 和默认端口
+func parseOllamaHost(host string) (*url.URL, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		host = os.Getenv("OLLAMA_HOST")
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if !strings.Contains(host, "://") {
+		hostport, path, _ := strings.Cut(host, "/")
+		if _, _, err := net.SplitHostPort(hostport); err != nil {
+			hostport = net.JoinHostPort(strings.Trim(hostport, "[]"), defaultOllamaPort)
+		}
+		host = "http://" + hostport
+		if path != "" {
+			host += "/" + path
+		}
+	}
+
+	base, err := url.Parse(host)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid Ollama host %q", host)
+	}
+	return base, nil
+}
+
+func (o ollamaOptions) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: o.insecure}
+	if o.caFile != "" {
+		pem, err := os.ReadFile(o.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.caFile)
+		}
+		config.RootCAs = pool
+	}
+	if o.certFile != "" || o.keyFile != "" {
+		if o.certFile == "" || o.keyFile == "" {
+			return nil, fmt.Errorf("--ollama-cert and --ollama-key must be used together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// headerTransport 为每个请求添加认证和自定义请求头
+type headerTransport struct {
+	header http.Header
+	next   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.header {
+		req.Header[name] = values
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/AlecAivazis/survey/v2"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/permissions"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+)
+
+// 确认提示的选项，选择"总是允许"后规则保存到项目的 .coding-agent/permissions.json，之后的会话不再询问
+const (
+	approveYes    = "Yes"
+	approveNo     = "No"
+	approveAlways = "Always (remember for this project)"
+)
+
+// projectDir 返回保存项目设置的目录：第一个 --workspace 目录，未指定时为当前目录
+func projectDir(workspaces []string) string {
+	if len(workspaces) > 0 {
+		return workspaces[0]
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return cwd
+}
+
+// maxApprovalArgLength 是确认提示中每个参数值显示的最大长度，如 write_file 的文件内容
+const maxApprovalArgLength = 1000
+
+// approveToolCall 在执行 MCP 工具之前显示参数并请求用户确认。配置中 autoApprove 列出的工具
+// （如只读工具）、项目中总是允许的工具和 --auto-approve-tools 不再询问；非交互模式下无法询问，
+// 需要确认的调用被拒绝
+func (a *Agent) approveToolCall(name string, args map[string]any) error {
+	if a.autoApproveTools || a.client().AutoApproved(name) || a.permissions.Allowed(permissions.KindTool, name) {
+		return nil
+	}
+	if a.headless {
+		return fmt.Errorf("tool %s needs approval, which is not possible with -p; add it to autoApprove in the MCP config or use --auto-approve-tools", name)
+	}
+
+	render.Stdout.Line(render.Magenta, "approve", "%s wants to run with:", name)
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := args[key]
+		text, ok := value.(string)
+		if !ok {
+			data, _ := json.Marshal(value)
+			text = string(data)
+		}
+		fmt.Printf("  %s: %s\n", key, truncateString(text, maxApprovalArgLength))
+	}
+
+	defer a.input.pause()()
+
+	answer := approveNo
+	prompt := &survey.Select{
+		Message: "Run this tool?",
+		Options: []string{approveYes, approveNo, approveAlways},
+		Default: approveNo,
+	}
+	if err := survey.AskOne(prompt, &answer); err != nil || answer == approveNo {
+		return fmt.Errorf("tool call %s was rejected by the user", name)
+	}
+	if answer == approveAlways {
+		a.rememberApproval(permissions.KindTool, name)
+	}
+	return nil
+}
+
+// rememberApproval 保存"总是允许"的选择，保存失败时只提示，本次请求仍然允许
+func (a *Agent) rememberApproval(kind, name string) {
+	if a.permissions == nil {
+		return
+	}
+	if err := a.permissions.Allow(kind, name); err != nil {
+		render.Stdout.Warning("permissions", "failed to save %s:%s: %v", kind, name, err)
+		return
+	}
+	render.Stdout.Note("permissions", "always allowing %s:%s in %s", kind, name, a.permissions.Path())
+}
+
+// runPermissionsCommand 执行 permissions 子命令：list 列出项目保存的规则，revoke <kind:name> 删除规则
+func runPermissionsCommand(dir string, args []string) error {
+	store, err := permissions.Load(dir)
+	if err != nil {
+		return err
+	}
+	action := "list"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "list":
+		rules := store.Rules()
+		if len(rules) == 0 {
+			fmt.Printf("No saved permissions in %s\n", store.Path())
+			return nil
+		}
+		fmt.Printf("Saved permissions in %s:\n", store.Path())
+		for _, rule := range rules {
+			fmt.Printf("  %-40s %s\n", rule, render.Stdout.Paint(render.Gray, "added "+rule.Added.Local().Format("2006-01-02 15:04")))
+		}
+		return nil
+	case "revoke":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: permissions revoke <kind:name>")
+		}
+		removed, err := store.Revoke(permissions.Parse(args[1]))
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return fmt.Errorf("no saved permission matches %q (see permissions list)", args[1])
+		}
+		for _, rule := range removed {
+			fmt.Printf("Revoked %s\n", rule)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown permissions command %q, use list or revoke", action)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultKeepAlive 让 Ollama 在两轮对话之间保持模型加载，模型卸载后前缀缓存（KV cache）也会丢失
+const DefaultKeepAlive = 30 * time.Minute
+
+// promptCache 跟踪请求中稳定前缀（开头的系统消息、仓库地图和工具列表）是否与上一次请求相同。
+// Ollama 对与上一次请求相同的前缀复用 KV cache，因此保持前缀逐字节不变、并让模型保持加载，
+// 可以省去重新处理这部分提示词的时间。系统消息直接按内容哈希；工具列表在同一轮对话中是同一个切片，
+// 只在切片变化时重新序列化
+type promptCache struct {
+	mu        sync.Mutex
+	tools     []api.Tool
+	toolsHash [sha256.Size]byte
+	toolsSize int
+	prefix    string // 上一次请求的前缀哈希
+}
+
+// observe 计算请求的前缀哈希并返回前缀大小（字节），以及前缀是否与上一次请求相同
+func (c *promptCache) observe(req *api.ChatRequest) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h := sha256.New()
+	size := 0
+	for _, message := range req.Messages {
+		if message.Role != "system" {
+			break
+		}
+		io.WriteString(h, message.Content)
+		h.Write([]byte{0})
+		size += len(message.Content)
+	}
+
+	if len(req.Tools) > 0 {
+		if !sameTools(c.tools, req.Tools) {
+			data, _ := json.Marshal(req.Tools)
+			c.tools = req.Tools
+			c.toolsHash = sha256.Sum256(data)
+			c.toolsSize = len(data)
+		}
+		h.Write(c.toolsHash[:])
+		size += c.toolsSize
+	}
+
+	key := ""
+	if size > 0 {
+		key = hex.EncodeToString(h.Sum(nil)[:8])
+	}
+	unchanged := key != "" && key == c.prefix
+	c.prefix = key
+	return size, unchanged
+}
+
+// sameTools 判断两个工具列表是否为同一个切片
+func sameTools(a, b []api.Tool) bool {
+	return len(a) == len(b) && len(a) > 0 && &a[0] == &b[0]
+}
+
+// prepareRequest 设置模型保持加载的时间，并记录提示词前缀是否可以被 Ollama 缓存
+func (a *Agent) prepareRequest(req *api.ChatRequest) {
+	if a.keepAlive > 0 && req.KeepAlive == nil {
+		req.KeepAlive = &api.Duration{Duration: a.keepAlive}
+	}
+	if a.numCtx > 0 {
+		if req.Options == nil {
+			req.Options = make(map[string]any)
+		}
+		if _, ok := req.Options["num_ctx"]; !ok {
+			req.Options["num_ctx"] = a.numCtx
+		}
+	}
+
+	// 只跟踪主对话的请求，标题生成、评审等一次性请求的前缀各不相同
+	if len(req.Tools) == 0 {
+		return
+	}
+	size, unchanged := a.promptCache.observe(req)
+	if unchanged {
+		a.debug.logf(debugLLM, "Prompt prefix unchanged (%d bytes), reusable from the model's cache", size)
+	} else {
+		a.debug.logf(debugLLM, "Prompt prefix changed (%d bytes), the model processes it again", size)
+	}
+}
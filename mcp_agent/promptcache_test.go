@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromptCacheObserve(t *testing.T) {
+	tools := []api.Tool{{Type: "function", Function: api.ToolFunction{Name: "read_file", Description: "Read a file"}}}
+	request := func(system string, tools []api.Tool, question string) *api.ChatRequest {
+		return &api.ChatRequest{
+			Messages: []api.Message{{Role: "system", Content: system}, {Role: "user", Content: question}},
+			Tools:    tools,
+		}
+	}
+
+	var c promptCache
+	size, unchanged := c.observe(request("You are a coding agent.", tools, "first"))
+	assert.False(t, unchanged)
+	assert.Greater(t, size, len("You are a coding agent."))
+
+	// 只有对话部分变化时前缀不变
+	_, unchanged = c.observe(request("You are a coding agent.", tools, "second"))
+	assert.True(t, unchanged)
+
+	// 内容相同的新工具列表不影响前缀
+	copied := append([]api.Tool(nil), tools...)
+	_, unchanged = c.observe(request("You are a coding agent.", copied, "third"))
+	assert.True(t, unchanged)
+
+	_, unchanged = c.observe(request("You are a careful coding agent.", copied, "fourth"))
+	assert.False(t, unchanged)
+
+	changed := []api.Tool{{Type: "function", Function: api.ToolFunction{Name: "write_file"}}}
+	_, unchanged = c.observe(request("You are a careful coding agent.", changed, "fifth"))
+	assert.False(t, unchanged)
+}
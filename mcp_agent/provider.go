@@ -0,0 +1,114 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/openai"
+	"github.com/ollama/ollama/api"
+)
+
+// 推理后端
+const (
+	providerOllama = "ollama"
+	providerOpenAI = "openai" // 兼容 OpenAI Chat Completions API 的服务，如 vLLM、LM Studio、DeepSeek
+)
+
+// modelClient 是 agent 使用的推理接口，*api.Client 直接实现了它
+type modelClient interface {
+	Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error
+	Embed(ctx context.Context, req *api.EmbedRequest) (*api.EmbedResponse, error)
+	Show(ctx context.Context, req *api.ShowRequest) (*api.ShowResponse, error)
+	Pull(ctx context.Context, req *api.PullRequest, fn api.PullProgressFunc) error
+	ListRunning(ctx context.Context) (*api.ProcessResponse, error)
+	List(ctx context.Context) (*api.ListResponse, error)
+}
+
+// openaiOptions 配置如何连接 OpenAI 兼容的服务
+type openaiOptions struct {
+	baseURL string // 如 http://localhost:8000/v1
+	apiKey  string // 可以引用环境变量，如 ${DEEPSEEK_API_KEY}，为空时使用 $OPENAI_API_KEY
+}
+
+// newModelClient 按 --provider 创建推理客户端
+func newModelClient(provider string, ollama ollamaOptions, opts openaiOptions) (modelClient, error) {
+	switch provider {
+	case providerOllama:
+		return newOllamaClient(ollama)
+	case providerOpenAI:
+		if ollama.configured() {
+			return nil, errors.New("--ollama-* options cannot be used with --provider openai")
+		}
+		// 默认值不写在 flag 中，避免 -h 输出 API key
+		apiKey := cmp.Or(os.ExpandEnv(opts.apiKey), os.Getenv("OPENAI_API_KEY"))
+		return openaiClient{openai.New(modelEndpoint(provider, ollama, opts), apiKey, nil)}, nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q, expected %s or %s", provider, providerOllama, providerOpenAI)
+	}
+}
+
+// modelEndpoint 返回推理服务的地址，用于启动信息
+func modelEndpoint(provider string, ollama ollamaOptions, opts openaiOptions) string {
+	if provider == providerOpenAI {
+		return cmp.Or(opts.baseURL, os.Getenv("OPENAI_BASE_URL"), openai.DefaultBaseURL)
+	}
+	base, err := parseOllamaHost(ollama.host)
+	if err != nil {
+		return ""
+	}
+	return base.String()
+}
+
+// errNotOllama 表示 OpenAI 兼容的服务没有对应的 Ollama 接口
+var errNotOllama = errors.New("not supported by OpenAI-compatible servers")
+
+// openaiClient 将 OpenAI 兼容的服务适配为 modelClient。模型信息和下载是 Ollama 独有的：
+// 查询模型信息返回错误，agent 因此不附加图片、不按参数量限制工具数，也不会提示下载模型
+type openaiClient struct {
+	*openai.Client
+}
+
+func (c openaiClient) Show(ctx context.Context, req *api.ShowRequest) (*api.ShowResponse, error) {
+	return nil, fmt.Errorf("show %s: %w", req.Model, errNotOllama)
+}
+
+func (c openaiClient) Pull(ctx context.Context, req *api.PullRequest, fn api.PullProgressFunc) error {
+	return fmt.Errorf("pull %s: %w", req.Model, errNotOllama)
+}
+
+// ListRunning 返回服务提供的模型，用于推理停滞时判断服务是否仍在响应
+func (c openaiClient) ListRunning(ctx context.Context) (*api.ProcessResponse, error) {
+	ids, err := c.Models(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.ProcessResponse{}
+	for _, id := range ids {
+		resp.Models = append(resp.Models, api.ProcessModelResponse{Name: id, Model: id})
+	}
+	return resp, nil
+}
+
+// List 返回服务提供的模型，用于 /model 选择模型
+func (c openaiClient) List(ctx context.Context) (*api.ListResponse, error) {
+	ids, err := c.Models(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.ListResponse{}
+	for _, id := range ids {
+		resp.Models = append(resp.Models, api.ListModelResponse{Name: id, Model: id})
+	}
+	return resp, nil
+}
+
+// serverName 返回推理服务的名称，用于提示信息
+func (a *Agent) serverName() string {
+	if a.provider == providerOpenAI {
+		return "OpenAI-compatible"
+	}
+	return "Ollama"
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewModelClientAPIKey(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   string
+	}{
+		{"from environment", "", "Bearer env-key"},
+		{"flag", "flag-key", "Bearer flag-key"},
+		{"flag referencing a variable", "${OTHER_API_KEY}", "Bearer other-key"},
+	}
+	t.Setenv("OPENAI_API_KEY", "env-key")
+	t.Setenv("OTHER_API_KEY", "other-key")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm, err := newModelClient(providerOpenAI, ollamaOptions{}, openaiOptions{baseURL: server.URL, apiKey: tt.apiKey})
+			require.NoError(t, err)
+			_, err = llm.(openaiClient).Models(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, auth)
+		})
+	}
+}
+
+func TestNewOllamaClientToken(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"models": []}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts ollamaOptions
+		want string
+	}{
+		{"from environment", ollamaOptions{}, "Bearer env-token"},
+		{"flag", ollamaOptions{token: "flag-token"}, "Bearer flag-token"},
+		{"flag referencing a variable", ollamaOptions{token: "${OTHER_TOKEN}"}, "Bearer other-token"},
+		{"basic auth ignores the environment", ollamaOptions{user: "bob:secret"}, "Basic Ym9iOnNlY3JldA=="},
+	}
+	t.Setenv("OLLAMA_TOKEN", "env-token")
+	t.Setenv("OTHER_TOKEN", "other-token")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.host = server.URL
+			llm, err := newModelClient(providerOllama, tt.opts, openaiOptions{})
+			require.NoError(t, err)
+			_, err = llm.List(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, auth)
+		})
+	}
+
+	// 只设置了 $OLLAMA_TOKEN 时仍可以使用 OpenAI 兼容的服务
+	_, err := newModelClient(providerOpenAI, ollamaOptions{}, openaiOptions{baseURL: server.URL})
+	assert.NoError(t, err)
+}
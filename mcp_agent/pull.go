@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// pullBarWidth 是下载进度条的宽度（字符数）
+const pullBarWidth = 30
+
+// pullMu 保证并发推理（如 ensemble 模式）时同一个模型只询问和下载一次
+var pullMu sync.Mutex
+
+// isModelNotFound 判断错误是否表示模型尚未下载到本地
+func isModelNotFound(err error) bool {
+	var statusErr api.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound &&
+		strings.Contains(statusErr.ErrorMessage, "not found")
+}
+
+// chatPulling 发送推理请求，模型尚未下载时询问用户是否下载，下载完成后重试请求
+func (a *Agent) chatPulling(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	err := a.chatWatched(ctx, req, fn)
+	if !isModelNotFound(err) {
+		return err
+	}
+
+	pulled, pullErr := a.offerPull(ctx, req.Model)
+	if pullErr != nil {
+		return fmt.Errorf("failed to pull %s: %w", req.Model, pullErr)
+	}
+	if !pulled {
+		return fmt.Errorf("model %s is not available, run 'ollama pull %s' first: %w", req.Model, req.Model, err)
+	}
+	return a.chatWatched(ctx, req, fn)
+}
+
+// offerPull 询问用户是否下载模型并显示下载进度，返回模型是否已可用。非交互模式下不下载
+func (a *Agent) offerPull(ctx context.Context, model string) (bool, error) {
+	pullMu.Lock()
+	defer pullMu.Unlock()
+
+	// 另一个并发请求可能已经下载了这个模型
+	if _, err := a.llm.Show(ctx, &api.ShowRequest{Model: model}); err == nil {
+		return true, nil
+	}
+	if a.headless {
+		return false, nil
+	}
+
+	fmt.Println()
+	render.Stdout.Warning("ollama", "model %s has not been pulled yet", model)
+	resume := a.input.pause()
+	pull := true
+	err := survey.AskOne(&survey.Confirm{Message: fmt.Sprintf("Pull %s now?", model), Default: true}, &pull)
+	resume()
+	if err != nil || !pull {
+		return false, nil
+	}
+
+	progress := &pullProgress{}
+	err = a.llm.Pull(ctx, &api.PullRequest{Model: model}, func(resp api.ProgressResponse) error {
+		progress.update(resp)
+		return nil
+	})
+	progress.finish()
+	if err != nil {
+		return false, err
+	}
+	render.Stdout.Success("ollama", "pulled %s, retrying the request", model)
+	return true, nil
+}
+
+// pullProgress 在同一行显示下载状态和进度条，每个状态（如下载某一层）结束后换行
+type pullProgress struct {
+	status string
+	digest string
+}
+
+func (p *pullProgress) update(resp api.ProgressResponse) {
+	status := resp.Status
+	if resp.Digest != "" {
+		status = "pulling " + shortDigest(resp.Digest)
+	}
+	if status != p.status || resp.Digest != p.digest {
+		p.finish()
+		p.status, p.digest = status, resp.Digest
+	}
+
+	if resp.Total <= 0 {
+		render.Stdout.Status("pull", "%s", status)
+		return
+	}
+	ratio := float64(resp.Completed) / float64(resp.Total)
+	filled := min(int(ratio*pullBarWidth), pullBarWidth)
+	render.Stdout.Status("pull", "%s [%s%s] %3.0f%% %s/%s", status,
+		strings.Repeat("#", filled), strings.Repeat(" ", pullBarWidth-filled),
+		ratio*100, formatBytes(resp.Completed), formatBytes(resp.Total))
+}
+
+// finish 结束当前状态的进度行
+func (p *pullProgress) finish() {
+	if p.status != "" {
+		fmt.Println()
+		p.status = ""
+	}
+}
+
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+// formatBytes 以易读的单位显示字节数
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+)
+
+// crashLogName 是记录工具 panic 堆栈的日志文件名，位于 ~/.mcp_agent 下
+const crashLogName = "crash.log"
+
+// crashLogPath 返回 panic 日志文件路径
+func crashLogPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".mcp_agent", crashLogName), nil
+}
+
+// toolPanic 将工具处理中的 panic 转换为工具错误，堆栈追加到 panic 日志文件，会话继续进行
+func (a *Agent) toolPanic(name string, recovered any) error {
+	stack := debug.Stack()
+	a.debug.logf(debugTools, "Tool %s panicked: %v\n%s", name, recovered, stack)
+
+	path, err := writeCrashLog(name, recovered, stack)
+	if err != nil {
+		a.debug.logf(debugTools, "Failed to write the crash log: %v", err)
+		return fmt.Errorf("tool %s crashed: %v", name, recovered)
+	}
+	return fmt.Errorf("tool %s crashed: %v (stack trace written to %s)", name, recovered, path)
+}
+
+// writeCrashLog 将 panic 信息和堆栈追加到日志文件，返回文件路径
+func writeCrashLog(name string, recovered any, stack []byte) (string, error) {
+	path, err := crashLogPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s panic in tool %s: %v\n%s\n", time.Now().Format(time.RFC3339), name, recovered, stack); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// defaultMaxRepeatedCalls 是一轮对话中同一个工具调用被重复的默认上限，超过后中止这一轮对话
+const defaultMaxRepeatedCalls = 3
+
+// errToolLoop 表示模型反复发出相同的工具调用，这一轮对话被中止
+var errToolLoop = fmt.Errorf("%w: the model kept repeating the same tool call", errCapExceeded)
+
+// repeatedCalls 记录一轮对话中执行过的工具调用及其结果。小模型经常陷入反复发出相同调用的循环，
+// 名称和参数都相同的调用不再执行，而是直接返回之前的结果并提醒模型。
+// 可能修改文件的调用执行后清空记录，之后相同的调用可能得到不同的结果，需要重新执行
+type repeatedCalls struct {
+	results map[string]api.Message
+	repeats map[string]int
+	worst   int // 重复次数最多的调用被重复的次数
+}
+
+// reset 在新一轮对话开始时清空记录
+func (r *repeatedCalls) reset() {
+	*r = repeatedCalls{}
+}
+
+// callKey 返回标识工具调用的键，参数按 JSON 编码，键的顺序固定
+func callKey(toolCall api.ToolCall) string {
+	args, _ := json.Marshal(toolCall.Function.Arguments)
+	return toolCall.Function.Name + " " + string(args)
+}
+
+// lookup 返回相同调用之前的结果，并记录一次重复
+func (r *repeatedCalls) lookup(toolCall api.ToolCall) (api.Message, bool) {
+	key := callKey(toolCall)
+	result, ok := r.results[key]
+	if !ok {
+		return api.Message{}, false
+	}
+	if r.repeats == nil {
+		r.repeats = make(map[string]int)
+	}
+	r.repeats[key]++
+	r.worst = max(r.worst, r.repeats[key])
+	return result, true
+}
+
+// seen 判断相同的调用是否已经执行过
+func (r *repeatedCalls) seen(toolCall api.ToolCall) bool {
+	_, ok := r.results[callKey(toolCall)]
+	return ok
+}
+
+// record 记录执行过的调用及其结果，mutating 表示调用可能修改了文件，之前的结果不再可信
+func (r *repeatedCalls) record(toolCall api.ToolCall, result api.Message, mutating bool) {
+	if mutating || r.results == nil {
+		r.results = make(map[string]api.Message)
+		r.repeats = nil
+		r.worst = 0
+	}
+	r.results[callKey(toolCall)] = result
+}
+
+// repeatToolCall 不再执行重复的工具调用，将之前的结果加入对话并提醒模型换一种做法
+func (a *Agent) repeatToolCall(conversation []api.Message, toolCall api.ToolCall, previous api.Message) []api.Message {
+	name := toolCall.Function.Name
+	render.Stdout.Warning("tools", "%s was called again with the same arguments, reusing the previous result", name)
+	a.debug.logf(debugTools, "Repeated tool call %s", callKey(toolCall))
+	return append(conversation, api.Message{
+		Role: "tool",
+		Content: fmt.Sprintf("You already called %s with exactly these arguments in this request, so it was not run again. "+
+			"Do not repeat the same call: use the previous result below, or try different arguments or another tool.\n\n%s", name, previous.Content),
+		ToolName:   name,
+		ToolCallID: toolCall.ID,
+	})
+}
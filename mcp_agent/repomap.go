@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// maxIndexedFiles 限制索引的文件数，避免超大仓库拖慢启动
+	maxIndexedFiles = 5000
+	// maxIndexedFileSize 超过该大小的文件只列出文件名，不提取符号
+	maxIndexedFileSize = 512 * 1024
+	// maxRepoMapSize 是放入系统上下文的仓库地图的最大字节数
+	maxRepoMapSize = 6 * 1024
+	// maxSymbolsPerFile 是仓库地图中每个文件最多列出的符号数
+	maxSymbolsPerFile = 8
+)
+
+// skippedDirs 是建立索引时跳过的目录
+var skippedDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "bin": true,
+	"dist": true, "build": true, "target": true, "__pycache__": true,
+}
+
+// symbolPatterns 按扩展名匹配非 Go 文件中的顶层定义
+var symbolPatterns = map[string]*regexp.Regexp{
+	".py": regexp.MustCompile(`(?m)^(?:async\s+)?(def|class)\s+([A-Za-z_]\w*)`),
+	".js": regexp.MustCompile(`(?m)^(?:export\s+)?(?:default\s+)?(?:async\s+)?(function|class)\s+([A-Za-z_$][\w$]*)`),
+	".ts": regexp.MustCompile(`(?m)^(?:export\s+)?(?:default\s+)?(?:async\s+)?(function|class|interface|type)\s+([A-Za-z_$][\w$]*)`),
+	".rs": regexp.MustCompile(`(?m)^(?:pub\s+)?(fn|struct|enum|trait)\s+([A-Za-z_]\w*)`),
+}
+
+// codeSymbol 是代码索引中的一个顶层定义
+type codeSymbol struct {
+	Name string
+	Kind string
+	File string
+	Line int
+}
+
+// repoIndex 是工作区的文件列表和代码索引
+type repoIndex struct {
+	root    string
+	files   []string
+	symbols map[string][]codeSymbol // 按文件（相对路径）分组
+}
+
+// indexWorkspace 在后台并发建立工作区的文件列表和代码索引，完成后生成仓库地图，
+// 之后的推理请求会自动带上它，因此第一个问题不需要等待索引完成
+func (a *Agent) indexWorkspace(ctx context.Context, root string) {
+	start := time.Now()
+	index, err := buildRepoIndex(ctx, root)
+	if err != nil {
+		a.debug.logf(debugUI, "Failed to index workspace %s: %v", root, err)
+		return
+	}
+
+	repoMap := index.render(maxRepoMapSize)
+	a.repoMap.Store(&repoMap)
+	a.debug.logf(debugUI, "Indexed %d files and %d symbols in %s", len(index.files), index.symbolCount(), time.Since(start).Round(time.Millisecond))
+
+	if a.retrieveChunks > 0 {
+		a.buildVectorIndex(ctx, index)
+	}
+}
+
+// buildRepoIndex 遍历目录的同时由多个 worker 并发提取符号
+func buildRepoIndex(ctx context.Context, root string) (*repoIndex, error) {
+	index := &repoIndex{root: root, symbols: make(map[string][]codeSymbol)}
+
+	paths := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rel := range paths {
+				symbols := extractSymbols(filepath.Join(root, rel), rel)
+				if len(symbols) == 0 {
+					continue
+				}
+				mu.Lock()
+				index.symbols[rel] = symbols
+				mu.Unlock()
+			}
+		}()
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if path != root && (skippedDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(index.files) >= maxIndexedFiles {
+			return filepath.SkipAll
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		index.files = append(index.files, rel)
+		paths <- rel
+		return nil
+	})
+	close(paths)
+	wg.Wait()
+
+	sort.Strings(index.files)
+	return index, err
+}
+
+// extractSymbols 提取文件中的顶层定义，Go 文件使用语法解析，其他语言使用正则
+func extractSymbols(path, rel string) []codeSymbol {
+	ext := filepath.Ext(path)
+	pattern := symbolPatterns[ext]
+	if ext != ".go" && pattern == nil {
+		return nil
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() > maxIndexedFileSize {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	if ext == ".go" {
+		return goSymbols(content, rel)
+	}
+
+	var symbols []codeSymbol
+	for _, m := range pattern.FindAllSubmatchIndex(content, -1) {
+		symbols = append(symbols, codeSymbol{
+			Name: string(content[m[4]:m[5]]),
+			Kind: string(content[m[2]:m[3]]),
+			File: rel,
+			Line: strings.Count(string(content[:m[0]]), "\n") + 1,
+		})
+	}
+	return symbols
+}
+
+// goSymbols 提取 Go 文件中的函数、方法和类型定义
+func goSymbols(content []byte, rel string) []codeSymbol {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, rel, content, parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	var symbols []codeSymbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name, kind := d.Name.Name, "func"
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name, kind = receiverName(d.Recv.List[0].Type)+"."+name, "method"
+			}
+			symbols = append(symbols, codeSymbol{Name: name, Kind: kind, File: rel, Line: fset.Position(d.Pos()).Line})
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				symbols = append(symbols, codeSymbol{Name: ts.Name.Name, Kind: "type", File: rel, Line: fset.Position(ts.Pos()).Line})
+			}
+		}
+	}
+	return symbols
+}
+
+// receiverName 返回方法接收者的类型名
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
+
+func (idx *repoIndex) symbolCount() int {
+	n := 0
+	for _, symbols := range idx.symbols {
+		n += len(symbols)
+	}
+	return n
+}
+
+// render 生成不超过 limit 字节的仓库地图：文件列表，以及每个文件中的主要定义
+func (idx *repoIndex) render(limit int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Repository map of the workspace %s (%d files):\n", idx.root, len(idx.files))
+
+	for i, file := range idx.files {
+		line := file
+		if symbols := idx.symbols[file]; len(symbols) > 0 {
+			names := make([]string, 0, maxSymbolsPerFile)
+			for _, s := range symbols[:min(len(symbols), maxSymbolsPerFile)] {
+				names = append(names, s.Name)
+			}
+			line += ": " + strings.Join(names, ", ")
+			if len(symbols) > maxSymbolsPerFile {
+				line += fmt.Sprintf(" (+%d more)", len(symbols)-maxSymbolsPerFile)
+			}
+		}
+		if sb.Len()+len(line)+1 > limit {
+			fmt.Fprintf(&sb, "... %d more files not shown\n", len(idx.files)-i)
+			break
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// withRepoMap 在发送给模型的消息前加上仓库地图（索引完成后才会出现），不修改对话本身。
+// 仓库地图描述的是 Agent 的工作区，使用独立工作区的用户会话不加
+func (a *Agent) withRepoMap(conversation []api.Message) []api.Message {
+	repoMap := a.repoMap.Load()
+	if repoMap == nil || a.userSpace != nil {
+		return conversation
+	}
+	messages := make([]api.Message, 0, len(conversation)+1)
+	messages = append(messages, api.Message{Role: "system", Content: *repoMap})
+	return append(messages, conversation...)
+}
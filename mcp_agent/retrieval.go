@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/vectorindex"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// defaultRetrieveChunks 是每轮对话前加入上下文的相关代码片段数
+	defaultRetrieveChunks = 4
+	// maxRetrievalChunks 限制向量索引的片段数，避免大仓库启动时占用嵌入模型太久
+	maxRetrievalChunks = 1000
+	// maxRetrievedSize 是加入上下文的代码片段的最大总字节数
+	maxRetrievedSize = 6 * 1024
+	// minRetrievalScore 是代码片段与问题的最低相似度，更低的片段与问题无关
+	minRetrievalScore = 0.2
+)
+
+// buildVectorIndex 将仓库索引中的文本文件切分成片段并计算嵌入向量，完成后每轮对话前检索相关片段。
+// 嵌入模型不可用时不检索
+func (a *Agent) buildVectorIndex(ctx context.Context, index *repoIndex) {
+	start := time.Now()
+	var chunks []vectorindex.Chunk
+	for _, file := range index.files {
+		path := filepath.Join(index.root, file)
+		if info, err := os.Stat(path); err != nil || info.Size() > maxIndexedFileSize {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || !isText(data) {
+			continue
+		}
+		chunks = append(chunks, vectorindex.Split(filepath.ToSlash(file), string(data), vectorindex.DefaultChunkLines)...)
+		if len(chunks) >= maxRetrievalChunks {
+			chunks = chunks[:maxRetrievalChunks]
+			break
+		}
+	}
+
+	vectors, err := vectorindex.Build(ctx, chunks, a.embed)
+	if err != nil {
+		a.debug.logf(debugUI, "Embedding model %s unavailable, not retrieving code: %v", a.embedModel, err)
+		return
+	}
+	a.vectorIndex.Store(vectors)
+	a.debug.logf(debugUI, "Embedded %d chunks in %s", vectors.Len(), time.Since(start).Round(time.Millisecond))
+}
+
+// embed 使用嵌入模型计算文本的嵌入向量
+func (a *Agent) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := a.llm.Embed(ctx, &api.EmbedRequest{Model: a.embedModel, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Embeddings, nil
+}
+
+// retrieveContext 在一轮对话开始时检索与最新用户消息最相关的代码片段，保存在会话中，
+// 之后的推理请求将它们放在对话之前，模型不需要先用工具查找就能看到相关文件
+func (a *Agent) retrieveContext(ctx context.Context, conversation []api.Message) {
+	a.retrieved = ""
+	index := a.vectorIndex.Load()
+	query := lastUserMessage(conversation)
+	// 索引建立在 Agent 的工作区上，使用独立工作区的用户会话不检索
+	if index == nil || a.retrieveChunks <= 0 || a.userSpace != nil || strings.TrimSpace(query) == "" {
+		return
+	}
+
+	vectors, err := a.embed(ctx, []string{query})
+	if err != nil || len(vectors) != 1 {
+		a.debug.logf(debugLLM, "Failed to embed the request for retrieval: %v", err)
+		return
+	}
+
+	var sb strings.Builder
+	var found []string
+	sb.WriteString("Code from the workspace that may be relevant to the request (found by similarity, may be incomplete):\n")
+	for _, result := range index.Search(vectors[0], a.retrieveChunks, minRetrievalScore) {
+		block := fmt.Sprintf("\n--- %s ---\n%s\n", result.Chunk, result.Text)
+		if sb.Len()+len(block) > maxRetrievedSize {
+			break
+		}
+		sb.WriteString(block)
+		found = append(found, result.Chunk.String())
+	}
+	if len(found) == 0 {
+		return
+	}
+	a.retrieved = sb.String()
+	render.Stdout.Note("context", "added %s", strings.Join(found, ", "))
+}
+
+// withRetrieved 在发送给模型的消息前加上检索到的代码片段，不修改对话本身
+func (a *Agent) withRetrieved(conversation []api.Message) []api.Message {
+	if a.retrieved == "" {
+		return conversation
+	}
+	messages := make([]api.Message, 0, len(conversation)+1)
+	messages = append(messages, api.Message{Role: "system", Content: a.retrieved})
+	return append(messages, conversation...)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+const (
+	// maxReviewRounds 限制评审与修复的往返次数，避免无限循环
+	maxReviewRounds = 2
+	// reviewApproved 是评审通过时模型需要输出的标记
+	reviewApproved = "APPROVED"
+)
+
+// reviewTurn 在 Agent 声称任务完成后，让评审模型检查本轮修改是否满足原始请求。
+// 评审不通过时，将具体修改意见作为用户消息送回对话并继续处理。
+// turnStart 是这一轮用户消息在对话中的位置。
+func (a *Agent) reviewTurn(ctx context.Context, conversation []api.Message, tools []api.Tool, turnStart int) ([]api.Message, error) {
+	request := conversation[turnStart].Content
+
+	for round := 1; round <= maxReviewRounds; round++ {
+		changes := a.collectChanges(conversation[turnStart:])
+		if len(changes) == 0 {
+			a.debug.logf(debugLLM, "No file changes in this turn, skipping review")
+			return conversation, nil
+		}
+
+		verdict, err := a.runReview(ctx, request, changes, conversation[len(conversation)-1].Content)
+		if err != nil {
+			// 评审失败不影响主流程
+			render.Stdout.Errorf("review", "%s", err.Error())
+			return conversation, nil
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(verdict), reviewApproved) {
+			render.Stdout.Success("review", "approved")
+			return conversation, nil
+		}
+
+		render.Stdout.Line(render.Magenta, "review", "%s", verdict)
+		conversation = append(conversation, api.Message{
+			Role:    "user",
+			Content: fmt.Sprintf("A reviewer checked your changes and requested fixes:\n%s\n\nPlease apply these fixes.", verdict),
+		})
+
+		if conversation, err = a.processTurn(ctx, conversation, tools); err != nil {
+			return conversation, err
+		}
+	}
+
+	return conversation, nil
+}
+
+// runReview 调用评审模型，返回 APPROVED 或具体的修改意见
+func (a *Agent) runReview(ctx context.Context, request string, changes []string, answer string) (string, error) {
+	model := a.reviewModel
+	if model == "" {
+		model = a.model
+	}
+
+	prompt := fmt.Sprintf(`You are a strict code reviewer. Check whether the changes fully and correctly implement the request.
+If they do, reply with exactly "%s". Otherwise reply with a short list of concrete fixes.
+
+Request:
+%s
+
+Changes:
+%s
+
+Agent's final answer:
+%s`, reviewApproved, request, strings.Join(changes, "\n"), answer)
+
+	a.debug.logf(debugLLM, "Running review with model %s on %d changes", model, len(changes))
+
+	message, err := a.runInferenceWithModel(ctx, model, []api.Message{{Role: "user", Content: prompt}}, nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(message.Content), nil
+}
+
+// collectChanges 从对话中提取编辑类工具调用，作为本轮修改的描述
+func (a *Agent) collectChanges(messages []api.Message) []string {
+	var changes []string
+	for _, m := range messages {
+		for _, toolCall := range m.ToolCalls {
+			if !a.isEditTool(toolCall.Function.Name) {
+				continue
+			}
+			argsJSON, _ := json.Marshal(toolCall.Function.Arguments)
+			changes = append(changes, fmt.Sprintf("- %s %s", toolCall.Function.Name, truncateString(string(argsJSON), 2000)))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedClient 依次返回预设的回答，并记录收到的请求
+type scriptedClient struct {
+	modelClient
+	replies  []api.Message
+	requests []*api.ChatRequest
+}
+
+func (c *scriptedClient) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	c.requests = append(c.requests, req)
+	reply := api.Message{Role: "assistant", Content: "APPROVED"}
+	if len(c.replies) > 0 {
+		reply, c.replies = c.replies[0], c.replies[1:]
+	}
+	return fn(api.ChatResponse{Message: reply, Done: true})
+}
+
+func TestReviewGetsUserRequest(t *testing.T) {
+	manager := NewSessionManager(newTestAgent(t))
+	session := manager.Create(nil)
+	client, err := mcp.NewClient(t.Context(), &mcp.Config{MCPServers: map[string]mcp.MCPServer{}}, &mcp.ClientOptions{})
+	require.NoError(t, err)
+	defer closeMCPClient(client)
+	session.mcpClient = client
+	session.autoApproveTools = true
+	session.review = true
+	session.maxTools = -1
+	session.sessions = dirStore{dir: t.TempDir()}
+	llm := &scriptedClient{replies: []api.Message{
+		{Role: "assistant", ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{
+			Name:      "filesystem__write_file",
+			Arguments: api.ToolCallFunctionArguments{"path": "main.go", "content": "package main"},
+		}}}},
+		{Role: "assistant", Content: "I wrote main.go"},
+	}}
+	session.llm = llm
+
+	_, err = manager.Send(t.Context(), session.ID, "create an empty main package")
+	require.NoError(t, err)
+
+	// 处理这一轮的两次推理之后是评审请求，最后是生成会话标题的请求
+	require.Len(t, llm.requests, 4)
+	review := llm.requests[2].Messages[0].Content
+	assert.Contains(t, review, "Request:\ncreate an empty main package\n")
+	assert.Contains(t, review, "I wrote main.go")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/permissions"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/ollama/ollama/api"
+)
+
+// handleSampling 处理 MCP 服务器发起的 sampling/createMessage 请求，使用 Agent 的 Ollama 模型生成回复
+func (a *Agent) handleSampling(ctx context.Context, server string, params *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+	var conversation []api.Message
+	if params.SystemPrompt != "" {
+		conversation = append(conversation, api.Message{Role: "system", Content: params.SystemPrompt})
+	}
+	for _, m := range params.Messages {
+		text, ok := m.Content.(*mcp.TextContent)
+		if !ok {
+			return nil, fmt.Errorf("unsupported sampling content type %T", m.Content)
+		}
+		conversation = append(conversation, api.Message{Role: string(m.Role), Content: text.Text})
+	}
+
+	a.debug.logf(debugMCP, "Sampling request from %s with %d messages", server, len(params.Messages))
+
+	if !a.approveSampling(server, conversation) {
+		return nil, fmt.Errorf("sampling request rejected by user")
+	}
+
+	message, err := a.runInference(ctx, conversation, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sampling failed: %w", err)
+	}
+
+	return &mcp.CreateMessageResult{
+		Model:      a.model,
+		Role:       "assistant",
+		Content:    &mcp.TextContent{Text: message.Content},
+		StopReason: "endTurn",
+	}, nil
+}
+
+// approveSampling 在调用模型之前请求用户确认，选择总是允许的服务器在这个项目中不再询问；
+// 非交互模式下无法询问，请求被拒绝
+func (a *Agent) approveSampling(server string, conversation []api.Message) bool {
+	if a.autoApproveSampling || a.permissions.Allowed(permissions.KindSampling, server) {
+		return true
+	}
+	if a.headless {
+		render.Stdout.Warning("sampling", "rejected a request from %s, approval is not possible with -p (use --auto-approve-sampling)", server)
+		return false
+	}
+
+	render.Stdout.Line(render.Magenta, "sampling", "server %s wants to use the model:", server)
+	for _, m := range conversation {
+		fmt.Printf("  [%s] %s\n", m.Role, truncateString(strings.TrimSpace(m.Content), 200))
+	}
+
+	defer a.input.pause()()
+
+	answer := approveNo
+	prompt := &survey.Select{
+		Message: "Allow this sampling request?",
+		Options: []string{approveYes, approveNo, approveAlways},
+		Default: approveNo,
+	}
+	if err := survey.AskOne(prompt, &answer); err != nil {
+		return false
+	}
+	if answer == approveAlways {
+		a.rememberApproval(permissions.KindSampling, server)
+	}
+	return answer != approveNo
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Session 是一个对话及其状态。同一会话同一时间只处理一轮对话，不同会话可以并发运行
+type Session struct {
+	// ID 由 SessionManager 分配，交互模式的默认会话为空
+	ID      string
+	created time.Time
+
+	// 会话使用的模型，/model 只切换当前会话的模型
+	model string
+
+	// 多用户 serve 模式下会话所属用户的独立工作区，nil 表示使用 Agent 的 MCP 客户端和工作区
+	userSpace *userWorkspace
+
+	// --auto --verify-cmd 时工具操作的工作区副本，nil 表示工具直接操作工作区
+	staging *stagingWorkspace
+
+	// turnMu 保证同一会话同一时间只处理一轮对话
+	turnMu       sync.Mutex
+	conversation []api.Message
+
+	// 本次会话的使用统计
+	usage *usageTracker
+
+	// 最近一次工具调用的错误，用于非交互模式判断运行结果
+	lastToolErr error
+
+	// 最近一轮对话是否因达到工具调用轮数上限而停止
+	toolLimitReached bool
+
+	// 被编辑文件的原始内容，用于 /share 生成 diff
+	snapshots fileSnapshots
+
+	// --auto 模式下当前一轮对话编辑的文件在这一轮开始前的内容，校验失败时恢复
+	turnEdits fileSnapshots
+
+	// 最近一次成功的工具调用及其完整结果，用于 /last-result
+	lastToolName   string
+	lastToolResult string
+
+	// 最近一轮对话中的工具调用耗时和资源使用，用于 /timeline
+	timeline turnTimeline
+
+	// 当前一轮对话中执行过的工具调用，用于发现重复的调用
+	repeats repeatedCalls
+
+	// 按问题选择发送给模型的工具
+	toolSelect toolSelector
+
+	// 这一轮对话前检索到的相关代码片段，为空表示没有
+	retrieved string
+
+	// /lang 设置的回答语言，为空时使用 --lang
+	replyLang string
+
+	// 会话的保存记录，第一次保存时创建
+	saved *savedSession
+
+	// 上一次为适应上下文窗口省略的消息数，省略更多时才提示用户
+	contextOmitted int
+
+	// 对话状态机的当前状态，以及订阅状态变化、回答和工具调用事件的处理函数
+	state     turnState
+	observers []func(turnEvent)
+}
+
+func newSession(model string, conversation []api.Message) *Session {
+	return &Session{
+		created:      time.Now(),
+		model:        model,
+		conversation: conversation,
+		usage:        newUsageTracker(model),
+	}
+}
+
+// errSessionNotFound 表示 SessionManager 中没有指定 ID 的会话
+var errSessionNotFound = errors.New("session not found")
+
+// SessionManager 管理同一进程中的多个会话（如 Web/API 模式下每个用户一个会话）。
+// 所有会话共享 Ollama 和 MCP 客户端及配置，对话、工具调用记录和使用统计各自独立
+type SessionManager struct {
+	core *agentCore
+	// 创建的会话使用的用户工作区，nil 表示使用 Agent 的工作区
+	space *userWorkspace
+
+	mu       sync.Mutex
+	sessions map[string]*Agent
+}
+
+// NewSessionManager 创建与 agent 共享客户端和配置的会话管理器
+func NewSessionManager(agent *Agent) *SessionManager {
+	return &SessionManager{core: agent.agentCore, sessions: make(map[string]*Agent)}
+}
+
+// Create 创建一个新会话，conversation 为已有的对话，可以为空。会话的事件不在终端显示，
+// 调用方通过 subscribe 订阅
+func (m *SessionManager) Create(conversation []api.Message) *Agent {
+	session := newSession(m.core.defaultModel, conversation)
+	session.ID = newSessionID(session.created)
+	session.userSpace = m.space
+	agent := &Agent{agentCore: m.core, Session: session}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[session.ID] = agent
+	return agent
+}
+
+// Get 返回指定 ID 的会话
+func (m *SessionManager) Get(id string) (*Agent, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	agent, ok := m.sessions[id]
+	return agent, ok
+}
+
+// List 返回所有会话的 ID，按创建时间排序
+func (m *SessionManager) List() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	agents := make([]*Agent, 0, len(m.sessions))
+	for _, agent := range m.sessions {
+		agents = append(agents, agent)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].created.Before(agents[j].created) })
+
+	ids := make([]string, len(agents))
+	for i, agent := range agents {
+		ids[i] = agent.ID
+	}
+	return ids
+}
+
+// Send 在指定会话中回答 prompt 并返回回答。同一会话的请求依次处理
+func (m *SessionManager) Send(ctx context.Context, id, prompt string) (string, error) {
+	agent, ok := m.Get(id)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errSessionNotFound, id)
+	}
+
+	agent.turnMu.Lock()
+	defer agent.turnMu.Unlock()
+
+	tools, err := agent.loadTools(ctx)
+	if err != nil {
+		return "", err
+	}
+	agent.conversation = append(agent.conversation, api.Message{Role: "user", Content: prompt})
+	if err := agent.completeTurn(ctx, tools); err != nil {
+		return "", err
+	}
+	return agent.conversation[len(agent.conversation)-1].Content, nil
+}
+
+// Close 结束会话并保存其使用统计，正在处理的一轮对话结束后才会关闭
+func (m *SessionManager) Close(id string) error {
+	m.mu.Lock()
+	agent, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", errSessionNotFound, id)
+	}
+
+	agent.turnMu.Lock()
+	defer agent.turnMu.Unlock()
+	return saveUsage(agent.usage.finish(true))
+}
+
+// CloseAll 结束所有会话，用于停止服务
+func (m *SessionManager) CloseAll() error {
+	var errs []error
+	for _, id := range m.List() {
+		if err := m.Close(id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionModelIsPerSession(t *testing.T) {
+	manager := NewSessionManager(newTestAgent(t))
+	first, second := manager.Create(nil), manager.Create(nil)
+	assert.Equal(t, "m", first.model)
+
+	// /model 只切换当前会话的模型
+	first.model = "other"
+	assert.Equal(t, "m", second.model)
+	assert.Equal(t, "m", manager.Create(nil).model)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// sessionsDirName 是默认保存会话的目录名，位于 ~/.agent 目录下，每个会话一个 JSON 文件
+const sessionsDirName = "sessions"
+
+// maxTitleLength 是会话标题的最大长度（按字符计）
+const maxTitleLength = 60
+
+// savedSession 是保存到磁盘的会话
+type savedSession struct {
+	ID        string        `json:"id"`
+	Title     string        `json:"title"`
+	Model     string        `json:"model"`
+	Workspace string        `json:"workspace"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Messages  []api.Message `json:"messages"`
+}
+
+// newSavedSession 为当前工作目录创建一个新的会话记录
+func newSavedSession(model string) *savedSession {
+	now := time.Now()
+	workspace, _ := os.Getwd()
+	return &savedSession{
+		ID:        newSessionID(now),
+		Model:     model,
+		Workspace: workspace,
+		CreatedAt: now,
+	}
+}
+
+// newSessionID 生成以创建时间开头的会话 ID，后缀的随机数避免同一秒内创建的会话重名
+func newSessionID(now time.Time) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// sessionsDir 返回默认的会话保存目录
+func sessionsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".agent", sessionsDirName), nil
+}
+
+// saveSession 保存当前对话，每轮对话结束后调用。第一次保存时根据第一轮对话生成标题
+func (a *Agent) saveSession(ctx context.Context, conversation []api.Message) error {
+	if len(conversation) == 0 {
+		return nil
+	}
+	if a.saved == nil {
+		a.saved = newSavedSession(a.model)
+		// SessionManager 创建的会话使用会话 ID，与 API 中的 ID 保持一致
+		if a.ID != "" {
+			a.saved.ID = a.ID
+		}
+	}
+	a.saved.Model = a.model
+	a.saved.Messages = conversation
+	a.saved.UpdatedAt = time.Now()
+	if a.saved.Title == "" {
+		a.saved.Title = a.generateTitle(ctx, conversation)
+	}
+
+	return a.sessions.save(a.saved)
+}
+
+// persistSession 保存对话，失败时只提示而不中断会话
+func (a *Agent) persistSession(ctx context.Context, conversation []api.Message) {
+	if err := a.saveSession(ctx, conversation); err != nil {
+		render.Stdout.Error(fmt.Errorf("failed to save session: %w", err))
+	}
+}
+
+// findSession 按 ID 查找保存的会话，也可以只给出 ID 的唯一前缀
+func findSession(store sessionStore, id string) (*savedSession, error) {
+	sessions, err := store.list()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*savedSession
+	for _, session := range sessions {
+		if session.ID == id {
+			return session, nil
+		}
+		if strings.HasPrefix(session.ID, id) {
+			matches = append(matches, session)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no saved session %q, run the sessions subcommand to list them", id)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("session ID %q is ambiguous, it matches %d sessions", id, len(matches))
+}
+
+// latestSession 返回在 workspace 目录中最近更新的会话
+func latestSession(store sessionStore, workspace string) (*savedSession, error) {
+	sessions, err := store.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.Workspace == workspace {
+			return session, nil
+		}
+	}
+	return nil, fmt.Errorf("no saved session in %s", workspace)
+}
+
+// thinkPattern 匹配推理模型输出的思考过程
+var thinkPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// generateTitle 让模型根据第一轮对话生成简短标题，失败时使用第一条用户消息
+func (a *Agent) generateTitle(ctx context.Context, conversation []api.Message) string {
+	var question, answer string
+	for _, message := range conversation {
+		switch {
+		case message.Role == "user" && question == "":
+			question = message.Content
+		case message.Role == "assistant" && question != "" && message.Content != "":
+			answer = message.Content
+		}
+		if answer != "" {
+			break
+		}
+	}
+	if question == "" {
+		return sessionTitle(conversation)
+	}
+
+	titleConversation := []api.Message{{
+		Role: "user",
+		Content: fmt.Sprintf("Write a short title (at most 8 words) for the following conversation. Reply with the title only, in the language of the question, without quotes.\n\nQuestion:\n%s\n\nAnswer:\n%s",
+			truncateString(question, 2000), truncateString(answer, 2000)),
+	}}
+	message, err := a.runInferenceWithModel(ctx, a.model, titleConversation, nil)
+	if err != nil {
+		a.debug.logf(debugLLM, "Failed to generate session title: %v", err)
+		return sessionTitle(conversation)
+	}
+
+	title := thinkPattern.ReplaceAllString(message.Content, "")
+	title = strings.Trim(strings.TrimSpace(title), "\"'`*#")
+	if title == "" {
+		return sessionTitle(conversation)
+	}
+	return truncateTitle(title)
+}
+
+// sessionTitle 以第一条用户消息作为会话标题
+func sessionTitle(conversation []api.Message) string {
+	for _, message := range conversation {
+		if message.Role == "user" {
+			return truncateTitle(message.Content)
+		}
+	}
+	return "untitled"
+}
+
+// truncateTitle 将文本压缩为单行并截断到 maxTitleLength 个字符
+func truncateTitle(s string) string {
+	title := []rune(strings.Join(strings.Fields(s), " "))
+	if len(title) > maxTitleLength {
+		return string(title[:maxTitleLength-3]) + "..."
+	}
+	return string(title)
+}
+
+// pickSession 让用户从 options 中选择一个会话，返回下标，取消时返回 -1
+func pickSession(message string, options []string) int {
+	const cancel = "Cancel"
+	options = append(options[:len(options):len(options)], cancel)
+
+	var choice int
+	if err := survey.AskOne(&survey.Select{Message: message, Options: options, PageSize: 15}, &choice); err != nil || options[choice] == cancel {
+		return -1
+	}
+	return choice
+}
+
+// chooseResume 询问用户查看还是恢复会话，选择查看时打印对话记录，返回是否恢复
+func chooseResume(session *savedSession) bool {
+	var action string
+	prompt := &survey.Select{
+		Message: fmt.Sprintf("%s:", session.Title),
+		Options: []string{"Resume", "Open", "Cancel"},
+	}
+	if err := survey.AskOne(prompt, &action); err != nil {
+		return false
+	}
+	if action == "Open" {
+		fmt.Print(renderTranscript(session.Messages))
+	}
+	return action == "Resume"
+}
+
+// selectSession 实现 "sessions" 子命令：列出保存的会话供用户选择，返回要恢复的会话
+func selectSession(store sessionStore) (*savedSession, error) {
+	sessions, err := store.list()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No saved sessions yet")
+		return nil, nil
+	}
+
+	home, _ := os.UserHomeDir()
+	options := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		workspace := s.Workspace
+		if home != "" && strings.HasPrefix(workspace, home) {
+			workspace = "~" + strings.TrimPrefix(workspace, home)
+		}
+		options = append(options, fmt.Sprintf("%s  %-40s  %-16s  %s",
+			s.UpdatedAt.Format("2006-01-02 15:04"), truncateString(s.Title, 40), truncateString(s.Model, 16), workspace))
+	}
+
+	for {
+		choice := pickSession(fmt.Sprintf("Sessions (%d):", len(sessions)), options)
+		if choice < 0 {
+			return nil, nil
+		}
+		if chooseResume(sessions[choice]) {
+			return sessions[choice], nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSessionID(t *testing.T) {
+	now := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	a, b := newSessionID(now), newSessionID(now)
+	assert.Regexp(t, `^20250304-050607-[0-9a-f]{8}$`, a)
+	assert.NotEqual(t, a, b)
+}
+
+func TestDefaultSessionStore(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	store, err := openSessionStore("")
+	require.NoError(t, err)
+	defer store.close()
+	session := newSavedSession("m")
+	require.NoError(t, store.save(session))
+
+	assert.FileExists(t, filepath.Join(home, ".agent", "sessions", session.ID+".json"))
+}
+
+func TestSessionStores(t *testing.T) {
+	dir := t.TempDir()
+	for _, spec := range []string{"dir:" + filepath.Join(dir, "sessions"), "sqlite:" + filepath.Join(dir, "sessions.db")} {
+		t.Run(spec, func(t *testing.T) {
+			store, err := openSessionStore(spec)
+			require.NoError(t, err)
+			defer store.close()
+
+			older := newSavedSession("m")
+			older.UpdatedAt = older.UpdatedAt.Add(-time.Hour)
+			newer := newSavedSession("m")
+			newer.Messages = []api.Message{{Role: "user", Content: "hi"}}
+			require.NoError(t, store.save(older))
+			require.NoError(t, store.save(newer))
+
+			// 保存 ID 相同的会话覆盖原来的会话
+			newer.Title = "hi"
+			require.NoError(t, store.save(newer))
+
+			sessions, err := store.list()
+			require.NoError(t, err)
+			require.Len(t, sessions, 2)
+			assert.Equal(t, newer.ID, sessions[0].ID)
+			assert.Equal(t, "hi", sessions[0].Title)
+			assert.Equal(t, newer.Messages, sessions[0].Messages)
+			assert.True(t, newer.UpdatedAt.Equal(sessions[0].UpdatedAt))
+			assert.Equal(t, older.ID, sessions[1].ID)
+		})
+	}
+}
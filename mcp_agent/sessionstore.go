@@ -0,0 +1,179 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sessionsSchema 是 SQLite 会话存储的表结构，每个会话一行，消息以 JSON 保存
+const sessionsSchema = `CREATE TABLE IF NOT EXISTS sessions (
+	id         TEXT PRIMARY KEY,
+	title      TEXT NOT NULL,
+	model      TEXT NOT NULL,
+	workspace  TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	messages   TEXT NOT NULL
+)`
+
+// sessionStore 保存和读取会话。默认每个会话一个 JSON 文件；Web/API 等服务模式下会话很多，
+// 可以改用一个 SQLite 数据库，避免目录中堆积成千上万个文件。以后可以在这里增加 S3 等远程存储
+type sessionStore interface {
+	// save 保存会话，ID 相同的会话被覆盖
+	save(session *savedSession) error
+	// list 返回所有会话，按最近更新时间排序
+	list() ([]*savedSession, error)
+	close() error
+}
+
+// openSessionStore 按 --session-store 打开会话存储：为空时使用 ~/.agent/sessions 目录，
+// "dir:<path>" 使用指定目录，"sqlite:<path>" 使用 SQLite 数据库文件
+func openSessionStore(spec string) (sessionStore, error) {
+	kind, path, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "":
+		dir, err := sessionsDir()
+		if err != nil {
+			return nil, err
+		}
+		return dirStore{dir: dir}, nil
+	case "dir":
+		if path == "" {
+			return nil, fmt.Errorf("missing directory in %q, expected dir:<path>", spec)
+		}
+		return dirStore{dir: expandPath(path)}, nil
+	case "sqlite":
+		if path == "" {
+			return nil, fmt.Errorf("missing database file in %q, expected sqlite:<path>", spec)
+		}
+		return openSQLStore(expandPath(path))
+	default:
+		return nil, fmt.Errorf("unknown session store %q, expected dir:<path> or sqlite:<path>", spec)
+	}
+}
+
+// dirStore 将每个会话保存为目录中的一个 JSON 文件
+type dirStore struct {
+	dir string
+}
+
+func (s dirStore) save(session *savedSession) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免中途退出留下损坏的会话
+	path := filepath.Join(s.dir, session.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s dirStore) list() ([]*savedSession, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []*savedSession
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var session savedSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			// 跳过损坏的会话文件
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (s dirStore) close() error {
+	return nil
+}
+
+// sqlStore 将所有会话保存在一个 SQLite 数据库中，每个会话一行，消息以 JSON 保存
+type sqlStore struct {
+	db *sql.DB
+}
+
+func openSQLStore(path string) (*sqlStore, error) {
+	db, err := openSQLite(path, sessionsSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStore{db: db}, nil
+}
+
+func (s *sqlStore) save(session *savedSession) error {
+	messages, err := json.Marshal(session.Messages)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO sessions (id, title, model, workspace, created_at, updated_at, messages)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET title = excluded.title, model = excluded.model, workspace = excluded.workspace,
+			updated_at = excluded.updated_at, messages = excluded.messages`,
+		session.ID, session.Title, session.Model, session.Workspace,
+		session.CreatedAt.Format(time.RFC3339Nano), session.UpdatedAt.Format(time.RFC3339Nano), string(messages))
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func (s *sqlStore) list() ([]*savedSession, error) {
+	rows, err := s.db.Query(`SELECT id, title, model, workspace, created_at, updated_at, messages FROM sessions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*savedSession
+	for rows.Next() {
+		var session savedSession
+		var created, updated, messages string
+		if err := rows.Scan(&session.ID, &session.Title, &session.Model, &session.Workspace, &created, &updated, &messages); err != nil {
+			return nil, err
+		}
+		session.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		session.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updated)
+		if err := json.Unmarshal([]byte(messages), &session.Messages); err != nil {
+			// 跳过损坏的会话
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+func (s *sqlStore) close() error {
+	return s.db.Close()
+}
+
+// sortSessions 按最近更新时间排序
+func sortSessions(sessions []*savedSession) {
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+}
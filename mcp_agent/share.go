@@ -0,0 +1,153 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ollama/ollama/api"
+)
+
+// 分享包中的文件
+const (
+	shareManifestFile     = "manifest.json"
+	shareConversationFile = "conversation.json"
+	shareTranscriptFile   = "transcript.md"
+	shareDiffFile         = "changes.diff"
+)
+
+// shareManifest 描述分享包的来源
+type shareManifest struct {
+	Version   int       `json:"version"`
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	Messages  int       `json:"messages"`
+	Tools     []string  `json:"tools,omitempty"`
+}
+
+// writeShareBundle 将对话、工具调用记录和文件修改打包为 tar.gz，便于报告问题时复现
+func (a *Agent) writeShareBundle(path string, conversation []api.Message, tools []api.Tool) error {
+	manifest := shareManifest{
+		Version:   1,
+		Model:     a.model,
+		CreatedAt: time.Now(),
+		Messages:  len(conversation),
+	}
+	for _, tool := range tools {
+		manifest.Tools = append(manifest.Tools, tool.Function.Name)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	conversationJSON, err := json.MarshalIndent(conversation, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{shareManifestFile, manifestJSON},
+		{shareConversationFile, conversationJSON},
+		{shareTranscriptFile, []byte(renderTranscript(conversation))},
+		{shareDiffFile, []byte(a.snapshots.diffs())},
+	}
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    0644,
+			Size:    int64(len(file.data)),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readShareBundle 读取分享包中的对话
+func readShareBundle(path string) (shareManifest, []api.Message, error) {
+	var manifest shareManifest
+	var conversation []api.Message
+
+	f, err := os.Open(path)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+
+		switch header.Name {
+		case shareManifestFile:
+			err = json.NewDecoder(tr).Decode(&manifest)
+		case shareConversationFile:
+			err = json.NewDecoder(tr).Decode(&conversation)
+		}
+		if err != nil {
+			return manifest, nil, fmt.Errorf("invalid %s in bundle: %w", header.Name, err)
+		}
+	}
+
+	if conversation == nil {
+		return manifest, nil, fmt.Errorf("bundle %s has no %s", path, shareConversationFile)
+	}
+	return manifest, conversation, nil
+}
+
+// renderTranscript 将对话渲染为便于阅读的 Markdown
+func renderTranscript(conversation []api.Message) string {
+	var sb strings.Builder
+	sb.WriteString("# Transcript\n")
+	for _, m := range conversation {
+		switch m.Role {
+		case "tool":
+			fmt.Fprintf(&sb, "\n## tool result: %s\n\n```\n%s\n```\n", m.ToolName, m.Content)
+		default:
+			fmt.Fprintf(&sb, "\n## %s\n", m.Role)
+			if m.Content != "" {
+				fmt.Fprintf(&sb, "\n%s\n", m.Content)
+			}
+			for _, toolCall := range m.ToolCalls {
+				argsJSON, _ := json.Marshal(toolCall.Function.Arguments)
+				fmt.Fprintf(&sb, "\n- tool call: `%s(%s)`\n", toolCall.Function.Name, argsJSON)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	// 纯 Go 实现的 SQLite 驱动，不需要 cgo
+	_ "modernc.org/sqlite"
+)
+
+// sqliteBusyTimeout 是等待其他进程释放数据库锁的时间（毫秒），多个 agent 可能同时写入
+const sqliteBusyTimeout = 5000
+
+// openSQLite 打开（必要时创建）SQLite 数据库并执行建表语句
+func openSQLite(path string, schema string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)", filepath.ToSlash(path), sqliteBusyTimeout))
+	if err != nil {
+		return nil, err
+	}
+	// SQLite 同一时间只允许一个写入者，共用一个连接避免 "database is locked"
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize %s: %w", path, err)
+	}
+	return db, nil
+}
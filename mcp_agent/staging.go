@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/workspace"
+)
+
+// stagingWorkspace 是 --auto --verify-cmd 模式下工作区的副本。MCP 客户端以副本为 roots 连接服务器，
+// 模型的修改只写入副本，校验命令也在副本中运行，通过校验后修改的文件才写回工作区，
+// 未通过校验的修改不会出现在工作区中
+type stagingWorkspace struct {
+	roots  []string // 工作区目录
+	copies []string // 与 roots 一一对应的副本目录
+	base   string   // 存放所有副本的临时目录
+	client *mcp.Client
+}
+
+// newStagingWorkspace 复制工作区，并连接以副本为 roots 的 MCP 客户端
+func newStagingWorkspace(ctx context.Context, roots []string, config *mcp.Config, opts *mcp.ClientOptions) (*stagingWorkspace, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("no workspace directory")
+	}
+	base, err := os.MkdirTemp("", "mcp_agent-verify-")
+	if err != nil {
+		return nil, err
+	}
+	// 工具参数中的路径会解析符号链接，副本的路径也要是解析后的（如 macOS 上的 /var）
+	if resolved, err := filepath.EvalSymlinks(base); err == nil {
+		base = resolved
+	}
+	s := &stagingWorkspace{base: base}
+	for i, root := range roots {
+		if root, err = filepath.Abs(root); err != nil {
+			os.RemoveAll(base)
+			return nil, err
+		}
+		s.roots = append(s.roots, root)
+		s.copies = append(s.copies, filepath.Join(base, fmt.Sprintf("%d-%s", i, filepath.Base(root))))
+	}
+	if err := s.sync(); err != nil {
+		os.RemoveAll(base)
+		return nil, err
+	}
+
+	stagingOpts := *opts
+	stagingOpts.Roots = s.copies
+	if s.client, err = mcp.NewClient(ctx, config, &stagingOpts); err != nil {
+		os.RemoveAll(base)
+		return nil, err
+	}
+	return s, nil
+}
+
+// sync 让副本与工作区一致。每轮对话开始前调用，丢弃上一轮没有写回的修改，并带上两轮之间工作区中的变化
+func (s *stagingWorkspace) sync() error {
+	for i, root := range s.roots {
+		if err := mirrorDir(root, s.copies[i]); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", root, err)
+		}
+	}
+	return nil
+}
+
+// workspacePath 将副本中的路径转换为工作区中对应的路径，不在副本中的路径原样返回
+func (s *stagingWorkspace) workspacePath(path string) string {
+	for i, copy := range s.copies {
+		if workspace.Contains(copy, path) {
+			rel, _ := filepath.Rel(copy, path)
+			return filepath.Join(s.roots[i], rel)
+		}
+	}
+	return path
+}
+
+// commandDir 返回运行校验命令的目录：当前目录在工作区中时为副本中对应的目录，否则为第一个副本
+func (s *stagingWorkspace) commandDir() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return s.copies[0]
+	}
+	for i, root := range s.roots {
+		if workspace.Contains(root, cwd) {
+			rel, _ := filepath.Rel(root, cwd)
+			return filepath.Join(s.copies[i], rel)
+		}
+	}
+	return s.copies[0]
+}
+
+// apply 将副本中修改过的文件写回工作区，副本中已删除的文件也从工作区删除
+func (s *stagingWorkspace) apply(paths []string) error {
+	for _, path := range paths {
+		target := s.workspacePath(path)
+		if target == path {
+			continue
+		}
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := copyFile(path, target, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close 关闭副本的 MCP 客户端并删除副本
+func (s *stagingWorkspace) close() {
+	closeMCPClient(s.client)
+	os.RemoveAll(s.base)
+}
+
+// mirrorDir 让 dst 成为 src 的副本：复制新增的和大小或修改时间不同的文件，删除 src 中已经没有的文件。
+// 复制的文件保留修改时间，之后的同步只需比较文件信息
+func mirrorDir(src, dst string) error {
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		current, statErr := os.Lstat(target)
+
+		switch {
+		case d.IsDir():
+			if statErr == nil && !current.IsDir() {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if currentLink, err := os.Readlink(target); err == nil && currentLink == link {
+				return nil
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			if statErr == nil && current.Mode().IsRegular() && current.Size() == info.Size() && current.ModTime().Equal(info.ModTime()) {
+				return nil
+			}
+			// 重新创建，只读文件也可以更新
+			if statErr == nil {
+				if err := os.RemoveAll(target); err != nil {
+					return err
+				}
+			}
+			if err := copyFile(path, target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chtimes(target, info.ModTime(), info.ModTime())
+		}
+		// 设备文件、socket 等不复制
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if _, err := os.Lstat(filepath.Join(src, rel)); !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// copyFile 将 src 的内容写入 dst，dst 不存在时以 perm 创建
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
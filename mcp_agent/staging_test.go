@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+// newTestStaging 创建工作区及其副本，不连接 MCP 服务器
+func newTestStaging(t *testing.T) (root string, s *stagingWorkspace) {
+	root, base := t.TempDir(), t.TempDir()
+	s = &stagingWorkspace{roots: []string{root}, copies: []string{filepath.Join(base, "0-root")}, base: base}
+	return root, s
+}
+
+func TestMirrorDir(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "copy")
+	writeFile(t, filepath.Join(src, "a.txt"), "a")
+	writeFile(t, filepath.Join(src, "dir", "b.txt"), "b")
+	require.NoError(t, os.WriteFile(filepath.Join(src, "readonly.txt"), []byte("r"), 0444))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(src, "link")))
+
+	require.NoError(t, mirrorDir(src, dst))
+	assert.Equal(t, "a", readFile(t, filepath.Join(dst, "a.txt")))
+	assert.Equal(t, "b", readFile(t, filepath.Join(dst, "dir", "b.txt")))
+	link, err := os.Readlink(filepath.Join(dst, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", link)
+
+	// 工作区中的变化和副本中多出的文件在下次同步时处理
+	writeFile(t, filepath.Join(src, "a.txt"), "changed")
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "readonly.txt"), later, later))
+	require.NoError(t, os.RemoveAll(filepath.Join(src, "dir")))
+	writeFile(t, filepath.Join(dst, "dir", "new.txt"), "stray")
+	writeFile(t, filepath.Join(dst, "stray.txt"), "stray")
+
+	require.NoError(t, mirrorDir(src, dst))
+	assert.Equal(t, "changed", readFile(t, filepath.Join(dst, "a.txt")))
+	assert.NoDirExists(t, filepath.Join(dst, "dir"))
+	assert.NoFileExists(t, filepath.Join(dst, "stray.txt"))
+	info, err := os.Stat(filepath.Join(dst, "readonly.txt"))
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(later))
+}
+
+func TestStagingWorkspaceApply(t *testing.T) {
+	root, s := newTestStaging(t)
+	writeFile(t, filepath.Join(root, "edited.txt"), "old")
+	writeFile(t, filepath.Join(root, "deleted.txt"), "old")
+	require.NoError(t, s.sync())
+
+	copy := s.copies[0]
+	writeFile(t, filepath.Join(copy, "edited.txt"), "new")
+	writeFile(t, filepath.Join(copy, "sub", "created.txt"), "new")
+	require.NoError(t, os.Remove(filepath.Join(copy, "deleted.txt")))
+	// 没有写回的修改不影响工作区
+	assert.Equal(t, "old", readFile(t, filepath.Join(root, "edited.txt")))
+
+	assert.Equal(t, filepath.Join(root, "sub", "created.txt"), s.workspacePath(filepath.Join(copy, "sub", "created.txt")))
+	assert.Equal(t, "/elsewhere/x", s.workspacePath("/elsewhere/x"))
+
+	require.NoError(t, s.apply([]string{
+		filepath.Join(copy, "edited.txt"),
+		filepath.Join(copy, "sub", "created.txt"),
+		filepath.Join(copy, "deleted.txt"),
+	}))
+	assert.Equal(t, "new", readFile(t, filepath.Join(root, "edited.txt")))
+	assert.Equal(t, "new", readFile(t, filepath.Join(root, "sub", "created.txt")))
+	assert.NoFileExists(t, filepath.Join(root, "deleted.txt"))
+}
+
+func TestStagingWorkspaceCommandDir(t *testing.T) {
+	root, s := newTestStaging(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "pkg"), 0755))
+
+	t.Chdir(filepath.Join(root, "pkg"))
+	assert.Equal(t, filepath.Join(s.copies[0], "pkg"), s.commandDir())
+	t.Chdir(t.TempDir())
+	assert.Equal(t, s.copies[0], s.commandDir())
+}
+
+func TestStagingCommandsUseStagingClient(t *testing.T) {
+	agent := newTestAgent(t)
+	config := &mcp.Config{MCPServers: map[string]mcp.MCPServer{}}
+	client, err := mcp.NewClient(t.Context(), config, &mcp.ClientOptions{})
+	require.NoError(t, err)
+	defer closeMCPClient(client)
+	agent.mcpClient = client
+	agent.staging, err = newStagingWorkspace(t.Context(), []string{t.TempDir()}, config, &mcp.ClientOptions{})
+	require.NoError(t, err)
+	defer agent.staging.close()
+
+	// 工具调用经过副本的客户端，跟踪也要在这个客户端上打开
+	_, _, err = agent.handleCommand(t.Context(), "/trace on", nil, nil)
+	require.NoError(t, err)
+	assert.True(t, agent.staging.client.Tracing())
+	assert.False(t, client.Tracing())
+}
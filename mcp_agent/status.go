@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// 非交互模式 (-p) 的退出码，CI 流水线可以根据退出码判断运行结果
+const (
+	exitSuccess     = 0
+	exitModelError  = 2   // 推理失败（模型不存在、Ollama 不可用等）
+	exitToolError   = 3   // 工具不可用，或最后一次工具调用失败后模型没有给出回答
+	exitCapExceeded = 4   // 超出运行限制（如工具调用轮数上限）
+	exitUserAbort   = 130 // 被用户中断 (SIGINT/SIGTERM)
+)
+
+var (
+	// errToolFailed 表示运行因工具失败而结束
+	errToolFailed = errors.New("tool failure")
+	// errCapExceeded 表示运行因超出限制而结束
+	errCapExceeded = errors.New("limit exceeded")
+	// errInterrupted 表示推理被用户按 Ctrl-C 取消
+	errInterrupted = fmt.Errorf("interrupted by the user: %w", context.Canceled)
+)
+
+// runStatus 是非交互模式结束时输出的机器可读状态
+type runStatus struct {
+	Status           string         `json:"status"` // success, model_error, tool_error, cap_exceeded, aborted
+	ExitCode         int            `json:"exit_code"`
+	Error            string         `json:"error,omitempty"`
+	Answer           string         `json:"answer,omitempty"`
+	Model            string         `json:"model"`
+	DurationMS       int64          `json:"duration_ms"`
+	Requests         int            `json:"requests"`
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	ToolCalls        map[string]int `json:"tool_calls,omitempty"`
+	ToolErrors       int            `json:"tool_errors"`
+}
+
+// newRunStatus 根据运行结果对失败进行分类
+func newRunStatus(ctx context.Context, answer string, err error, usage SessionUsage) runStatus {
+	status := runStatus{
+		Status:           "success",
+		ExitCode:         exitSuccess,
+		Answer:           answer,
+		Model:            usage.Model,
+		DurationMS:       usage.Duration.Milliseconds(),
+		Requests:         usage.Requests,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		ToolCalls:        usage.ToolCalls,
+		ToolErrors:       usage.ToolErrors,
+	}
+	if err == nil {
+		return status
+	}
+
+	status.Error = err.Error()
+	switch {
+	case ctx.Err() != nil || errors.Is(err, context.Canceled):
+		status.Status, status.ExitCode = "aborted", exitUserAbort
+	case errors.Is(err, errCapExceeded):
+		status.Status, status.ExitCode = "cap_exceeded", exitCapExceeded
+	case errors.Is(err, errToolFailed):
+		status.Status, status.ExitCode = "tool_error", exitToolError
+	default:
+		status.Status, status.ExitCode = "model_error", exitModelError
+	}
+	return status
+}
+
+// writeStatus 将状态写入文件，path 为 "-" 时写到标准输出
+func writeStatus(path string, status runStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write status: %w", err)
+	}
+	return nil
+}
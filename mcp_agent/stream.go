@@ -2,41 +2,68 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"strings"
+	"time"
 
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/stream"
 	"github.com/ollama/ollama/api"
 )
 
 func (a *Agent) runInferenceStreaming(ctx context.Context, conversation []api.Message, tools []api.Tool) (api.Message, error) {
-	if a.verbose {
-		log.Printf("Making streaming request with model: %v and %d tools", a.model, len(tools))
-	}
+	a.debug.logf(debugLLM, "Making streaming request with model: %v and %d tools", a.model, len(tools))
 
 	// 启用流式传输
-	stream := true
+	streaming := true
 	req := &api.ChatRequest{
 		Model:    a.model,
-		Stream:   &stream,
+		Stream:   &streaming,
 		Messages: conversation,
 		Tools:    tools,
 	}
 
 	var finalMessage api.Message
-	var contentBuilder string
+	// 超过输出上限后停止接收，避免失控的输出占满内存
+	content := stream.NewBuilder(a.maxOutput)
+
+	// 记录首个 token 的到达时间
+	start := time.Now()
+	var firstToken time.Duration
+	// 收到第一段文本时才输出 "Ollama:"，只调用工具的回答不显示空行
+	prefixed := false
 
 	// 流式响应
 	respFunc := func(resp api.ChatResponse) error {
+		if firstToken == 0 && (resp.Message.Content != "" || len(resp.Message.ToolCalls) > 0) {
+			firstToken = time.Since(start)
+		}
+
 		// 实时传输文本内容
 		if resp.Message.Content != "" {
-			fmt.Print(resp.Message.Content)
-			contentBuilder += resp.Message.Content
+			if !prefixed {
+				render.Stdout.AssistantPrefix()
+				prefixed = true
+			}
+			kept, err := content.Write(resp.Message.Content)
+			fmt.Print(kept)
+			if err != nil {
+				return err
+			}
 		}
 
 		if resp.Done {
+			a.usage.recordInference(resp.Metrics)
+			// 之前的片段中收到的工具调用保留下来（OpenAI 兼容接口在结束前单独发送工具调用）
+			toolCalls := finalMessage.ToolCalls
 			finalMessage = resp.Message
-			finalMessage.Content = contentBuilder
-			fmt.Print("\r\n")
+			finalMessage.ToolCalls = toolCalls
+			finalMessage.Content = content.String()
+			if prefixed {
+				fmt.Print("\r\n")
+			}
+			render.Stdout.Hint("%s", formatSpeed(firstToken, resp.Metrics))
 		}
 
 		// 收集工具调用
@@ -48,16 +75,37 @@ func (a *Agent) runInferenceStreaming(ctx context.Context, conversation []api.Me
 	}
 
 	// 发送流式请求
-	if err := a.ollamaClient.Chat(ctx, req, respFunc); err != nil {
-		if a.verbose {
-			log.Printf("Chat streaming error: %v", err)
+	err := a.chat(ctx, req, respFunc)
+	if errors.Is(err, stream.ErrLimit) {
+		fmt.Print("\r\n")
+		render.Stdout.Warning("warning", "response stopped after %d bytes (--max-output)", content.Len())
+		finalMessage.Role = "assistant"
+		finalMessage.Content = content.String()
+		return finalMessage, nil
+	}
+	if err != nil {
+		a.debug.logf(debugLLM, "Chat streaming error: %v", err)
+		// 返回已经收到的部分，请求被用户取消时保留在对话中
+		if prefixed {
+			fmt.Print("\r\n")
 		}
-		return api.Message{}, fmt.Errorf("chat streaming error: %w", err)
+		return api.Message{Role: "assistant", Content: content.String()}, fmt.Errorf("chat streaming error: %w", err)
 	}
 
-	if a.verbose {
-		log.Printf("Streaming API call successful, response received")
-	}
+	a.debug.logf(debugLLM, "Streaming API call successful, response received")
 
 	return finalMessage, nil
 }
+
+// formatSpeed 格式化首 token 延迟与生成速度，帮助用户为交互使用选择模型和量化版本
+func formatSpeed(firstToken time.Duration, metrics api.Metrics) string {
+	parts := []string{fmt.Sprintf("first token %.2fs", firstToken.Seconds())}
+	if metrics.LoadDuration > 100*time.Millisecond {
+		parts = append(parts, fmt.Sprintf("load %.2fs", metrics.LoadDuration.Seconds()))
+	}
+	if metrics.EvalDuration > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f tokens/s", float64(metrics.EvalCount)/metrics.EvalDuration.Seconds()))
+	}
+	parts = append(parts, fmt.Sprintf("%d tokens", metrics.EvalCount))
+	return "[" + strings.Join(parts, ", ") + "]"
+}
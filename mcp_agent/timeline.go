@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// timelineWidth 是甘特图条形区域的宽度（字符数）
+const timelineWidth = 40
+
+// toolUsage 是一次工具调用使用的资源
+type toolUsage struct {
+	duration time.Duration // 墙上时间
+	// cpu 是调用期间本进程及其子进程的 CPU 时间，MCP 服务器进程自身的 CPU 时间不包括在内；
+	// 同时进行的其他工作也会计入，因此只是近似值。cpuKnown 为 false 表示平台不支持测量
+	cpu      time.Duration
+	cpuKnown bool
+	bytes    int // 工具结果的字节数（文本和图片）
+}
+
+// measureTool 开始测量一次工具调用，返回结束测量的函数
+func measureTool() func(result api.Message) toolUsage {
+	start := time.Now()
+	cpuStart, cpuKnown := processCPUTime()
+	return func(result api.Message) toolUsage {
+		usage := toolUsage{duration: time.Since(start), bytes: len(result.Content)}
+		for _, image := range result.Images {
+			usage.bytes += len(image)
+		}
+		if cpuEnd, ok := processCPUTime(); ok && cpuKnown {
+			usage.cpu, usage.cpuKnown = cpuEnd-cpuStart, true
+		}
+		return usage
+	}
+}
+
+// toolTiming 记录一次工具调用的开始时间、资源使用和结果
+type toolTiming struct {
+	name   string
+	server string
+	start  time.Time
+	usage  toolUsage
+	err    error
+}
+
+// turnTimeline 记录最近一轮对话中的工具调用，用于 /timeline
+type turnTimeline struct {
+	start time.Time
+	calls []toolTiming
+}
+
+// reset 在新一轮对话开始时清空记录
+func (t *turnTimeline) reset() {
+	t.start = time.Now()
+	t.calls = nil
+}
+
+// record 记录一次工具调用，server 为 "builtin" 表示内置工具
+func (t *turnTimeline) record(name, server string, start time.Time, usage toolUsage, err error) {
+	t.calls = append(t.calls, toolTiming{name: name, server: server, start: start, usage: usage, err: err})
+}
+
+// serverOf 返回工具所属的 MCP 服务器，内置工具返回 "builtin"
+func (a *Agent) serverOf(name string) string {
+	if a.client() != nil {
+		if server, _, err := a.client().ResolveToolName(name); err == nil {
+			return server
+		}
+	}
+	return "builtin"
+}
+
+// render 以甘特图的形式显示工具调用的开始时间、耗时和状态
+func (t *turnTimeline) render() string {
+	if len(t.calls) == 0 {
+		return "No tool calls in the last turn"
+	}
+
+	// 时间轴从本轮开始到最后一个调用结束
+	var span time.Duration
+	nameWidth, serverWidth := len("tool"), len("server")
+	for _, call := range t.calls {
+		span = max(span, call.start.Sub(t.start)+call.usage.duration)
+		nameWidth = max(nameWidth, len(call.name))
+		serverWidth = max(serverWidth, len(call.server))
+	}
+	span = max(span, time.Millisecond)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-*s  %-*s  %8s  %8s  %8s  %8s  %-6s  |%s|\n", nameWidth, "tool", serverWidth, "server", "start", "duration", "cpu", "output", "status", strings.Repeat(" ", timelineWidth))
+	for _, call := range t.calls {
+		offset := call.start.Sub(t.start)
+		from := int(float64(offset) / float64(span) * timelineWidth)
+		length := max(1, int(float64(call.usage.duration)/float64(span)*timelineWidth))
+		from = min(from, timelineWidth-1)
+		length = min(length, timelineWidth-from)
+
+		status, style := "ok", render.Green
+		if call.err != nil {
+			status, style = "error", render.Red
+		}
+		bar := strings.Repeat(" ", from) + render.Stdout.Paint(style, strings.Repeat("█", length)) + strings.Repeat(" ", timelineWidth-from-length)
+		cpu := "-"
+		if call.usage.cpuKnown {
+			cpu = fmt.Sprintf("%.2fs", call.usage.cpu.Seconds())
+		}
+		fmt.Fprintf(&sb, "%-*s  %-*s  %7.2fs  %7.2fs  %8s  %8s  %-6s  |%s|\n", nameWidth, call.name, serverWidth, call.server,
+			offset.Seconds(), call.usage.duration.Seconds(), cpu, formatBytes(int64(call.usage.bytes)), status, bar)
+	}
+	fmt.Fprintf(&sb, "%d tool calls, %.2fs in tools, turn span %.2fs", len(t.calls), t.toolTime().Seconds(), span.Seconds())
+	return sb.String()
+}
+
+// toolTime 返回所有工具调用的总耗时
+func (t *turnTimeline) toolTime() time.Duration {
+	var total time.Duration
+	for _, call := range t.calls {
+		total += call.usage.duration
+	}
+	return total
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// clientOptions 返回 MCP 客户端选项，将服务器发起的请求和通知交给 Agent 处理
+func (a *Agent) clientOptions() *mcp.ClientOptions {
+	opts := &mcp.ClientOptions{
+		SamplingHandler:    a.handleSampling,
+		ElicitationHandler: a.handleElicitation,
+		OnToolsChanged:     a.onToolsChanged,
+		LogHandler:         a.onServerLog,
+	}
+	return opts
+}
+
+// onServerLog 显示 MCP 服务器通过 notifications/message 发送的日志。只在调试 mcp 通道时显示，
+// 避免打扰正常对话；每条日志到达时检查，/debug 在运行中开启后立即生效
+func (a *Agent) onServerLog(message mcp.LogMessage) {
+	if a.serverLogs.Load() {
+		slog.Debug(fmt.Sprintf("[%s] %s: %s", message.Level, message.Server, message.Text()), "channel", debugMCP)
+	}
+}
+
+// loadTools 获取所有 MCP 工具以及内置工具
+func (a *Agent) loadTools(ctx context.Context) ([]api.Tool, error) {
+	tools, err := a.client().GetTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MCP tools: %w", err)
+	}
+	tools = append(tools, builtinTools()...)
+	if a.offline {
+		tools = a.filterOfflineTools(tools)
+	}
+	return a.localizeTools(tools, a.toolLang), nil
+}
+
+// onToolsChanged 在服务器发送 tools/list_changed 通知时被调用
+func (a *Agent) onToolsChanged(server string) {
+	a.debug.logf(debugMCP, "Tool list changed on server %s", server)
+	a.toolsChanged.Store(true)
+}
+
+// refreshTools 每轮推理前重新获取工具列表（GetTools 带缓存，开销很小），
+// 服务器通知工具列表变化时提示用户
+func (a *Agent) refreshTools(ctx context.Context, tools []api.Tool) []api.Tool {
+	changed := a.toolsChanged.Swap(false)
+
+	refreshed, err := a.loadTools(ctx)
+	if err != nil {
+		render.Stdout.Error(fmt.Errorf("failed to refresh tools: %w", err))
+		return tools
+	}
+
+	if changed {
+		render.Stdout.Note("tools", "tool list updated (%d -> %d)", len(tools), len(refreshed))
+	}
+	return refreshed
+}
+
+// reloadConfig 在配置文件变化后重新加载配置，增删或重连受影响的 MCP 服务器，
+// 工具列表在下一轮推理前刷新
+func (a *Agent) reloadConfig(ctx context.Context, paths []string, profile string) {
+	config, err := loadConfig(paths, profile)
+	if err != nil {
+		fmt.Println()
+		render.Stdout.Error(fmt.Errorf("config changed but could not be loaded: %w", err))
+		return
+	}
+	useBundledBinaries(config, a.debug)
+	a.urlPolicy.Store(config.URLPolicy)
+	a.useURLProxy(config)
+
+	result, err := a.mcpClient.Reload(ctx, config)
+	if err != nil {
+		fmt.Println()
+		render.Stdout.Error(fmt.Errorf("failed to apply config: %w", err))
+		return
+	}
+	if a.staging != nil {
+		if _, err := a.staging.client.Reload(ctx, config); err != nil {
+			fmt.Println()
+			render.Stdout.Error(fmt.Errorf("failed to apply config to the copy of the workspace: %w", err))
+		}
+	}
+	if result.Empty() {
+		a.debug.logf(debugMCP, "Config changed, no server affected")
+		return
+	}
+
+	var changes []string
+	for _, c := range []struct {
+		label string
+		names []string
+	}{{"added", result.Added}, {"removed", result.Removed}, {"reconnected", result.Changed}} {
+		if len(c.names) > 0 {
+			changes = append(changes, fmt.Sprintf("%s %s", c.label, strings.Join(c.names, ", ")))
+		}
+	}
+	fmt.Println()
+	render.Stdout.Note("config", "MCP config reloaded: %s", strings.Join(changes, "; "))
+}
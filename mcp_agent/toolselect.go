@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/vectorindex"
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultEmbedModel 是选择相关工具时使用的嵌入模型
+const DefaultEmbedModel = "nomic-embed-text"
+
+// toolLimits 按模型参数量限制发送给模型的工具数，小模型面对几十个工具时很难选对，
+// 大模型在服务器很多时也只接收相关的工具，以控制提示词的长度
+var toolLimits = []struct {
+	maxParams float64 // 参数量上限（十亿）
+	tools     int
+}{
+	{2, 8},
+	{5, 16},
+	{10, 24},
+	{math.Inf(1), 32},
+}
+
+// searchResultCount 是 search_tools 每次返回的工具数
+const searchResultCount = 5
+
+// embedRetryInterval 是嵌入模型出错后改用关键词匹配的时间，之后再尝试嵌入模型
+const embedRetryInterval = 5 * time.Minute
+
+// toolSelector 按用户问题挑选最相关的工具（tool RAG），其余工具可以通过 search_tools 找到
+type toolSelector struct {
+	// search_tools 是只读工具，可能与其他调用同时执行，mu 保护下面所有字段
+	mu sync.Mutex
+
+	limits     map[string]int       // 按模型缓存的工具数上限，0 表示不限制
+	embeddings map[string][]float32 // 按文本缓存的嵌入向量
+	embedRetry time.Time            // 嵌入模型出错后，在此时间之前改用关键词匹配
+	announced  bool
+
+	all        []api.Tool      // 本轮可用的全部工具
+	discovered map[string]bool // 本轮通过 search_tools 找到的工具
+
+	// 同一轮对话中复用上一次的选择
+	lastKey    string
+	lastResult []api.Tool
+}
+
+// startTurn 在新一轮对话开始时清空已发现的工具
+func (s *toolSelector) startTurn() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.discovered = nil
+}
+
+// toolLimit 返回当前模型的工具数上限：--max-tools 优先，否则按模型参数量估计。调用时需持有 toolSelect.mu
+func (a *Agent) toolLimit(ctx context.Context) int {
+	if a.maxTools != 0 {
+		return max(a.maxTools, 0)
+	}
+	if limit, ok := a.toolSelect.limits[a.model]; ok {
+		return limit
+	}
+
+	limit := 0
+	resp, err := a.llm.Show(ctx, &api.ShowRequest{Model: a.model})
+	if err != nil {
+		a.debug.logf(debugLLM, "Failed to query the size of %s: %v", a.model, err)
+	} else if params, ok := parseParameterSize(resp.Details.ParameterSize); ok {
+		for _, l := range toolLimits {
+			if params < l.maxParams {
+				limit = l.tools
+				break
+			}
+		}
+		a.debug.logf(debugTools, "Model %s has %s parameters, tool limit %d", a.model, resp.Details.ParameterSize, limit)
+	}
+
+	if a.toolSelect.limits == nil {
+		a.toolSelect.limits = make(map[string]int)
+	}
+	a.toolSelect.limits[a.model] = limit
+	return limit
+}
+
+// parseParameterSize 解析 Ollama 报告的参数量（如 "1.7B"、"494.03M"），单位为十亿
+func parseParameterSize(size string) (float64, bool) {
+	size = strings.TrimSpace(strings.ToUpper(size))
+	if size == "" {
+		return 0, false
+	}
+	scale := 1.0
+	switch size[len(size)-1] {
+	case 'K':
+		scale = 1e-6
+	case 'M':
+		scale = 1e-3
+	case 'B':
+		scale = 1
+	case 'T':
+		scale = 1e3
+	default:
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(size[:len(size)-1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return n * scale, true
+}
+
+// selectTools 在工具数超过上限时，只保留与最近一个用户问题最相关的工具，保持原有顺序
+func (a *Agent) selectTools(ctx context.Context, conversation []api.Message, tools []api.Tool) []api.Tool {
+	a.toolSelect.mu.Lock()
+	defer a.toolSelect.mu.Unlock()
+	limit := a.toolLimit(ctx)
+	if limit == 0 || len(tools) <= limit {
+		return tools
+	}
+	a.toolSelect.all = tools
+
+	prompt := lastUserMessage(conversation)
+	discovered := make([]string, 0, len(a.toolSelect.discovered))
+	for name := range a.toolSelect.discovered {
+		discovered = append(discovered, name)
+	}
+	sort.Strings(discovered)
+	key := fmt.Sprintf("%d\x00%s\x00%s\x00%s", limit, toolNames(tools), strings.Join(discovered, ","), prompt)
+	if key == a.toolSelect.lastKey {
+		return a.toolSelect.lastResult
+	}
+
+	order, method := a.rankTools(ctx, prompt, tools)
+	chosen := order[:limit]
+	sort.Ints(chosen)
+
+	// 选中的工具加上本轮已发现的工具，以及用于发现其余工具的 search_tools
+	selected := make([]api.Tool, 0, limit+len(discovered)+1)
+	for i, tool := range tools {
+		if slices.Contains(chosen, i) || a.toolSelect.discovered[tool.Function.Name] {
+			selected = append(selected, tool)
+		}
+	}
+	selected = append(selected, a.localizeTools([]api.Tool{searchToolsTool()}, a.toolLang)...)
+
+	if !a.toolSelect.announced {
+		render.Stdout.Note("tools", "sending %s the %d most relevant of %d tools per question (%s), search_tools finds the others (--max-tools=-1 sends all)", a.model, limit, len(tools), method)
+		a.toolSelect.announced = true
+	}
+	a.debug.logf(debugTools, "Selected tools by %s: %s", method, toolNames(selected))
+
+	a.toolSelect.lastKey, a.toolSelect.lastResult = key, selected
+	return selected
+}
+
+// searchToolsTool 是工具被筛选时提供给模型的元工具，用于查找未发送的工具
+func searchToolsTool() api.Tool {
+	return api.Tool{
+		Type: mcp.ToolTypeFunction,
+		Function: api.ToolFunction{
+			Name:        toolSearchTools,
+			Description: "Search all available tools by what you want to do. Only the tools most relevant to the question are listed; call this when none of them fits. The tools found become callable right away.",
+			Parameters: api.ToolFunctionParameters{
+				Type:     "object",
+				Required: []string{"query"},
+				Properties: map[string]api.ToolProperty{
+					"query": {
+						Type:        api.PropertyType{"string"},
+						Description: "What the tool should do, e.g. \"take a screenshot of a web page\".",
+					},
+				},
+			},
+		},
+	}
+}
+
+// searchTools 按描述搜索全部工具，找到的工具在本轮剩余的推理中一起发送给模型
+func (a *Agent) searchTools(ctx context.Context, query string) (string, error) {
+	if query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	a.toolSelect.mu.Lock()
+	defer a.toolSelect.mu.Unlock()
+	tools := a.toolSelect.all
+	if len(tools) == 0 {
+		return "All available tools are already listed.", nil
+	}
+
+	order, _ := a.rankTools(ctx, query, tools)
+	if a.toolSelect.discovered == nil {
+		a.toolSelect.discovered = make(map[string]bool)
+	}
+	var sb strings.Builder
+	sb.WriteString("These tools are now available:\n")
+	for _, i := range order[:min(searchResultCount, len(order))] {
+		tool := tools[i].Function
+		a.toolSelect.discovered[tool.Name] = true
+		fmt.Fprintf(&sb, "- %s: %s\n", tool.Name, tool.Description)
+	}
+	return sb.String(), nil
+}
+
+// rankTools 按与问题的相关度从高到低返回工具的下标，相关度相同时保持原有顺序，
+// 同时返回计算相关度的方法
+func (a *Agent) rankTools(ctx context.Context, prompt string, tools []api.Tool) ([]int, string) {
+	texts := make([]string, len(tools))
+	for i, tool := range tools {
+		texts[i] = tool.Function.Name + ": " + tool.Function.Description
+	}
+	scores, method := a.scoreTools(ctx, prompt, texts)
+
+	order := make([]int, len(tools))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	return order, method
+}
+
+// scoreTools 计算每个工具与问题的相关度，优先使用嵌入向量的余弦相似度。
+// 嵌入模型出错时这一次改用关键词匹配，并在 embedRetryInterval 之后再尝试
+func (a *Agent) scoreTools(ctx context.Context, prompt string, texts []string) ([]float64, string) {
+	if time.Now().After(a.toolSelect.embedRetry) {
+		scores, err := a.embeddingScores(ctx, prompt, texts)
+		if err == nil {
+			return scores, "embedding similarity"
+		}
+		a.toolSelect.embedRetry = time.Now().Add(embedRetryInterval)
+		a.debug.logf(debugTools, "Embedding model %s unavailable, using keyword matching for %s: %v", a.embedModel, embedRetryInterval, err)
+	}
+	return keywordScores(prompt, texts), "keyword matching"
+}
+
+// keywordScores 按与问题共有的关键词数计算相关度
+func keywordScores(prompt string, texts []string) []float64 {
+	words := keywords(prompt)
+	scores := make([]float64, len(texts))
+	for i, text := range texts {
+		for word := range keywords(text) {
+			if words[word] {
+				scores[i]++
+			}
+		}
+	}
+	return scores
+}
+
+// embeddingScores 计算问题与各工具描述的余弦相似度，工具描述的嵌入向量会被缓存
+func (a *Agent) embeddingScores(ctx context.Context, prompt string, texts []string) ([]float64, error) {
+	if a.toolSelect.embeddings == nil {
+		a.toolSelect.embeddings = make(map[string][]float32)
+	}
+
+	missing := []string{prompt}
+	for _, text := range texts {
+		if _, ok := a.toolSelect.embeddings[text]; !ok {
+			missing = append(missing, text)
+		}
+	}
+	resp, err := a.llm.Embed(ctx, &api.EmbedRequest{Model: a.embedModel, Input: missing})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(missing) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(missing), len(resp.Embeddings))
+	}
+	for i, text := range missing[1:] {
+		a.toolSelect.embeddings[text] = resp.Embeddings[i+1]
+	}
+
+	query := resp.Embeddings[0]
+	scores := make([]float64, len(texts))
+	for i, text := range texts {
+		scores[i] = vectorindex.Cosine(query, a.toolSelect.embeddings[text])
+	}
+	return scores, nil
+}
+
+// keywords 提取文本中的英文单词（按 "_" 拆分工具名）和汉字
+func keywords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) || unicode.Is(unicode.Han, r)
+	}) {
+		if len(field) >= 3 {
+			words[field] = true
+		}
+	}
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			words[string(r)] = true
+		}
+	}
+	return words
+}
+
+// lastUserMessage 返回对话中最近一条用户消息
+func lastUserMessage(conversation []api.Message) string {
+	for i := len(conversation) - 1; i >= 0; i-- {
+		if conversation[i].Role == "user" {
+			return conversation[i].Content
+		}
+	}
+	return ""
+}
+
+func toolNames(tools []api.Tool) string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Function.Name
+	}
+	return strings.Join(names, ",")
+}
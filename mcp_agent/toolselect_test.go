@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseParameterSize(t *testing.T) {
+	tests := []struct {
+		size string
+		want float64
+		ok   bool
+	}{
+		{"1.7B", 1.7, true},
+		{"494.03M", 0.49403, true},
+		{" 70b ", 70, true},
+		{"1.2T", 1200, true},
+		{"500K", 0.0005, true},
+		{"", 0, false},
+		{"7", 0, false},
+		{"B", 0, false},
+		{"seven B", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseParameterSize(tt.size)
+		assert.Equal(t, tt.ok, ok, tt.size)
+		assert.InDelta(t, tt.want, got, 1e-9, tt.size)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"read_file: Read a file", []string{"read", "file"}},
+		{"Take a SCREENSHOT of the page", []string{"take", "screenshot", "the", "page"}},
+		{"go to it", nil},
+		{"search_code 搜索代码", []string{"search", "code", "搜", "索", "代", "码"}},
+		{"版本v2.0", []string{"版", "本"}},
+	}
+	for _, tt := range tests {
+		got := keywords(tt.text)
+		var words []string
+		for word := range got {
+			words = append(words, word)
+		}
+		assert.ElementsMatch(t, tt.want, words, tt.text)
+	}
+}
+
+// embedClient 是只实现 Embed 的模型客户端
+type embedClient struct {
+	modelClient
+	err   error
+	calls int
+}
+
+func (c *embedClient) Embed(ctx context.Context, req *api.EmbedRequest) (*api.EmbedResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := &api.EmbedResponse{}
+	for range req.Input.([]string) {
+		resp.Embeddings = append(resp.Embeddings, []float32{1, 0})
+	}
+	return resp, nil
+}
+
+func TestScoreToolsFallsBackPerCall(t *testing.T) {
+	llm := &embedClient{err: errors.New("model not found")}
+	a := newTestAgent(t)
+	a.llm = llm
+
+	texts := []string{"read_file: Read a file", "take_screenshot: Take a screenshot"}
+	scores, method := a.scoreTools(context.Background(), "read the file", texts)
+	assert.Equal(t, "keyword matching", method)
+	assert.Greater(t, scores[0], scores[1])
+
+	// 出错后一段时间内不再请求嵌入模型
+	_, method = a.scoreTools(context.Background(), "read the file", texts)
+	assert.Equal(t, "keyword matching", method)
+	assert.Equal(t, 1, llm.calls)
+
+	// 之后重新尝试，嵌入模型恢复后继续使用
+	llm.err = nil
+	a.toolSelect.embedRetry = time.Now().Add(-time.Second)
+	_, method = a.scoreTools(context.Background(), "read the file", texts)
+	assert.Equal(t, "embedding similarity", method)
+	assert.Equal(t, 2, llm.calls)
+}
+
+func TestSearchToolsConcurrently(t *testing.T) {
+	a := newTestAgent(t)
+	a.llm = &embedClient{}
+	a.toolSelect.all = []api.Tool{
+		{Function: api.ToolFunction{Name: "read_file", Description: "Read a file"}},
+		{Function: api.ToolFunction{Name: "take_screenshot", Description: "Take a screenshot"}},
+	}
+
+	// search_tools 是只读工具，同一批调用会同时执行
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := a.searchTools(context.Background(), fmt.Sprintf("query %d", i))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, a.toolSelect.discovered, 2)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+)
+
+// checkURLPolicy 在网络类工具调用发出前，按配置中的 urlPolicy 检查参数中的 URL。
+// 这里只能检查模型给出的 URL，重定向和 DNS rebinding 由 urlProxy 在连接时检查
+func (a *Agent) checkURLPolicy(ctx context.Context, name string, args map[string]interface{}) error {
+	policy := a.urlPolicy.Load()
+	if policy == nil || !a.isNetworkTool(name) {
+		return nil
+	}
+
+	for key, value := range args {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if key != "url" && key != "uri" && !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+			continue
+		}
+		if err := checkURL(ctx, policy, raw); err != nil {
+			return fmt.Errorf("%w: %w", errURLPolicy, err)
+		}
+	}
+	return nil
+}
+
+// checkURL 检查单个 URL 是否符合策略
+func checkURL(ctx context.Context, policy *mcp.URLPolicy, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q", raw)
+	}
+	// 浏览器可以打开 file:// 等本地地址，只允许 http 和 https
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme %q is not allowed in %s", u.Scheme, raw)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("invalid URL %q", raw)
+	}
+
+	_, err = checkHost(ctx, policy, u.Hostname())
+	return err
+}
+
+// checkHost 检查主机名是否符合策略。启用 blockPrivateNetworks 时返回检查过的 IP，
+// 连接时应直接使用这些 IP，不能再次解析，否则 DNS rebinding 可以绕过检查
+func checkHost(ctx context.Context, policy *mcp.URLPolicy, host string) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if domain, ok := matchDomain(host, policy.BlockedDomains); ok {
+		return nil, fmt.Errorf("%s is blocked (blockedDomains: %s)", host, domain)
+	}
+	if len(policy.AllowedDomains) > 0 {
+		if _, ok := matchDomain(host, policy.AllowedDomains); !ok {
+			return nil, fmt.Errorf("%s is not in allowedDomains", host)
+		}
+	}
+	if !policy.BlockPrivateNetworks {
+		return nil, nil
+	}
+
+	ips, err := resolveHost(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if isPrivateIP(ip) {
+			return nil, fmt.Errorf("%s resolves to the private address %s", host, ip)
+		}
+	}
+	return ips, nil
+}
+
+// errURLPolicy 表示连接被 URL 访问策略拒绝
+var errURLPolicy = errors.New("URL policy")
+
+// policyDialer 在建立连接时按当前的 URL 访问策略检查目标主机，并连接检查过的 IP
+type policyDialer struct {
+	policy func() *mcp.URLPolicy
+	dialer net.Dialer
+}
+
+func (d *policyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	policy := d.policy()
+	if policy == nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := checkHost(ctx, policy, host)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errURLPolicy, err)
+	}
+	if ips == nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	var errs []error
+	for _, ip := range ips {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// matchDomain 判断 host 是否为列表中的域名或其子域名，支持 "*.example.com" 写法
+func matchDomain(host string, domains []string) (string, bool) {
+	for _, domain := range domains {
+		d := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "*."))
+		if d == "" {
+			continue
+		}
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return domain, true
+		}
+	}
+	return "", false
+}
+
+// resolveHost 解析主机名，IP 地址直接返回
+func resolveHost(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// isPrivateIP 判断地址是否属于本机、内网或链路本地网段
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsInterfaceLocalMulticast()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckURL(t *testing.T) {
+	private := &mcp.URLPolicy{BlockPrivateNetworks: true}
+	allowlist := &mcp.URLPolicy{AllowedDomains: []string{"go.dev", "*.github.com"}, BlockedDomains: []string{"gist.github.com"}}
+
+	tests := []struct {
+		name   string
+		policy *mcp.URLPolicy
+		url    string
+		err    string
+	}{
+		{"public address", private, "http://93.184.216.34/", ""},
+		{"loopback", private, "http://127.0.0.1:8080/", "private address 127.0.0.1"},
+		{"loopback name", private, "http://localhost/", "private address"},
+		{"ipv6 loopback", private, "http://[::1]/", "private address ::1"},
+		{"private", private, "https://10.1.2.3/", "private address 10.1.2.3"},
+		{"private 192.168", private, "https://192.168.0.1/", "private address 192.168.0.1"},
+		{"link-local metadata", private, "http://169.254.169.254/latest/meta-data", "private address 169.254.169.254"},
+		{"unspecified", private, "http://0.0.0.0/", "private address 0.0.0.0"},
+		{"allowed domain", allowlist, "https://go.dev/doc", ""},
+		{"allowed subdomain", allowlist, "https://api.github.com/repos", ""},
+		{"allowed domain with trailing dot", allowlist, "https://GO.DEV./", ""},
+		{"not allowed", allowlist, "https://example.com/", "not in allowedDomains"},
+		{"suffix is not a subdomain", allowlist, "https://evilgo.dev/", "not in allowedDomains"},
+		{"blocked beats allowed", allowlist, "https://gist.github.com/x", "is blocked"},
+		{"scheme", allowlist, "file:///etc/passwd", `scheme "file"`},
+		{"no host", allowlist, "http:///path", "invalid URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkURL(context.Background(), tt.policy, tt.url)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPolicyDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	var policy *mcp.URLPolicy
+	dialer := &policyDialer{policy: func() *mcp.URLPolicy { return policy }}
+
+	conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("localhost", port))
+	require.NoError(t, err)
+	conn.Close()
+
+	// 连接时重新检查，被拒绝的地址不会建立连接
+	policy = &mcp.URLPolicy{BlockPrivateNetworks: true}
+	_, err = dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("localhost", port))
+	assert.ErrorIs(t, err, errURLPolicy)
+
+	policy = &mcp.URLPolicy{AllowedDomains: []string{"127.0.0.1"}}
+	conn, err = dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("127.0.0.1", port))
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestURLProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "secure")
+	}))
+	defer tlsServer.Close()
+
+	policy := &mcp.URLPolicy{AllowedDomains: []string{"127.0.0.1"}}
+	proxy, err := startURLProxy(func() *mcp.URLPolicy { return policy })
+	require.NoError(t, err)
+	proxyURL, err := url.Parse("http://" + proxy.addr)
+	require.NoError(t, err)
+
+	transport := tlsServer.Client().Transport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	client := &http.Client{Transport: transport}
+
+	get := func(target string) (int, string) {
+		resp, err := client.Get(target)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := get(server.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello", body)
+	status, body = get(tlsServer.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "secure", body)
+
+	// 已建立的隧道不受影响，新的连接按更新后的策略检查
+	policy = &mcp.URLPolicy{BlockPrivateNetworks: true}
+	client.CloseIdleConnections()
+	status, body = get(server.URL)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, body, "private address")
+	_, err = client.Get(tlsServer.URL)
+	assert.ErrorContains(t, err, "Forbidden")
+}
+
+func TestUseURLProxy(t *testing.T) {
+	config := &mcp.Config{
+		URLPolicy: &mcp.URLPolicy{BlockPrivateNetworks: true},
+		MCPServers: map[string]mcp.MCPServer{
+			"web_browser": {Command: "web_browser"},
+			"fetch":       {Command: "fetch", Env: map[string]string{"HTTPS_PROXY": "http://corp:3128"}},
+			"filesystem":  {Command: "filesystem"},
+			"remote_web":  {Type: "sse", URL: "http://localhost:19621/sse"},
+		},
+	}
+	a := &agentCore{}
+	a.urlPolicy.Store(config.URLPolicy)
+	a.useURLProxy(config)
+	require.NotNil(t, a.urlProxy)
+
+	proxy := "http://" + a.urlProxy.addr
+	assert.Equal(t, proxy, config.MCPServers["web_browser"].Env["HTTP_PROXY"])
+	assert.Equal(t, proxy, config.MCPServers["fetch"].Env["HTTP_PROXY"])
+	assert.Equal(t, "http://corp:3128", config.MCPServers["fetch"].Env["HTTPS_PROXY"])
+	assert.Empty(t, config.MCPServers["filesystem"].Env)
+	assert.Empty(t, config.MCPServers["remote_web"].Env)
+}
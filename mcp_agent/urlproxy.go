@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+)
+
+// proxyEnv 是传给网络类 stdio 服务器的代理环境变量
+var proxyEnv = []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"}
+
+// urlProxy 是只监听本机的 HTTP 代理，网络类 stdio 服务器通过它访问网络。
+// 代理在建立每个连接时检查 urlPolicy 并连接检查过的 IP，因此页面中的重定向、
+// 子资源请求和 DNS rebinding 都不能绕过策略
+type urlProxy struct {
+	addr   string
+	dialer *policyDialer
+	proxy  *httputil.ReverseProxy
+}
+
+// startURLProxy 在随机端口上启动代理，policy 返回当前的访问策略
+func startURLProxy(policy func() *mcp.URLPolicy) (*urlProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	p := &urlProxy{
+		addr:   listener.Addr().String(),
+		dialer: &policyDialer{policy: policy, dialer: net.Dialer{Timeout: 30 * time.Second}},
+	}
+	p.proxy = &httputil.ReverseProxy{
+		// 代理请求中的 URL 已经是完整地址，原样转发
+		Rewrite: func(*httputil.ProxyRequest) {},
+		// 不复用连接，每个请求都按当前策略重新检查
+		Transport:    &http.Transport{DialContext: p.dialer.DialContext, DisableKeepAlives: true, ResponseHeaderTimeout: time.Minute},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) { proxyError(w, err) },
+	}
+	go http.Serve(listener, p)
+	return p, nil
+}
+
+func (p *urlProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() || r.URL.Scheme != "http" {
+		http.Error(w, "only proxy requests for http:// URLs are supported", http.StatusBadRequest)
+		return
+	}
+	p.proxy.ServeHTTP(w, r)
+}
+
+// tunnel 处理 HTTPS 使用的 CONNECT 请求，在客户端和检查过的目标地址之间转发数据
+func (p *urlProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	target, err := p.dialer.DialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		proxyError(w, err)
+		return
+	}
+	defer target.Close()
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(target, buffered)
+		// 客户端不再发送数据时关闭写方向，让目标服务器知道请求已结束
+		if tcp, ok := target.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+		close(done)
+	}()
+	io.Copy(client, target)
+	client.Close()
+	<-done
+}
+
+// proxyError 返回连接失败的原因，被策略拒绝时返回 403
+func proxyError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	if errors.Is(err, errURLPolicy) {
+		status = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// useURLProxy 在配置了 urlPolicy 时启动代理，并让本机运行的网络类服务器（按服务器名判断，
+// 如 web_browser、fetch）通过代理访问网络。服务器配置中已有的代理变量不被覆盖；
+// 远程服务器在其他进程中访问网络，不经过代理，只检查模型给出的 URL
+func (a *agentCore) useURLProxy(config *mcp.Config) {
+	if a.urlProxy == nil {
+		if config.URLPolicy == nil {
+			return
+		}
+		proxy, err := startURLProxy(a.urlPolicy.Load)
+		if err != nil {
+			render.Stdout.Warning("urlPolicy", "failed to start the proxy: %v, only URLs in tool arguments are checked", err)
+			return
+		}
+		a.urlProxy = proxy
+	}
+
+	for name, server := range config.MCPServers {
+		if server.Remote() || server.Type == "docker" || !offlineToolPattern.MatchString(name) {
+			continue
+		}
+		env := make(map[string]string, len(server.Env)+len(proxyEnv))
+		for k, v := range server.Env {
+			env[k] = v
+		}
+		for _, k := range proxyEnv {
+			if _, ok := env[k]; !ok {
+				env[k] = "http://" + a.urlProxy.addr
+			}
+		}
+		server.Env = env
+		config.MCPServers[name] = server
+	}
+}
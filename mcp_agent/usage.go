@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// usageDBName 是使用统计数据库的文件名，位于 ~/.mcp_agent 目录下，每个会话一行
+const usageDBName = "usage.db"
+
+// legacyUsageFileName 是旧版本的使用统计文件 (JSON Lines)，第一次打开数据库时导入
+const legacyUsageFileName = "usage.jsonl"
+
+const usageSchema = `CREATE TABLE IF NOT EXISTS usage (
+	id                INTEGER PRIMARY KEY,
+	model             TEXT NOT NULL,
+	started_at        TEXT NOT NULL,
+	duration_ms       INTEGER NOT NULL,
+	requests          INTEGER NOT NULL,
+	prompt_tokens     INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL,
+	tool_calls        TEXT NOT NULL,
+	tool_errors       INTEGER NOT NULL,
+	success           INTEGER NOT NULL
+)`
+
+// SessionUsage 记录一次会话的使用情况
+type SessionUsage struct {
+	Model            string         `json:"model"`
+	StartedAt        time.Time      `json:"started_at"`
+	Duration         time.Duration  `json:"duration"`
+	Requests         int            `json:"requests"`
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	ToolCalls        map[string]int `json:"tool_calls,omitempty"`
+	ToolErrors       int            `json:"tool_errors"`
+	Success          bool           `json:"success"`
+}
+
+// usageTracker 在会话期间累计使用情况，推理可能并发执行（如 ensemble 模式），因此需要加锁
+type usageTracker struct {
+	mu    sync.Mutex
+	usage SessionUsage
+}
+
+func newUsageTracker(model string) *usageTracker {
+	return &usageTracker{
+		usage: SessionUsage{
+			Model:     model,
+			StartedAt: time.Now(),
+			ToolCalls: make(map[string]int),
+		},
+	}
+}
+
+// recordInference 累计一次推理的 token 用量
+func (t *usageTracker) recordInference(metrics api.Metrics) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.Requests++
+	t.usage.PromptTokens += metrics.PromptEvalCount
+	t.usage.CompletionTokens += metrics.EvalCount
+}
+
+// recordToolCall 累计一次工具调用
+func (t *usageTracker) recordToolCall(name string, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.ToolCalls[name]++
+	if failed {
+		t.usage.ToolErrors++
+	}
+}
+
+// finish 结束会话并返回最终的使用记录
+func (t *usageTracker) finish(success bool) SessionUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.Duration = time.Since(t.usage.StartedAt)
+	t.usage.Success = success
+	return t.usage
+}
+
+// openUsageDB 打开使用统计数据库，旧版本的统计文件导入后重命名为 usage.jsonl.imported
+func openUsageDB() (*sql.DB, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(home, ".mcp_agent")
+	db, err := openSQLite(filepath.Join(dir, usageDBName), usageSchema)
+	if err != nil {
+		return nil, err
+	}
+	if err := importLegacyUsage(db, filepath.Join(dir, legacyUsageFileName)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// importLegacyUsage 将旧版本 JSON Lines 文件中的记录导入数据库
+func importLegacyUsage(db *sql.DB, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open usage file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var usage SessionUsage
+		if err := json.Unmarshal(scanner.Bytes(), &usage); err != nil {
+			// 跳过损坏的记录
+			continue
+		}
+		if err := insertUsage(db, usage); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	f.Close()
+	return os.Rename(path, path+".imported")
+}
+
+// saveUsage 将会话使用记录写入统计数据库
+func saveUsage(usage SessionUsage) error {
+	db, err := openUsageDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return insertUsage(db, usage)
+}
+
+func insertUsage(db *sql.DB, usage SessionUsage) error {
+	toolCalls, err := json.Marshal(usage.ToolCalls)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO usage (model, started_at, duration_ms, requests, prompt_tokens, completion_tokens, tool_calls, tool_errors, success)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		usage.Model, usage.StartedAt.Format(time.RFC3339Nano), usage.Duration.Milliseconds(), usage.Requests,
+		usage.PromptTokens, usage.CompletionTokens, string(toolCalls), usage.ToolErrors, usage.Success)
+	if err != nil {
+		return fmt.Errorf("failed to save usage: %w", err)
+	}
+	return nil
+}
+
+// loadUsage 读取所有历史会话使用记录，按开始时间排序
+func loadUsage() ([]SessionUsage, error) {
+	db, err := openUsageDB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT model, started_at, duration_ms, requests, prompt_tokens, completion_tokens, tool_calls, tool_errors, success
+		FROM usage ORDER BY started_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []SessionUsage
+	for rows.Next() {
+		var usage SessionUsage
+		var startedAt, toolCalls string
+		var durationMS int64
+		if err := rows.Scan(&usage.Model, &startedAt, &durationMS, &usage.Requests, &usage.PromptTokens,
+			&usage.CompletionTokens, &toolCalls, &usage.ToolErrors, &usage.Success); err != nil {
+			return nil, err
+		}
+		usage.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+		usage.Duration = time.Duration(durationMS) * time.Millisecond
+		// 损坏的工具统计不影响其他字段
+		_ = json.Unmarshal([]byte(toolCalls), &usage.ToolCalls)
+		sessions = append(sessions, usage)
+	}
+	return sessions, rows.Err()
+}
+
+// modelStats 是单个模型的聚合统计
+type modelStats struct {
+	sessions         int
+	successes        int
+	duration         time.Duration
+	promptTokens     int
+	completionTokens int
+	toolCalls        int
+	toolErrors       int
+}
+
+// printStats 显示按模型聚合的统计表以及按周的使用趋势
+func printStats(sessions []SessionUsage) {
+	if len(sessions) == 0 {
+		fmt.Println("No usage recorded yet")
+		return
+	}
+
+	byModel := make(map[string]*modelStats)
+	byWeek := make(map[string]*modelStats)
+	for _, s := range sessions {
+		year, week := s.StartedAt.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+		for _, key := range []struct {
+			m    map[string]*modelStats
+			name string
+		}{{byModel, s.Model}, {byWeek, weekKey}} {
+			stats, ok := key.m[key.name]
+			if !ok {
+				stats = &modelStats{}
+				key.m[key.name] = stats
+			}
+			stats.add(s)
+		}
+	}
+
+	render.Stdout.Heading(fmt.Sprintf("Usage by model (%d sessions)", len(sessions)))
+	fmt.Printf("%-24s %8s %8s %10s %12s %12s %8s %8s\n", "MODEL", "SESSIONS", "SUCCESS", "AVG TIME", "PROMPT TOK", "OUTPUT TOK", "TOOLS", "ERRORS")
+	for _, name := range sortedKeys(byModel) {
+		st := byModel[name]
+		fmt.Printf("%-24s %8d %7.0f%% %10s %12d %12d %8d %8d\n",
+			truncateString(name, 24), st.sessions, st.successRate(), st.avgDuration(),
+			st.promptTokens, st.completionTokens, st.toolCalls, st.toolErrors)
+	}
+
+	fmt.Println()
+	render.Stdout.Heading("Weekly trend")
+	fmt.Printf("%-10s %8s %12s %8s\n", "WEEK", "SESSIONS", "TOKENS", "TOOLS")
+	weeks := sortedKeys(byWeek)
+	maxTokens := 0
+	for _, week := range weeks {
+		if t := byWeek[week].promptTokens + byWeek[week].completionTokens; t > maxTokens {
+			maxTokens = t
+		}
+	}
+	for _, week := range weeks {
+		st := byWeek[week]
+		tokens := st.promptTokens + st.completionTokens
+		bar := ""
+		if maxTokens > 0 {
+			bar = strings.Repeat("█", tokens*30/maxTokens)
+		}
+		fmt.Printf("%-10s %8d %12d %8d  %s\n", week, st.sessions, tokens, st.toolCalls, bar)
+	}
+}
+
+func (st *modelStats) add(s SessionUsage) {
+	st.sessions++
+	if s.Success {
+		st.successes++
+	}
+	st.duration += s.Duration
+	st.promptTokens += s.PromptTokens
+	st.completionTokens += s.CompletionTokens
+	for _, n := range s.ToolCalls {
+		st.toolCalls += n
+	}
+	st.toolErrors += s.ToolErrors
+}
+
+func (st *modelStats) successRate() float64 {
+	return float64(st.successes) / float64(st.sessions) * 100
+}
+
+func (st *modelStats) avgDuration() time.Duration {
+	return (st.duration / time.Duration(st.sessions)).Round(time.Second)
+}
+
+func sortedKeys(m map[string]*modelStats) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+)
+
+// userWorkspace 是多用户 serve 模式下用户独立的工作区：MCP 客户端以该目录为 roots 连接服务器，
+// filesystem、code_search 等服务器只能访问这个目录
+type userWorkspace struct {
+	dir    string
+	client *mcp.Client
+}
+
+// client 返回会话使用的 MCP 客户端：--verify-cmd 时为工作区副本的客户端，用户有独立工作区时为该工作区的客户端
+func (a *Agent) client() *mcp.Client {
+	if a.staging != nil {
+		return a.staging.client
+	}
+	if a.userSpace != nil {
+		return a.userSpace.client
+	}
+	return a.mcpClient
+}
+
+// toolRoots 返回会话中文件类工具可以访问的目录
+func (a *Agent) toolRoots() []string {
+	if a.staging != nil {
+		return a.staging.copies
+	}
+	if a.userSpace != nil {
+		return []string{a.userSpace.dir}
+	}
+	return a.roots
+}
+
+// apiUser 是 serve 模式下配置的一个用户，会话、工作区和速率限制与其他用户相互独立
+type apiUser struct {
+	name    string
+	token   string
+	manager *SessionManager
+	limiter *rateLimiter
+}
+
+// loadAPIUsers 为配置中的每个用户创建会话管理器。设置了 workspace 的用户使用以该目录为 roots 的
+// 独立 MCP 客户端（服务器进程也是独立的），否则与 agent 共享 MCP 客户端和工作区
+func loadAPIUsers(ctx context.Context, agent *Agent, config *mcp.Config, opts *mcp.ClientOptions) ([]*apiUser, error) {
+	users := make([]*apiUser, 0, len(config.Users))
+	fail := func(err error) ([]*apiUser, error) {
+		closeAPIUsers(users)
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	tokens := make(map[string]bool)
+	for _, u := range config.Users {
+		token := os.ExpandEnv(u.Token)
+		switch {
+		case u.Name == "":
+			return fail(errors.New("users: every user needs a name"))
+		case names[u.Name]:
+			return fail(fmt.Errorf("users: duplicate user %q", u.Name))
+		case token == "":
+			return fail(fmt.Errorf("users: user %q has no token", u.Name))
+		case tokens[token]:
+			return fail(fmt.Errorf("users: user %q has the same token as another user", u.Name))
+		case u.RateLimit < 0:
+			return fail(fmt.Errorf("users: user %q has a negative rateLimit", u.Name))
+		}
+		names[u.Name] = true
+		tokens[token] = true
+
+		user := &apiUser{name: u.Name, token: token, manager: NewSessionManager(agent), limiter: &rateLimiter{limit: u.RateLimit}}
+		if u.Workspace != "" {
+			space, err := openUserWorkspace(ctx, u.Workspace, config, opts)
+			if err != nil {
+				return fail(fmt.Errorf("users: workspace of %q: %w", u.Name, err))
+			}
+			user.manager.space = space
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// openUserWorkspace 连接以 dir 为工作区的 MCP 客户端
+func openUserWorkspace(ctx context.Context, dir string, config *mcp.Config, opts *mcp.ClientOptions) (*userWorkspace, error) {
+	dir, err := filepath.Abs(expandPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	userOpts := *opts
+	userOpts.Roots = []string{dir}
+	client, err := mcp.NewClient(ctx, config, &userOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &userWorkspace{dir: dir, client: client}, nil
+}
+
+// findAPIUser 返回 token 对应的用户，逐个比较所有用户，比较时间与 token 内容无关
+func findAPIUser(users []*apiUser, token string) *apiUser {
+	var found *apiUser
+	for _, user := range users {
+		if subtle.ConstantTimeCompare([]byte(user.token), []byte(token)) == 1 {
+			found = user
+		}
+	}
+	return found
+}
+
+// closeAPIUsers 结束所有用户的会话并关闭独立工作区的 MCP 客户端
+func closeAPIUsers(users []*apiUser) error {
+	var errs []error
+	for _, user := range users {
+		if err := user.manager.CloseAll(); err != nil {
+			errs = append(errs, err)
+		}
+		if user.manager.space != nil {
+			closeMCPClient(user.manager.space.client)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rateLimiter 限制每分钟的请求数，limit 为 0 表示不限制
+type rateLimiter struct {
+	limit int
+
+	mu     sync.Mutex
+	recent []time.Time
+}
+
+// allow 记录一次请求。超出限制时不记录，返回 false 和可以再次请求前需要等待的时间
+func (l *rateLimiter) allow(now time.Time) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cutoff := now.Add(-time.Minute)
+	kept := l.recent[:0]
+	for _, t := range l.recent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	l.recent = kept
+	if len(l.recent) >= l.limit {
+		return false, l.recent[0].Sub(cutoff)
+	}
+	l.recent = append(l.recent, now)
+	return true, 0
+}
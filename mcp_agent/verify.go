@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/workspace"
+	"gopkg.in/yaml.v3"
+)
+
+// editTools 是会修改文件内容的 MCP 工具名（不含服务器前缀）
+var editTools = map[string]bool{
+	"write_file": true,
+	"edit_file":  true,
+}
+
+// isEditTool 判断工具调用是否会修改文件，name 可以是别名
+func (a *Agent) isEditTool(name string) bool {
+	_, toolName, err := a.client().ResolveToolName(name)
+	return err == nil && editTools[toolName]
+}
+
+// toolPath 将文件工具参数中的路径解析为绝对路径：与 filesystem 服务器一样，相对路径相对于第一个工作区目录，
+// 工作区之外的路径返回错误
+func (a *Agent) toolPath(path string) (string, error) {
+	return workspace.Resolve(a.toolRoots(), expandPath(path))
+}
+
+// verifyEditedFile 对编辑后的文件做快速语法检查，返回发现的语法错误。path 是 toolPath 解析后的路径
+func verifyEditedFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s after edit: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		if _, err := parser.ParseFile(token.NewFileSet(), path, content, parser.AllErrors); err != nil {
+			return err
+		}
+	case ".json":
+		if !json.Valid(content) {
+			var v interface{}
+			return fmt.Errorf("%s: invalid JSON: %v", path, json.Unmarshal(content, &v))
+		}
+	case ".yaml", ".yml":
+		var v interface{}
+		if err := yaml.Unmarshal(content, &v); err != nil {
+			return fmt.Errorf("%s: invalid YAML: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// expandPath 展开路径开头的 "~" 为用户主目录
+func expandPath(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"slices"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/types/model"
+)
+
+// supportsVision 查询模型是否支持图片输入，结果按模型缓存
+func (a *Agent) supportsVision(ctx context.Context, modelName string) bool {
+	a.visionMu.Lock()
+	supported, ok := a.visionSupport[modelName]
+	a.visionMu.Unlock()
+	if ok {
+		return supported
+	}
+
+	resp, err := a.llm.Show(ctx, &api.ShowRequest{Model: modelName})
+	if err != nil {
+		a.debug.logf(debugLLM, "Failed to query capabilities of %s: %v", modelName, err)
+	} else {
+		supported = slices.Contains(resp.Capabilities, model.CapabilityVision)
+	}
+
+	a.visionMu.Lock()
+	defer a.visionMu.Unlock()
+	if a.visionSupport == nil {
+		a.visionSupport = make(map[string]bool)
+	}
+	a.visionSupport[modelName] = supported
+	return supported
+}
+
+// toolImages 返回需要附加到工具结果消息上的图片。模型不支持图片输入时不附加，
+// 结果文本中只保留图片的占位描述，避免将大段 base64 放入上下文
+func (a *Agent) toolImages(ctx context.Context, result *mcp.ToolResult) []api.ImageData {
+	images := result.Images()
+	if len(images) == 0 {
+		return nil
+	}
+
+	if !a.supportsVision(ctx, a.model) {
+		render.Stdout.Note("image", "%s does not support images, %d image(s) omitted", a.model, len(images))
+		return nil
+	}
+
+	data := make([]api.ImageData, 0, len(images))
+	for _, image := range images {
+		data = append(data, api.ImageData(image.Data))
+	}
+	render.Stdout.Note("image", "attached %d image(s) to the conversation", len(data))
+	return data
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/render"
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultStallTimeout 是默认的无响应等待时间，超过后探测 Ollama 状态
+const DefaultStallTimeout = 60 * time.Second
+
+// errStalled 表示推理请求长时间没有响应并被放弃
+var errStalled = errors.New("model stalled")
+
+// 推理停滞时的处理选项
+const (
+	stallWait  = "Keep waiting"
+	stallRetry = "Retry the request"
+	stallAbort = "Abort"
+)
+
+// stallPromptMu 保证并发推理（如 ensemble 模式）时同一时间只有一个询问
+var stallPromptMu sync.Mutex
+
+// modelState 是探测 /api/ps 得到的模型状态
+type modelState int
+
+const (
+	modelUnreachable modelState = iota // 推理服务本身没有响应
+	modelLoading                       // 模型尚未出现在运行列表中，仍在加载
+	modelLoaded                        // 模型已加载但没有输出，可能卡住
+)
+
+// chat 发送推理请求，模型尚未下载时提示下载后重试
+func (a *Agent) chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	a.prepareRequest(req)
+	return a.chatPulling(ctx, req, fn)
+}
+
+// chatWatched 发送推理请求并监视响应：stallTimeout 内没有收到任何响应时探测 Ollama，
+// 告知用户模型是在加载还是卡住，并由用户选择继续等待、重试或放弃
+func (a *Agent) chatWatched(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	if a.stallTimeout <= 0 {
+		return a.llm.Chat(ctx, req, fn)
+	}
+
+	for {
+		chatCtx, cancel := context.WithCancel(ctx)
+		activity := make(chan struct{}, 1)
+		done := make(chan error, 1)
+		go func() {
+			done <- a.llm.Chat(chatCtx, req, func(resp api.ChatResponse) error {
+				select {
+				case activity <- struct{}{}:
+				default:
+				}
+				return fn(resp)
+			})
+		}()
+
+		retry, err := a.watchChat(ctx, req.Model, activity, done)
+		cancel()
+		if !retry {
+			return err
+		}
+		<-done
+		render.Stdout.Warning("watchdog", "retrying request to %s", req.Model)
+	}
+}
+
+// watchChat 等待推理结束，返回是否需要重试
+func (a *Agent) watchChat(ctx context.Context, model string, activity <-chan struct{}, done <-chan error) (bool, error) {
+	timer := time.NewTimer(a.stallTimeout)
+	defer timer.Stop()
+
+	started := time.Now()
+	received := false
+	for {
+		select {
+		case err := <-done:
+			return false, err
+		case <-activity:
+			received = true
+			timer.Reset(a.stallTimeout)
+		case <-timer.C:
+			state := a.probeModel(ctx, model)
+			a.debug.logf(debugLLM, "No response from %s for %s, probe state: %d", model, a.stallTimeout, state)
+
+			switch a.decideStall(model, state, time.Since(started), received) {
+			case stallRetry:
+				return true, nil
+			case stallAbort:
+				return false, fmt.Errorf("%w: no response from %s after %s", errStalled, model, time.Since(started).Round(time.Second))
+			}
+			timer.Reset(a.stallTimeout)
+		}
+	}
+}
+
+// probeModel 通过 /api/ps（OpenAI 兼容的服务为 /models）判断模型是仍在加载还是已加载却没有输出
+func (a *Agent) probeModel(ctx context.Context, model string) modelState {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	running, err := a.llm.ListRunning(ctx)
+	if err != nil {
+		a.debug.logf(debugLLM, "Failed to probe running models: %v", err)
+		return modelUnreachable
+	}
+
+	if a.provider == providerOllama && !strings.Contains(model, ":") {
+		model += ":latest"
+	}
+	for _, m := range running.Models {
+		if m.Name == model || m.Model == model {
+			return modelLoaded
+		}
+	}
+	return modelLoading
+}
+
+// decideStall 告知用户停滞原因并询问如何处理。非交互模式下模型仍在加载时继续等待，否则放弃
+func (a *Agent) decideStall(model string, state modelState, waited time.Duration, received bool) string {
+	waited = waited.Round(time.Second)
+
+	var reason string
+	switch state {
+	case modelLoading:
+		reason = fmt.Sprintf("%s is still being loaded by the %s server (waited %s)", model, a.serverName(), waited)
+	case modelLoaded:
+		reason = fmt.Sprintf("%s is loaded but has not produced output for %s, it may be hung", model, a.stallTimeout)
+	default:
+		reason = fmt.Sprintf("the %s server is not responding (waited %s)", a.serverName(), waited)
+	}
+	fmt.Println()
+	render.Stdout.Warning("watchdog", "%s", reason)
+
+	if a.headless {
+		if state == modelLoading {
+			return stallWait
+		}
+		return stallAbort
+	}
+
+	// 已输出部分内容时重试会重复输出，只提供等待或放弃
+	options := []string{stallWait, stallAbort}
+	if !received {
+		options = []string{stallWait, stallRetry, stallAbort}
+	}
+	defaultOption := stallWait
+	if state != modelLoading && !received {
+		defaultOption = stallRetry
+	}
+
+	stallPromptMu.Lock()
+	defer stallPromptMu.Unlock()
+	defer a.input.pause()()
+
+	choice := defaultOption
+	prompt := &survey.Select{
+		Message: "What do you want to do?",
+		Options: options,
+		Default: defaultOption,
+	}
+	if err := survey.AskOne(prompt, &choice); err != nil {
+		return stallAbort
+	}
+	return choice
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/servers"
+)
+
+// runBuildServers 编译所有内置 MCP 服务器到 ./bin
+func runBuildServers() error {
+	if _, err := os.Stat("mcp_tool"); err != nil {
+		return fmt.Errorf("mcp_tool not found, please run this command from the repository root")
+	}
+
+	version := servers.Version()
+	for _, s := range servers.Bundled {
+		fmt.Printf("  🔨 Building %s%s%s (%s) -> %s\n", Bold, s.Name, ColorReset, version, servers.Binary(s, servers.DefaultBinDir))
+		if _, err := servers.Build(s, servers.DefaultBinDir); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("  %s✅ Built %d servers into ./%s%s\n", ColorGreen, len(servers.Bundled), servers.DefaultBinDir, ColorReset)
+	return nil
+}
+
+// runMCPInit 交互式地生成 mcp.json：编译所选的内置服务器并填入绝对路径
+func runMCPInit() error {
+	if _, err := os.Stat("mcp_tool"); err != nil {
+		return fmt.Errorf("mcp_tool not found, please run this command from the repository root")
+	}
+
+	fmt.Printf("%s%s═══════════════════════════════════%s\n", Bold, ColorBlue, ColorReset)
+	fmt.Printf("%s%s   Generate MCP Config%s\n", Bold, ColorCyan, ColorReset)
+	fmt.Printf("%s%s═══════════════════════════════════%s\n\n", Bold, ColorBlue, ColorReset)
+
+	options := make([]string, len(servers.Bundled))
+	var defaults []string
+	for i, s := range servers.Bundled {
+		options[i] = s.Name
+		if s.Type == "stdio" {
+			defaults = append(defaults, s.Name)
+		}
+	}
+
+	var selected []string
+	if err := survey.AskOne(&survey.MultiSelect{
+		Message: "Select the MCP servers to enable:",
+		Options: options,
+		Default: defaults,
+		Description: func(value string, index int) string {
+			return servers.Bundled[index].Description
+		},
+	}, &selected); err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no server selected")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	binDir := filepath.Join(home, ".mcp_agent", "bin")
+	if err := survey.AskOne(&survey.Input{Message: "Install server binaries to:", Default: binDir}, &binDir); err != nil {
+		return err
+	}
+	outPath := "mcp.json"
+	if err := survey.AskOne(&survey.Input{Message: "Write config to:", Default: outPath}, &outPath); err != nil {
+		return err
+	}
+	if _, err := os.Stat(outPath); err == nil {
+		overwrite := false
+		if err := survey.AskOne(&survey.Confirm{Message: fmt.Sprintf("%s already exists, overwrite?", outPath)}, &overwrite); err != nil {
+			return err
+		}
+		if !overwrite {
+			return nil
+		}
+	}
+
+	binDir, err = filepath.Abs(binDir)
+	if err != nil {
+		return err
+	}
+	config := mcp.Config{MCPServers: make(map[string]mcp.MCPServer)}
+	var sseBinaries []string
+	for _, s := range servers.Bundled {
+		if !contains(selected, s.Name) {
+			continue
+		}
+
+		fmt.Printf("  🔨 Building %s%s%s -> %s\n", Bold, s.Name, ColorReset, servers.Binary(s, binDir))
+		binary, err := servers.Build(s, binDir)
+		if err != nil {
+			return err
+		}
+
+		if s.Type == "sse" {
+			config.MCPServers[s.Name] = mcp.MCPServer{Type: "sse", URL: s.URL, Args: []string{}, AutoApprove: s.ReadOnly}
+			sseBinaries = append(sseBinaries, binary)
+		} else {
+			config.MCPServers[s.Name] = mcp.MCPServer{Command: binary, Args: []string{}, AutoApprove: s.ReadOnly}
+		}
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outPath, err)
+	}
+
+	absOut, _ := filepath.Abs(outPath)
+	fmt.Printf("\n  %s✅ Wrote %s%s\n", ColorGreen, absOut, ColorReset)
+	for _, binary := range sseBinaries {
+		fmt.Printf("  %s💡 Start the SSE server before running the agent: %s%s\n", ColorYellow, binary, ColorReset)
+	}
+	fmt.Printf("  🚀 %sCommand:%s go run ./mcp_agent --config %s\n", ColorBlue, ColorReset, absOut)
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/registry"
+	"golang.org/x/term"
+)
+
+// runMCPSearch 在 MCP 服务器索引中搜索，显示安装方式，并可以将选中的服务器加入 mcp.json
+func runMCPSearch(args []string) error {
+	flags := flag.NewFlagSet("mcp search", flag.ContinueOnError)
+	defaultIndex := os.Getenv("MCP_REGISTRY")
+	if defaultIndex == "" {
+		defaultIndex = registry.DefaultURL
+	}
+	index := flags.String("registry", defaultIndex, "MCP server index: registry URL or local JSON file (default: $MCP_REGISTRY or the official registry)")
+	configPath := flags.String("config", "mcp.json", "Config file the chosen server is added to")
+	limit := flags.Int("limit", 10, "Maximum number of results")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	query := strings.Join(flags.Args(), " ")
+	if query == "" {
+		return fmt.Errorf("usage: mcp search [--registry URL|FILE] [--config mcp.json] <query>")
+	}
+
+	fmt.Printf("  🔍 Searching %s for %s%q%s\n\n", *index, Bold, query, ColorReset)
+	results, err := registry.Search(context.Background(), *index, query, *limit)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Printf("  %sNo servers found%s\n", ColorYellow, ColorReset)
+		return nil
+	}
+
+	var installable []registry.Server
+	for i, server := range results {
+		fmt.Printf("  %s%d.%s %s%s%s %s\n", ColorCyan, i+1, ColorReset, Bold, server.Name, ColorReset, server.Version)
+		if server.Description != "" {
+			fmt.Printf("     📝 %s\n", server.Description)
+		}
+		if server.Repository.URL != "" {
+			fmt.Printf("     🔗 %s\n", server.Repository.URL)
+		}
+		for _, line := range server.Instructions() {
+			if strings.HasPrefix(line, " ") {
+				fmt.Printf("     %s\n", line) // 环境变量
+			} else {
+				fmt.Printf("     🚀 %s\n", line)
+			}
+		}
+		fmt.Println()
+		if _, ok := server.Config(); ok {
+			installable = append(installable, server)
+		}
+	}
+
+	// 只在终端中询问是否加入配置，输出被重定向时只显示结果
+	if len(installable) == 0 || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	const none = "(none)"
+	options := []string{none}
+	for _, server := range installable {
+		options = append(options, server.Name)
+	}
+	var choice string
+	if err := survey.AskOne(&survey.Select{Message: "Add a server to " + *configPath + ":", Options: options, Default: none}, &choice); err != nil {
+		return err
+	}
+	if choice == none {
+		return nil
+	}
+	server := installable[indexOf(options, choice)-1]
+
+	name := server.Key()
+	if err := survey.AskOne(&survey.Input{Message: "Server name in the config:", Default: name}, &name); err != nil {
+		return err
+	}
+	config, _ := server.Config()
+	if config.Args == nil {
+		config.Args = []string{}
+	}
+	if err := addServerToConfig(*configPath, name, config); err != nil {
+		return err
+	}
+	fmt.Printf("\n  %s✅ Added %s to %s%s\n", ColorGreen, name, *configPath, ColorReset)
+	fmt.Printf("  💡 Set the environment variables listed above before running the agent, or add them to \"env\"\n")
+	return nil
+}
+
+// addServerToConfig 将服务器加入配置文件的 mcpServers，文件不存在时创建，其余内容保持不变。
+// 同名服务器已存在时需要用户确认覆盖
+func addServerToConfig(path, name string, server mcp.MCPServer) error {
+	config := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("invalid %s: %w", path, err)
+		}
+	}
+
+	servers := make(map[string]json.RawMessage)
+	if raw, ok := config["mcpServers"]; ok {
+		if err := json.Unmarshal(raw, &servers); err != nil {
+			return fmt.Errorf("invalid mcpServers in %s: %w", path, err)
+		}
+	}
+	if _, exists := servers[name]; exists {
+		overwrite := false
+		if err := survey.AskOne(&survey.Confirm{Message: fmt.Sprintf("%s already has a server %s, replace it?", path, name)}, &overwrite); err != nil {
+			return err
+		}
+		if !overwrite {
+			return nil
+		}
+	}
+
+	if servers[name], err = json.Marshal(server); err != nil {
+		return err
+	}
+	if config["mcpServers"], err = json.Marshal(servers); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// version 在编译时通过 -ldflags "-X main.version=..." 注入
+var version = "1.0.0"
+
 const (
 	DEFAULT_PORT    = "9621"
 	DEFAULT_TIMEOUT = 30 * time.Second
@@ -19,12 +27,14 @@ func main() {
 	if port == "" {
 		port = DEFAULT_PORT
 	}
+	// MCP_HOST 为监听地址，默认监听所有网卡
+	host := os.Getenv("MCP_HOST")
 
 	// 创建 SSE Handler
 	sseHandler := mcp.NewSSEHandler(func(request *http.Request) *mcp.Server {
 		server := mcp.NewServer(&mcp.Implementation{
 			Name:    "web-browser",
-			Version: "1.0.0",
+			Version: version,
 		}, nil)
 
 		// 注册工具
@@ -34,12 +44,29 @@ func main() {
 	}, nil)
 
 	// 启动 HTTP 服务器
-	addr := ":" + port
+	addr := net.JoinHostPort(host, port)
+	displayHost := host
+	if displayHost == "" || displayHost == "0.0.0.0" {
+		displayHost = "localhost"
+	}
 	log.Printf("🌐 Web Browser MCP Server 启动中...")
-	log.Printf("📡 SSE 端点: http://localhost%s/", addr)
+	log.Printf("📡 SSE 端点: http://%s/", net.JoinHostPort(displayHost, port))
 	log.Printf("📨 使用官方 go-sdk 的 SSE Transport")
 
-	if err := http.ListenAndServe(addr, sseHandler); err != nil {
+	httpServer := &http.Server{Addr: addr, Handler: sseHandler}
+
+	// 收到 SIGINT/SIGTERM 时优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		log.Printf("🛑 正在关闭服务器...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("服务器启动失败: %v", err)
 	}
 }
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
@@ -75,7 +74,7 @@ func registerTools(server *mcp.Server) {
 	mcp.AddTool(server,
 		&mcp.Tool{
 			Name:        "screenshot",
-			Description: "对网页进行截图，返回 base64 编码的 PNG 图片。",
+			Description: "对网页进行截图，返回 PNG 图片。",
 		},
 		handleScreenshot,
 	)
@@ -155,6 +154,7 @@ func handleScreenshot(ctx context.Context, req *mcp.CallToolRequest, args Screen
 	}
 
 	log.Printf("[screenshot] 开始截图: %s, fullpage: %v", args.URL, args.FullPage)
+	notifyProgress(ctx, req, 0, 3, "正在打开页面")
 
 	timeout := getTimeout(args.Timeout)
 	imgData, err := takeScreenshot(args.URL, args.FullPage, timeout)
@@ -164,11 +164,16 @@ func handleScreenshot(ctx context.Context, req *mcp.CallToolRequest, args Screen
 	}
 
 	log.Printf("[screenshot] 成功，图片大小: %d bytes", len(imgData))
+	notifyProgress(ctx, req, 2, 3, "截图完成，正在编码")
 
-	// 返回 base64 编码的图片（作为文本返回，方便 LLM 处理）
-	base64Img := base64.StdEncoding.EncodeToString(imgData)
-	result := fmt.Sprintf("截图成功！\n\nBase64 编码的 PNG 图片 (data:image/png;base64,...):\n\n%s", base64Img)
-	return textResult(result), nil, nil
+	// 以图片内容返回，由客户端决定是否交给支持图片输入的模型
+	notifyProgress(ctx, req, 3, 3, "完成")
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("截图成功！PNG 图片，%d bytes", len(imgData))},
+			&mcp.ImageContent{MIMEType: "image/png", Data: imgData},
+		},
+	}, nil, nil
 }
 
 // ==================== 浏览器操作函数 ====================
@@ -207,7 +212,8 @@ func createBrowserContext(timeout time.Duration) (context.Context, context.Cance
 	// 检查是否设置了代理
 	if proxy := os.Getenv("HTTP_PROXY"); proxy != "" {
 		log.Printf("[browser] 使用代理: %s", proxy)
-		opts = append(opts, chromedp.ProxyServer(proxy))
+		// Chrome 默认不代理本机地址，"<-loopback>" 取消这一例外，让访问策略代理同样检查本机地址
+		opts = append(opts, chromedp.ProxyServer(proxy), chromedp.Flag("proxy-bypass-list", "<-loopback>"))
 	}
 
 	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
@@ -316,6 +322,24 @@ func getTimeout(seconds int) time.Duration {
 	return DEFAULT_TIMEOUT
 }
 
+// notifyProgress 向客户端发送进度通知，客户端未提供 progressToken 时忽略
+func notifyProgress(ctx context.Context, req *mcp.CallToolRequest, progress, total float64, message string) {
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return
+	}
+
+	err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+	if err != nil {
+		log.Printf("[progress] 发送进度通知失败: %v", err)
+	}
+}
+
 // textResult 创建文本结果
 func textResult(text string) *mcp.CallToolResult {
 	return &mcp.CallToolResult{
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io/fs"
@@ -22,6 +21,9 @@ const (
 	MAX_FILE_SIZE = 1024 * 1024
 )
 
+// version 在编译时通过 -ldflags "-X main.version=..." 注入
+var version = "1.0.0"
+
 var defaultIgnorePatterns = []string{
 	".git",
 	"node_modules",
@@ -37,8 +39,8 @@ func main() {
 	// 创建 MCP Server
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "code_search",
-		Version: "1.0.0",
-	}, nil)
+		Version: version,
+	}, &mcp.ServerOptions{RootsListChangedHandler: workspaceRoots.Invalidate})
 
 	// 注册工具
 	registerTools(server)
@@ -152,16 +154,17 @@ func handleGrepSearch(ctx context.Context, req *mcp.CallToolRequest, args GrepSe
 
 	// grep_search: 搜索模式, 路径, 文件类型
 
-	rootPath := args.Path
-	if rootPath == "" {
-		rootPath = DEFAULT_ROOT
+	rootPath, err := scopePath(ctx, req.Session, args.Path)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
 	}
 
 	// 尝试使用系统 ripgrep (rg) 命令，如果不存在则使用内置实现
+	var skipped []string
 	results, err := grepWithRipgrep(args, rootPath)
 	if err != nil {
 		// ripgrep 不可用，使用内置搜索
-		results, err = grepBuiltin(args, rootPath)
+		results, skipped, err = grepBuiltin(args, rootPath)
 		if err != nil {
 			// 搜索失败
 			return errorResult("搜索失败: " + err.Error()), nil, nil
@@ -171,15 +174,16 @@ func handleGrepSearch(ctx context.Context, req *mcp.CallToolRequest, args GrepSe
 	// 找到匹配结果
 
 	if len(results) == 0 {
-		return textResult("未找到匹配的结果"), nil, nil
+		return textResult("未找到匹配的结果" + skippedNotice(skipped)), nil, nil
 	}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("找到 %d 个匹配:\n\n", len(results)))
 	for _, r := range results {
 		sb.WriteString(fmt.Sprintf("📄 %s:%d\n", r.File, r.Line))
-		sb.WriteString(fmt.Sprintf("   %s\n\n", strings.TrimSpace(r.Content)))
+		sb.WriteString(fmt.Sprintf("   %s\n\n", displayLine(strings.TrimSpace(r.Content))))
 	}
+	sb.WriteString(skippedNotice(skipped))
 
 	return textResult(sb.String()), nil, nil
 }
@@ -192,9 +196,9 @@ func handleFindFiles(ctx context.Context, req *mcp.CallToolRequest, args FindFil
 
 	// find_files: 查找文件
 
-	rootPath := args.Path
-	if rootPath == "" {
-		rootPath = DEFAULT_ROOT
+	rootPath, err := scopePath(ctx, req.Session, args.Path)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
 	}
 
 	maxResults := args.MaxResults
@@ -294,6 +298,11 @@ func handleReadFile(ctx context.Context, req *mcp.CallToolRequest, args ReadFile
 	}
 
 	// read_file: 读取文件
+	path, err := scopePath(ctx, req.Session, args.Path)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+	args.Path = path
 
 	// 检查文件是否存在
 	info, err := os.Stat(args.Path)
@@ -308,8 +317,8 @@ func handleReadFile(ctx context.Context, req *mcp.CallToolRequest, args ReadFile
 		return errorResult("指定的路径是目录，不是文件"), nil, nil
 	}
 
-	// 检查文件大小
-	if info.Size() > MAX_FILE_SIZE {
+	// 不指定 limit 时检查文件大小，指定时只读取需要的行，不受大小限制
+	if args.Limit <= 0 && info.Size() > MAX_FILE_SIZE {
 		return errorResult(fmt.Sprintf("文件太大 (%s)，超过限制 (%s)。请使用 offset 和 limit 参数分段读取。",
 			formatSize(info.Size()), formatSize(MAX_FILE_SIZE))), nil, nil
 	}
@@ -321,7 +330,6 @@ func handleReadFile(ctx context.Context, req *mcp.CallToolRequest, args ReadFile
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
 	var lines []string
 	lineNum := 0
 	offset := args.Offset
@@ -329,25 +337,32 @@ func handleReadFile(ctx context.Context, req *mcp.CallToolRequest, args ReadFile
 		offset = 1
 	}
 
-	for scanner.Scan() {
-		lineNum++
-		if lineNum < offset {
-			continue
+	// 逐行读取，读够 limit 行后停止，不读取文件的其余部分
+	complete := true
+	err = forEachLine(file, func(num int, line string) bool {
+		lineNum = num
+		if num < offset {
+			return true
 		}
 		if args.Limit > 0 && len(lines) >= args.Limit {
-			break
+			complete = false
+			return false
 		}
-		lines = append(lines, scanner.Text())
-	}
-
-	if err := scanner.Err(); err != nil {
+		lines = append(lines, line)
+		return true
+	})
+	if err != nil {
 		return errorResult("读取文件失败: " + err.Error()), nil, nil
 	}
 
 	// 成功读取文件
 
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("📄 %s (第 %d-%d 行，共 %d 行)\n\n", args.Path, offset, offset+len(lines)-1, lineNum))
+	if complete {
+		sb.WriteString(fmt.Sprintf("📄 %s (第 %d-%d 行，共 %d 行)\n\n", args.Path, offset, offset+len(lines)-1, lineNum))
+	} else {
+		sb.WriteString(fmt.Sprintf("📄 %s (第 %d-%d 行，文件还有更多内容)\n\n", args.Path, offset, offset+len(lines)-1))
+	}
 	for i, line := range lines {
 		sb.WriteString(fmt.Sprintf("%4d | %s\n", offset+i, line))
 	}
@@ -362,6 +377,11 @@ func handleListDir(ctx context.Context, req *mcp.CallToolRequest, args ListDirAr
 	}
 
 	// list_dir: 列出目录
+	path, err := scopePath(ctx, req.Session, args.Path)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
+	}
+	args.Path = path
 
 	// 检查目录是否存在
 	info, err := os.Stat(args.Path)
@@ -484,17 +504,18 @@ func handleSearchSymbol(ctx context.Context, req *mcp.CallToolRequest, args Sear
 
 	// search_symbol: 搜索符号
 
-	rootPath := args.Path
-	if rootPath == "" {
-		rootPath = DEFAULT_ROOT
+	rootPath, err := scopePath(ctx, req.Session, args.Path)
+	if err != nil {
+		return errorResult(err.Error()), nil, nil
 	}
 
 	// 根据文件类型构建符号定义的正则表达式
 	patterns := buildSymbolPatterns(args.Symbol, args.FileType, args.Type)
 
 	var results []SearchResult
+	var skipped []string
 
-	err := filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
+	err = filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return nil
 		}
@@ -516,6 +537,10 @@ func handleSearchSymbol(ctx context.Context, req *mcp.CallToolRequest, args Sear
 		if !isCodeFile(path) {
 			return nil
 		}
+		if tooLarge(d) {
+			skipped = append(skipped, path)
+			return nil
+		}
 
 		// 在文件中搜索符号
 		fileResults, err := searchSymbolInFile(path, patterns)
@@ -539,15 +564,16 @@ func handleSearchSymbol(ctx context.Context, req *mcp.CallToolRequest, args Sear
 	// 找到符号定义
 
 	if len(results) == 0 {
-		return textResult("未找到符号定义: " + args.Symbol), nil, nil
+		return textResult("未找到符号定义: " + args.Symbol + skippedNotice(skipped)), nil, nil
 	}
 
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("找到 %d 个符号定义:\n\n", len(results)))
 	for _, r := range results {
 		sb.WriteString(fmt.Sprintf("📍 %s:%d [%s]\n", r.File, r.Line, r.Type))
-		sb.WriteString(fmt.Sprintf("   %s\n\n", strings.TrimSpace(r.Content)))
+		sb.WriteString(fmt.Sprintf("   %s\n\n", displayLine(strings.TrimSpace(r.Content))))
 	}
+	sb.WriteString(skippedNotice(skipped))
 
 	return textResult(sb.String()), nil, nil
 }
@@ -583,6 +609,9 @@ func grepWithRipgrep(args GrepSearchArgs, rootPath string) ([]SearchResult, erro
 		"--line-number",
 		"--no-heading",
 		"--color=never",
+		"--max-filesize", fmt.Sprintf("%d", maxSearchFileSize),
+		"--max-columns", fmt.Sprintf("%d", MAX_DISPLAY_LINE),
+		"--max-columns-preview",
 	}
 
 	if args.IgnoreCase {
@@ -647,8 +676,8 @@ func parseRipgrepOutput(output string) ([]SearchResult, error) {
 	return results, nil
 }
 
-// grepBuiltin 内置搜索实现
-func grepBuiltin(args GrepSearchArgs, rootPath string) ([]SearchResult, error) {
+// grepBuiltin 内置搜索实现，同时返回因为太大而跳过的文件
+func grepBuiltin(args GrepSearchArgs, rootPath string) ([]SearchResult, []string, error) {
 	pattern := args.Pattern
 	if args.IgnoreCase {
 		pattern = "(?i)" + pattern
@@ -656,7 +685,7 @@ func grepBuiltin(args GrepSearchArgs, rootPath string) ([]SearchResult, error) {
 
 	re, err := regexp.Compile(pattern)
 	if err != nil {
-		return nil, fmt.Errorf("无效的正则表达式: %v", err)
+		return nil, nil, fmt.Errorf("无效的正则表达式: %v", err)
 	}
 
 	maxResults := args.MaxResults
@@ -665,6 +694,7 @@ func grepBuiltin(args GrepSearchArgs, rootPath string) ([]SearchResult, error) {
 	}
 
 	var results []SearchResult
+	var skipped []string
 
 	err = filepath.WalkDir(rootPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -690,6 +720,10 @@ func grepBuiltin(args GrepSearchArgs, rootPath string) ([]SearchResult, error) {
 		if !isTextFile(path) {
 			return nil
 		}
+		if tooLarge(d) {
+			skipped = append(skipped, path)
+			return nil
+		}
 
 		// 在文件中搜索
 		fileResults, err := searchInFile(path, re, maxResults-len(results))
@@ -706,7 +740,7 @@ func grepBuiltin(args GrepSearchArgs, rootPath string) ([]SearchResult, error) {
 		return nil
 	})
 
-	return results, err
+	return results, skipped, err
 }
 
 // searchInFile 在文件中搜索
@@ -718,27 +752,24 @@ func searchInFile(path string, re *regexp.Regexp, maxResults int) ([]SearchResul
 	defer file.Close()
 
 	var results []SearchResult
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-
+	err = forEachLine(file, func(lineNum int, line string) bool {
 		if re.MatchString(line) {
 			results = append(results, SearchResult{
 				File:    path,
 				Line:    lineNum,
 				Content: line,
 			})
-
-			if len(results) >= maxResults {
-				break
-			}
 		}
-	}
+		return len(results) < maxResults
+	})
 
-	return results, scanner.Err()
+	return results, err
+}
+
+// tooLarge 判断文件是否超过搜索的大小限制
+func tooLarge(d fs.DirEntry) bool {
+	info, err := d.Info()
+	return err == nil && info.Size() > maxSearchFileSize
 }
 
 // searchSymbolInFile 在文件中搜索符号
@@ -750,13 +781,7 @@ func searchSymbolInFile(path string, patterns []*regexp.Regexp) ([]SearchResult,
 	defer file.Close()
 
 	var results []SearchResult
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-
+	err = forEachLine(file, func(lineNum int, line string) bool {
 		for _, re := range patterns {
 			if re.MatchString(line) {
 				symbolType := detectSymbolType(line, filepath.Ext(path))
@@ -769,9 +794,10 @@ func searchSymbolInFile(path string, patterns []*regexp.Regexp) ([]SearchResult,
 				break
 			}
 		}
-	}
+		return true
+	})
 
-	return results, scanner.Err()
+	return results, err
 }
 
 // buildSymbolPatterns 构建符号搜索的正则表达式
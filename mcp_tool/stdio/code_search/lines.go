@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DEFAULT_MAX_SEARCH_FILE_SIZE 是搜索时默认跳过的文件大小，可以用环境变量 CODE_SEARCH_MAX_FILE_SIZE（字节数）修改
+	DEFAULT_MAX_SEARCH_FILE_SIZE = 10 * 1024 * 1024
+	// MAX_LINE_LENGTH 是读取一行时保留的最大字节数，更长的行（如压缩过的 JS）只保留开头部分
+	MAX_LINE_LENGTH = 64 * 1024
+	// MAX_DISPLAY_LINE 是结果中每行显示的最大字符数
+	MAX_DISPLAY_LINE = 500
+)
+
+// maxSearchFileSize 是搜索的最大文件大小，更大的文件被跳过并在结果中提示
+var maxSearchFileSize = searchFileSizeLimit()
+
+func searchFileSizeLimit() int64 {
+	if value := os.Getenv("CODE_SEARCH_MAX_FILE_SIZE"); value != "" {
+		if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > 0 {
+			return size
+		}
+		fmt.Fprintf(os.Stderr, "invalid CODE_SEARCH_MAX_FILE_SIZE %q, using %d\n", value, DEFAULT_MAX_SEARCH_FILE_SIZE)
+	}
+	return DEFAULT_MAX_SEARCH_FILE_SIZE
+}
+
+// forEachLine 逐行读取 r，不把整个文件读入内存，也不受 bufio.Scanner 64KB 行长度的限制。
+// 超过 MAX_LINE_LENGTH 的行只保留开头部分，行号保持正确；fn 返回 false 时停止读取
+func forEachLine(r io.Reader, fn func(lineNum int, line string) bool) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var line []byte
+	lineNum := 0
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if room := MAX_LINE_LENGTH - len(line); room > 0 {
+			line = append(line, chunk[:min(len(chunk), room)]...)
+		}
+		if isPrefix {
+			continue
+		}
+		lineNum++
+		if !fn(lineNum, string(line)) {
+			return nil
+		}
+		line = line[:0]
+	}
+}
+
+// displayLine 截断过长的行，避免一行压缩代码占满结果
+func displayLine(line string) string {
+	runes := []rune(line)
+	if len(runes) <= MAX_DISPLAY_LINE {
+		return line
+	}
+	return string(runes[:MAX_DISPLAY_LINE]) + "...（已截断）"
+}
+
+// skippedNotice 提示因为太大而没有搜索的文件
+func skippedNotice(skipped []string) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+	const maxListed = 10
+	listed := skipped
+	if len(listed) > maxListed {
+		listed = listed[:maxListed]
+	}
+	notice := fmt.Sprintf("\n⚠️ 跳过了 %d 个大于 %s 的文件（可通过环境变量 CODE_SEARCH_MAX_FILE_SIZE 调整）:\n   %s\n",
+		len(skipped), formatSize(maxSearchFileSize), strings.Join(listed, "\n   "))
+	if len(skipped) > maxListed {
+		notice += fmt.Sprintf("   ... 以及另外 %d 个文件\n", len(skipped)-maxListed)
+	}
+	return notice
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/workspace"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// workspaceRoots 缓存客户端通过 roots 能力声明的工作区目录，客户端发送 roots/list_changed 后重新获取
+var workspaceRoots workspace.Roots
+
+// scopePath 将路径限制在客户端的工作区内，见 scopeInRoots
+func scopePath(ctx context.Context, session *mcp.ServerSession, path string) (string, error) {
+	return scopeInRoots(workspaceRoots.Get(ctx, session), path)
+}
+
+// scopeInRoots 解析路径并限制在工作区内：为空时使用第一个工作区目录（没有工作区时为当前目录），
+// 相对路径相对于第一个工作区目录。路径中的符号链接会被解析，通过 ../ 或指向外部的符号链接离开工作区的路径会被拒绝
+func scopeInRoots(roots []string, path string) (string, error) {
+	if len(roots) == 0 {
+		if path == "" {
+			return DEFAULT_ROOT, nil
+		}
+		return path, nil
+	}
+
+	if path == "" {
+		path = roots[0]
+	}
+	absPath, err := workspace.Resolve(roots, path)
+	if errors.Is(err, workspace.ErrOutside) {
+		return "", fmt.Errorf("%s 不在工作区 (%s) 内", path, strings.Join(roots, ", "))
+	}
+	return absPath, err
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeInRoots(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	root := filepath.Join(base, "workspace")
+	outside := filepath.Join(base, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0o755))
+	require.NoError(t, os.MkdirAll(outside, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o644))
+	roots := []string{root}
+
+	path, err := scopeInRoots(roots, "")
+	require.NoError(t, err)
+	assert.Equal(t, root, path)
+	path, err = scopeInRoots(roots, "src")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "src"), path)
+
+	_, err = scopeInRoots(roots, "../outside/secret")
+	assert.ErrorContains(t, err, "不在工作区")
+
+	// 指向工作区外的符号链接不能用来读取或搜索外部的文件
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	for _, path := range []string{"link", "link/secret", filepath.Join(root, "link", "secret")} {
+		_, err := scopeInRoots(roots, path)
+		assert.ErrorContains(t, err, "不在工作区", path)
+	}
+}
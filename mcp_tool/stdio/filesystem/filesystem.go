@@ -7,15 +7,20 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/filelock"
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/textenc"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+// version 在编译时通过 -ldflags "-X main.version=..." 注入
+var version = "1.0.0"
+
 func main() {
 	// 创建 MCP Server
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "filesystem",
-		Version: "1.0.0",
-	}, nil)
+		Version: version,
+	}, &mcp.ServerOptions{RootsListChangedHandler: workspaceRoots.Invalidate})
 
 	// 注册工具
 	registerTools(server)
@@ -122,7 +127,7 @@ func registerTools(server *mcp.Server) {
 // handleReadFile 处理读取文件请求
 func handleReadFile(ctx context.Context, req *mcp.CallToolRequest, args ReadFileArgs) (*mcp.CallToolResult, any, error) {
 	// 解析路径
-	absPath, err := resolvePath(args.Path)
+	absPath, err := scopePath(ctx, req.Session, args.Path)
 	if err != nil {
 		return errorResult(fmt.Sprintf("无法解析路径: %v", err)), nil, nil
 	}
@@ -141,8 +146,8 @@ func handleReadFile(ctx context.Context, req *mcp.CallToolRequest, args ReadFile
 		return errorResult(fmt.Sprintf("%s 是一个目录，不是文件", absPath)), nil, nil
 	}
 
-	// 读取文件内容
-	content, err := os.ReadFile(absPath)
+	// 读取文件内容，GBK、Latin-1 等编码的文件转换为 UTF-8
+	content, _, err := textenc.ReadFile(absPath)
 	if err != nil {
 		return errorResult(fmt.Sprintf("读取文件失败: %v", err)), nil, nil
 	}
@@ -150,7 +155,7 @@ func handleReadFile(ctx context.Context, req *mcp.CallToolRequest, args ReadFile
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			&mcp.TextContent{
-				Text: string(content),
+				Text: content,
 			},
 		},
 	}, nil, nil
@@ -159,7 +164,7 @@ func handleReadFile(ctx context.Context, req *mcp.CallToolRequest, args ReadFile
 // handleListDirectory 处理列出目录请求
 func handleListDirectory(ctx context.Context, req *mcp.CallToolRequest, args ListDirectoryArgs) (*mcp.CallToolResult, any, error) {
 	// 解析路径
-	absPath, err := resolvePath(args.Path)
+	absPath, err := scopePath(ctx, req.Session, args.Path)
 	if err != nil {
 		return errorResult(fmt.Sprintf("无法解析路径: %v", err)), nil, nil
 	}
@@ -186,6 +191,7 @@ func handleListDirectory(ctx context.Context, req *mcp.CallToolRequest, args Lis
 				return nil // 跳过无法访问的文件
 			}
 			relPath, _ := filepath.Rel(absPath, path)
+			relPath = filepath.ToSlash(relPath)
 			if relPath == "." {
 				return nil
 			}
@@ -228,7 +234,7 @@ func handleListDirectory(ctx context.Context, req *mcp.CallToolRequest, args Lis
 // handleGetFileInfo 处理获取文件信息请求
 func handleGetFileInfo(ctx context.Context, req *mcp.CallToolRequest, args GetFileInfoArgs) (*mcp.CallToolResult, any, error) {
 	// 解析路径
-	absPath, err := resolvePath(args.Path)
+	absPath, err := scopePath(ctx, req.Session, args.Path)
 	if err != nil {
 		return errorResult(fmt.Sprintf("无法解析路径: %v", err)), nil, nil
 	}
@@ -267,7 +273,7 @@ func handleGetFileInfo(ctx context.Context, req *mcp.CallToolRequest, args GetFi
 // handleSearchFiles 处理搜索文件请求
 func handleSearchFiles(ctx context.Context, req *mcp.CallToolRequest, args SearchFilesArgs) (*mcp.CallToolResult, any, error) {
 	// 解析路径
-	absPath, err := resolvePath(args.Path)
+	absPath, err := scopePath(ctx, req.Session, args.Path)
 	if err != nil {
 		return errorResult(fmt.Sprintf("无法解析路径: %v", err)), nil, nil
 	}
@@ -332,7 +338,7 @@ func handleSearchFiles(ctx context.Context, req *mcp.CallToolRequest, args Searc
 // handleWriteFile 处理写入文件请求
 func handleWriteFile(ctx context.Context, req *mcp.CallToolRequest, args WriteFileArgs) (*mcp.CallToolResult, any, error) {
 	// 解析路径
-	absPath, err := resolvePath(args.Path)
+	absPath, err := scopePath(ctx, req.Session, args.Path)
 	if err != nil {
 		return errorResult(fmt.Sprintf("无法解析路径: %v", err)), nil, nil
 	}
@@ -343,8 +349,14 @@ func handleWriteFile(ctx context.Context, req *mcp.CallToolRequest, args WriteFi
 		return errorResult(fmt.Sprintf("无法创建目录: %v", err)), nil, nil
 	}
 
-	// 写入文件
-	if err := os.WriteFile(absPath, []byte(args.Content), 0644); err != nil {
+	// 覆盖已有文件时保持原来的编码和换行符
+	data, err := textenc.Match(absPath, args.Content)
+	if err != nil {
+		return errorResult(fmt.Sprintf("写入文件失败: %v", err)), nil, nil
+	}
+
+	// 写入文件，同时写同一文件的其他调用需要等待
+	if err := filelock.WriteFile(absPath, data, 0644); err != nil {
 		return errorResult(fmt.Sprintf("写入文件失败: %v", err)), nil, nil
 	}
 
@@ -360,17 +372,28 @@ func handleWriteFile(ctx context.Context, req *mcp.CallToolRequest, args WriteFi
 // handleEditFile 处理编辑文件请求
 func handleEditFile(ctx context.Context, req *mcp.CallToolRequest, args EditFileArgs) (*mcp.CallToolResult, any, error) {
 	// 解析路径
-	absPath, err := resolvePath(args.Path)
+	absPath, err := scopePath(ctx, req.Session, args.Path)
 	if err != nil {
 		return errorResult(fmt.Sprintf("无法解析路径: %v", err)), nil, nil
 	}
+	// 持有文件锁直到编辑完成，防止并发的编辑交错写入
+	unlock, err := filelock.Lock(absPath)
+	if err != nil {
+		return errorResult(fmt.Sprintf("无法锁定文件: %v", err)), nil, nil
+	}
+	defer unlock()
+
 	// 检查文件是否存在
 	if _, err := os.Stat(absPath); os.IsNotExist(err) {
 		return errorResult(fmt.Sprintf("文件不存在: %s", absPath)), nil, nil
 	}
 
-	// 编辑文件
-	if err := os.WriteFile(absPath, []byte(args.Content), 0644); err != nil {
+	// 编辑文件，保持原来的编码和换行符
+	data, err := textenc.Match(absPath, args.Content)
+	if err != nil {
+		return errorResult(fmt.Sprintf("编辑文件失败: %v", err)), nil, nil
+	}
+	if err := os.WriteFile(absPath, data, 0644); err != nil {
 		return errorResult(fmt.Sprintf("编辑文件失败: %v", err)), nil, nil
 	}
 
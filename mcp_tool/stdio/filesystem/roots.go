@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/workspace"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// workspaceRoots 缓存客户端通过 roots 能力声明的工作区目录，客户端发送 roots/list_changed 后重新获取
+var workspaceRoots workspace.Roots
+
+// scopePath 解析路径并限制在工作区内，相对路径相对于第一个工作区目录。
+// 路径中的符号链接会被解析，通过 ../ 或指向外部的符号链接离开工作区的路径会被拒绝
+func scopePath(ctx context.Context, session *mcp.ServerSession, path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		var err error
+		if path, err = resolvePath(path); err != nil {
+			return "", err
+		}
+	}
+
+	roots := workspaceRoots.Get(ctx, session)
+	absPath, err := workspace.Resolve(roots, path)
+	if errors.Is(err, workspace.ErrOutside) {
+		return "", fmt.Errorf("%s 不在工作区 (%s) 内", path, strings.Join(roots, ", "))
+	}
+	return absPath, err
+}
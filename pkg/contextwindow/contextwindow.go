@@ -0,0 +1,154 @@
+// Package contextwindow keeps conversations within the context window of a
+// model. Ollama silently drops the start of prompts that do not fit, which
+// loses the system prompt first, so conversations are trimmed before they are
+// sent: old tool results are shortened and the oldest turns are left out.
+package contextwindow
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultSize is the context window Ollama uses when num_ctx is not set.
+const DefaultSize = 4096
+
+// messageOverhead approximates the tokens of the chat template around a message.
+const messageOverhead = 4
+
+// truncatedResultLen is the number of characters kept of old tool results.
+const truncatedResultLen = 200
+
+// EstimateText approximates the number of tokens of text: about four ASCII
+// characters per token, and one token per character of other scripts such as
+// Chinese, which tokenizers split much finer.
+func EstimateText(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// EstimateMessage approximates the number of tokens of a message.
+func EstimateMessage(message api.Message) int {
+	tokens := messageOverhead + EstimateText(message.Content) + EstimateText(message.Thinking)
+	for _, call := range message.ToolCalls {
+		args, _ := json.Marshal(call.Function.Arguments)
+		tokens += EstimateText(call.Function.Name) + EstimateText(string(args))
+	}
+	// Images are encoded into a fixed number of tokens by most vision models.
+	tokens += 768 * len(message.Images)
+	return tokens
+}
+
+// Estimate approximates the number of tokens of a request with the messages and tools.
+func Estimate(messages []api.Message, tools []api.Tool) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += EstimateMessage(message)
+	}
+	if len(tools) > 0 {
+		data, _ := json.Marshal(tools)
+		tokens += EstimateText(string(data))
+	}
+	return tokens
+}
+
+// Report describes how Fit changed a conversation.
+type Report struct {
+	Tokens           int // estimated tokens before fitting
+	Fitted           int // estimated tokens after fitting
+	TruncatedResults int // old tool results that were shortened
+	OmittedMessages  int // oldest messages that were left out
+}
+
+// Changed reports whether the conversation was trimmed.
+func (r Report) Changed() bool {
+	return r.TruncatedResults > 0 || r.OmittedMessages > 0
+}
+
+// Fit returns messages trimmed so that they and the tools take at most budget
+// tokens. The leading system messages and the last turn, i.e. everything from
+// the last user message on including its tool results, are always kept. To make
+// room, tool results of earlier turns are shortened first, then the oldest turns
+// are omitted and replaced by a note. The messages are not modified.
+func Fit(messages []api.Message, tools []api.Tool, budget int) ([]api.Message, Report) {
+	report := Report{Tokens: Estimate(messages, tools)}
+	report.Fitted = report.Tokens
+	if report.Tokens <= budget {
+		return messages, report
+	}
+
+	system := 0
+	for system < len(messages) && messages[system].Role == "system" {
+		system++
+	}
+	last := len(messages)
+	for i := len(messages) - 1; i >= system; i-- {
+		if messages[i].Role == "user" {
+			last = i
+			break
+		}
+	}
+
+	fitted := append([]api.Message(nil), messages...)
+	tokens := report.Tokens
+
+	// Shorten the tool results of earlier turns, oldest first.
+	for i := system; i < last && tokens > budget; i++ {
+		message := fitted[i]
+		if message.Role != "tool" || utf8.RuneCountInString(message.Content) <= truncatedResultLen {
+			continue
+		}
+		before := EstimateMessage(message)
+		runes := []rune(message.Content)
+		message.Content = fmt.Sprintf("%s\n[tool result truncated, %d of %d characters omitted]",
+			string(runes[:truncatedResultLen]), len(runes)-truncatedResultLen, len(runes))
+		fitted[i] = message
+		tokens += EstimateMessage(message) - before
+		report.TruncatedResults++
+	}
+
+	// Omit the oldest turns, making room for the note that replaces them. Whole
+	// turns are omitted so that no tool result is left without the assistant
+	// message that called the tool.
+	start := system
+	noteTokens := EstimateMessage(omittedNote(len(messages)))
+	for start < last && tokens+noteTokens > budget {
+		end := start + 1
+		for end < last && fitted[end].Role != "user" {
+			end++
+		}
+		for _, message := range fitted[start:end] {
+			tokens -= EstimateMessage(message)
+		}
+		report.OmittedMessages += end - start
+		start = end
+	}
+	if report.OmittedMessages > 0 {
+		note := omittedNote(report.OmittedMessages)
+		tokens += EstimateMessage(note)
+		result := make([]api.Message, 0, system+1+len(fitted)-start)
+		result = append(result, fitted[:system]...)
+		result = append(result, note)
+		fitted = append(result, fitted[start:]...)
+	}
+
+	report.Fitted = tokens
+	return fitted, report
+}
+
+// omittedNote tells the model that earlier messages are missing.
+func omittedNote(omitted int) api.Message {
+	return api.Message{
+		Role:    "system",
+		Content: fmt.Sprintf("[%d earlier messages of this conversation were omitted to fit the context window]", omitted),
+	}
+}
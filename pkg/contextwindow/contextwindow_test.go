@@ -0,0 +1,75 @@
+package contextwindow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateText(t *testing.T) {
+	assert.Equal(t, 0, EstimateText(""))
+	assert.Equal(t, 3, EstimateText("hello world!"))
+	assert.Equal(t, 4, EstimateText("你好世界"))
+}
+
+// conversation returns a system prompt followed by turns with a large tool result each.
+func conversation(turns int) []api.Message {
+	messages := []api.Message{{Role: "system", Content: "You are a coding agent."}}
+	for i := range turns {
+		messages = append(messages,
+			api.Message{Role: "user", Content: "read the file"},
+			api.Message{Role: "assistant", ToolCalls: []api.ToolCall{{Function: api.ToolCallFunction{Name: "read_file"}}}},
+			api.Message{Role: "tool", Content: strings.Repeat("x", 4000), ToolName: "read_file"},
+			api.Message{Role: "assistant", Content: "done " + string(rune('a'+i))},
+		)
+	}
+	return messages
+}
+
+func TestFit_WithinBudget(t *testing.T) {
+	messages := conversation(2)
+	fitted, report := Fit(messages, nil, 10000)
+	assert.Equal(t, messages, fitted)
+	assert.False(t, report.Changed())
+}
+
+func TestFit_TruncatesOldToolResults(t *testing.T) {
+	messages := conversation(3)
+	fitted, report := Fit(messages, nil, 1500)
+
+	assert.Equal(t, 2, report.TruncatedResults)
+	assert.Zero(t, report.OmittedMessages)
+	assert.LessOrEqual(t, report.Fitted, 1500)
+	require.Len(t, fitted, len(messages))
+	assert.Contains(t, fitted[3].Content, "tool result truncated")
+	// The tool result of the last turn and the original messages are unchanged.
+	assert.Equal(t, messages[11], fitted[11])
+	assert.Len(t, messages[3].Content, 4000)
+}
+
+func TestFit_OmitsOldestTurns(t *testing.T) {
+	messages := conversation(4)
+	fitted, report := Fit(messages, nil, 1200)
+
+	assert.LessOrEqual(t, report.Fitted, 1200)
+	assert.Equal(t, 8, report.OmittedMessages)
+	// System prompt, note, then whole turns starting with a user message.
+	assert.Equal(t, messages[0], fitted[0])
+	assert.Equal(t, "system", fitted[1].Role)
+	assert.Contains(t, fitted[1].Content, "8 earlier messages")
+	assert.Equal(t, "user", fitted[2].Role)
+	assert.Equal(t, messages[len(messages)-1], fitted[len(fitted)-1])
+	assert.Equal(t, Estimate(fitted, nil), report.Fitted)
+}
+
+func TestFit_KeepsLastTurn(t *testing.T) {
+	messages := conversation(2)
+	fitted, report := Fit(messages, nil, 10)
+
+	// The last turn is kept even if it alone exceeds the budget.
+	assert.Equal(t, messages[5:], fitted[2:])
+	assert.Equal(t, 4, report.OmittedMessages)
+}
@@ -0,0 +1,97 @@
+// Package filelock serializes writes to the same file, within a process and
+// across processes, so that concurrent edits cannot interleave.
+//
+// Locks are advisory: they only exclude other writers that use this package.
+// The lock of a file is held on a separate lock file in the temporary
+// directory, so locking never creates or modifies the file itself.
+package filelock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// lockDirName is the directory under os.TempDir that holds the lock files.
+const lockDirName = "coding-agent-locks"
+
+var (
+	mu    sync.Mutex
+	locks = make(map[string]*fileLock) // by absolute path
+)
+
+// fileLock is the in-process lock of a file. refs counts the goroutines
+// holding or waiting for it, so that unused entries can be dropped.
+type fileLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock acquires the exclusive lock of path and returns the function that
+// releases it. Hold the lock across a read-modify-write to make it atomic.
+func Lock(path string) (unlock func(), err error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	mu.Lock()
+	l, ok := locks[abs]
+	if !ok {
+		l = &fileLock{}
+		locks[abs] = l
+	}
+	l.refs++
+	mu.Unlock()
+
+	l.Lock()
+	release := func() {
+		l.Unlock()
+		mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(locks, abs)
+		}
+		mu.Unlock()
+	}
+
+	f, err := openLockFile(abs)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		release()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+		release()
+	}, nil
+}
+
+// WriteFile writes data to path like os.WriteFile, holding the lock of path.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	unlock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return os.WriteFile(path, data, perm)
+}
+
+// openLockFile opens the lock file of the absolute path abs.
+func openLockFile(abs string) (*os.File, error) {
+	dir := filepath.Join(os.TempDir(), lockDirName)
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return os.OpenFile(filepath.Join(dir, hex.EncodeToString(sum[:16])+".lock"), os.O_RDWR|os.O_CREATE, 0o666)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package filelock
+
+import "os"
+
+// Without flock only writers in the same process are excluded.
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) {}
@@ -0,0 +1,56 @@
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock_SerializesReadModifyWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+	require.NoError(t, os.WriteFile(path, []byte("0"), 0o644))
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := Lock(path)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer unlock()
+			data, _ := os.ReadFile(path)
+			n, err := strconv.Atoi(string(data))
+			assert.NoError(t, err)
+			assert.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(n+1)), 0o644))
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "50", string(data))
+
+	mu.Lock()
+	assert.Empty(t, locks, "released locks are dropped")
+	mu.Unlock()
+}
+
+func TestLock_DoesNotCreateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.txt")
+	unlock, err := Lock(path)
+	require.NoError(t, err)
+	unlock()
+	assert.NoFileExists(t, path)
+
+	require.NoError(t, WriteFile(path, []byte("hello"), 0o644))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
@@ -0,0 +1,26 @@
+package mcp
+
+import "path"
+
+// AutoApproved reports whether the tool with the exposed name may be called
+// without asking the user, because it matches the AutoApprove list of its
+// server. Patterns use path.Match syntax, so "*" approves every tool of the
+// server and "read_*" all tools starting with "read_".
+func (c *Client) AutoApproved(name string) bool {
+	server, tool, err := c.ResolveToolName(name)
+	if err != nil {
+		return false
+	}
+	return c.serverConfig(server).autoApproves(tool)
+}
+
+// autoApproves reports whether the tool, by its name on the server, matches the
+// AutoApprove list.
+func (s MCPServer) autoApproves(tool string) bool {
+	for _, pattern := range s.AutoApprove {
+		if ok, _ := path.Match(pattern, tool); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoApproved(t *testing.T) {
+	c := newTestClient()
+	c.servers["filesystem"] = MCPServer{AutoApprove: []string{"read_*", "list_directory"}}
+	c.servers["git"] = MCPServer{AutoApprove: []string{"*"}}
+	c.servers["bash"] = MCPServer{}
+	c.registerAliases(map[string]MCPServer{"filesystem": {Aliases: map[string]string{"read_file": "read"}}})
+
+	assert.True(t, c.AutoApproved("filesystem__read_text"))
+	assert.True(t, c.AutoApproved("read"))
+	assert.True(t, c.AutoApproved("filesystem__list_directory"))
+	assert.False(t, c.AutoApproved("filesystem__write_file"))
+	assert.True(t, c.AutoApproved("git__commit"))
+	assert.False(t, c.AutoApproved("bash__run"))
+	assert.False(t, c.AutoApproved("unknown__tool"))
+	assert.False(t, c.AutoApproved("not_qualified"))
+}
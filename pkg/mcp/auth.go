@@ -0,0 +1,236 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenTTL is how long a token printed by a token command is reused when
+// the command does not report an expiry.
+const DefaultTokenTTL = 5 * time.Minute
+
+// tokenRefreshMargin refreshes tokens slightly before they expire.
+const tokenRefreshMargin = 30 * time.Second
+
+// AuthConfig configures bearer token authentication for SSE servers. Tokens are
+// obtained either from an OAuth2 token endpoint (client credentials, or a refresh
+// token when RefreshToken is set) or from a command, cached until they expire and
+// refreshed when the server answers 401 Unauthorized. Secret values may reference
+// environment variables, e.g. "${MY_CLIENT_SECRET}".
+type AuthConfig struct {
+	TokenURL     string   `json:"tokenUrl,omitempty"`
+	ClientID     string   `json:"clientId,omitempty"`
+	ClientSecret string   `json:"clientSecret,omitempty"`
+	RefreshToken string   `json:"refreshToken,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+
+	// TokenCommand prints an access token, either as plain text or as a JSON token
+	// response with "access_token" and "expires_in", e.g. ["gcloud", "auth", "print-access-token"].
+	TokenCommand []string `json:"tokenCommand,omitempty"`
+	// TokenTTL is how long a plain text token from TokenCommand is reused. Defaults to DefaultTokenTTL.
+	TokenTTL Duration `json:"tokenTtl,omitempty"`
+}
+
+func (a *AuthConfig) validate() error {
+	switch {
+	case a.TokenURL == "" && len(a.TokenCommand) == 0:
+		return errors.New("auth needs a tokenUrl or a tokenCommand")
+	case a.TokenURL != "" && len(a.TokenCommand) > 0:
+		return errors.New("auth cannot have both a tokenUrl and a tokenCommand")
+	}
+	return nil
+}
+
+// tokenResponse is an OAuth2 token response, also accepted from token commands.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokenSource fetches and caches the bearer token of a server.
+type tokenSource struct {
+	config AuthConfig
+	client *http.Client // used for the token endpoint
+
+	mu           sync.Mutex
+	token        string
+	expiry       time.Time
+	refreshToken string
+}
+
+func newTokenSource(config AuthConfig, client *http.Client) *tokenSource {
+	return &tokenSource{config: config, client: client, refreshToken: os.ExpandEnv(config.RefreshToken)}
+}
+
+// Token returns a valid token, fetching a new one if the cached one is about to expire.
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Add(tokenRefreshMargin).Before(s.expiry) {
+		return s.token, nil
+	}
+
+	var resp *tokenResponse
+	var err error
+	if len(s.config.TokenCommand) > 0 {
+		resp, err = s.runCommand(ctx)
+	} else {
+		resp, err = s.fetch(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	if resp.AccessToken == "" {
+		return "", errors.New("token response has no access_token")
+	}
+
+	s.token = resp.AccessToken
+	ttl := time.Duration(resp.ExpiresIn) * time.Second
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+		if s.config.TokenTTL > 0 {
+			ttl = time.Duration(s.config.TokenTTL)
+		}
+	}
+	s.expiry = time.Now().Add(ttl)
+	if resp.RefreshToken != "" {
+		s.refreshToken = resp.RefreshToken
+	}
+	return s.token, nil
+}
+
+// Invalidate drops the cached token, e.g. after the server rejected it.
+func (s *tokenSource) Invalidate(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+// fetch requests a token from the OAuth2 token endpoint.
+func (s *tokenSource) fetch(ctx context.Context) (*tokenResponse, error) {
+	form := url.Values{}
+	if s.refreshToken != "" {
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", s.refreshToken)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+	if len(s.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if s.config.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(s.config.ClientID), url.QueryEscape(os.ExpandEnv(s.config.ClientSecret)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	return &token, nil
+}
+
+// runCommand runs the token command and parses its output.
+func (s *tokenSource) runCommand(ctx context.Context) (*tokenResponse, error) {
+	cmd := exec.CommandContext(ctx, s.config.TokenCommand[0], s.config.TokenCommand[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("token command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	output := strings.TrimSpace(string(out))
+	var token tokenResponse
+	if strings.HasPrefix(output, "{") {
+		if err := json.Unmarshal([]byte(output), &token); err != nil {
+			return nil, fmt.Errorf("invalid token command output: %w", err)
+		}
+		return &token, nil
+	}
+	token.AccessToken = output
+	return &token, nil
+}
+
+// authTransport adds a bearer token to every request and retries once with a
+// fresh token when the server answers 401 Unauthorized.
+type authTransport struct {
+	Transport http.RoundTripper
+	Source    *tokenSource
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Source.Token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	resp, err := t.Transport.RoundTrip(withBearer(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// The body has been consumed; only retry requests that can be replayed.
+	retry := req
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry = req.Clone(req.Context())
+		retry.Body = body
+	}
+
+	t.Source.Invalidate(token)
+	token, err = t.Source.Token(req.Context())
+	if err != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	return t.Transport.RoundTrip(withBearer(retry, token))
+}
+
+// withBearer returns a copy of req with the Authorization header set, since
+// RoundTrippers must not modify the request they are given.
+func withBearer(req *http.Request, token string) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
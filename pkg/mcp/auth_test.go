@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthTransport_ClientCredentialsAndRetry(t *testing.T) {
+	var issued atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "read write", r.Form.Get("scope"))
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "agent", id)
+		assert.Equal(t, "s3cret", secret)
+
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"access_token":"token-`+string(rune('0'+n))+`","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	// The first token is revoked, so the transport must refresh it and replay the request.
+	var bodies []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		io.WriteString(w, "ok")
+	}))
+	defer api.Close()
+
+	t.Setenv("TEST_CLIENT_SECRET", "s3cret")
+	client, err := newHTTPClient(MCPServer{Type: "sse", Auth: &AuthConfig{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "agent",
+		ClientSecret: "${TEST_CLIENT_SECRET}",
+		Scopes:       []string{"read", "write"},
+	}})
+	require.NoError(t, err)
+
+	resp, err := client.Post(api.URL, "application/json", strings.NewReader(`{"id":1}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{`{"id":1}`}, bodies)
+
+	// The refreshed token is cached.
+	resp, err = client.Get(api.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), issued.Load())
+}
+
+func TestTokenSource_Command(t *testing.T) {
+	source := newTokenSource(AuthConfig{TokenCommand: []string{"echo", `{"access_token":"abc","expires_in":60}`}}, http.DefaultClient)
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "abc", token)
+
+	source = newTokenSource(AuthConfig{TokenCommand: []string{"echo", "plain-token"}}, http.DefaultClient)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "plain-token", token)
+}
+
+func TestAuthConfig_Validate(t *testing.T) {
+	_, err := newHTTPClient(MCPServer{Type: "sse", Auth: &AuthConfig{}})
+	assert.Error(t, err)
+
+	_, err = newHTTPClient(MCPServer{Type: "sse", Auth: &AuthConfig{TokenURL: "http://localhost", TokenCommand: []string{"echo"}}})
+	assert.Error(t, err)
+}
@@ -3,11 +3,15 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/ollama/ollama/api"
@@ -15,18 +19,129 @@ import (
 
 const ToolTypeFunction = "function"
 
+// SamplingHandler handles a sampling/createMessage request initiated by the named server.
+type SamplingHandler func(ctx context.Context, server string, params *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error)
+
+// ElicitationHandler handles an elicitation/create request initiated by the named
+// server, which asks the user for additional input while one of its tools is running.
+type ElicitationHandler func(ctx context.Context, server string, params *mcp.ElicitParams) (*mcp.ElicitResult, error)
+
+// ClientOptions configures optional client capabilities.
+type ClientOptions struct {
+	// SamplingHandler, if set, advertises the sampling capability to servers
+	// and routes their completion requests to the handler.
+	SamplingHandler SamplingHandler
+
+	// ElicitationHandler, if set, advertises the elicitation capability to servers
+	// and routes their requests for user input to the handler.
+	ElicitationHandler ElicitationHandler
+
+	// OnToolsChanged, if set, is called with the server name whenever a server
+	// sends a tools/list_changed notification. Call GetTools again to pick up the new tools.
+	OnToolsChanged func(server string)
+
+	// Trace, if set, receives a log of all JSON-RPC traffic. See also SetTrace.
+	Trace io.Writer
+
+	// ToolsCacheTTL bounds how long GetTools reuses a server's tool list.
+	// Zero uses DefaultToolsCacheTTL and a negative value disables caching.
+	ToolsCacheTTL time.Duration
+
+	// Roots are the workspace directories advertised to servers through the roots
+	// capability, so that they can scope their operations to the project.
+	Roots []string
+
+	// LogHandler, if set, receives the log notifications sent by servers.
+	LogHandler func(LogMessage)
+
+	// LogDir, if set, is where each server's log notifications are appended to <server>.log.
+	LogDir string
+
+	// LogLevel is the minimum level requested from servers that support logging
+	// when LogHandler or LogDir is set. Empty means DefaultLogLevel.
+	LogLevel string
+
+	// StderrDir, if set, is where the stderr output of each stdio and docker server
+	// is appended to <server>.log, instead of the agent's stderr. It may be the
+	// same directory as LogDir.
+	StderrDir string
+
+	// StderrTee, if set together with StderrDir, also receives the servers' stderr
+	// output, e.g. os.Stderr in verbose mode.
+	StderrTee io.Writer
+
+	// Retryable, if set, decides which tool call errors are retried, see
+	// MCPServer.CallRetries. Defaults to IsTransient.
+	Retryable func(err error) bool
+}
+
 // Client manages connections to multiple MCP servers.
 type Client struct {
+	opts ClientOptions
+
+	// mu guards the connection state below, which changes when crashed servers are restarted.
+	mu       sync.RWMutex
 	sessions map[string]*mcp.ClientSession
+	servers  map[string]MCPServer
+	dead     map[string]bool
+	restarts map[string]int
+	closed   bool
+
+	// separator joins server and tool names; toolNames and exposed map the names
+	// advertised by GetTools to tools and back.
+	separator string
+	toolNames map[string]toolRef
+	exposed   map[toolRef]string
+
+	// restartMu serializes server restarts.
+	restartMu sync.Mutex
+
+	progressMu sync.Mutex
+	progress   map[string]ProgressFunc
+
+	toolsMu    sync.Mutex
+	toolsCache map[string]cachedTools
+	// schemas holds the input schemas of the listed tools by server and tool name.
+	// Unlike toolsCache it survives InvalidateTools, so arguments can still be validated.
+	schemas map[string]map[string]map[string]any
+
+	healthMu sync.Mutex
+	health   map[string]callHealth
+
+	traceMu sync.RWMutex
+	trace   io.Writer
+
+	middlewareMu sync.RWMutex
+	middleware   []Middleware
+
+	metricsMu sync.Mutex
+	metrics   map[toolRef]*toolStats
+
+	// queues limits the concurrent tool calls of each server, see MCPServer.MaxConcurrency.
+	queueMu sync.Mutex
+	queues  map[string]chan struct{}
 }
 
 // NewClient creates a new MCP client and connects to the servers defined in the config.
-func NewClient(ctx context.Context, config *Config) (*Client, error) {
-	c := &Client{
-		sessions: make(map[string]*mcp.ClientSession),
+// The opts may be nil.
+func NewClient(ctx context.Context, config *Config, opts *ClientOptions) (*Client, error) {
+	c := newClient(opts)
+	if config.ToolNameSeparator != "" {
+		if sanitizeToolName(config.ToolNameSeparator) != config.ToolNameSeparator {
+			return nil, fmt.Errorf("invalid tool name separator %q: only letters, digits, '_' and '-' are allowed", config.ToolNameSeparator)
+		}
+		c.separator = config.ToolNameSeparator
 	}
+	c.registerAliases(config.MCPServers)
 
 	for name, server := range config.MCPServers {
+		c.servers[name] = server
+		if server.lazy() {
+			continue
+		}
+		if server.Lazy {
+			fmt.Fprintf(os.Stderr, "MCP server %s is lazy but has no tools manifest, connecting now\n", name)
+		}
 		if err := c.connectToServer(ctx, name, server); err != nil {
 			// Log error but continue connecting to other servers
 			fmt.Fprintf(os.Stderr, "Failed to connect to MCP server %s: %v\n", name, err)
@@ -36,52 +151,220 @@ func NewClient(ctx context.Context, config *Config) (*Client, error) {
 	return c, nil
 }
 
+func newClient(opts *ClientOptions) *Client {
+	c := &Client{
+		sessions:  make(map[string]*mcp.ClientSession),
+		servers:   make(map[string]MCPServer),
+		separator: DefaultToolNameSeparator,
+		toolNames: make(map[string]toolRef),
+		exposed:   make(map[toolRef]string),
+		dead:      make(map[string]bool),
+		restarts:  make(map[string]int),
+
+		toolsCache: make(map[string]cachedTools),
+	}
+	if opts != nil {
+		c.opts = *opts
+		c.trace = opts.Trace
+	}
+	return c
+}
+
+// connectToServer connects to the server, retrying according to its retry policy.
 func (c *Client) connectToServer(ctx context.Context, name string, server MCPServer) error {
-	var transport mcp.Transport
+	attempts := server.MaxRetries + 1
+	backoff := server.retryBackoff()
 
-	if server.Type == "sse" {
-		sseTransport := &mcp.SSEClientTransport{
-			Endpoint: server.URL,
-		}
-		if len(server.Headers) > 0 {
-			sseTransport.HTTPClient = &http.Client{
-				Transport: &headerTransport{
-					Transport: http.DefaultTransport,
-					Headers:   server.Headers,
-				},
-			}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = c.connectOnce(ctx, name, server); err == nil {
+			return nil
 		}
-		transport = sseTransport
-	} else {
-		// Default to stdio
-		cmd := exec.Command(server.Command, server.Args...)
-		cmd.Env = os.Environ()
-		for k, v := range server.Env {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+		if attempt == attempts {
+			break
 		}
 
-		// Capture stderr for debugging
-		cmd.Stderr = os.Stderr
-
-		transport = &mcp.CommandTransport{
-			Command: cmd,
+		fmt.Fprintf(os.Stderr, "Connecting to MCP server %s failed (attempt %d/%d): %v, retrying in %s\n", name, attempt, attempts, err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
+		backoff *= 2
 	}
 
+	if attempts > 1 {
+		return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+	}
+	return err
+}
+
+// newSDKClient creates the SDK client used to connect to the named server.
+func (c *Client) newSDKClient(name string) *mcp.Client {
 	mcpClient := mcp.NewClient(&mcp.Implementation{
 		Name:    "goskills",
 		Version: "0.1.0",
-	}, nil)
+	}, c.sessionOptions(name))
+	mcpClient.AddRoots(c.roots()...)
+	return mcpClient
+}
+
+// connectOnce makes a single connection attempt bounded by the server's connect timeout.
+func (c *Client) connectOnce(ctx context.Context, name string, server MCPServer) error {
+	ctx, cancel := context.WithTimeout(ctx, server.connectTimeout())
+	defer cancel()
 
-	session, err := mcpClient.Connect(ctx, transport, nil)
+	serverTransport, err := newTransport(server, c.stderrWriter(name))
+	if err != nil {
+		return err
+	}
+	// Always wrap the transport so that tracing can be toggled at runtime.
+	transport := &mcp.LoggingTransport{
+		Transport: &detachedTransport{serverTransport},
+		Writer:    &traceWriter{client: c, server: name},
+	}
+
+	session, err := c.newSDKClient(name).Connect(ctx, transport, nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to server: %w", err)
 	}
 
-	c.sessions[name] = session
+	c.setSession(name, session)
+	c.enableLogging(ctx, name, session)
 	return nil
 }
 
+// detachedTransport keeps the connection alive after the connect timeout. Transports
+// such as SSE tie the stream they open to the context passed to Connect, which
+// would otherwise drop the connection as soon as the handshake returns.
+type detachedTransport struct {
+	mcp.Transport
+}
+
+func (t *detachedTransport) Connect(ctx context.Context) (mcp.Connection, error) {
+	connCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, cancel)
+	conn, err := t.Transport.Connect(connCtx)
+	if !stop() {
+		// The connect timeout expired while connecting.
+		if err == nil {
+			conn.Close()
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		cancel()
+	}
+	return conn, err
+}
+
+// newTransport creates a fresh transport for the server. A new one is needed for
+// every connection attempt because a command can only be started once. The
+// stderr output of spawned servers goes to stderr.
+func newTransport(server MCPServer, stderr io.Writer) (mcp.Transport, error) {
+	if server.Type == "ws" {
+		return &wsTransport{server: server}, nil
+	}
+	if server.Type == "sse" {
+		httpClient, err := newHTTPClient(server)
+		if err != nil {
+			return nil, err
+		}
+		if httpClient == nil {
+			httpClient = &http.Client{}
+		}
+		httpClient.Transport = &statusTransport{Transport: httpClient.Transport}
+		return &mcp.SSEClientTransport{
+			Endpoint:   server.URL,
+			HTTPClient: httpClient,
+		}, nil
+	}
+
+	var cmd *exec.Cmd
+	if server.Type == "docker" {
+		// The server runs inside a container; its environment is passed with -e instead.
+		args, err := server.dockerArgs()
+		if err != nil {
+			return nil, err
+		}
+		cmd = exec.Command("docker", args...)
+	} else {
+		// Default to stdio
+		cmd = exec.Command(server.Command, server.Args...)
+		cmd.Env = server.environ()
+	}
+
+	cmd.Stderr = stderr
+
+	return &mcp.CommandTransport{
+		Command: cmd,
+	}, nil
+}
+
+// newHTTPClient builds the HTTP client of a remote server, adding the configured
+// headers and bearer token authentication. It returns nil to use the default client.
+func newHTTPClient(server MCPServer) (*http.Client, error) {
+	if len(server.Headers) == 0 && server.Auth == nil && !server.customTLS() {
+		return nil, nil
+	}
+
+	transport, err := server.baseTransport()
+	if err != nil {
+		return nil, err
+	}
+	// The token endpoint is usually on the same internal network, so it shares the TLS settings.
+	tokenClient := &http.Client{Transport: transport}
+
+	if len(server.Headers) > 0 {
+		transport = &headerTransport{
+			Transport: transport,
+			Headers:   server.Headers,
+		}
+	}
+	if server.Auth != nil {
+		if err := server.Auth.validate(); err != nil {
+			return nil, err
+		}
+		transport = &authTransport{
+			Transport: transport,
+			Source:    newTokenSource(*server.Auth, tokenClient),
+		}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// sessionOptions builds the SDK client options for the named server.
+func (c *Client) sessionOptions(name string) *mcp.ClientOptions {
+	opts := &mcp.ClientOptions{
+		ProgressNotificationHandler: c.handleProgress,
+	}
+	if c.opts.SamplingHandler != nil {
+		handler := c.opts.SamplingHandler
+		opts.CreateMessageHandler = func(ctx context.Context, req *mcp.CreateMessageRequest) (*mcp.CreateMessageResult, error) {
+			return handler(ctx, name, req.Params)
+		}
+	}
+	if c.opts.ElicitationHandler != nil {
+		handler := c.opts.ElicitationHandler
+		opts.ElicitationHandler = func(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			return handler(ctx, name, req.Params)
+		}
+	}
+	if c.logging() {
+		opts.LoggingMessageHandler = func(ctx context.Context, req *mcp.LoggingMessageRequest) {
+			c.handleLog(name, req.Params)
+		}
+	}
+	onToolsChanged := c.opts.OnToolsChanged
+	opts.ToolListChangedHandler = func(ctx context.Context, req *mcp.ToolListChangedRequest) {
+		c.InvalidateTools(name)
+		if onToolsChanged != nil {
+			onToolsChanged(name)
+		}
+	}
+	return opts
+}
+
 type headerTransport struct {
 	Transport http.RoundTripper
 	Headers   map[string]string
@@ -94,79 +377,187 @@ func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return t.Transport.RoundTrip(req)
 }
 
-// Close closes all connections.
-func (c *Client) Close() error {
-	var errs []error
-	for _, session := range c.sessions {
-		if err := session.Close(); err != nil {
-			errs = append(errs, err)
-		}
-	}
-	if len(errs) > 0 {
-		return fmt.Errorf("failed to close some connections: %v", errs)
-	}
-	return nil
-}
-
 // GetTools fetches tools from all connected servers and converts them to OpenAI tools.
+// Tools are named "serverName__toolName" (see Config.ToolNameSeparator), with characters
+// that Ollama rejects replaced by "_", unless the server config gives them an alias.
+// When several tools claim the same alias, the one on the alphabetically first server
+// (then first tool) gets it and the others keep their qualified names.
+//
+// Tool lists are cached per server session for ClientOptions.ToolsCacheTTL and
+// invalidated by tools/list_changed notifications, restarts and InvalidateTools.
 func (c *Client) GetTools(ctx context.Context) ([]api.Tool, error) {
 	var allTools []api.Tool
 
-	for serverName, session := range c.sessions {
-		listToolsResult, err := session.ListTools(ctx, &mcp.ListToolsParams{})
+	for _, serverName := range c.serverNames() {
+		server := c.serverConfig(serverName)
+		if _, ok := c.session(serverName); !ok {
+			if server.lazy() {
+				allTools = append(allTools, c.manifestTools(serverName, server)...)
+			}
+			continue
+		}
+		// Dead servers are respawned if possible, otherwise their tools are left out.
+		session, err := c.liveSession(ctx, serverName)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to list tools from server %s: %v\n", serverName, err)
+			fmt.Fprintf(os.Stderr, "Skipping tools of server %s: %v\n", serverName, err)
 			continue
 		}
-
-		for _, tool := range listToolsResult.Tools {
-			openaiTool := api.Tool{
-				Type: ToolTypeFunction,
-				Function: api.ToolFunction{
-					Name:        fmt.Sprintf("%s__%s", serverName, tool.Name),
-					Description: tool.Description,
-					Parameters:  convertToOllamaParameters(tool.InputSchema),
-				},
-			}
-			allTools = append(allTools, openaiTool)
+		if tools, ok := c.cachedServerTools(serverName, session); ok {
+			allTools = append(allTools, tools...)
+			continue
 		}
+		serverTools, err := c.listServerTools(ctx, serverName, server, session)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list tools from server %s: %v\n", serverName, err)
+			continue
+		}
+		allTools = append(allTools, serverTools...)
 	}
 
 	return allTools, nil
 }
 
+// listServerTools fetches the tools of a server, converts them and caches them.
+func (c *Client) listServerTools(ctx context.Context, serverName string, server MCPServer, session *mcp.ClientSession) ([]api.Tool, error) {
+	listToolsResult, err := session.ListTools(ctx, &mcp.ListToolsParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	var serverTools []api.Tool
+	schemas := make(map[string]map[string]any, len(listToolsResult.Tools))
+	overrides := server.Descriptions
+	for _, tool := range listToolsResult.Tools {
+		schemas[tool.Name] = schemaMap(tool.InputSchema)
+		description := tool.Description
+		if override, ok := overrides[tool.Name]; ok {
+			description = override
+		}
+		openaiTool := api.Tool{
+			Type: ToolTypeFunction,
+			Function: api.ToolFunction{
+				Name:        c.exposedName(serverName, tool.Name),
+				Description: description,
+				Parameters:  convertToOllamaParameters(tool.InputSchema),
+			},
+		}
+		serverTools = append(serverTools, openaiTool)
+	}
+	c.cacheServerTools(serverName, session, serverTools)
+	c.setToolSchemas(serverName, schemas)
+	return serverTools, nil
+}
+
 // CallTool calls a tool on the appropriate server.
-// The tool name is expected to be in the format "serverName__toolName".
-func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
-	serverName, toolName, err := parseToolName(name)
+// The tool name is expected to be a name returned by GetTools, by default "serverName__toolName".
+// Calls pass through the middleware registered with Use.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}, opts ...CallOption) (*ToolResult, error) {
+	var callOpts callOptions
+	for _, opt := range opts {
+		opt(&callOpts)
+	}
+
+	call := func(ctx context.Context, name string, args map[string]any) (*ToolResult, error) {
+		return c.callTool(ctx, name, args, callOpts)
+	}
+	return c.chain(call)(ctx, name, args)
+}
+
+// callTool resolves the tool name and calls the tool on its server.
+func (c *Client) callTool(ctx context.Context, name string, args map[string]interface{}, callOpts callOptions) (*ToolResult, error) {
+	serverName, toolName, err := c.ResolveToolName(name)
 	if err != nil {
 		return nil, err
 	}
 
-	session, ok := c.sessions[serverName]
-	if !ok {
-		return nil, fmt.Errorf("server %s not found", serverName)
+	if err := c.validateArguments(serverName, toolName, name, args); err != nil {
+		return nil, err
+	}
+
+	if callOpts.timeout == 0 {
+		callOpts.timeout = time.Duration(c.serverConfig(serverName).CallTimeout)
+	}
+	if callOpts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, callOpts.timeout)
+		defer cancel()
+	}
+
+	session, err := c.liveSession(ctx, serverName)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+	params := &mcp.CallToolParams{
 		Name:      toolName,
 		Arguments: args,
-	})
+	}
+	if callOpts.onProgress != nil {
+		token, unregister := c.registerProgress(callOpts.onProgress)
+		defer unregister()
+		// Set Meta directly: the SDK's SetProgressToken drops the token when Meta is nil.
+		params.Meta = mcp.Meta{"progressToken": token}
+	}
+
+	release, err := c.acquireSlot(ctx, serverName)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %s was still queued after %s", ErrCallTimeout, name, callOpts.timeout)
+		}
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+	result, err := c.callWithRetry(ctx, serverName, session, params)
+	latency := time.Since(start)
+	c.recordCall(serverName, latency, err)
+	c.recordMetrics(serverName, toolName, latency, err != nil || result.IsError)
 	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %s did not respond within %s", ErrCallTimeout, name, callOpts.timeout)
+		}
 		return nil, fmt.Errorf("failed to call tool: %w", err)
 	}
 
-	return result, nil
+	return newToolResult(result), nil
 }
 
-func parseToolName(name string) (string, string, error) {
-	parts := strings.Split(name, "__")
+func parseToolName(name, separator string) (string, string, error) {
+	parts := strings.Split(name, separator)
 	if len(parts) != 2 {
 		return "", "", fmt.Errorf("invalid tool name format: %s", name)
 	}
 	return parts[0], parts[1], nil
 }
 
+// manifestTools converts the tools manifest of a not yet connected lazy server.
+func (c *Client) manifestTools(serverName string, server MCPServer) []api.Tool {
+	tools := make([]api.Tool, 0, len(server.Tools))
+	for _, tool := range server.Tools {
+		description := tool.Description
+		if override, ok := server.Descriptions[tool.Name]; ok {
+			description = override
+		}
+		params := api.ToolFunctionParameters{
+			Type:       "object",
+			Properties: make(map[string]api.ToolProperty),
+		}
+		if tool.InputSchema != nil {
+			params = convertToOllamaParameters(tool.InputSchema)
+		}
+		tools = append(tools, api.Tool{
+			Type: ToolTypeFunction,
+			Function: api.ToolFunction{
+				Name:        c.exposedName(serverName, tool.Name),
+				Description: description,
+				Parameters:  params,
+			},
+		})
+	}
+	return tools
+}
+
 func convertToOllamaParameters(inputScheme interface{}) api.ToolFunctionParameters {
 	var params api.ToolFunctionParameters
 
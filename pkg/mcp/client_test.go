@@ -1,9 +1,13 @@
 package mcp
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseToolName(t *testing.T) {
@@ -40,7 +44,7 @@ func TestParseToolName(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server, tool, err := parseToolName(tt.input)
+			server, tool, err := parseToolName(tt.input, DefaultToolNameSeparator)
 			if tt.expectError {
 				assert.Error(t, err)
 			} else {
@@ -51,3 +55,170 @@ func TestParseToolName(t *testing.T) {
 		})
 	}
 }
+
+// newTestClient returns a Client without any connected servers.
+func newTestClient() *Client {
+	return newClient(nil)
+}
+
+// connectTestServer connects c to an in-memory server registered under name.
+func connectTestServer(t *testing.T, c *Client, name string, server *mcp.Server) {
+	t.Helper()
+	ctx := context.Background()
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+
+	session, err := c.newSDKClient(name).Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+
+	c.setSession(name, session)
+	c.enableLogging(ctx, name, session)
+	t.Cleanup(func() {
+		session.Close()
+		serverSession.Wait()
+	})
+}
+
+func TestOnToolsChanged(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "dynamic", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "first"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	changed := make(chan string, 1)
+	c := newTestClient()
+	c.opts.OnToolsChanged = func(server string) {
+		select {
+		case changed <- server:
+		default:
+		}
+	}
+	connectTestServer(t, c, "dynamic", server)
+
+	tools, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, tools, 1)
+
+	mcp.AddTool(server, &mcp.Tool{Name: "second"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	select {
+	case name := <-changed:
+		assert.Equal(t, "dynamic", name)
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected tools/list_changed notification")
+	}
+
+	tools, err = c.GetTools(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, tools, 2)
+}
+
+func TestGetTools_DescriptionOverrides(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "fs", Version: "1.0.0"}, nil)
+	for _, name := range []string{"read_file", "list_directory"} {
+		mcp.AddTool(server, &mcp.Tool{Name: name, Description: "读取文件"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	}
+
+	c := newTestClient()
+	c.servers["fs"] = MCPServer{Descriptions: map[string]string{"read_file": "Read a file."}}
+	connectTestServer(t, c, "fs", server)
+
+	tools, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+
+	descriptions := make(map[string]string)
+	for _, tool := range tools {
+		descriptions[tool.Function.Name] = tool.Function.Description
+	}
+	assert.Equal(t, "Read a file.", descriptions["fs__read_file"])
+	assert.Equal(t, "读取文件", descriptions["fs__list_directory"])
+}
+
+func TestConnectToServer_GivesUpAfterRetries(t *testing.T) {
+	c := newTestClient()
+	err := c.connectToServer(context.Background(), "broken", MCPServer{
+		Command:        "/nonexistent/mcp-server",
+		ConnectTimeout: Duration(time.Second),
+		MaxRetries:     2,
+		RetryBackoff:   Duration(time.Millisecond),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+	assert.NotContains(t, c.sessions, "broken")
+}
+
+func TestGetTools_Aliases(t *testing.T) {
+	newServer := func() *mcp.Server {
+		server := mcp.NewServer(&mcp.Implementation{Name: "fs", Version: "1.0.0"}, nil)
+		mcp.AddTool(server, &mcp.Tool{Name: "read_file"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+		return server
+	}
+
+	servers := map[string]MCPServer{
+		"b_fs": {Aliases: map[string]string{"read_file": "read"}},
+		"a_fs": {Aliases: map[string]string{"read_file": "read"}},
+	}
+	c := newTestClient()
+	c.servers = servers
+	c.registerAliases(servers)
+	connectTestServer(t, c, "a_fs", newServer())
+	connectTestServer(t, c, "b_fs", newServer())
+
+	tools, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Function.Name)
+	}
+	assert.Equal(t, []string{"read", "b_fs__read_file"}, names)
+
+	server, tool, err := c.ResolveToolName("read")
+	require.NoError(t, err)
+	assert.Equal(t, "a_fs", server)
+	assert.Equal(t, "read_file", tool)
+
+	_, err = c.CallTool(context.Background(), "read", map[string]interface{}{})
+	require.NoError(t, err)
+	_, err = c.CallTool(context.Background(), "b_fs__read_file", map[string]interface{}{})
+	require.NoError(t, err)
+}
+
+func TestGetTools_SeparatorAndSanitization(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "docs", Version: "1.0.0"}, nil)
+	for _, name := range []string{"fetch.url", "fetch url"} {
+		mcp.AddTool(server, &mcp.Tool{Name: name}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: req.Params.Name}}}, nil, nil
+		})
+	}
+
+	c := newTestClient()
+	c.separator = "-"
+	connectTestServer(t, c, "my.docs", server)
+
+	tools, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Function.Name)
+	}
+	assert.ElementsMatch(t, []string{"my_docs-fetch_url", "my_docs-fetch_url_2"}, names)
+
+	for _, name := range names {
+		server, tool, err := c.ResolveToolName(name)
+		require.NoError(t, err)
+		assert.Equal(t, "my.docs", server)
+
+		result, err := c.CallTool(context.Background(), name, map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Equal(t, tool, result.Text())
+	}
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// splitCommands splits the command lines of servers without args, such as
+// "npx -y @modelcontextprotocol/server-filesystem /tmp", into the command and
+// its arguments, so that commands can be pasted from other tools' docs.
+// Relative paths are resolved against dir, the directory of the config file.
+func splitCommands(servers map[string]MCPServer, dir string) error {
+	for name, server := range servers {
+		if len(server.Args) > 0 || !strings.ContainsAny(server.Command, " \t\n'\"") {
+			continue
+		}
+		// A path with spaces such as "C:\Program Files\server.exe" is a command, not a command line
+		path := server.Command
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		words, err := splitCommand(server.Command)
+		if err != nil {
+			return fmt.Errorf("invalid command of server %s: %w", name, err)
+		}
+		if len(words) == 0 {
+			continue
+		}
+		server.Command, server.Args = words[0], words[1:]
+		servers[name] = server
+	}
+	return nil
+}
+
+// splitCommand splits a command line into words like a POSIX shell, without
+// expansions: words are separated by whitespace, single quotes preserve their
+// content literally, and in double quotes and unquoted text a backslash escapes
+// the next character.
+func splitCommand(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case quote == '"':
+			switch {
+			case c == '"':
+				quote = 0
+			case c == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]):
+				i++
+				word.WriteRune(runes[i])
+			default:
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == '\\':
+			if i+1 == len(runes) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -2,27 +2,300 @@ package mcp
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Config represents the structure of the ~/.claude.json file.
 type Config struct {
 	MCPServers map[string]MCPServer `json:"mcpServers"`
+
+	// ToolNameSeparator joins server and tool names in the tools advertised to the
+	// model. Defaults to "__"; use e.g. "-" for models that mangle underscores.
+	ToolNameSeparator string `json:"toolNameSeparator,omitempty"`
+
+	// URLPolicy restricts the URLs that web tools may access. It is not used by
+	// the client itself; agents enforce it before calling the tools.
+	URLPolicy *URLPolicy `json:"urlPolicy,omitempty"`
+
+	// Users are the accounts of an agent's HTTP API. Like URLPolicy they are not
+	// used by the client itself.
+	Users []User `json:"users,omitempty"`
+
+	// Profiles are alternative server sets in the same file, e.g. "dev" and "prod",
+	// selected with ApplyProfile.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Profile adds, replaces or disables servers of the top-level mcpServers when selected.
+type Profile struct {
+	MCPServers map[string]MCPServer `json:"mcpServers,omitempty"`
+	// Disabled lists servers of mcpServers that are not used in this profile.
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// ApplyProfile merges the named profile into MCPServers: its servers replace
+// those with the same name and its disabled servers are removed. The empty
+// name leaves the configuration unchanged.
+func (c *Config) ApplyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("unknown profile %q, the config defines no profiles", name)
+		}
+		return fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	for _, disabled := range profile.Disabled {
+		if _, ok := c.MCPServers[disabled]; !ok {
+			return fmt.Errorf("profile %q disables unknown server %q", name, disabled)
+		}
+		delete(c.MCPServers, disabled)
+	}
+	if c.MCPServers == nil {
+		c.MCPServers = make(map[string]MCPServer)
+	}
+	for serverName, server := range profile.MCPServers {
+		c.MCPServers[serverName] = server
+	}
+	return nil
+}
+
+// ProfileNames returns the names of the profiles in alphabetical order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// URLPolicy lists the domains web tools may or may not access. A domain also
+// matches its subdomains, so "example.com" covers "docs.example.com".
+type URLPolicy struct {
+	// AllowedDomains, if not empty, is the only set of domains that may be accessed.
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+	// BlockedDomains are never accessed, even when they are also allowed.
+	BlockedDomains []string `json:"blockedDomains,omitempty"`
+	// BlockPrivateNetworks rejects hosts that resolve to loopback, private or
+	// link-local addresses, to prevent server-side request forgery.
+	BlockPrivateNetworks bool `json:"blockPrivateNetworks,omitempty"`
+}
+
+// User is an account of an agent's HTTP API, with its own sessions.
+type User struct {
+	Name string `json:"name"`
+	// Token authenticates the user's requests. "$VAR" references are expanded
+	// from the environment, so that the token need not be stored in the file.
+	Token string `json:"token"`
+	// Workspace is the directory the user's tools work in. Empty means the
+	// agent's own workspace.
+	Workspace string `json:"workspace,omitempty"`
+	// RateLimit is the number of messages the user may send per minute, zero
+	// means no limit.
+	RateLimit int `json:"rateLimit,omitempty"`
 }
 
 // MCPServer represents a single MCP server configuration.
 type MCPServer struct {
+	// Command is the executable of a stdio server. Without Args it may be a whole
+	// command line, e.g. "npx -y @modelcontextprotocol/server-filesystem /tmp",
+	// which is split into words like a shell does, respecting quotes. A backslash
+	// escapes the next character, so Windows paths in a command line need single
+	// quotes, unless the whole command is an existing file.
 	Command string            `json:"command"`
 	Args    []string          `json:"args"`
 	Env     map[string]string `json:"env,omitempty"`
-	Type    string            `json:"type,omitempty"`    // "stdio" (default) or "sse"
-	URL     string            `json:"url,omitempty"`     // For SSE
-	Headers map[string]string `json:"headers,omitempty"` // For SSE
+
+	// InheritEnv false passes only PATH, HOME and a few other basic variables of the
+	// host environment to a stdio server, plus those in EnvAllowlist (a trailing "*"
+	// matches a prefix), so that host secrets do not leak to it. Defaults to true.
+	InheritEnv   *bool    `json:"inheritEnv,omitempty"`
+	EnvAllowlist []string `json:"envAllowlist,omitempty"`
+
+	Type    string            `json:"type,omitempty"`    // "stdio" (default), "docker", "sse" or "ws"
+	URL     string            `json:"url,omitempty"`     // For SSE and ws
+	Headers map[string]string `json:"headers,omitempty"` // For SSE and ws
+	Auth    *AuthConfig       `json:"auth,omitempty"`    // For SSE and ws, bearer tokens that are refreshed automatically
+
+	// TLS settings of remote servers: a custom CA for self-signed servers, and a
+	// client certificate and key for mutual TLS.
+	TLSCAFile          string `json:"tlsCaFile,omitempty"`
+	TLSCertFile        string `json:"tlsCertFile,omitempty"`
+	TLSKeyFile         string `json:"tlsKeyFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"` // disables certificate verification, for testing only
+
+	// Docker runs a stdio server in a container: Command and Args run inside the
+	// image, Env is passed with -e and only the listed mounts are visible.
+	Image      string   `json:"image,omitempty"`
+	Mounts     []string `json:"mounts,omitempty"`     // "host:container[:ro]", e.g. ".:/workspace:ro"
+	Network    string   `json:"network,omitempty"`    // defaults to "none"
+	DockerArgs []string `json:"dockerArgs,omitempty"` // extra "docker run" flags, e.g. ["--memory", "512m"]
+
+	// Connection policy. Zero values fall back to the defaults below.
+	ConnectTimeout Duration `json:"connectTimeout,omitempty"` // e.g. "10s"
+	MaxRetries     int      `json:"maxRetries,omitempty"`     // extra attempts after the first one
+	RetryBackoff   Duration `json:"retryBackoff,omitempty"`   // delay before the first retry, doubled after each one
+	MaxRestarts    int      `json:"maxRestarts,omitempty"`    // respawns of a crashed stdio server, -1 disables
+	CallTimeout    Duration `json:"callTimeout,omitempty"`    // default deadline of a tool call, zero means none
+	// CallRetries is how often a tool call that failed with a transient transport
+	// error is retried, with RetryBackoff between attempts. Zero retries only the
+	// tools in AutoApprove, DefaultCallRetries times; a positive value retries every
+	// tool of the server and -1 disables retries.
+	CallRetries int `json:"callRetries,omitempty"`
+	// MaxConcurrency limits the tool calls in flight to the server; further calls wait
+	// in a queue. Defaults to DefaultStdioConcurrency for stdio and docker servers and
+	// to no limit for remote servers; -1 removes the limit.
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
+
+	// AutoApprove lists the tools, by their name on the server, that the agent may
+	// call without asking the user first, e.g. read-only tools. Patterns like
+	// "read_*" are allowed and "*" approves every tool of the server.
+	AutoApprove []string `json:"autoApprove,omitempty"`
+
+	// Descriptions overrides the descriptions published by the server, keyed by tool name.
+	Descriptions map[string]string `json:"descriptions,omitempty"`
+
+	// Aliases exposes tools under shorter names, keyed by tool name, e.g. {"read_file": "read"}.
+	// Aliases must not contain the tool name separator; see GetTools for how collisions are resolved.
+	Aliases map[string]string `json:"aliases,omitempty"`
+
+	// Lazy defers spawning/connecting the server until one of its tools is called.
+	// Until then its tools are advertised from the Tools manifest, which is required.
+	Lazy  bool           `json:"lazy,omitempty"`
+	Tools []ToolManifest `json:"tools,omitempty"`
+}
+
+// ToolManifest describes a tool of a lazily connected server.
+type ToolManifest struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema,omitempty"`
 }
 
-// LoadConfig loads the MCP configuration from the specified path.
-func LoadConfig(path string) (*Config, error) {
+const (
+	DefaultConnectTimeout = 30 * time.Second
+	DefaultRetryBackoff   = time.Second
+)
+
+// Remote reports whether the server is reached over the network rather than spawned locally.
+func (s MCPServer) Remote() bool {
+	return s.Type == "sse" || s.Type == "ws"
+}
+
+// lazy reports whether the server should only be connected on its first tool call.
+func (s MCPServer) lazy() bool {
+	return s.Lazy && len(s.Tools) > 0
+}
+
+func (s MCPServer) connectTimeout() time.Duration {
+	if s.ConnectTimeout > 0 {
+		return time.Duration(s.ConnectTimeout)
+	}
+	return DefaultConnectTimeout
+}
+
+func (s MCPServer) maxRestarts() int {
+	switch {
+	case s.MaxRestarts < 0:
+		return 0
+	case s.MaxRestarts == 0:
+		return DefaultMaxRestarts
+	default:
+		return s.MaxRestarts
+	}
+}
+
+func (s MCPServer) retryBackoff() time.Duration {
+	if s.RetryBackoff > 0 {
+		return time.Duration(s.RetryBackoff)
+	}
+	return DefaultRetryBackoff
+}
+
+// Duration is a time.Duration encoded in JSON as a string such as "500ms" or "10s".
+type Duration time.Duration
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig loads the MCP configuration from the given paths and merges them in
+// order, so that later files (e.g. a project's .mcp.json) override earlier ones
+// (e.g. ~/.claude.json). A server defined in several files is taken from the last
+// one as a whole, and so is a profile. Files that do not exist are skipped, but
+// at least one must exist.
+func LoadConfig(paths ...string) (*Config, error) {
+	merged := &Config{MCPServers: make(map[string]MCPServer), Profiles: make(map[string]Profile)}
+
+	var firstErr error
+	loaded := 0
+	for _, path := range paths {
+		config, err := loadConfigFile(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			return nil, err
+		}
+		loaded++
+
+		for name, server := range config.MCPServers {
+			merged.MCPServers[name] = server
+		}
+		for name, profile := range config.Profiles {
+			merged.Profiles[name] = profile
+		}
+		if config.ToolNameSeparator != "" {
+			merged.ToolNameSeparator = config.ToolNameSeparator
+		}
+		if config.URLPolicy != nil {
+			merged.URLPolicy = config.URLPolicy
+		}
+		if config.Users != nil {
+			merged.Users = config.Users
+		}
+	}
+
+	if loaded == 0 {
+		if firstErr == nil {
+			return nil, errors.New("no config file given")
+		}
+		return nil, firstErr
+	}
+	return merged, nil
+}
+
+// loadConfigFile loads a single configuration file.
+func loadConfigFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -30,7 +303,17 @@ func LoadConfig(path string) (*Config, error) {
 
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := splitCommands(config.MCPServers, dir); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	for _, profile := range config.Profiles {
+		if err := splitCommands(profile.MCPServers, dir); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
 	}
 
 	return &config, nil
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -87,3 +88,209 @@ func TestLoadConfig_InvalidJSON(t *testing.T) {
 	_, err = LoadConfig(configPath)
 	assert.Error(t, err)
 }
+
+func TestLoadConfig_ConnectionPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "policy.json")
+
+	configContent := `{
+  "mcpServers": {
+    "slow": {
+      "command": "slow-server",
+      "connectTimeout": "5s",
+      "maxRetries": 2,
+      "retryBackoff": "250ms"
+    },
+    "default": {
+      "command": "server"
+    }
+  }
+}`
+	err := os.WriteFile(configPath, []byte(configContent), 0644)
+	require.NoError(t, err)
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	slow := config.MCPServers["slow"]
+	assert.Equal(t, 5*time.Second, slow.connectTimeout())
+	assert.Equal(t, 2, slow.MaxRetries)
+	assert.Equal(t, 250*time.Millisecond, slow.retryBackoff())
+
+	def := config.MCPServers["default"]
+	assert.Equal(t, DefaultConnectTimeout, def.connectTimeout())
+	assert.Equal(t, 0, def.MaxRetries)
+	assert.Equal(t, DefaultRetryBackoff, def.retryBackoff())
+}
+
+func TestLoadConfig_InvalidDuration(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "invalid_duration.json")
+	err := os.WriteFile(configPath, []byte(`{"mcpServers": {"s": {"command": "x", "connectTimeout": "soon"}}}`), 0644)
+	require.NoError(t, err)
+
+	_, err = LoadConfig(configPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_MergesLayers(t *testing.T) {
+	tmpDir := t.TempDir()
+	global := filepath.Join(tmpDir, "claude.json")
+	project := filepath.Join(tmpDir, ".mcp.json")
+
+	require.NoError(t, os.WriteFile(global, []byte(`{
+  "mcpServers": {
+    "filesystem": {"command": "global-fs", "args": ["/"]},
+    "search": {"command": "global-search"}
+  },
+  "users": [{"name": "alice", "token": "$ALICE_TOKEN", "workspace": "/srv/alice", "rateLimit": 10}]
+}`), 0644))
+	require.NoError(t, os.WriteFile(project, []byte(`{
+  "toolNameSeparator": "-",
+  "mcpServers": {
+    "filesystem": {"command": "project-fs"},
+    "browser": {"type": "sse", "url": "http://localhost:9621/sse"}
+  }
+}`), 0644))
+
+	config, err := LoadConfig(global, filepath.Join(tmpDir, "missing.json"), project)
+	require.NoError(t, err)
+
+	assert.Len(t, config.MCPServers, 3)
+	assert.Equal(t, "global-search", config.MCPServers["search"].Command)
+	assert.Equal(t, "sse", config.MCPServers["browser"].Type)
+	// The project entry replaces the global one as a whole.
+	assert.Equal(t, "project-fs", config.MCPServers["filesystem"].Command)
+	assert.Empty(t, config.MCPServers["filesystem"].Args)
+	assert.Equal(t, "-", config.ToolNameSeparator)
+	// Users are kept from the layer that sets them.
+	assert.Equal(t, []User{{Name: "alice", Token: "$ALICE_TOKEN", Workspace: "/srv/alice", RateLimit: 10}}, config.Users)
+}
+
+func TestLoadConfig_NoFileExists(t *testing.T) {
+	tmpDir := t.TempDir()
+	_, err := LoadConfig(filepath.Join(tmpDir, "a.json"), filepath.Join(tmpDir, "b.json"))
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_Profiles(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "mcp.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{
+  "mcpServers": {
+    "filesystem": {"command": "fs", "args": ["."]},
+    "browser": {"type": "sse", "url": "http://localhost:9621/sse"}
+  },
+  "profiles": {
+    "dev": {
+      "mcpServers": {"filesystem": {"command": "fs", "args": ["/tmp"]}}
+    },
+    "prod": {
+      "mcpServers": {"search": {"command": "search"}},
+      "disabled": ["browser"]
+    }
+  }
+}`), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod"}, config.ProfileNames())
+
+	require.NoError(t, config.ApplyProfile("dev"))
+	assert.Len(t, config.MCPServers, 2)
+	assert.Equal(t, []string{"/tmp"}, config.MCPServers["filesystem"].Args)
+
+	config, err = LoadConfig(configPath)
+	require.NoError(t, err)
+	require.NoError(t, config.ApplyProfile("prod"))
+	assert.Len(t, config.MCPServers, 2)
+	assert.Contains(t, config.MCPServers, "search")
+	assert.NotContains(t, config.MCPServers, "browser")
+
+	err = config.ApplyProfile("staging")
+	assert.ErrorContains(t, err, "available: dev, prod")
+	assert.NoError(t, config.ApplyProfile(""))
+}
+
+func TestConfig_ApplyProfile_UnknownServer(t *testing.T) {
+	config := &Config{
+		MCPServers: map[string]MCPServer{"filesystem": {Command: "fs"}},
+		Profiles:   map[string]Profile{"ci": {Disabled: []string{"browser"}}},
+	}
+	assert.ErrorContains(t, config.ApplyProfile("ci"), `disables unknown server "browser"`)
+}
+
+func TestLoadConfig_CommandLine(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "mcp.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{
+  "mcpServers": {
+    "filesystem": {"command": "npx -y @modelcontextprotocol/server-filesystem /tmp"},
+    "quoted": {"command": "python3 \"/opt/my server/main.py\" --name 'a b'"},
+    "explicit": {"command": "my server", "args": ["--flag"]}
+  },
+  "profiles": {
+    "dev": {"mcpServers": {"git": {"command": "uvx mcp-server-git --repository ."}}}
+  }
+}`), 0644))
+
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "npx", config.MCPServers["filesystem"].Command)
+	assert.Equal(t, []string{"-y", "@modelcontextprotocol/server-filesystem", "/tmp"}, config.MCPServers["filesystem"].Args)
+	assert.Equal(t, "python3", config.MCPServers["quoted"].Command)
+	assert.Equal(t, []string{"/opt/my server/main.py", "--name", "a b"}, config.MCPServers["quoted"].Args)
+	// With explicit args the command is taken as is.
+	assert.Equal(t, "my server", config.MCPServers["explicit"].Command)
+	assert.Equal(t, []string{"--flag"}, config.MCPServers["explicit"].Args)
+
+	assert.Equal(t, "uvx", config.Profiles["dev"].MCPServers["git"].Command)
+	assert.Equal(t, []string{"mcp-server-git", "--repository", "."}, config.Profiles["dev"].MCPServers["git"].Args)
+}
+
+func TestLoadConfig_CommandLineUnterminatedQuote(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "mcp.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"mcpServers": {"broken": {"command": "npx 'server"}}}`), 0644))
+
+	_, err := LoadConfig(configPath)
+	assert.ErrorContains(t, err, "invalid command of server broken: unterminated ' quote")
+}
+
+func TestLoadConfig_CommandWithSpacesNextToConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "my server"), nil, 0755))
+	configPath := filepath.Join(dir, "mcp.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"mcpServers": {"local": {"command": "my server"}}}`), 0644))
+
+	// The file is found relative to the config file, not the working directory.
+	t.Chdir(t.TempDir())
+	config, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "my server", config.MCPServers["local"].Command)
+	assert.Empty(t, config.MCPServers["local"].Args)
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"npx -y server", []string{"npx", "-y", "server"}},
+		{"  npx \t -y  ", []string{"npx", "-y"}},
+		{`cmd "a b" 'c d'`, []string{"cmd", "a b", "c d"}},
+		{`cmd a"b c"d`, []string{"cmd", "ab cd"}},
+		{`cmd "" ''`, []string{"cmd", "", ""}},
+		{`cmd a\ b "q\"uote" 'back\slash'`, []string{"cmd", "a b", `q"uote`, `back\slash`}},
+		{`cmd "C:\path"`, []string{"cmd", `C:\path`}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.line)
+		require.NoError(t, err, tt.line)
+		assert.Equal(t, tt.want, got, tt.line)
+	}
+
+	for _, line := range []string{`cmd "open`, "cmd 'open", `cmd \`} {
+		_, err := splitCommand(line)
+		assert.Error(t, err, line)
+	}
+}
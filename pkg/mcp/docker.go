@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultDockerNetwork isolates containerized servers from the network unless
+// their config asks for one.
+const DefaultDockerNetwork = "none"
+
+// dockerArgs builds the "docker run" arguments of a containerized stdio server.
+// The container is removed when the server exits and only sees the configured
+// mounts, environment and network.
+func (s MCPServer) dockerArgs() ([]string, error) {
+	if s.Image == "" {
+		return nil, errors.New("docker server needs an image")
+	}
+
+	network := s.Network
+	if network == "" {
+		network = DefaultDockerNetwork
+	}
+	args := []string{"run", "-i", "--rm", "--init", "--network", network}
+
+	for _, mount := range s.Mounts {
+		host, container, ok := strings.Cut(mount, ":")
+		if !ok || host == "" || container == "" {
+			return nil, fmt.Errorf("invalid mount %q, expected host:container[:ro]", mount)
+		}
+		// Docker requires absolute host paths; relative ones are relative to the working directory.
+		if !filepath.IsAbs(host) {
+			abs, err := filepath.Abs(host)
+			if err != nil {
+				return nil, err
+			}
+			host = abs
+		}
+		args = append(args, "-v", host+":"+container)
+	}
+
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", k+"="+s.Env[k])
+	}
+
+	args = append(args, s.DockerArgs...)
+	args = append(args, s.Image)
+	if s.Command != "" {
+		args = append(args, s.Command)
+	}
+	return append(args, s.Args...), nil
+}
@@ -0,0 +1,49 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerArgs(t *testing.T) {
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	args, err := MCPServer{
+		Type:       "docker",
+		Image:      "coding-agent/bash:latest",
+		Command:    "/app/bash",
+		Args:       []string{"--verbose"},
+		Env:        map[string]string{"B": "2", "A": "1"},
+		Mounts:     []string{".:/workspace:ro", "/tmp/cache:/cache"},
+		DockerArgs: []string{"--memory", "512m"},
+	}.dockerArgs()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"run", "-i", "--rm", "--init", "--network", "none",
+		"-v", cwd + ":/workspace:ro",
+		"-v", "/tmp/cache:/cache",
+		"-e", "A=1", "-e", "B=2",
+		"--memory", "512m",
+		"coding-agent/bash:latest", "/app/bash", "--verbose",
+	}, args)
+}
+
+func TestDockerArgs_Network(t *testing.T) {
+	args, err := MCPServer{Type: "docker", Image: "fetch", Network: "bridge"}.dockerArgs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"run", "-i", "--rm", "--init", "--network", "bridge", "fetch"}, args)
+}
+
+func TestDockerArgs_Invalid(t *testing.T) {
+	_, err := MCPServer{Type: "docker"}.dockerArgs()
+	assert.Error(t, err)
+
+	_, err = MCPServer{Type: "docker", Image: "fetch", Mounts: []string{filepath.Join("only", "host")}}.dockerArgs()
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElicitationHandler(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "deployer", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "deploy"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		result, err := req.Session.Elicit(ctx, &mcp.ElicitParams{
+			Message: "Which environment?",
+			RequestedSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"env": map[string]any{"type": "string", "enum": []any{"staging", "production"}},
+				},
+				"required": []any{"env"},
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		text := result.Action
+		if env, ok := result.Content["env"].(string); ok {
+			text += " " + env
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}, nil, nil
+	})
+
+	var gotServer, gotMessage string
+	c := newTestClient()
+	c.opts.ElicitationHandler = func(ctx context.Context, server string, params *mcp.ElicitParams) (*mcp.ElicitResult, error) {
+		gotServer, gotMessage = server, params.Message
+		return &mcp.ElicitResult{Action: "accept", Content: map[string]any{"env": "staging"}}, nil
+	}
+	connectTestServer(t, c, "deployer", server)
+
+	result, err := c.CallTool(context.Background(), "deployer__deploy", map[string]interface{}{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "accept staging", result.Text())
+	assert.Equal(t, "deployer", gotServer)
+	assert.Equal(t, "Which environment?", gotMessage)
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// baseEnv are the host variables passed to servers that do not inherit the
+// environment, since most commands need them to start at all.
+var baseEnv = []string{"PATH", "HOME", "USER", "LOGNAME", "LANG", "LC_ALL", "TMPDIR", "TEMP", "TMP", "SystemRoot", "USERPROFILE"}
+
+// inheritsEnv reports whether the server gets the full host environment.
+func (s MCPServer) inheritsEnv() bool {
+	return s.InheritEnv == nil || *s.InheritEnv
+}
+
+// environ builds the environment of a stdio server: the host environment (all of
+// it, or only the base and allowlisted variables when inheritEnv is false)
+// followed by the variables from the config, which take precedence.
+func (s MCPServer) environ() []string {
+	var env []string
+	if s.inheritsEnv() {
+		env = os.Environ()
+	} else {
+		for _, kv := range os.Environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			if matchEnv(name, baseEnv) || matchEnv(name, s.EnvAllowlist) {
+				env = append(env, kv)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", k, s.Env[k]))
+	}
+	return env
+}
+
+// matchEnv reports whether the variable is in the list. A trailing "*" matches a prefix, e.g. "GO*".
+func matchEnv(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnviron_InheritsByDefault(t *testing.T) {
+	t.Setenv("MCP_TEST_SECRET", "s3cret")
+
+	env := MCPServer{Env: map[string]string{"MODE": "test"}}.environ()
+	assert.Contains(t, env, "MCP_TEST_SECRET=s3cret")
+	assert.Equal(t, "MODE=test", env[len(env)-1])
+}
+
+func TestEnviron_Allowlist(t *testing.T) {
+	t.Setenv("MCP_TEST_SECRET", "s3cret")
+	t.Setenv("GOFLAGS", "-mod=mod")
+	t.Setenv("NPM_TOKEN", "token")
+	t.Setenv("PATH", "/usr/bin")
+
+	inherit := false
+	env := MCPServer{
+		InheritEnv:   &inherit,
+		EnvAllowlist: []string{"GO*", "NPM_TOKEN"},
+		Env:          map[string]string{"MODE": "test"},
+	}.environ()
+
+	assert.NotContains(t, env, "MCP_TEST_SECRET=s3cret")
+	assert.Contains(t, env, "PATH=/usr/bin")
+	assert.Contains(t, env, "GOFLAGS=-mod=mod")
+	assert.Contains(t, env, "NPM_TOKEN=token")
+	assert.Contains(t, env, "MODE=test")
+}
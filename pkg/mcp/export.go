@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/ollama/ollama/api"
+)
+
+// OpenAITool is a tool in the format of the OpenAI Chat Completions API
+// (and the many servers compatible with it).
+type OpenAITool struct {
+	Type     string         `json:"type"`
+	Function OpenAIFunction `json:"function"`
+}
+
+// OpenAIFunction describes the function of an OpenAITool.
+type OpenAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// AnthropicTool is a tool in the format of the Anthropic Messages API.
+type AnthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+// GetToolsOpenAI returns the same tools as GetTools in the OpenAI format. The
+// input schemas are passed on as published by the servers.
+func (c *Client) GetToolsOpenAI(ctx context.Context) ([]OpenAITool, error) {
+	tools, err := c.GetTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]OpenAITool, len(tools))
+	for i, tool := range tools {
+		result[i] = OpenAITool{
+			Type: ToolTypeFunction,
+			Function: OpenAIFunction{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  c.exportSchema(tool),
+			},
+		}
+	}
+	return result, nil
+}
+
+// GetToolsAnthropic returns the same tools as GetTools in the Anthropic format.
+// The input schemas are passed on as published by the servers.
+func (c *Client) GetToolsAnthropic(ctx context.Context) ([]AnthropicTool, error) {
+	tools, err := c.GetTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]AnthropicTool, len(tools))
+	for i, tool := range tools {
+		result[i] = AnthropicTool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: c.exportSchema(tool),
+		}
+	}
+	return result, nil
+}
+
+// exportSchema returns the JSON schema of a tool's input. The original schema is
+// preferred over the Ollama parameters, which drop keywords Ollama does not know.
+// Both providers require an object schema with properties.
+func (c *Client) exportSchema(tool api.Tool) map[string]any {
+	var schema map[string]any
+	if server, name, err := c.ResolveToolName(tool.Function.Name); err == nil {
+		schema, _ = c.toolSchema(server, name)
+	}
+	if schema == nil {
+		schema = schemaMap(tool.Function.Parameters)
+	}
+
+	exported := make(map[string]any, len(schema)+2)
+	for k, v := range schema {
+		if k != "$schema" {
+			exported[k] = v
+		}
+	}
+	exported["type"] = "object"
+	if props, ok := exported["properties"].(map[string]any); !ok || props == nil {
+		exported["properties"] = map[string]any{}
+	}
+	return exported
+}
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetToolsOpenAIAndAnthropic(t *testing.T) {
+	type searchArgs struct {
+		Query string `json:"query" jsonschema:"text to search for"`
+		Limit int    `json:"limit,omitempty"`
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "search", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "search", Description: "Search the code"}, func(ctx context.Context, req *mcp.CallToolRequest, args searchArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	mcp.AddTool(server, &mcp.Tool{Name: "ping", InputSchema: map[string]any{"type": "object"}}, func(ctx context.Context, req *mcp.CallToolRequest, args map[string]any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "code", server)
+
+	openai, err := c.GetToolsOpenAI(context.Background())
+	require.NoError(t, err)
+	require.Len(t, openai, 2)
+	byName := map[string]OpenAITool{}
+	for _, tool := range openai {
+		byName[tool.Function.Name] = tool
+	}
+
+	search := byName["code__search"]
+	assert.Equal(t, "function", search.Type)
+	assert.Equal(t, "Search the code", search.Function.Description)
+	assert.Equal(t, "object", search.Function.Parameters["type"])
+	assert.Equal(t, []any{"query"}, search.Function.Parameters["required"])
+	props := search.Function.Parameters["properties"].(map[string]any)
+	assert.Equal(t, "text to search for", props["query"].(map[string]any)["description"])
+	// Keywords Ollama does not know are kept.
+	assert.Equal(t, false, search.Function.Parameters["additionalProperties"])
+
+	// Tools without properties get an empty properties object.
+	assert.Equal(t, map[string]any{}, byName["code__ping"].Function.Parameters["properties"])
+
+	anthropic, err := c.GetToolsAnthropic(context.Background())
+	require.NoError(t, err)
+	require.Len(t, anthropic, 2)
+	data, err := json.Marshal(anthropic)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"input_schema":{`)
+	for _, tool := range anthropic {
+		assert.Equal(t, byName[tool.Name].Function.Parameters, tool.InputSchema)
+	}
+}
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Server states reported by Status.
+const (
+	StateConnected = "connected" // the session is up
+	StateDead      = "dead"      // the connection was lost; stdio servers are respawned on the next call
+	StateLazy      = "lazy"      // not connected yet, connects on the first tool call
+)
+
+// ServerStatus describes the health of one server.
+type ServerStatus struct {
+	Name  string
+	Type  string // "stdio", "docker", "sse" or "ws"
+	State string
+	// Restarts is the number of times the server was respawned after a crash.
+	Restarts int
+	// LastCall is when the last tool call or ping completed, zero if there was none.
+	LastCall    time.Time
+	LastLatency time.Duration
+	// LastError is the error of the last tool call or ping, nil if it succeeded.
+	LastError error
+}
+
+// Alive reports whether the server currently has a working session.
+func (s ServerStatus) Alive() bool {
+	return s.State == StateConnected
+}
+
+// callHealth records the outcome of the last request sent to a server.
+type callHealth struct {
+	at      time.Time
+	latency time.Duration
+	err     error
+}
+
+// recordCall remembers the latency and error of a request sent to the server.
+func (c *Client) recordCall(name string, latency time.Duration, err error) {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	if c.health == nil {
+		c.health = make(map[string]callHealth)
+	}
+	c.health[name] = callHealth{at: time.Now(), latency: latency, err: err}
+}
+
+// Ping checks that the named server responds and returns the round-trip time.
+// A server whose connection turns out to be closed is marked dead.
+func (c *Client) Ping(ctx context.Context, serverName string) (time.Duration, error) {
+	session, ok := c.session(serverName)
+	if !ok {
+		if c.serverConfig(serverName).lazy() {
+			return 0, fmt.Errorf("server %s is not connected yet", serverName)
+		}
+		return 0, fmt.Errorf("server %s not found", serverName)
+	}
+
+	start := time.Now()
+	err := session.Ping(ctx, nil)
+	latency := time.Since(start)
+	if err != nil && isConnectionClosed(err) {
+		c.markDead(serverName, session)
+	}
+	if err != nil {
+		err = fmt.Errorf("ping %s: %w", serverName, err)
+	}
+	c.recordCall(serverName, latency, err)
+	return latency, err
+}
+
+// Status reports the state of all configured or connected servers, sorted by name.
+func (c *Client) Status() []ServerStatus {
+	names := c.serverNames()
+
+	c.mu.RLock()
+	statuses := make([]ServerStatus, 0, len(names))
+	for _, name := range names {
+		status := ServerStatus{
+			Name:     name,
+			Type:     c.servers[name].Type,
+			State:    StateConnected,
+			Restarts: c.restarts[name],
+		}
+		if status.Type == "" {
+			status.Type = "stdio"
+		}
+		if _, ok := c.sessions[name]; !ok {
+			status.State = StateLazy
+		} else if c.dead[name] {
+			status.State = StateDead
+		}
+		statuses = append(statuses, status)
+	}
+	c.mu.RUnlock()
+
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	for i := range statuses {
+		if h, ok := c.health[statuses[i].Name]; ok {
+			statuses[i].LastCall = h.at
+			statuses[i].LastLatency = h.latency
+			statuses[i].LastError = h.err
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingAndStatus(t *testing.T) {
+	server := testServerConfig()
+	server.MaxRestarts = -1
+	lazy := testServerConfig()
+	lazy.Lazy = true
+	lazy.Tools = []ToolManifest{{Name: "pid"}}
+
+	c, err := NewClient(context.Background(), &Config{
+		MCPServers: map[string]MCPServer{"proc": server, "later": lazy},
+	}, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	latency, err := c.Ping(context.Background(), "proc")
+	require.NoError(t, err)
+	assert.Positive(t, latency)
+
+	_, err = c.Ping(context.Background(), "later")
+	assert.Error(t, err)
+
+	statuses := c.Status()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "later", statuses[0].Name)
+	assert.Equal(t, StateLazy, statuses[0].State)
+	assert.Equal(t, "proc", statuses[1].Name)
+	assert.True(t, statuses[1].Alive())
+	assert.Equal(t, "stdio", statuses[1].Type)
+	assert.False(t, statuses[1].LastCall.IsZero())
+	assert.NoError(t, statuses[1].LastError)
+
+	pid, err := callPid(t, c, "proc")
+	require.NoError(t, err)
+	killServer(t, c, "proc", pid)
+
+	statuses = c.Status()
+	assert.Equal(t, StateDead, statuses[1].State)
+
+	// The dead server cannot be restarted, so only the lazy server's tools are listed.
+	tools, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "later__pid", tools[0].Function.Name)
+}
@@ -0,0 +1,150 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultLogLevel is the minimum level requested from servers when logging is enabled.
+const DefaultLogLevel = "info"
+
+// LogMessage is a notifications/message sent by a server.
+type LogMessage struct {
+	Server string
+	Level  string // debug, info, notice, warning, error, critical, alert or emergency
+	Logger string // optional name of the server component that logged the message
+	Data   any
+	Time   time.Time
+}
+
+// Text renders the message data, which may be a string or any JSON value.
+func (m LogMessage) Text() string {
+	if s, ok := m.Data.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(m.Data)
+	if err != nil {
+		return fmt.Sprint(m.Data)
+	}
+	return string(data)
+}
+
+// String formats the message as a single log line.
+func (m LogMessage) String() string {
+	logger := ""
+	if m.Logger != "" {
+		logger = " " + m.Logger + ":"
+	}
+	return fmt.Sprintf("%s [%s] %s:%s %s", m.Time.Format(time.RFC3339), m.Level, m.Server, logger, m.Text())
+}
+
+// logging reports whether server log notifications should be requested.
+func (c *Client) logging() bool {
+	return c.opts.LogHandler != nil || c.opts.LogDir != ""
+}
+
+// enableLogging asks the server to send log notifications at the configured level,
+// if the server supports logging.
+func (c *Client) enableLogging(ctx context.Context, name string, session *mcp.ClientSession) {
+	if !c.logging() {
+		return
+	}
+	init := session.InitializeResult()
+	if init == nil || init.Capabilities == nil || init.Capabilities.Logging == nil {
+		return
+	}
+
+	level := c.opts.LogLevel
+	if level == "" {
+		level = DefaultLogLevel
+	}
+	if err := session.SetLoggingLevel(ctx, &mcp.SetLoggingLevelParams{Level: mcp.LoggingLevel(level)}); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set the log level of MCP server %s: %v\n", name, err)
+	}
+}
+
+// handleLog passes a log notification to the LogHandler and appends it to the server's log file.
+func (c *Client) handleLog(name string, params *mcp.LoggingMessageParams) {
+	message := LogMessage{
+		Server: name,
+		Level:  string(params.Level),
+		Logger: params.Logger,
+		Data:   params.Data,
+		Time:   time.Now(),
+	}
+
+	if c.opts.LogHandler != nil {
+		c.opts.LogHandler(message)
+	}
+	if c.opts.LogDir != "" {
+		if err := appendLog(c.opts.LogDir, message); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write the log of MCP server %s: %v\n", name, err)
+		}
+	}
+}
+
+// appendLog appends the message to <dir>/<server>.log.
+func appendLog(dir string, message LogMessage) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(dir, sanitizeToolName(message.Server)+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = fmt.Fprintln(file, message.String())
+	return err
+}
+
+// stderrWriter returns where the stderr output of the named server goes.
+func (c *Client) stderrWriter(name string) io.Writer {
+	if c.opts.StderrDir == "" {
+		return os.Stderr
+	}
+	w := &stderrLog{dir: c.opts.StderrDir, server: name}
+	if c.opts.StderrTee != nil {
+		return io.MultiWriter(c.opts.StderrTee, w)
+	}
+	return w
+}
+
+// stderrLog appends the stderr output of a server to <dir>/<server>.log. The file
+// is opened for each write, so that nothing is left open when the server exits.
+type stderrLog struct {
+	dir    string
+	server string
+
+	failed sync.Once
+}
+
+func (l *stderrLog) Write(p []byte) (int, error) {
+	if err := l.append(p); err != nil {
+		// Never fail the write, which would break the server's stderr pipe.
+		l.failed.Do(func() {
+			fmt.Fprintf(os.Stderr, "Failed to write the stderr log of MCP server %s: %v\n", l.server, err)
+		})
+	}
+	return len(p), nil
+}
+
+func (l *stderrLog) append(p []byte) error {
+	if err := os.MkdirAll(l.dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(l.dir, sanitizeToolName(l.server)+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(p)
+	return err
+}
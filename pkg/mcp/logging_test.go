@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLoggingServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "chatty", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "work"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		req.Session.Log(ctx, &mcp.LoggingMessageParams{Level: "debug", Data: "too verbose"})
+		req.Session.Log(ctx, &mcp.LoggingMessageParams{Level: "warning", Logger: "worker", Data: "disk almost full"})
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	return server
+}
+
+func TestLogHandler(t *testing.T) {
+	messages := make(chan LogMessage, 10)
+	c := newTestClient()
+	c.opts.LogHandler = func(m LogMessage) { messages <- m }
+	connectTestServer(t, c, "chatty", newLoggingServer())
+
+	_, err := c.CallTool(context.Background(), "chatty__work", map[string]interface{}{})
+	require.NoError(t, err)
+
+	// Debug messages are below the default level and are not sent.
+	var m LogMessage
+	select {
+	case m = <-messages:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no log message received")
+	}
+	assert.Equal(t, "chatty", m.Server)
+	assert.Equal(t, "warning", m.Level)
+	assert.Equal(t, "worker", m.Logger)
+	assert.Equal(t, "disk almost full", m.Text())
+	assert.Empty(t, messages)
+}
+
+func TestLogDir(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestClient()
+	c.opts.LogDir = dir
+	c.opts.LogLevel = "debug"
+	connectTestServer(t, c, "chatty", newLoggingServer())
+
+	_, err := c.CallTool(context.Background(), "chatty__work", map[string]interface{}{})
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "chatty.log")
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && strings.Count(string(data), "\n") == 2
+	}, time.Second, 10*time.Millisecond)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Contains(t, lines[0], "[debug] chatty: too verbose")
+	assert.Contains(t, lines[1], "[warning] chatty: worker: disk almost full")
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStderrDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	var tee syncBuffer
+	c, err := NewClient(context.Background(), &Config{
+		MCPServers: map[string]MCPServer{"proc": testServerConfig()},
+	}, &ClientOptions{StderrDir: dir, StderrTee: &tee})
+	require.NoError(t, err)
+	defer c.Close()
+
+	path := filepath.Join(dir, "proc.log")
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		return err == nil && strings.Contains(string(data), "test server started")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(tee.String(), "test server started")
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,42 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// testServerEnv makes the test binary act as a stdio MCP server, so tests can
+// exercise real process transports without external dependencies.
+const testServerEnv = "PKG_MCP_TEST_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testServerEnv) != "" {
+		runTestServer()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runTestServer() {
+	fmt.Fprintln(os.Stderr, "test server started")
+	server := mcp.NewServer(&mcp.Implementation{Name: "test-server", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "pid", Description: "Returns the server process id"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: strconv.Itoa(os.Getpid())}}}, nil, nil
+	})
+	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
+		os.Exit(1)
+	}
+}
+
+// testServerConfig returns a stdio server config that spawns the test binary as an MCP server.
+func testServerConfig() MCPServer {
+	return MCPServer{
+		Command: os.Args[0],
+		Env:     map[string]string{testServerEnv: "1"},
+	}
+}
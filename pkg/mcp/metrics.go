@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// maxLatencySamples bounds the latencies kept per tool for the percentiles; older
+// samples are overwritten so that long sessions use constant memory.
+const maxLatencySamples = 1024
+
+// ToolMetrics summarizes the calls of one tool made with CallTool.
+type ToolMetrics struct {
+	Server string
+	Tool   string // name on the server
+	Calls  int
+	// Errors counts calls that failed or whose result was marked as an error.
+	Errors int
+	// Latency percentiles over the most recent calls.
+	P50, P90, P99 time.Duration
+	Total         time.Duration // summed latency of all calls
+}
+
+// ErrorRate returns the fraction of calls that failed.
+func (m ToolMetrics) ErrorRate() float64 {
+	if m.Calls == 0 {
+		return 0
+	}
+	return float64(m.Errors) / float64(m.Calls)
+}
+
+// Mean returns the average latency.
+func (m ToolMetrics) Mean() time.Duration {
+	if m.Calls == 0 {
+		return 0
+	}
+	return m.Total / time.Duration(m.Calls)
+}
+
+// toolStats accumulates the calls of one tool.
+type toolStats struct {
+	calls     int
+	errors    int
+	total     time.Duration
+	latencies []time.Duration // ring buffer of the most recent latencies
+}
+
+// recordMetrics counts a tool call and its latency.
+func (c *Client) recordMetrics(server, tool string, latency time.Duration, failed bool) {
+	c.metricsMu.Lock()
+	defer c.metricsMu.Unlock()
+	if c.metrics == nil {
+		c.metrics = make(map[toolRef]*toolStats)
+	}
+	ref := toolRef{server: server, tool: tool}
+	stats, ok := c.metrics[ref]
+	if !ok {
+		stats = &toolStats{}
+		c.metrics[ref] = stats
+	}
+
+	if len(stats.latencies) < maxLatencySamples {
+		stats.latencies = append(stats.latencies, latency)
+	} else {
+		stats.latencies[stats.calls%maxLatencySamples] = latency
+	}
+	stats.calls++
+	stats.total += latency
+	if failed {
+		stats.errors++
+	}
+}
+
+// Metrics returns the call counts, latency percentiles and errors of every tool
+// called so far, sorted by server and tool name.
+func (c *Client) Metrics() []ToolMetrics {
+	c.metricsMu.Lock()
+	defer c.metricsMu.Unlock()
+
+	metrics := make([]ToolMetrics, 0, len(c.metrics))
+	for ref, stats := range c.metrics {
+		sorted := append([]time.Duration(nil), stats.latencies...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		metrics = append(metrics, ToolMetrics{
+			Server: ref.server,
+			Tool:   ref.tool,
+			Calls:  stats.calls,
+			Errors: stats.errors,
+			P50:    percentile(sorted, 0.50),
+			P90:    percentile(sorted, 0.90),
+			P99:    percentile(sorted, 0.99),
+			Total:  stats.total,
+		})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Server != metrics[j].Server {
+			return metrics[i].Server < metrics[j].Server
+		}
+		return metrics[i].Tool < metrics[j].Tool
+	})
+	return metrics
+}
+
+// percentile returns the p-th percentile of sorted latencies using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
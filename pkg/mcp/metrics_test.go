@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	type failArgs struct {
+		Fail bool `json:"fail,omitempty"`
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "maybe_fail"}, func(ctx context.Context, req *mcp.CallToolRequest, args failArgs) (*mcp.CallToolResult, any, error) {
+		if args.Fail {
+			return nil, nil, errors.New("failed")
+		}
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	mcp.AddTool(server, &mcp.Tool{Name: "ok"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "test", server)
+	assert.Empty(t, c.Metrics())
+
+	ctx := context.Background()
+	for _, fail := range []bool{false, true, false, true} {
+		_, err := c.CallTool(ctx, "test__maybe_fail", map[string]any{"fail": fail})
+		require.NoError(t, err)
+	}
+	_, err := c.CallTool(ctx, "test__ok", nil)
+	require.NoError(t, err)
+
+	metrics := c.Metrics()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "maybe_fail", metrics[0].Tool)
+	assert.Equal(t, "test", metrics[0].Server)
+	assert.Equal(t, 4, metrics[0].Calls)
+	assert.Equal(t, 2, metrics[0].Errors)
+	assert.InDelta(t, 0.5, metrics[0].ErrorRate(), 1e-9)
+	assert.Positive(t, metrics[0].P50)
+	assert.LessOrEqual(t, metrics[0].P50, metrics[0].P99)
+	assert.Equal(t, "ok", metrics[1].Tool)
+	assert.Equal(t, 1, metrics[1].Calls)
+	assert.Zero(t, metrics[1].Errors)
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 0.50))
+	assert.Equal(t, 90*time.Millisecond, percentile(latencies, 0.90))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 0.99))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.5))
+	assert.Equal(t, 7*time.Millisecond, percentile([]time.Duration{7 * time.Millisecond}, 0.99))
+}
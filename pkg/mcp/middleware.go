@@ -0,0 +1,30 @@
+package mcp
+
+import "context"
+
+// CallFunc calls a tool by its exposed name, like Client.CallTool.
+type CallFunc func(ctx context.Context, name string, args map[string]any) (*ToolResult, error)
+
+// Middleware intercepts tool calls, e.g. to log, redact arguments or results,
+// require approval or collect metrics. It returns a CallFunc that usually calls
+// next, possibly with changed arguments, and may inspect or replace the result.
+// Returning without calling next rejects the call.
+type Middleware func(next CallFunc) CallFunc
+
+// Use registers middleware around every tool call made with CallTool. The first
+// registered middleware is the outermost: it sees the call first and the result last.
+func (c *Client) Use(middleware ...Middleware) {
+	c.middlewareMu.Lock()
+	defer c.middlewareMu.Unlock()
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// chain wraps call with the registered middleware.
+func (c *Client) chain(call CallFunc) CallFunc {
+	c.middlewareMu.RLock()
+	defer c.middlewareMu.RUnlock()
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		call = c.middleware[i](call)
+	}
+	return call
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUse(t *testing.T) {
+	type echoArgs struct {
+		Text string `json:"text"`
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "echo", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "echo"}, func(ctx context.Context, req *mcp.CallToolRequest, args echoArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: args.Text}}}, nil, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "echo", server)
+
+	var order []string
+	trace := func(label string) Middleware {
+		return func(next CallFunc) CallFunc {
+			return func(ctx context.Context, name string, args map[string]any) (*ToolResult, error) {
+				order = append(order, "before "+label)
+				result, err := next(ctx, name, args)
+				order = append(order, "after "+label)
+				return result, err
+			}
+		}
+	}
+	redact := func(next CallFunc) CallFunc {
+		return func(ctx context.Context, name string, args map[string]any) (*ToolResult, error) {
+			args["text"] = "[redacted]"
+			return next(ctx, name, args)
+		}
+	}
+	c.Use(trace("outer"), trace("inner"), redact)
+
+	result, err := c.CallTool(context.Background(), "echo__echo", map[string]any{"text": "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, "[redacted]", result.String())
+	assert.Equal(t, []string{"before outer", "before inner", "after inner", "after outer"}, order)
+
+	errDenied := errors.New("denied")
+	c.Use(func(next CallFunc) CallFunc {
+		return func(ctx context.Context, name string, args map[string]any) (*ToolResult, error) {
+			return nil, errDenied
+		}
+	})
+	_, err = c.CallTool(context.Background(), "echo__echo", map[string]any{"text": "hi"})
+	assert.ErrorIs(t, err, errDenied)
+}
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultToolNameSeparator joins server and tool names, as in "serverName__toolName".
+const DefaultToolNameSeparator = "__"
+
+// maxToolNameLength is the longest tool name accepted by OpenAI-compatible APIs.
+const maxToolNameLength = 64
+
+// invalidToolNameChars matches characters rejected in tool names by Ollama and
+// OpenAI-compatible APIs, which only accept ^[a-zA-Z0-9_-]{1,64}$.
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// sanitizeToolName replaces characters that are not allowed in tool names with "_".
+func sanitizeToolName(name string) string {
+	return invalidToolNameChars.ReplaceAllString(name, "_")
+}
+
+// toolRef identifies a tool on a specific server.
+type toolRef struct {
+	server string
+	tool   string
+}
+
+// registerAliases resolves the per-server tool aliases of the config and registers
+// them for reverse lookup. Servers and tools are visited in sorted order and the
+// first claim of an alias wins; tools that lose a collision keep their qualified name.
+func (c *Client) registerAliases(servers map[string]MCPServer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, server := range names {
+		tools := make([]string, 0, len(servers[server].Aliases))
+		for tool := range servers[server].Aliases {
+			tools = append(tools, tool)
+		}
+		sort.Strings(tools)
+
+		for _, tool := range tools {
+			alias := servers[server].Aliases[tool]
+			if alias == "" || strings.Contains(alias, c.separator) || sanitizeToolName(alias) != alias || len(alias) > maxToolNameLength {
+				fmt.Fprintf(os.Stderr, "Ignoring invalid alias %q for tool %s on server %s\n", alias, tool, server)
+				continue
+			}
+			if owner, taken := c.toolNames[alias]; taken {
+				fmt.Fprintf(os.Stderr, "Alias %q of %s%s%s collides with %s%s%s, keeping the qualified name\n",
+					alias, server, c.separator, tool, owner.server, c.separator, owner.tool)
+				continue
+			}
+			ref := toolRef{server: server, tool: tool}
+			c.toolNames[alias] = ref
+			c.exposed[ref] = alias
+		}
+	}
+}
+
+// exposedName returns the name under which a tool is advertised to the model and
+// registers it for reverse lookup. Names are stable for the lifetime of the client:
+// when sanitizing makes two names equal, later ones get a numeric suffix.
+func (c *Client) exposedName(server, tool string) string {
+	ref := toolRef{server: server, tool: tool}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name, ok := c.exposed[ref]; ok {
+		return name
+	}
+
+	base := sanitizeToolName(server) + c.separator + sanitizeToolName(tool)
+	if len(base) > maxToolNameLength {
+		base = base[:maxToolNameLength]
+	}
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := c.toolNames[name]; !taken {
+			break
+		}
+		suffix := fmt.Sprintf("_%d", i)
+		name = base[:min(len(base), maxToolNameLength-len(suffix))] + suffix
+	}
+
+	c.toolNames[name] = ref
+	c.exposed[ref] = name
+	return name
+}
+
+// ResolveToolName maps a tool name returned by GetTools back to the server and the
+// tool name on that server. Names that were never advertised are split on the separator.
+func (c *Client) ResolveToolName(name string) (string, string, error) {
+	c.mu.RLock()
+	ref, ok := c.toolNames[name]
+	separator := c.separator
+	c.mu.RUnlock()
+	if ok {
+		return ref.server, ref.tool, nil
+	}
+	return parseToolName(name, separator)
+}
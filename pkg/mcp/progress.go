@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ProgressFunc receives progress notifications emitted by a server while a tool call is running.
+// The total is zero when the server does not know it.
+type ProgressFunc func(progress, total float64, message string)
+
+// CallOption configures a single CallTool invocation.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	onProgress ProgressFunc
+	timeout    time.Duration
+}
+
+// WithProgress registers fn to receive progress notifications for the call.
+func WithProgress(fn ProgressFunc) CallOption {
+	return func(o *callOptions) {
+		o.onProgress = fn
+	}
+}
+
+// WithTimeout bounds the call by d, overriding the server's callTimeout.
+// A call that runs out of time is cancelled and fails with ErrCallTimeout.
+func WithTimeout(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = d
+	}
+}
+
+// ErrCallTimeout is returned by CallTool when the call exceeded its timeout.
+var ErrCallTimeout = errors.New("tool call timed out")
+
+var progressTokenCounter atomic.Int64
+
+// registerProgress assigns a new progress token to fn and returns it with a function that unregisters it.
+func (c *Client) registerProgress(fn ProgressFunc) (string, func()) {
+	token := fmt.Sprintf("progress-%d", progressTokenCounter.Add(1))
+
+	c.progressMu.Lock()
+	if c.progress == nil {
+		c.progress = make(map[string]ProgressFunc)
+	}
+	c.progress[token] = fn
+	c.progressMu.Unlock()
+
+	return token, func() {
+		c.progressMu.Lock()
+		delete(c.progress, token)
+		c.progressMu.Unlock()
+	}
+}
+
+// handleProgress dispatches a progress notification to the callback registered for its token.
+func (c *Client) handleProgress(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+	token, ok := req.Params.ProgressToken.(string)
+	if !ok {
+		return
+	}
+
+	c.progressMu.Lock()
+	fn := c.progress[token]
+	c.progressMu.Unlock()
+
+	if fn != nil {
+		fn(req.Params.Progress, req.Params.Total, req.Params.Message)
+	}
+}
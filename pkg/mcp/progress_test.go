@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type emptyArgs struct{}
+
+func TestCallTool_WithProgress(t *testing.T) {
+	// delivered is closed by the client callback once all updates arrived,
+	// so the tool only returns after the progress was observed.
+	delivered := make(chan struct{})
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "slow", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "work"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		token := req.Params.GetProgressToken()
+		for i := 1; i <= 2; i++ {
+			err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      float64(i),
+				Total:         2,
+				Message:       "step",
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		select {
+		case <-delivered:
+		case <-time.After(time.Second):
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "slow", server)
+
+	var updates []float64
+	_, err := c.CallTool(context.Background(), "slow__work", map[string]interface{}{}, WithProgress(func(progress, total float64, message string) {
+		updates = append(updates, progress)
+		assert.Equal(t, float64(2), total)
+		assert.Equal(t, "step", message)
+		if len(updates) == 2 {
+			close(delivered)
+		}
+	}))
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []float64{1, 2}, updates)
+	assert.Empty(t, c.progress, "progress callback should be unregistered after the call")
+}
+
+func TestCallTool_WithTimeout(t *testing.T) {
+	cancelled := make(chan struct{}, 2)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "hung", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "wait"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		select {
+		case <-ctx.Done():
+			cancelled <- struct{}{}
+		case <-time.After(5 * time.Second):
+		}
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "hung", server)
+
+	start := time.Now()
+	_, err := c.CallTool(context.Background(), "hung__wait", map[string]interface{}{}, WithTimeout(100*time.Millisecond))
+	require.ErrorIs(t, err, ErrCallTimeout)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server was not notified of the cancellation")
+	}
+
+	// Cancelling the caller's context is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	_, err = c.CallTool(ctx, "hung__wait", map[string]interface{}{})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCallTimeout)
+}
@@ -0,0 +1,62 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Prompt is a prompt template offered by one of the connected servers.
+type Prompt struct {
+	Server      string
+	Name        string
+	Description string
+	Arguments   []*mcp.PromptArgument
+}
+
+// ListPrompts fetches prompt templates from all connected servers that advertise the prompts capability.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var allPrompts []Prompt
+
+	for _, serverName := range c.serverNames() {
+		session, ok := c.session(serverName)
+		if !ok || serverCapabilities(session).Prompts == nil {
+			continue
+		}
+
+		for prompt, err := range session.Prompts(ctx, &mcp.ListPromptsParams{}) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to list prompts from server %s: %v\n", serverName, err)
+				break
+			}
+			allPrompts = append(allPrompts, Prompt{
+				Server:      serverName,
+				Name:        prompt.Name,
+				Description: prompt.Description,
+				Arguments:   prompt.Arguments,
+			})
+		}
+	}
+
+	return allPrompts, nil
+}
+
+// GetPrompt renders the named prompt template on the given server with the provided arguments.
+func (c *Client) GetPrompt(ctx context.Context, serverName, name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	session, err := c.liveSession(ctx, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := session.GetPrompt(ctx, &mcp.GetPromptParams{
+		Name:      name,
+		Arguments: args,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+
+	return result, nil
+}
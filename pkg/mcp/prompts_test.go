@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAndGetPrompts(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "review", Version: "1.0.0"}, nil)
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "code_review",
+		Description: "Review a file",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "path", Required: true},
+		},
+	}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return &mcp.GetPromptResult{
+			Messages: []*mcp.PromptMessage{
+				{Role: "user", Content: &mcp.TextContent{Text: "Please review " + req.Params.Arguments["path"]}},
+			},
+		}, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "review", server)
+
+	ctx := context.Background()
+	prompts, err := c.ListPrompts(ctx)
+	require.NoError(t, err)
+	require.Len(t, prompts, 1)
+	assert.Equal(t, "review", prompts[0].Server)
+	assert.Equal(t, "code_review", prompts[0].Name)
+	require.Len(t, prompts[0].Arguments, 1)
+	assert.True(t, prompts[0].Arguments[0].Required)
+
+	result, err := c.GetPrompt(ctx, "review", "code_review", map[string]string{"path": "main.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, "Please review main.go", result.Messages[0].Content.(*mcp.TextContent).Text)
+}
+
+func TestGetPrompt_UnknownServer(t *testing.T) {
+	c := newTestClient()
+	_, err := c.GetPrompt(context.Background(), "missing", "prompt", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package mcp
+
+import (
+	"context"
+)
+
+// DefaultStdioConcurrency is the number of concurrent tool calls sent to a stdio
+// or docker server when MCPServer.MaxConcurrency is zero. Many local servers are
+// written for one client at a time, so calls are queued and sent one by one.
+const DefaultStdioConcurrency = 1
+
+func (s MCPServer) maxConcurrency() int {
+	switch {
+	case s.MaxConcurrency < 0:
+		return 0
+	case s.MaxConcurrency > 0:
+		return s.MaxConcurrency
+	case s.Remote():
+		return 0
+	default:
+		return DefaultStdioConcurrency
+	}
+}
+
+// acquireSlot waits until the server accepts another concurrent tool call and
+// returns a function that releases the slot. Calls are served in the order they
+// arrive; a call whose context ends while it is queued is not sent.
+func (c *Client) acquireSlot(ctx context.Context, name string) (func(), error) {
+	limit := c.serverConfig(name).maxConcurrency()
+	if limit == 0 {
+		return func() {}, nil
+	}
+
+	c.queueMu.Lock()
+	if c.queues == nil {
+		c.queues = make(map[string]chan struct{})
+	}
+	slots, ok := c.queues[name]
+	// A reload may have changed the limit; calls holding a slot of the old queue release it there.
+	if !ok || cap(slots) != limit {
+		slots = make(chan struct{}, limit)
+		c.queues[name] = slots
+	}
+	c.queueMu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
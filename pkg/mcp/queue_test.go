@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maxInFlight calls the slow tool of server n times in parallel and returns the
+// largest number of calls the server handled at once.
+func maxInFlight(t *testing.T, server MCPServer, n int) int32 {
+	t.Helper()
+	var inFlight, peak atomic.Int32
+	s := mcp.NewServer(&mcp.Implementation{Name: "slow", Version: "1.0.0"}, nil)
+	mcp.AddTool(s, &mcp.Tool{Name: "wait"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	c := newTestClient()
+	c.servers["slow"] = server
+	connectTestServer(t, c, "slow", s)
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.CallTool(context.Background(), "slow__wait", nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	return peak.Load()
+}
+
+func TestCallTool_Queueing(t *testing.T) {
+	assert.Equal(t, int32(1), maxInFlight(t, MCPServer{}, 4), "stdio servers get one call at a time")
+	assert.Equal(t, int32(2), maxInFlight(t, MCPServer{MaxConcurrency: 2}, 4))
+	assert.Equal(t, int32(4), maxInFlight(t, MCPServer{MaxConcurrency: -1}, 4))
+}
+
+func TestCallTool_QueueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	s := mcp.NewServer(&mcp.Implementation{Name: "busy", Version: "1.0.0"}, nil)
+	mcp.AddTool(s, &mcp.Tool{Name: "block"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		<-release
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	c := newTestClient()
+	connectTestServer(t, c, "busy", s)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := c.CallTool(context.Background(), "busy__block", nil)
+		assert.NoError(t, err)
+	}()
+	require.Eventually(t, func() bool {
+		c.queueMu.Lock()
+		defer c.queueMu.Unlock()
+		return len(c.queues["busy"]) == 1
+	}, time.Second, time.Millisecond)
+
+	_, err := c.CallTool(context.Background(), "busy__block", nil, WithTimeout(10*time.Millisecond))
+	assert.ErrorIs(t, err, ErrCallTimeout)
+	assert.Contains(t, err.Error(), "queued")
+
+	close(release)
+	<-done
+}
@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultWatchInterval is how often WatchConfig checks the config files for changes.
+const DefaultWatchInterval = 2 * time.Second
+
+// ReloadResult lists the servers affected by Reload.
+type ReloadResult struct {
+	Added   []string
+	Removed []string
+	Changed []string // reconnected because their configuration changed
+}
+
+// Empty reports whether the reload did not affect any server.
+func (r ReloadResult) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// Reload applies a new configuration to a running client: servers that were removed
+// are disconnected, new servers are connected and servers whose configuration changed
+// are reconnected. Unchanged servers keep their sessions. OnToolsChanged is called for
+// every affected server so that callers refresh their tool list with GetTools.
+func (c *Client) Reload(ctx context.Context, config *Config) (ReloadResult, error) {
+	separator := DefaultToolNameSeparator
+	if config.ToolNameSeparator != "" {
+		if sanitizeToolName(config.ToolNameSeparator) != config.ToolNameSeparator {
+			return ReloadResult{}, fmt.Errorf("invalid tool name separator %q: only letters, digits, '_' and '-' are allowed", config.ToolNameSeparator)
+		}
+		separator = config.ToolNameSeparator
+	}
+
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+
+	var result ReloadResult
+	var stale []*mcp.ClientSession
+
+	c.mu.Lock()
+	for name, old := range c.servers {
+		server, ok := config.MCPServers[name]
+		switch {
+		case !ok:
+			result.Removed = append(result.Removed, name)
+		case !reflect.DeepEqual(old, server):
+			result.Changed = append(result.Changed, name)
+		default:
+			continue
+		}
+		// Drop the session before closing it, so that watchSession does not report a crash.
+		if session, ok := c.sessions[name]; ok {
+			stale = append(stale, session)
+		}
+		delete(c.sessions, name)
+		delete(c.servers, name)
+		delete(c.dead, name)
+		delete(c.restarts, name)
+	}
+	for name := range config.MCPServers {
+		if _, ok := c.servers[name]; !ok && !slices.Contains(result.Changed, name) {
+			result.Added = append(result.Added, name)
+		}
+	}
+
+	// Rebuild the tool names, since aliases or the separator may have changed.
+	c.separator = separator
+	c.toolNames = make(map[string]toolRef)
+	c.exposed = make(map[toolRef]string)
+	c.mu.Unlock()
+	c.registerAliases(config.MCPServers)
+
+	for _, session := range stale {
+		session.Close()
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Changed)
+
+	for _, name := range append(append([]string{}, result.Added...), result.Changed...) {
+		server := config.MCPServers[name]
+		c.mu.Lock()
+		c.servers[name] = server
+		c.mu.Unlock()
+		if server.lazy() {
+			continue
+		}
+		if err := c.connectToServer(ctx, name, server); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to connect to MCP server %s: %v\n", name, err)
+		}
+	}
+
+	// Every exposed name may have changed, so drop all cached tool lists.
+	c.InvalidateTools()
+	if c.opts.OnToolsChanged != nil {
+		for _, names := range [][]string{result.Added, result.Removed, result.Changed} {
+			for _, name := range names {
+				c.opts.OnToolsChanged(name)
+			}
+		}
+	}
+	return result, nil
+}
+
+// WatchConfig polls the given config files every interval and calls onChange when
+// one of them is created, modified or deleted. It blocks until ctx is done.
+func WatchConfig(ctx context.Context, paths []string, interval time.Duration, onChange func()) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	last := configFingerprint(paths)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := configFingerprint(paths)
+			if current != last {
+				last = current
+				onChange()
+			}
+		}
+	}
+}
+
+// configFingerprint summarizes the modification time and size of the files.
+func configFingerprint(paths []string) string {
+	var fingerprint string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fingerprint += path + ":missing;"
+			continue
+		}
+		fingerprint += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return fingerprint
+}
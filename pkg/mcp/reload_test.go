@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolNames(t *testing.T, c *Client) []string {
+	t.Helper()
+	tools, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Function.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReload(t *testing.T) {
+	var changed []string
+	c, err := NewClient(context.Background(), &Config{
+		MCPServers: map[string]MCPServer{
+			"keep":   testServerConfig(),
+			"change": testServerConfig(),
+			"remove": testServerConfig(),
+		},
+	}, &ClientOptions{OnToolsChanged: func(server string) { changed = append(changed, server) }})
+	require.NoError(t, err)
+	defer c.Close()
+
+	keepPid, err := callPid(t, c, "keep")
+	require.NoError(t, err)
+	changePid, err := callPid(t, c, "change")
+	require.NoError(t, err)
+
+	updated := testServerConfig()
+	updated.Aliases = map[string]string{"pid": "change_pid"}
+	result, err := c.Reload(context.Background(), &Config{
+		MCPServers: map[string]MCPServer{
+			"keep":   testServerConfig(),
+			"change": updated,
+			"add":    testServerConfig(),
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"add"}, result.Added)
+	assert.Equal(t, []string{"remove"}, result.Removed)
+	assert.Equal(t, []string{"change"}, result.Changed)
+	assert.ElementsMatch(t, []string{"add", "remove", "change"}, changed)
+
+	assert.Equal(t, []string{"add__pid", "change_pid", "keep__pid"}, toolNames(t, c))
+
+	// The unchanged server keeps its process, the changed one is respawned.
+	pid, err := callPid(t, c, "keep")
+	require.NoError(t, err)
+	assert.Equal(t, keepPid, pid)
+
+	called, err := c.CallTool(context.Background(), "change_pid", map[string]interface{}{})
+	require.NoError(t, err)
+	newPid, err := strconv.Atoi(called.Text())
+	require.NoError(t, err)
+	assert.NotEqual(t, changePid, newPid)
+
+	_, err = callPid(t, c, "remove")
+	assert.Error(t, err)
+
+	c.mu.RLock()
+	assert.Empty(t, c.dead, "closing replaced sessions must not be reported as crashes")
+	c.mu.RUnlock()
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 10)
+	go WatchConfig(ctx, []string{path}, 10*time.Millisecond, func() { changes <- struct{}{} })
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, os.WriteFile(path, []byte(`{"mcpServers": {}}`), 0644))
+
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change was not detected")
+	}
+}
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Resource is a resource published by one of the connected servers.
+type Resource struct {
+	Server      string
+	URI         string
+	Name        string
+	Description string
+	MIMEType    string
+}
+
+// ListResources fetches resources from all connected servers that advertise the resources capability.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var allResources []Resource
+
+	for _, serverName := range c.serverNames() {
+		session, ok := c.session(serverName)
+		if !ok || serverCapabilities(session).Resources == nil {
+			continue
+		}
+
+		for resource, err := range session.Resources(ctx, &mcp.ListResourcesParams{}) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to list resources from server %s: %v\n", serverName, err)
+				break
+			}
+			allResources = append(allResources, Resource{
+				Server:      serverName,
+				URI:         resource.URI,
+				Name:        resource.Name,
+				Description: resource.Description,
+				MIMEType:    resource.MIMEType,
+			})
+		}
+	}
+
+	return allResources, nil
+}
+
+// ReadResource reads the resource identified by uri from the given server.
+func (c *Client) ReadResource(ctx context.Context, serverName, uri string) ([]*mcp.ResourceContents, error) {
+	session, err := c.liveSession(ctx, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource: %w", err)
+	}
+
+	return result.Contents, nil
+}
+
+// serverCapabilities returns the capabilities advertised by the server during initialization.
+func serverCapabilities(session *mcp.ClientSession) *mcp.ServerCapabilities {
+	result := session.InitializeResult()
+	if result == nil || result.Capabilities == nil {
+		return &mcp.ServerCapabilities{}
+	}
+	return result.Capabilities
+}
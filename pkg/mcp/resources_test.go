@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAndReadResources(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "docs", Version: "1.0.0"}, nil)
+	server.AddResource(&mcp.Resource{
+		URI:      "file:///README.md",
+		Name:     "README.md",
+		MIMEType: "text/markdown",
+	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{
+				{URI: req.Params.URI, MIMEType: "text/markdown", Text: "# Hello"},
+			},
+		}, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "docs", server)
+
+	ctx := context.Background()
+	resources, err := c.ListResources(ctx)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "docs", resources[0].Server)
+	assert.Equal(t, "file:///README.md", resources[0].URI)
+	assert.Equal(t, "text/markdown", resources[0].MIMEType)
+
+	contents, err := c.ReadResource(ctx, "docs", "file:///README.md")
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+	assert.Equal(t, "# Hello", contents[0].Text)
+}
+
+func TestListResources_SkipsServersWithoutCapability(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "tools-only", Version: "1.0.0"}, nil)
+
+	c := newTestClient()
+	connectTestServer(t, c, "tools-only", server)
+
+	resources, err := c.ListResources(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, resources)
+}
+
+func TestReadResource_UnknownServer(t *testing.T) {
+	c := newTestClient()
+	_, err := c.ReadResource(context.Background(), "missing", "file:///x")
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ToolResult is the typed result of a tool call.
+type ToolResult struct {
+	// Content holds the content blocks returned by the tool (text, images, resources, ...).
+	Content []mcp.Content
+	// StructuredContent is the structured output of the tool, if it returned one.
+	StructuredContent any
+	// IsError reports whether the tool itself failed; the error message is in Content.
+	IsError bool
+}
+
+func newToolResult(result *mcp.CallToolResult) *ToolResult {
+	return &ToolResult{
+		Content:           result.Content,
+		StructuredContent: result.StructuredContent,
+		IsError:           result.IsError,
+	}
+}
+
+// Text concatenates the text content blocks.
+func (r *ToolResult) Text() string {
+	var texts []string
+	for _, content := range r.Content {
+		if text, ok := content.(*mcp.TextContent); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// String returns the result as it should be fed back to the model: the structured
+// content as compact JSON when present, otherwise the content blocks as text with
+// placeholders for non-text content.
+func (r *ToolResult) String() string {
+	if r.StructuredContent != nil && !r.IsError {
+		if data, err := json.Marshal(r.StructuredContent); err == nil {
+			return string(data)
+		}
+	}
+
+	parts := make([]string, 0, len(r.Content))
+	for _, content := range r.Content {
+		parts = append(parts, contentString(content))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// contentString describes a single content block as text.
+func contentString(content mcp.Content) string {
+	switch c := content.(type) {
+	case *mcp.TextContent:
+		return c.Text
+	case *mcp.ImageContent:
+		return fmt.Sprintf("[image: %s, %d bytes]", c.MIMEType, len(c.Data))
+	case *mcp.AudioContent:
+		return fmt.Sprintf("[audio: %s, %d bytes]", c.MIMEType, len(c.Data))
+	case *mcp.ResourceLink:
+		return fmt.Sprintf("[resource link: %s]", c.URI)
+	case *mcp.EmbeddedResource:
+		if c.Resource == nil {
+			return "[resource]"
+		}
+		if c.Resource.Text != "" {
+			return c.Resource.Text
+		}
+		return fmt.Sprintf("[resource: %s, %s, %d bytes]", c.Resource.URI, c.Resource.MIMEType, len(c.Resource.Blob))
+	default:
+		return fmt.Sprintf("[%T content]", content)
+	}
+}
+
+// Image is an image returned by a tool.
+type Image struct {
+	MIMEType string
+	Data     []byte
+}
+
+// Images returns the decoded images of the result, including embedded image resources.
+func (r *ToolResult) Images() []Image {
+	var images []Image
+	for _, content := range r.Content {
+		switch c := content.(type) {
+		case *mcp.ImageContent:
+			images = append(images, Image{MIMEType: c.MIMEType, Data: c.Data})
+		case *mcp.EmbeddedResource:
+			if c.Resource != nil && len(c.Resource.Blob) > 0 && strings.HasPrefix(c.Resource.MIMEType, "image/") {
+				images = append(images, Image{MIMEType: c.Resource.MIMEType, Data: c.Resource.Blob})
+			}
+		}
+	}
+	return images
+}
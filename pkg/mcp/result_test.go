@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type weatherOutput struct {
+	City        string  `json:"city"`
+	Temperature float64 `json:"temperature"`
+}
+
+func TestCallTool_StructuredResult(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "weather", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "current"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, weatherOutput, error) {
+		return nil, weatherOutput{City: "Hangzhou", Temperature: 21.5}, nil
+	})
+	mcp.AddTool(server, &mcp.Tool{Name: "broken"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: "sensor offline"}}}, nil, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "weather", server)
+
+	result, err := c.CallTool(context.Background(), "weather__current", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.NotNil(t, result.StructuredContent)
+	assert.JSONEq(t, `{"city":"Hangzhou","temperature":21.5}`, result.String())
+
+	result, err = c.CallTool(context.Background(), "weather__broken", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "sensor offline", result.String())
+}
+
+func TestToolResult_String(t *testing.T) {
+	result := &ToolResult{Content: []mcp.Content{
+		&mcp.TextContent{Text: "screenshot taken"},
+		&mcp.ImageContent{MIMEType: "image/png", Data: []byte{1, 2, 3}},
+		&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///a.txt", Text: "hello"}},
+	}}
+	assert.Equal(t, "screenshot taken\n[image: image/png, 3 bytes]\nhello", result.String())
+	assert.Equal(t, "screenshot taken", result.Text())
+}
+
+func TestCallTool_ImageContent(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+	server := mcp.NewServer(&mcp.Implementation{Name: "camera", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "snap"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{
+			&mcp.TextContent{Text: "done"},
+			&mcp.ImageContent{MIMEType: "image/png", Data: png},
+			&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file:///b.jpg", MIMEType: "image/jpeg", Blob: []byte{0xff, 0xd8}}},
+		}}, nil, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "camera", server)
+
+	result, err := c.CallTool(context.Background(), "camera__snap", map[string]interface{}{})
+	require.NoError(t, err)
+
+	images := result.Images()
+	require.Len(t, images, 2)
+	assert.Equal(t, Image{MIMEType: "image/png", Data: png}, images[0])
+	assert.Equal(t, "image/jpeg", images[1].MIMEType)
+	assert.NotContains(t, result.String(), "iVBOR", "image data must not be inlined as base64")
+}
@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultCallRetries is the number of times a tool call that failed with a
+// transient transport error is retried when MCPServer.CallRetries is zero. Only
+// calls of tools in the server's AutoApprove list, which are expected to be
+// read-only, are retried by default: a failed call of another tool may already
+// have taken effect on the server.
+const DefaultCallRetries = 2
+
+// StatusError is returned for a request that an HTTP server rejected with a
+// non-2xx status, such as the POST of a message to an SSE server.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server responded with %s", e.Status)
+}
+
+// statusTransport turns rejected POSTs into a *StatusError, because the SSE
+// transport of the SDK only reports them by their status text.
+type statusTransport struct {
+	Transport http.RoundTripper // nil means http.DefaultTransport
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil || req.Method != http.MethodPost || resp.StatusCode/100 == 2 {
+		return resp, err
+	}
+	resp.Body.Close()
+	return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+}
+
+// IsTransient reports whether err is a transport-level failure that is likely to
+// go away when the call is retried, such as a dropped or refused connection or a
+// 429, 502, 503 or 504 from an SSE server. Errors returned by the server itself,
+// such as JSON-RPC errors, and cancelled or timed out calls are not transient.
+func IsTransient(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, ErrCallTimeout), errors.Is(err, ErrInvalidArguments):
+		return false
+	case errors.Is(err, mcp.ErrConnectionClosed), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// Failures to dial, read or write a connection; other net.Errors, such as
+	// TLS certificate errors wrapped in a *url.Error, do not go away on a retry.
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// callRetries returns how often a failed call of the tool, by its name on the
+// server, is retried.
+func (s MCPServer) callRetries(tool string) int {
+	switch {
+	case s.CallRetries < 0:
+		return 0
+	case s.CallRetries > 0:
+		return s.CallRetries
+	case s.autoApproves(tool):
+		return DefaultCallRetries
+	default:
+		return 0
+	}
+}
+
+// retryable classifies call errors with ClientOptions.Retryable, or IsTransient.
+func (c *Client) retryable(err error) bool {
+	if c.opts.Retryable != nil {
+		return c.opts.Retryable(err)
+	}
+	return IsTransient(err)
+}
+
+// callWithRetry calls the tool, retrying transient failures with exponential
+// backoff. A connection found closed is first re-established, which respawns
+// crashed stdio servers.
+func (c *Client) callWithRetry(ctx context.Context, serverName string, session *mcp.ClientSession, params *mcp.CallToolParams) (*mcp.CallToolResult, error) {
+	server := c.serverConfig(serverName)
+	retries := server.callRetries(params.Name)
+	backoff := server.retryBackoff()
+
+	for attempt := 0; ; attempt++ {
+		result, err := session.CallTool(ctx, params)
+		if err == nil || attempt >= retries || ctx.Err() != nil || !c.retryable(err) {
+			return result, err
+		}
+
+		if isConnectionClosed(err) {
+			// Reconnect right away: respawning a crashed server or dialing a remote one has its own backoff.
+			c.markDead(serverName, session)
+		} else {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return nil, err
+			}
+		}
+		var sessionErr error
+		if session, sessionErr = c.liveSession(ctx, serverName); sessionErr != nil {
+			return nil, sessionErr
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{mcp.ErrConnectionClosed, true},
+		{fmt.Errorf("calling tool: %w", syscall.ECONNRESET), true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{&url.Error{Op: "Post", Err: &StatusError{StatusCode: 503, Status: "503 Service Unavailable"}}, true},
+		{fmt.Errorf("calling tool: %w", &StatusError{StatusCode: 429, Status: "429 Too Many Requests"}), true},
+		{&StatusError{StatusCode: 400, Status: "400 Bad Request"}, false},
+		// Only the typed status counts, not text that looks like one.
+		{errors.New("failed to write: 503 Service Unavailable"), false},
+		{&url.Error{Op: "Get", Err: errors.New("x509: certificate signed by unknown authority")}, false},
+		{context.Canceled, false},
+		{fmt.Errorf("%w: slow", ErrCallTimeout), false},
+		{errors.New("unknown tool"), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.transient, IsTransient(tt.err), "%v", tt.err)
+	}
+}
+
+func TestCallTool_RetriesTransientErrors(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "flaky", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "ping"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "pong"}}}, nil, nil
+	})
+	var writes atomic.Int32
+	mcp.AddTool(server, &mcp.Tool{Name: "write"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		writes.Add(1)
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "written"}}}, nil, nil
+	})
+
+	// Reject the POSTs of tools/call requests with 503 while failures is positive.
+	var failures atomic.Int32
+	handler := mcp.NewSSEHandler(func(*http.Request) *mcp.Server { return server }, nil)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if bytes.Contains(body, []byte(`"tools/call"`)) && failures.Add(-1) >= 0 {
+				http.Error(w, "overloaded", http.StatusServiceUnavailable)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer httpServer.Close()
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, &Config{
+		MCPServers: map[string]MCPServer{
+			"flaky": {Type: "sse", URL: httpServer.URL, RetryBackoff: Duration(time.Millisecond), MaxRestarts: 10, AutoApprove: []string{"ping"}},
+		},
+	}, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	failures.Store(2)
+	result, err := c.CallTool(ctx, "flaky__ping", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", result.String())
+
+	// More failures than retries surface the error.
+	failures.Store(DefaultCallRetries + 1)
+	_, err = c.CallTool(ctx, "flaky__ping", nil)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+
+	// Tools that are not auto-approved may not be idempotent and are not retried by default.
+	failures.Store(1)
+	_, err = c.CallTool(ctx, "flaky__write", nil)
+	assert.Error(t, err)
+	assert.Zero(t, writes.Load())
+
+	// A custom classification can disable retries.
+	c.opts.Retryable = func(error) bool { return false }
+	failures.Store(1)
+	_, err = c.CallTool(ctx, "flaky__ping", nil)
+	assert.Error(t, err)
+	failures.Store(0)
+}
+
+func TestCallRetries(t *testing.T) {
+	readOnly := MCPServer{AutoApprove: []string{"read_*"}}
+	assert.Equal(t, DefaultCallRetries, readOnly.callRetries("read_file"))
+	assert.Zero(t, readOnly.callRetries("write_file"))
+
+	idempotent := MCPServer{CallRetries: 3}
+	assert.Equal(t, 3, idempotent.callRetries("write_file"))
+
+	disabled := MCPServer{CallRetries: -1, AutoApprove: []string{"*"}}
+	assert.Zero(t, disabled.callRetries("read_file"))
+}
@@ -0,0 +1,21 @@
+package mcp
+
+import (
+	"path/filepath"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/workspace"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// roots converts the workspace directories in ClientOptions.Roots to MCP roots.
+func (c *Client) roots() []*mcp.Root {
+	roots := make([]*mcp.Root, 0, len(c.opts.Roots))
+	for _, dir := range c.opts.Roots {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		roots = append(roots, &mcp.Root{URI: workspace.FileURI(abs), Name: filepath.Base(abs)})
+	}
+	return roots
+}
@@ -0,0 +1,35 @@
+package mcp
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootsAreAdvertised(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "fs", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "roots"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		result, err := req.Session.ListRoots(ctx, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var uris []string
+		for _, root := range result.Roots {
+			uris = append(uris, root.URI)
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: strings.Join(uris, "\n")}}}, nil, nil
+	})
+
+	workspace := t.TempDir()
+	c := newClient(&ClientOptions{Roots: []string{workspace}})
+	connectTestServer(t, c, "fs", server)
+
+	result, err := c.CallTool(context.Background(), "fs__roots", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.ToSlash(workspace), result.Text())
+}
@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type askArgs struct {
+	Question string `json:"question"`
+}
+
+func TestSamplingHandler(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "asker", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "ask"}, func(ctx context.Context, req *mcp.CallToolRequest, args askArgs) (*mcp.CallToolResult, any, error) {
+		result, err := req.Session.CreateMessage(ctx, &mcp.CreateMessageParams{
+			MaxTokens: 100,
+			Messages: []*mcp.SamplingMessage{
+				{Role: "user", Content: &mcp.TextContent{Text: args.Question}},
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &mcp.CallToolResult{Content: []mcp.Content{result.Content}}, nil, nil
+	})
+
+	var gotServer string
+	c := newTestClient()
+	c.opts.SamplingHandler = func(ctx context.Context, server string, params *mcp.CreateMessageParams) (*mcp.CreateMessageResult, error) {
+		gotServer = server
+		question := params.Messages[0].Content.(*mcp.TextContent).Text
+		return &mcp.CreateMessageResult{
+			Model:   "test-model",
+			Role:    "assistant",
+			Content: &mcp.TextContent{Text: "answer to " + question},
+		}, nil
+	}
+	connectTestServer(t, c, "asker", server)
+
+	result, err := c.CallTool(context.Background(), "asker__ask", map[string]interface{}{"question": "ping"})
+	require.NoError(t, err)
+
+	require.Len(t, result.Content, 1)
+	assert.Equal(t, "answer to ping", result.Text())
+	assert.Equal(t, "asker", gotServer)
+}
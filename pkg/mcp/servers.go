@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ServerInfo describes a configured or connected server as reported by ListServers.
+type ServerInfo struct {
+	Name  string // name in the config
+	Type  string // "stdio", "docker", "sse" or "ws"
+	State string // StateConnected, StateDead or StateLazy
+
+	// Implementation and Version are the name and version the server reported when
+	// connecting; they are empty for servers that are not connected.
+	Implementation  string
+	Version         string
+	ProtocolVersion string
+	Instructions    string
+
+	// Capabilities lists the features the server declared, e.g. "tools", "prompts",
+	// "resources" or "logging", with " (listChanged)" or " (subscribe)" where applicable.
+	Capabilities []string
+
+	// ToolCount is the number of tools the server offers, or -1 if it is unknown
+	// because the server is disconnected or failed to list them.
+	ToolCount int
+}
+
+// ListServers describes all configured or connected servers, sorted by name.
+// Tool counts of connected servers come from the tool cache when possible.
+func (c *Client) ListServers(ctx context.Context) []ServerInfo {
+	statuses := c.Status()
+	infos := make([]ServerInfo, 0, len(statuses))
+	for _, status := range statuses {
+		info := ServerInfo{Name: status.Name, Type: status.Type, State: status.State, ToolCount: -1}
+		server := c.serverConfig(status.Name)
+
+		if status.State == StateLazy {
+			if server.lazy() {
+				info.ToolCount = len(server.Tools)
+			}
+			infos = append(infos, info)
+			continue
+		}
+
+		session, ok := c.session(status.Name)
+		if !ok {
+			infos = append(infos, info)
+			continue
+		}
+		if init := session.InitializeResult(); init != nil {
+			if init.ServerInfo != nil {
+				info.Implementation = init.ServerInfo.Name
+				info.Version = init.ServerInfo.Version
+			}
+			info.ProtocolVersion = init.ProtocolVersion
+			info.Instructions = init.Instructions
+			info.Capabilities = capabilityNames(init.Capabilities)
+		}
+
+		if status.State == StateConnected {
+			if tools, ok := c.cachedServerTools(status.Name, session); ok {
+				info.ToolCount = len(tools)
+			} else if tools, err := c.listServerTools(ctx, status.Name, server, session); err == nil {
+				info.ToolCount = len(tools)
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// capabilityNames lists the declared server capabilities.
+func capabilityNames(caps *mcp.ServerCapabilities) []string {
+	if caps == nil {
+		return nil
+	}
+	var names []string
+	if caps.Tools != nil {
+		names = append(names, withFlags("tools", caps.Tools.ListChanged, false))
+	}
+	if caps.Prompts != nil {
+		names = append(names, withFlags("prompts", caps.Prompts.ListChanged, false))
+	}
+	if caps.Resources != nil {
+		names = append(names, withFlags("resources", caps.Resources.ListChanged, caps.Resources.Subscribe))
+	}
+	if caps.Logging != nil {
+		names = append(names, "logging")
+	}
+	if caps.Completions != nil {
+		names = append(names, "completions")
+	}
+	return names
+}
+
+func withFlags(name string, listChanged, subscribe bool) string {
+	switch {
+	case listChanged && subscribe:
+		return name + " (listChanged, subscribe)"
+	case listChanged:
+		return name + " (listChanged)"
+	case subscribe:
+		return name + " (subscribe)"
+	}
+	return name
+}
@@ -0,0 +1,43 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListServers(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "files", Version: "2.3.0"}, &mcp.ServerOptions{Instructions: "Use read first."})
+	for _, name := range []string{"read", "write"} {
+		mcp.AddTool(server, &mcp.Tool{Name: name}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	}
+
+	c := newTestClient()
+	connectTestServer(t, c, "fs", server)
+
+	servers := c.ListServers(context.Background())
+	require.Len(t, servers, 1)
+	info := servers[0]
+	assert.Equal(t, "fs", info.Name)
+	assert.Equal(t, StateConnected, info.State)
+	assert.Equal(t, "files", info.Implementation)
+	assert.Equal(t, "2.3.0", info.Version)
+	assert.Equal(t, "Use read first.", info.Instructions)
+	assert.NotEmpty(t, info.ProtocolVersion)
+	assert.Contains(t, info.Capabilities, "tools (listChanged)")
+	assert.Contains(t, info.Capabilities, "logging")
+	assert.Equal(t, 2, info.ToolCount)
+}
+
+func TestCapabilityNames(t *testing.T) {
+	assert.Nil(t, capabilityNames(nil))
+	assert.Equal(t, []string{"tools", "resources (listChanged, subscribe)"}, capabilityNames(&mcp.ServerCapabilities{
+		Tools:     &mcp.ToolCapabilities{},
+		Resources: &mcp.ResourceCapabilities{ListChanged: true, Subscribe: true},
+	}))
+}
@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultMaxRestarts is the number of times a crashed stdio server is respawned
+// when MCPServer.MaxRestarts is zero.
+const DefaultMaxRestarts = 3
+
+// serverNames returns the names of all configured or connected servers in a stable order.
+func (c *Client) serverNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.servers))
+	for name := range c.servers {
+		names = append(names, name)
+	}
+	for name := range c.sessions {
+		if _, ok := c.servers[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// serverConfig returns the configuration the named server was connected with.
+func (c *Client) serverConfig(name string) MCPServer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.servers[name]
+}
+
+// session returns the current session of the named server.
+func (c *Client) session(name string) (*mcp.ClientSession, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	session, ok := c.sessions[name]
+	return session, ok
+}
+
+// setSession registers a newly connected session and starts watching it for crashes.
+func (c *Client) setSession(name string, session *mcp.ClientSession) {
+	c.mu.Lock()
+	c.sessions[name] = session
+	delete(c.dead, name)
+	c.mu.Unlock()
+
+	go c.watchSession(name, session)
+}
+
+// watchSession marks the server as dead once its connection is closed unexpectedly.
+func (c *Client) watchSession(name string, session *mcp.ClientSession) {
+	err := session.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.sessions[name] != session {
+		return
+	}
+	c.dead[name] = true
+	fmt.Fprintf(os.Stderr, "MCP server %s exited: %v\n", name, err)
+}
+
+// liveSession returns the session of the named server, connecting lazy servers on
+// first use and transparently respawning crashed stdio servers within their restart limit.
+func (c *Client) liveSession(ctx context.Context, name string) (*mcp.ClientSession, error) {
+	c.mu.RLock()
+	session, ok := c.sessions[name]
+	dead := c.dead[name]
+	server, known := c.servers[name]
+	c.mu.RUnlock()
+
+	if !ok {
+		if known && server.lazy() {
+			return c.connectLazy(ctx, name, server)
+		}
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	if !dead {
+		return session, nil
+	}
+	return c.restart(ctx, name)
+}
+
+// connectLazy connects a lazy server on its first use.
+func (c *Client) connectLazy(ctx context.Context, name string, server MCPServer) (*mcp.ClientSession, error) {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+
+	// Another caller may have connected the server while we were waiting.
+	if session, ok := c.session(name); ok {
+		return session, nil
+	}
+	if err := c.connectToServer(ctx, name, server); err != nil {
+		return nil, fmt.Errorf("failed to connect to server %s: %w", name, err)
+	}
+
+	session, _ := c.session(name)
+	return session, nil
+}
+
+// restart respawns the named server, or reconnects to a remote one, and re-runs the MCP handshake.
+func (c *Client) restart(ctx context.Context, name string) (*mcp.ClientSession, error) {
+	c.restartMu.Lock()
+	defer c.restartMu.Unlock()
+
+	c.mu.Lock()
+	server, known := c.servers[name]
+	session := c.sessions[name]
+	dead := c.dead[name]
+	restarts := c.restarts[name]
+	c.mu.Unlock()
+
+	// Another caller may have restarted the server while we were waiting.
+	if !dead {
+		return session, nil
+	}
+	if !known {
+		return nil, fmt.Errorf("server %s is disconnected", name)
+	}
+	if limit := server.maxRestarts(); restarts >= limit {
+		return nil, fmt.Errorf("server %s crashed and reached its restart limit (%d)", name, limit)
+	}
+
+	c.mu.Lock()
+	c.restarts[name]++
+	c.mu.Unlock()
+
+	action := "Restarting"
+	if server.Remote() {
+		action = "Reconnecting to"
+	}
+	fmt.Fprintf(os.Stderr, "%s MCP server %s (restart %d/%d)\n", action, name, restarts+1, server.maxRestarts())
+	if err := c.connectToServer(ctx, name, server); err != nil {
+		return nil, fmt.Errorf("failed to restart server %s: %w", name, err)
+	}
+
+	session, _ = c.session(name)
+	return session, nil
+}
+
+// markDead flags the session as dead if it is still the current session of the server.
+func (c *Client) markDead(name string, session *mcp.ClientSession) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sessions[name] == session {
+		c.dead[name] = true
+	}
+}
+
+// isConnectionClosed reports whether err means the server connection is gone.
+func isConnectionClosed(err error) bool {
+	return errors.Is(err, mcp.ErrConnectionClosed)
+}
@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func callPid(t *testing.T, c *Client, name string) (int, error) {
+	t.Helper()
+	result, err := c.CallTool(context.Background(), name+"__pid", map[string]interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(result.Text())
+	require.NoError(t, err)
+	return pid, nil
+}
+
+func killServer(t *testing.T, c *Client, name string, pid int) {
+	t.Helper()
+	process, err := os.FindProcess(pid)
+	require.NoError(t, err)
+	require.NoError(t, process.Kill())
+
+	assert.Eventually(t, func() bool {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.dead[name]
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCrashedStdioServerIsRestarted(t *testing.T) {
+	c, err := NewClient(context.Background(), &Config{
+		MCPServers: map[string]MCPServer{"proc": testServerConfig()},
+	}, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	firstPid, err := callPid(t, c, "proc")
+	require.NoError(t, err)
+
+	killServer(t, c, "proc", firstPid)
+
+	secondPid, err := callPid(t, c, "proc")
+	require.NoError(t, err)
+	assert.NotEqual(t, firstPid, secondPid)
+	assert.Equal(t, 1, c.restarts["proc"])
+}
+
+func TestCrashedStdioServer_RestartLimit(t *testing.T) {
+	server := testServerConfig()
+	server.MaxRestarts = -1
+
+	c, err := NewClient(context.Background(), &Config{
+		MCPServers: map[string]MCPServer{"proc": server},
+	}, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	pid, err := callPid(t, c, "proc")
+	require.NoError(t, err)
+
+	killServer(t, c, "proc", pid)
+
+	_, err = callPid(t, c, "proc")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "restart limit")
+}
+
+func TestLazyServer_ConnectsOnFirstCall(t *testing.T) {
+	server := testServerConfig()
+	server.Lazy = true
+	server.Tools = []ToolManifest{{Name: "pid", Description: "Returns the server process id"}}
+
+	c, err := NewClient(context.Background(), &Config{
+		MCPServers: map[string]MCPServer{"proc": server},
+	}, nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, connected := c.session("proc")
+	assert.False(t, connected)
+
+	tools, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "proc__pid", tools[0].Function.Name)
+	assert.Equal(t, "object", tools[0].Function.Parameters.Type)
+
+	pid, err := callPid(t, c, "proc")
+	require.NoError(t, err)
+	assert.NotZero(t, pid)
+
+	_, connected = c.session("proc")
+	assert.True(t, connected)
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultCloseTimeout bounds how long Close waits for the servers to shut down.
+const DefaultCloseTimeout = 10 * time.Second
+
+// ShutdownError reports the servers that did not shut down cleanly.
+type ShutdownError struct {
+	// Failed holds the error of each server, by name. Servers that were still
+	// shutting down at the deadline have an error wrapping the context's error.
+	Failed map[string]error
+}
+
+// Servers returns the names of the failed servers in alphabetical order.
+func (e *ShutdownError) Servers() []string {
+	return slices.Sorted(maps.Keys(e.Failed))
+}
+
+func (e *ShutdownError) Error() string {
+	parts := make([]string, 0, len(e.Failed))
+	for _, name := range e.Servers() {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e.Failed[name]))
+	}
+	return "failed to shut down MCP servers: " + strings.Join(parts, "; ")
+}
+
+// Close closes all connections, waiting at most DefaultCloseTimeout. See Shutdown.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCloseTimeout)
+	defer cancel()
+	return c.Shutdown(ctx)
+}
+
+// Shutdown closes the connections to all servers concurrently, so that a wedged
+// server does not delay the others. It returns a *ShutdownError listing the
+// servers that failed to close or were still closing when ctx was done; those
+// are left to finish closing in the background.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	sessions := maps.Clone(c.sessions)
+	c.mu.Unlock()
+
+	type closed struct {
+		name string
+		err  error
+	}
+	results := make(chan closed, len(sessions))
+	for name, session := range sessions {
+		go func() {
+			results <- closed{name: name, err: session.Close()}
+		}()
+	}
+
+	failed := make(map[string]error)
+	pending := len(sessions)
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			delete(sessions, r.name)
+			if r.err != nil {
+				failed[r.name] = r.err
+			}
+		case <-ctx.Done():
+			for name := range sessions {
+				failed[name] = fmt.Errorf("still shutting down: %w", ctx.Err())
+			}
+			pending = 0
+		}
+	}
+	if len(failed) > 0 {
+		return &ShutdownError{Failed: failed}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wedgedTransport returns connections whose Close blocks until release is closed.
+type wedgedTransport struct {
+	mcp.Transport
+	release chan struct{}
+}
+
+func (t *wedgedTransport) Connect(ctx context.Context) (mcp.Connection, error) {
+	conn, err := t.Transport.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wedgedConn{Connection: conn, release: t.release}, nil
+}
+
+type wedgedConn struct {
+	mcp.Connection
+	release chan struct{}
+}
+
+func (c *wedgedConn) Close() error {
+	<-c.release
+	return c.Connection.Close()
+}
+
+func TestShutdown_ReportsWedgedServers(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient()
+	connectTestServer(t, c, "healthy", mcp.NewServer(&mcp.Implementation{Name: "healthy", Version: "1.0.0"}, nil))
+
+	release := make(chan struct{})
+	defer close(release)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	server := mcp.NewServer(&mcp.Implementation{Name: "wedged", Version: "1.0.0"}, nil)
+	_, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	session, err := c.newSDKClient("wedged").Connect(ctx, &wedgedTransport{Transport: clientTransport, release: release}, nil)
+	require.NoError(t, err)
+	c.setSession("wedged", session)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = c.Shutdown(shutdownCtx)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	var shutdownErr *ShutdownError
+	require.ErrorAs(t, err, &shutdownErr)
+	assert.Equal(t, []string{"wedged"}, shutdownErr.Servers())
+	assert.True(t, errors.Is(shutdownErr.Failed["wedged"], context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "wedged: still shutting down")
+}
+
+func TestClose_NoServers(t *testing.T) {
+	assert.NoError(t, newTestClient().Close())
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// customTLS reports whether the server needs TLS settings other than the defaults.
+func (s MCPServer) customTLS() bool {
+	return s.TLSCAFile != "" || s.TLSCertFile != "" || s.TLSKeyFile != "" || s.InsecureSkipVerify
+}
+
+// tlsConfig builds the TLS configuration of a remote server. The CA file is
+// added to the system roots, so that public servers remain reachable.
+func (s MCPServer) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	}
+
+	if s.TLSCAFile != "" {
+		pem, err := os.ReadFile(s.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tlsCaFile: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tlsCaFile %s contains no PEM certificates", s.TLSCAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (s.TLSCertFile == "") != (s.TLSKeyFile == "") {
+		return nil, errors.New("tlsCertFile and tlsKeyFile must be set together")
+	}
+	if s.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.TLSCertFile, s.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// baseTransport returns the HTTP transport of a remote server with its TLS settings applied.
+func (s MCPServer) baseTransport() (http.RoundTripper, error) {
+	if !s.customTLS() {
+		return http.DefaultTransport, nil
+	}
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertPEM writes the certificate to a PEM file in dir and returns its path.
+func writeCertPEM(t *testing.T, dir, name string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return path
+}
+
+// newClientCert creates a self-signed client certificate and returns it with the paths of its PEM files.
+func newClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "coding-agent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return cert, writeCertPEM(t, dir, "client.pem", der), keyPath
+}
+
+func TestNewHTTPClient_CustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caFile := writeCertPEM(t, t.TempDir(), "ca.pem", server.Certificate().Raw)
+
+	// Without the CA the self-signed certificate is rejected.
+	client, err := newHTTPClient(MCPServer{Type: "sse", Headers: map[string]string{"X-Test": "1"}})
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	client, err = newHTTPClient(MCPServer{Type: "sse", TLSCAFile: caFile})
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestNewHTTPClient_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCert, certFile, keyFile := newClientCert(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	caFile := writeCertPEM(t, dir, "ca.pem", server.Certificate().Raw)
+
+	client, err := newHTTPClient(MCPServer{Type: "sse", TLSCAFile: caFile})
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err, "the server requires a client certificate")
+
+	client, err = newHTTPClient(MCPServer{Type: "sse", TLSCAFile: caFile, TLSCertFile: certFile, TLSKeyFile: keyFile})
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestTLSConfig_Errors(t *testing.T) {
+	_, err := newHTTPClient(MCPServer{Type: "sse", TLSCertFile: "client.pem"})
+	assert.ErrorContains(t, err, "must be set together")
+
+	_, err = newHTTPClient(MCPServer{Type: "sse", TLSCAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.ErrorContains(t, err, "tlsCaFile")
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultToolsCacheTTL is how long GetTools reuses a server's tool list when
+// ClientOptions.ToolsCacheTTL is zero.
+const DefaultToolsCacheTTL = 5 * time.Minute
+
+// cachedTools is the converted tool list of one server session.
+type cachedTools struct {
+	session *mcp.ClientSession
+	tools   []api.Tool
+	fetched time.Time
+}
+
+func (c *Client) toolsCacheTTL() time.Duration {
+	if c.opts.ToolsCacheTTL == 0 {
+		return DefaultToolsCacheTTL
+	}
+	return c.opts.ToolsCacheTTL
+}
+
+// cachedServerTools returns the cached tools of the server if they were fetched
+// from the same session within the TTL.
+func (c *Client) cachedServerTools(name string, session *mcp.ClientSession) ([]api.Tool, bool) {
+	ttl := c.toolsCacheTTL()
+	if ttl < 0 {
+		return nil, false
+	}
+
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	entry, ok := c.toolsCache[name]
+	if !ok || entry.session != session || time.Since(entry.fetched) > ttl {
+		return nil, false
+	}
+	return entry.tools, true
+}
+
+func (c *Client) cacheServerTools(name string, session *mcp.ClientSession, tools []api.Tool) {
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	c.toolsCache[name] = cachedTools{session: session, tools: tools, fetched: time.Now()}
+}
+
+// InvalidateTools drops the cached tool lists so that the next GetTools call
+// fetches them from the servers again. With no arguments all servers are invalidated.
+func (c *Client) InvalidateTools(servers ...string) {
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	if len(servers) == 0 {
+		clear(c.toolsCache)
+		return
+	}
+	for _, name := range servers {
+		delete(c.toolsCache, name)
+	}
+}
@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingServer returns a server with one tool that counts tools/list requests.
+func newCountingServer(listCalls *atomic.Int32) *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "counting", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "noop"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	server.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method == "tools/list" {
+				listCalls.Add(1)
+			}
+			return next(ctx, method, req)
+		}
+	})
+	return server
+}
+
+func TestGetTools_CachedUntilInvalidated(t *testing.T) {
+	var listCalls atomic.Int32
+	c := newTestClient()
+	connectTestServer(t, c, "counting", newCountingServer(&listCalls))
+
+	for range 3 {
+		tools, err := c.GetTools(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, tools, 1)
+	}
+	assert.Equal(t, int32(1), listCalls.Load())
+
+	c.InvalidateTools()
+	_, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), listCalls.Load())
+}
+
+func TestGetTools_CacheTTL(t *testing.T) {
+	var listCalls atomic.Int32
+	c := newTestClient()
+	c.opts.ToolsCacheTTL = 10 * time.Millisecond
+	connectTestServer(t, c, "counting", newCountingServer(&listCalls))
+
+	_, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = c.GetTools(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), listCalls.Load())
+}
+
+func TestGetTools_CacheDisabled(t *testing.T) {
+	var listCalls atomic.Int32
+	c := newTestClient()
+	c.opts.ToolsCacheTTL = -1
+	connectTestServer(t, c, "counting", newCountingServer(&listCalls))
+
+	for range 2 {
+		_, err := c.GetTools(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), listCalls.Load())
+}
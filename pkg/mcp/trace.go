@@ -0,0 +1,41 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+)
+
+// SetTrace enables tracing of all JSON-RPC traffic with the servers to w.
+// A nil w disables tracing. It can be called at any time, including mid-session.
+func (c *Client) SetTrace(w io.Writer) {
+	c.traceMu.Lock()
+	defer c.traceMu.Unlock()
+	c.trace = w
+}
+
+// Tracing reports whether MCP traffic tracing is enabled.
+func (c *Client) Tracing() bool {
+	c.traceMu.RLock()
+	defer c.traceMu.RUnlock()
+	return c.trace != nil
+}
+
+// traceWriter forwards the traffic log of one server to the client's current trace writer.
+type traceWriter struct {
+	client *Client
+	server string
+}
+
+func (t *traceWriter) Write(p []byte) (int, error) {
+	t.client.traceMu.RLock()
+	w := t.client.trace
+	t.client.traceMu.RUnlock()
+
+	if w == nil {
+		return len(p), nil
+	}
+	if _, err := fmt.Fprintf(w, "[mcp:%s] %s", t.server, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceWriter(t *testing.T) {
+	c := newTestClient()
+	w := &traceWriter{client: c, server: "filesystem"}
+
+	// Tracing disabled: output is dropped.
+	n, err := w.Write([]byte("write: {}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 10, n)
+	assert.False(t, c.Tracing())
+
+	var buf bytes.Buffer
+	c.SetTrace(&buf)
+	assert.True(t, c.Tracing())
+	_, err = w.Write([]byte("read: {}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "[mcp:filesystem] read: {}\n", buf.String())
+
+	c.SetTrace(nil)
+	_, err = w.Write([]byte("write: {}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "[mcp:filesystem] read: {}\n", buf.String())
+}
@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidArguments is returned by CallTool when the arguments do not match the
+// tool's input schema. The error lists every problem found, so that a model can
+// correct its call instead of getting a confusing failure from the server.
+var ErrInvalidArguments = errors.New("invalid tool arguments")
+
+// schemaMap converts an input schema as decoded by the SDK to a generic JSON object.
+func schemaMap(schema any) map[string]any {
+	if m, ok := schema.(map[string]any); ok {
+		return m
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+func (c *Client) setToolSchemas(server string, schemas map[string]map[string]any) {
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
+	if c.schemas == nil {
+		c.schemas = make(map[string]map[string]map[string]any)
+	}
+	c.schemas[server] = schemas
+}
+
+// toolSchema returns the input schema of a tool, from the last tool listing or
+// the manifest of a lazy server.
+func (c *Client) toolSchema(server, tool string) (map[string]any, bool) {
+	c.toolsMu.Lock()
+	schema, ok := c.schemas[server][tool]
+	c.toolsMu.Unlock()
+	if ok {
+		return schema, schema != nil
+	}
+
+	for _, manifest := range c.serverConfig(server).Tools {
+		if manifest.Name == tool && manifest.InputSchema != nil {
+			return manifest.InputSchema, true
+		}
+	}
+	return nil, false
+}
+
+// validateArguments checks args against the input schema of the tool. Tools whose
+// schema is unknown, because they were never listed, are not validated.
+//
+// Only the common subset of JSON Schema is checked: type, enum, required,
+// properties, additionalProperties: false and items.
+func (c *Client) validateArguments(server, tool, name string, args map[string]any) error {
+	schema, ok := c.toolSchema(server, tool)
+	if !ok {
+		return nil
+	}
+
+	var problems []string
+	var value any = args
+	if args == nil {
+		value = map[string]any{}
+	}
+	validateValue("", schema, value, &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w for %s: %s", ErrInvalidArguments, name, strings.Join(problems, "; "))
+}
+
+// validateValue appends the ways value violates schema to problems. path names
+// the value in messages, e.g. "options.depth" or "files[0]"; it is empty for the
+// arguments object itself.
+func validateValue(path string, schema map[string]any, value any, problems *[]string) {
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		actual := jsonType(value)
+		if !typeAllowed(types, actual) {
+			*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", displayPath(path), strings.Join(types, " or "), actual))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		found := false
+		for _, allowed := range enum {
+			if equalJSON(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values := make([]string, len(enum))
+			for i, allowed := range enum {
+				data, _ := json.Marshal(allowed)
+				values[i] = string(data)
+			}
+			data, _ := json.Marshal(value)
+			*problems = append(*problems, fmt.Sprintf("%s: %s is not one of %s", displayPath(path), data, strings.Join(values, ", ")))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, field := range required {
+				name, _ := field.(string)
+				if _, present := v[name]; name != "" && !present {
+					*problems = append(*problems, fmt.Sprintf("missing required field %q", joinPath(path, name)))
+				}
+			}
+		}
+
+		// Iterate in a stable order so that the error message is deterministic.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if property, ok := properties[key].(map[string]any); ok {
+				validateValue(joinPath(path, key), property, v[key], problems)
+			} else if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				*problems = append(*problems, fmt.Sprintf("unexpected field %q", joinPath(path, key)))
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateValue(fmt.Sprintf("%s[%d]", path, i), items, item, problems)
+			}
+		}
+	}
+}
+
+// schemaTypes returns the types allowed by a "type" keyword, which is either a
+// string or a list of strings.
+func schemaTypes(t any) []string {
+	switch t := t.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, s := range t {
+			if s, ok := s.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func typeAllowed(types []string, actual string) bool {
+	for _, t := range types {
+		// Every integer is also a number.
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value. Numbers without
+// a fractional part are reported as integers.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case float32:
+		return jsonType(float64(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+// equalJSON compares two decoded JSON values, treating numbers of any Go type as equal
+// when their values are.
+func equalJSON(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "arguments"
+	}
+	return fmt.Sprintf("field %q", path)
+}
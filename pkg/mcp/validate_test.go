@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateValue(t *testing.T) {
+	schema := map[string]any{
+		"type":                 "object",
+		"required":             []any{"path"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"path":  map[string]any{"type": "string"},
+			"line":  map[string]any{"type": "integer"},
+			"ratio": map[string]any{"type": "number"},
+			"mode":  map[string]any{"type": "string", "enum": []any{"read", "write"}},
+			"tags":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"options": map[string]any{
+				"type":       "object",
+				"required":   []any{"depth"},
+				"properties": map[string]any{"depth": map[string]any{"type": []any{"integer", "null"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		problems []string
+	}{
+		{
+			name: "valid",
+			args: map[string]any{"path": "a.go", "line": float64(3), "ratio": float64(3), "mode": "read", "tags": []any{"x"}, "options": map[string]any{"depth": nil}},
+		},
+		{
+			name:     "missing required",
+			args:     map[string]any{},
+			problems: []string{`missing required field "path"`},
+		},
+		{
+			name:     "wrong types",
+			args:     map[string]any{"path": float64(1), "line": 1.5},
+			problems: []string{`field "line": expected integer, got number`, `field "path": expected string, got integer`},
+		},
+		{
+			name:     "enum",
+			args:     map[string]any{"path": "a.go", "mode": "delete"},
+			problems: []string{`field "mode": "delete" is not one of "read", "write"`},
+		},
+		{
+			name: "nested",
+			args: map[string]any{"path": "a.go", "tags": []any{"x", true}, "options": map[string]any{"depth": "2"}},
+			problems: []string{
+				`field "options.depth": expected integer or null, got string`,
+				`field "tags[1]": expected string, got boolean`,
+			},
+		},
+		{
+			name:     "missing nested",
+			args:     map[string]any{"path": "a.go", "options": map[string]any{}},
+			problems: []string{`missing required field "options.depth"`},
+		},
+		{
+			name:     "unexpected field",
+			args:     map[string]any{"path": "a.go", "file": "b.go"},
+			problems: []string{`unexpected field "file"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problems []string
+			validateValue("", schema, tt.args, &problems)
+			assert.Equal(t, tt.problems, problems)
+		})
+	}
+}
+
+func TestCallTool_InvalidArguments(t *testing.T) {
+	type readArgs struct {
+		Path string `json:"path"`
+		Line int    `json:"line,omitempty"`
+	}
+	called := false
+	server := mcp.NewServer(&mcp.Implementation{Name: "files", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "read"}, func(ctx context.Context, req *mcp.CallToolRequest, args readArgs) (*mcp.CallToolResult, any, error) {
+		called = true
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: args.Path}}}, nil, nil
+	})
+
+	c := newTestClient()
+	connectTestServer(t, c, "fs", server)
+	_, err := c.GetTools(context.Background())
+	require.NoError(t, err)
+
+	_, err = c.CallTool(context.Background(), "fs__read", map[string]any{"line": "3"})
+	require.ErrorIs(t, err, ErrInvalidArguments)
+	assert.Contains(t, err.Error(), `missing required field "path"`)
+	assert.Contains(t, err.Error(), `field "line": expected integer, got string`)
+	assert.False(t, called, "the server must not be called with invalid arguments")
+
+	// The schemas survive cache invalidation.
+	c.InvalidateTools()
+	_, err = c.CallTool(context.Background(), "fs__read", map[string]any{})
+	require.ErrorIs(t, err, ErrInvalidArguments)
+
+	result, err := c.CallTool(context.Background(), "fs__read", map[string]any{"path": "a.go", "line": float64(3)})
+	require.NoError(t, err)
+	assert.Equal(t, "a.go", result.String())
+}
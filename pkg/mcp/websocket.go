@@ -0,0 +1,261 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// wsSubprotocol is requested during the WebSocket handshake.
+	wsSubprotocol = "mcp"
+	// wsSessionHeader carries the session id assigned by the server, which is sent
+	// back when reconnecting so that the server can resume the session.
+	wsSessionHeader = "Mcp-Session-Id"
+	// maxWSReconnects bounds the attempts to restore a dropped connection.
+	maxWSReconnects = 5
+)
+
+// wsTransport connects to an MCP server over WebSocket, one JSON-RPC message per
+// text frame. Unlike SSE it is not buffered by proxies. If the connection drops,
+// it is re-established transparently with the same session id; messages sent by
+// the server while disconnected are lost unless the server replays them.
+type wsTransport struct {
+	server MCPServer
+}
+
+func (t *wsTransport) Connect(ctx context.Context) (mcp.Connection, error) {
+	dialer := ws.Dialer{Protocols: []string{wsSubprotocol}}
+	if t.server.customTLS() {
+		tlsConfig, err := t.server.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		dialer.TLSConfig = tlsConfig
+	}
+
+	var tokens *tokenSource
+	if t.server.Auth != nil {
+		if err := t.server.Auth.validate(); err != nil {
+			return nil, err
+		}
+		transport, err := t.server.baseTransport()
+		if err != nil {
+			return nil, err
+		}
+		tokens = newTokenSource(*t.server.Auth, &http.Client{Transport: transport})
+	}
+
+	conn := &wsConn{
+		dial: func(ctx context.Context, sessionID string) (net.Conn, io.Reader, string, error) {
+			header := http.Header{}
+			for k, v := range t.server.Headers {
+				header.Set(k, v)
+			}
+			if tokens != nil {
+				token, err := tokens.Token(ctx)
+				if err != nil {
+					return nil, nil, "", fmt.Errorf("auth: %w", err)
+				}
+				header.Set("Authorization", "Bearer "+token)
+			}
+			if sessionID != "" {
+				header.Set(wsSessionHeader, sessionID)
+			}
+
+			d := dialer
+			d.Header = ws.HandshakeHeaderHTTP(header)
+			d.OnHeader = func(key, value []byte) error {
+				if http.CanonicalHeaderKey(string(key)) == wsSessionHeader {
+					sessionID = string(value)
+				}
+				return nil
+			}
+			netConn, br, _, err := d.Dial(ctx, t.server.URL)
+			if err != nil {
+				return nil, nil, "", err
+			}
+			return netConn, bufferedReader(netConn, br), sessionID, nil
+		},
+		state:  ws.StateClientSide,
+		closed: make(chan struct{}),
+	}
+	if err := conn.connect(ctx); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// bufferedReader reads the frames the peer sent right after the handshake
+// before reading from the connection itself.
+func bufferedReader(conn net.Conn, br *bufio.Reader) io.Reader {
+	if br == nil || br.Buffered() == 0 {
+		return conn
+	}
+	return io.MultiReader(br, conn)
+}
+
+// wsConn is a JSON-RPC connection over WebSocket that reconnects when the
+// socket breaks. Without dial (on the server side) nothing is reconnected.
+type wsConn struct {
+	dial  func(ctx context.Context, sessionID string) (net.Conn, io.Reader, string, error)
+	state ws.State
+
+	mu        sync.Mutex // guards the fields below and serializes reconnects
+	conn      net.Conn
+	reader    io.Reader
+	sessionID string
+
+	writeMu   sync.Mutex // frames, including pongs sent while reading, must not interleave
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// connect dials the server, resuming the current session if there is one. The caller must hold mu
+// or be the only user of the connection.
+func (c *wsConn) connect(ctx context.Context) error {
+	conn, reader, sessionID, err := c.dial(ctx, c.sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to connect to WebSocket server: %w", err)
+	}
+	c.conn, c.reader, c.sessionID = conn, reader, sessionID
+	return nil
+}
+
+// current returns the socket in use.
+func (c *wsConn) current() (net.Conn, io.Reader) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn, c.reader
+}
+
+func (c *wsConn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// reconnect replaces the broken socket, unless another goroutine already did.
+func (c *wsConn) reconnect(ctx context.Context, broken net.Conn) error {
+	if c.dial == nil {
+		return mcp.ErrConnectionClosed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != broken {
+		return nil
+	}
+	broken.Close()
+
+	backoff := DefaultRetryBackoff
+	var err error
+	for attempt := 0; attempt < maxWSReconnects; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-c.closed:
+				return mcp.ErrConnectionClosed
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if c.isClosed() {
+			return mcp.ErrConnectionClosed
+		}
+		if err = c.connect(ctx); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// lockedWriter serializes the frames written to the socket.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+func (c *wsConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	for {
+		conn, reader := c.current()
+		rw := struct {
+			io.Reader
+			io.Writer
+		}{reader, lockedWriter{&c.writeMu, conn}}
+
+		data, _, err := wsutil.ReadData(rw, c.state)
+		if err == nil {
+			return jsonrpc.DecodeMessage(data)
+		}
+		// A normal closure by the peer ends the session; anything else is a dropped connection.
+		var closed wsutil.ClosedError
+		if c.isClosed() || (errors.As(err, &closed) && closed.Code == ws.StatusNormalClosure) {
+			return nil, mcp.ErrConnectionClosed
+		}
+		if err := c.reconnect(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *wsConn) Write(ctx context.Context, msg jsonrpc.Message) error {
+	data, err := jsonrpc.EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	// Retry once on a fresh socket if the current one is broken.
+	for attempt := 0; ; attempt++ {
+		conn, _ := c.current()
+		c.writeMu.Lock()
+		err = wsutil.WriteMessage(conn, c.state, ws.OpText, data)
+		c.writeMu.Unlock()
+		if err == nil || c.isClosed() || attempt > 0 {
+			return err
+		}
+		if err := c.reconnect(ctx, conn); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *wsConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		conn, _ := c.current()
+		c.writeMu.Lock()
+		wsutil.WriteMessage(conn, c.state, ws.OpClose, ws.NewCloseFrameBody(ws.StatusNormalClosure, ""))
+		c.writeMu.Unlock()
+		err = conn.Close()
+	})
+	return err
+}
+
+func (c *wsConn) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connTransport hands an accepted connection to mcp.Server.Connect.
+type connTransport struct {
+	conn mcp.Connection
+}
+
+func (t connTransport) Connect(context.Context) (mcp.Connection, error) {
+	return t.conn, nil
+}
+
+// upgrade accepts a WebSocket connection and assigns it the given session id.
+func upgrade(t *testing.T, w http.ResponseWriter, r *http.Request, sessionID string) (net.Conn, io.Reader) {
+	upgrader := ws.HTTPUpgrader{
+		Header:   http.Header{wsSessionHeader: []string{sessionID}},
+		Protocol: func(p string) bool { return p == wsSubprotocol },
+	}
+	conn, rw, _, err := upgrader.Upgrade(r, w)
+	require.NoError(t, err)
+	return conn, bufferedReader(conn, rw.Reader)
+}
+
+func TestWebSocketTransport(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "remote", Version: "1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "hello"}, func(ctx context.Context, req *mcp.CallToolRequest, args emptyArgs) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "hello over ws"}}}, nil, nil
+	})
+
+	var authorization string
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		conn, reader := upgrade(t, w, r, "session-1")
+		wsConn := &wsConn{conn: conn, reader: reader, sessionID: "session-1", state: ws.StateServerSide, closed: make(chan struct{})}
+		session, err := server.Connect(context.Background(), connTransport{wsConn}, nil)
+		require.NoError(t, err)
+		go session.Wait()
+	}))
+	defer httpServer.Close()
+
+	c := newTestClient()
+	err := c.connectOnce(context.Background(), "remote", MCPServer{
+		Type:    "ws",
+		URL:     "ws" + strings.TrimPrefix(httpServer.URL, "http"),
+		Headers: map[string]string{"Authorization": "Bearer static"},
+	})
+	require.NoError(t, err)
+	defer c.Close()
+
+	result, err := c.CallTool(context.Background(), "remote__hello", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "hello over ws", result.Text())
+	assert.Equal(t, "Bearer static", authorization)
+}
+
+func TestWebSocketTransport_Reconnect(t *testing.T) {
+	var mu sync.Mutex
+	var handshakes []string
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		handshakes = append(handshakes, r.Header.Get(wsSessionHeader))
+		first := len(handshakes) == 1
+		mu.Unlock()
+
+		conn, reader := upgrade(t, w, r, "session-1")
+		go func() {
+			defer conn.Close()
+			rw := struct {
+				io.Reader
+				io.Writer
+			}{reader, conn}
+			for {
+				data, _, err := wsutil.ReadData(rw, ws.StateServerSide)
+				if err != nil || first {
+					// Drop the first connection without a close frame, as a proxy would.
+					return
+				}
+				if wsutil.WriteServerText(conn, data) != nil {
+					return
+				}
+			}
+		}()
+	}))
+	defer httpServer.Close()
+
+	transport := &wsTransport{server: MCPServer{Type: "ws", URL: "ws" + strings.TrimPrefix(httpServer.URL, "http")}}
+	conn, err := transport.Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, "session-1", conn.SessionID())
+
+	ctx := context.Background()
+	id1, _ := jsonrpc.MakeID(int64(1))
+	require.NoError(t, conn.Write(ctx, &jsonrpc.Request{ID: id1, Method: "ping"}))
+
+	// The first socket is dropped after the message, so Read reconnects and waits on the second one.
+	received := make(chan jsonrpc.Message, 1)
+	go func() {
+		msg, err := conn.Read(ctx)
+		assert.NoError(t, err)
+		received <- msg
+	}()
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handshakes) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	id2, _ := jsonrpc.MakeID(int64(2))
+	require.NoError(t, conn.Write(ctx, &jsonrpc.Request{ID: id2, Method: "ping"}))
+
+	msg := <-received
+	request, ok := msg.(*jsonrpc.Request)
+	require.True(t, ok)
+	assert.Equal(t, id2, request.ID)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"", "session-1"}, handshakes)
+}
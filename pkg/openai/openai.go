@@ -0,0 +1,569 @@
+// Package openai talks to servers that implement the OpenAI Chat Completions
+// API, such as OpenAI, DeepSeek, vLLM and LM Studio. Requests and responses use
+// the types of the Ollama API, so that the agents can switch providers without
+// changing their conversation handling.
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// DefaultBaseURL is the base URL of the OpenAI API.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
+// Client is a client of an OpenAI-compatible server.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// New returns a client for the server at baseURL (e.g. http://localhost:8000/v1).
+// The API key is sent as a bearer token unless it is empty, as local servers
+// usually do not require one. A nil httpClient uses http.DefaultClient.
+func New(baseURL, apiKey string, httpClient *http.Client) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), apiKey: apiKey, http: httpClient}
+}
+
+// StatusError is returned when the server responds with an error status.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("openai: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("openai: %d %s", e.StatusCode, e.Message)
+}
+
+type chatRequest struct {
+	Model          string         `json:"model"`
+	Messages       []message      `json:"messages"`
+	Tools          []tool         `json:"tools,omitempty"`
+	Stream         bool           `json:"stream"`
+	StreamOptions  *streamOptions `json:"stream_options,omitempty"`
+	ResponseFormat any            `json:"response_format,omitempty"`
+
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	MaxTokens        *int     `json:"max_tokens,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+	Stop             any      `json:"stop,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type message struct {
+	Role       string     `json:"role"`
+	Content    any        `json:"content"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type tool struct {
+	Type     string   `json:"type"`
+	Function function `json:"function"`
+}
+
+type function struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type toolCall struct {
+	Index    *int             `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function toolCallFunction `json:"function"`
+}
+
+type toolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+type chatResponse struct {
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+	Usage   *usage   `json:"usage"`
+}
+
+type choice struct {
+	Message      responseMessage `json:"message"`
+	Delta        responseMessage `json:"delta"`
+	FinishReason string          `json:"finish_reason"`
+}
+
+type responseMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []toolCall `json:"tool_calls"`
+
+	// Reasoning is returned by DeepSeek as reasoning_content and by vLLM as reasoning.
+	ReasoningContent string `json:"reasoning_content"`
+	Reasoning        string `json:"reasoning"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Chat sends a chat request and calls fn with the response. Like the Ollama
+// client it streams unless req.Stream is false, calling fn for every chunk of
+// content and finally with Done set. Tool calls are reported in one response
+// before the final one, once their arguments are complete.
+func (c *Client) Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+	body, err := newChatRequest(req)
+	if err != nil {
+		return err
+	}
+	resp, err := c.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !body.Stream {
+		var result chatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("openai: invalid response: %w", err)
+		}
+		if len(result.Choices) == 0 {
+			return errors.New("openai: response has no choices")
+		}
+		msg, err := convertResponse(result.Choices[0].Message, result.Choices[0].Message.ToolCalls)
+		if err != nil {
+			return err
+		}
+		return fn(api.ChatResponse{
+			Model:      result.Model,
+			Message:    msg,
+			Done:       true,
+			DoneReason: doneReason(result.Choices[0].FinishReason),
+			Metrics:    result.Usage.metrics(),
+		})
+	}
+	return readStream(resp.Body, fn)
+}
+
+// readStream reads the server-sent events of a streamed chat completion.
+func readStream(r io.Reader, fn api.ChatResponseFunc) error {
+	var (
+		model  string
+		reason string
+		u      *usage
+		calls  = map[int]*toolCall{}
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		// Errors after the response started are sent as an event of their own.
+		var apiErr errorResponse
+		if json.Unmarshal([]byte(data), &apiErr) == nil && apiErr.Error.Message != "" {
+			return errors.New("openai: " + apiErr.Error.Message)
+		}
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("openai: invalid stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			u = chunk.Usage
+		}
+		for _, ch := range chunk.Choices {
+			if ch.FinishReason != "" {
+				reason = ch.FinishReason
+			}
+			for i, delta := range ch.Delta.ToolCalls {
+				index := i
+				if delta.Index != nil {
+					index = *delta.Index
+				}
+				call, ok := calls[index]
+				if !ok {
+					call = &toolCall{}
+					calls[index] = call
+				}
+				if delta.ID != "" {
+					call.ID = delta.ID
+				}
+				call.Function.Name += delta.Function.Name
+				call.Function.Arguments += delta.Function.Arguments
+			}
+
+			thinking := ch.Delta.ReasoningContent + ch.Delta.Reasoning
+			if ch.Delta.Content == "" && thinking == "" {
+				continue
+			}
+			err := fn(api.ChatResponse{
+				Model:   model,
+				Message: api.Message{Role: "assistant", Content: ch.Delta.Content, Thinking: thinking},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(calls) > 0 {
+		indexes := make([]int, 0, len(calls))
+		for index := range calls {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		ordered := make([]toolCall, len(indexes))
+		for i, index := range indexes {
+			ordered[i] = *calls[index]
+		}
+		msg, err := convertResponse(responseMessage{}, ordered)
+		if err != nil {
+			return err
+		}
+		if err := fn(api.ChatResponse{Model: model, Message: msg}); err != nil {
+			return err
+		}
+	}
+	return fn(api.ChatResponse{
+		Model:      model,
+		Message:    api.Message{Role: "assistant"},
+		Done:       true,
+		DoneReason: doneReason(reason),
+		Metrics:    u.metrics(),
+	})
+}
+
+// Embed computes the embeddings of the input, a string or a slice of strings.
+func (c *Client) Embed(ctx context.Context, req *api.EmbedRequest) (*api.EmbedResponse, error) {
+	resp, err := c.post(ctx, "/embeddings", map[string]any{"model": req.Model, "input": req.Input})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Model string `json:"model"`
+		Data  []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage *usage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("openai: invalid response: %w", err)
+	}
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].Index < result.Data[j].Index })
+
+	embeddings := make([][]float32, len(result.Data))
+	for i, d := range result.Data {
+		embeddings[i] = d.Embedding
+	}
+	out := &api.EmbedResponse{Model: result.Model, Embeddings: embeddings}
+	if result.Usage != nil {
+		out.PromptEvalCount = result.Usage.PromptTokens
+	}
+	return out, nil
+}
+
+// Models returns the IDs of the models served by the server.
+func (c *Client) Models(ctx context.Context) ([]string, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("openai: invalid response: %w", err)
+	}
+	ids := make([]string, len(result.Data))
+	for i, m := range result.Data {
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
+
+func (c *Client) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodPost, path, bytes.NewReader(data))
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		statusErr := StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		var apiErr errorResponse
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			statusErr.Message = apiErr.Error.Message
+		}
+		return nil, statusErr
+	}
+	return resp, nil
+}
+
+// newChatRequest converts an Ollama chat request. Options without an
+// equivalent, such as num_ctx, and the keep-alive are dropped.
+func newChatRequest(req *api.ChatRequest) (*chatRequest, error) {
+	body := &chatRequest{
+		Model:  req.Model,
+		Stream: req.Stream == nil || *req.Stream,
+	}
+	if body.Stream {
+		body.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	messages, err := convertMessages(req.Messages)
+	if err != nil {
+		return nil, err
+	}
+	body.Messages = messages
+
+	for _, t := range req.Tools {
+		params, err := json.Marshal(t.Function.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("openai: invalid parameters of tool %s: %w", t.Function.Name, err)
+		}
+		body.Tools = append(body.Tools, tool{
+			Type:     "function",
+			Function: function{Name: t.Function.Name, Description: t.Function.Description, Parameters: params},
+		})
+	}
+
+	switch format := strings.TrimSpace(string(req.Format)); {
+	case format == "" || format == "null" || format == `""`:
+	case format == `"json"`:
+		body.ResponseFormat = map[string]any{"type": "json_object"}
+	default:
+		body.ResponseFormat = map[string]any{
+			"type":        "json_schema",
+			"json_schema": map[string]any{"name": "response", "schema": json.RawMessage(req.Format)},
+		}
+	}
+
+	body.Temperature = floatOption(req.Options, "temperature")
+	body.TopP = floatOption(req.Options, "top_p")
+	body.PresencePenalty = floatOption(req.Options, "presence_penalty")
+	body.FrequencyPenalty = floatOption(req.Options, "frequency_penalty")
+	if n := floatOption(req.Options, "num_predict"); n != nil && *n > 0 {
+		body.MaxTokens = intPtr(int(*n))
+	}
+	if seed := floatOption(req.Options, "seed"); seed != nil {
+		body.Seed = intPtr(int(*seed))
+	}
+	if stop, ok := req.Options["stop"]; ok {
+		body.Stop = stop
+	}
+	return body, nil
+}
+
+// convertMessages converts the conversation. Ollama matches tool results to
+// calls by position, OpenAI by ID, so calls without an ID get one and tool
+// results without an ID take the IDs of the preceding calls in order. OpenAI
+// expects the results right after the calls, one per call, so further tool
+// messages are sent as user messages, and these and system notes added between
+// the results are moved after the last result.
+func convertMessages(messages []api.Message) ([]message, error) {
+	result := make([]message, 0, len(messages))
+	var pending []string
+	var deferred []message
+	for i, m := range messages {
+		out := message{Role: m.Role, Content: m.Content}
+		switch m.Role {
+		case "assistant":
+			pending = pending[:0]
+			for j, call := range m.ToolCalls {
+				id := call.ID
+				if id == "" {
+					id = fmt.Sprintf("call_%d_%d", i, j)
+				}
+				args, err := json.Marshal(call.Function.Arguments)
+				if err != nil {
+					return nil, fmt.Errorf("openai: invalid arguments of %s: %w", call.Function.Name, err)
+				}
+				out.ToolCalls = append(out.ToolCalls, toolCall{
+					ID:       id,
+					Type:     "function",
+					Function: toolCallFunction{Name: call.Function.Name, Arguments: string(args)},
+				})
+				pending = append(pending, id)
+			}
+			if len(out.ToolCalls) > 0 && m.Content == "" {
+				out.Content = nil
+			}
+		case "tool":
+			id := m.ToolCallID
+			if id == "" && len(pending) > 0 {
+				id = pending[0]
+			}
+			j := slices.Index(pending, id)
+			if j < 0 {
+				deferred = append(deferred, message{Role: "user", Content: m.Content})
+				continue
+			}
+			pending = slices.Delete(pending, j, j+1)
+			out.ToolCallID = id
+		case "system":
+			if len(pending) > 0 {
+				deferred = append(deferred, out)
+				continue
+			}
+		case "user":
+			if len(m.Images) > 0 {
+				parts := []contentPart{{Type: "text", Text: m.Content}}
+				for _, image := range m.Images {
+					parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: dataURL(image)}})
+				}
+				out.Content = parts
+			}
+		}
+		if m.Role != "tool" {
+			// Calls left unanswered, e.g. by an interruption, do not hold back later messages.
+			result = append(result, deferred...)
+			deferred = nil
+		}
+		result = append(result, out)
+		if len(pending) == 0 {
+			result = append(result, deferred...)
+			deferred = nil
+		}
+	}
+	return append(result, deferred...), nil
+}
+
+// convertResponse converts a response message and its tool calls, whose
+// arguments are JSON strings, to an Ollama message.
+func convertResponse(m responseMessage, calls []toolCall) (api.Message, error) {
+	msg := api.Message{Role: "assistant", Content: m.Content, Thinking: m.ReasoningContent + m.Reasoning}
+	for i, call := range calls {
+		args := api.ToolCallFunctionArguments{}
+		if strings.TrimSpace(call.Function.Arguments) != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return api.Message{}, fmt.Errorf("openai: invalid arguments for %s: %w", call.Function.Name, err)
+			}
+		}
+		msg.ToolCalls = append(msg.ToolCalls, api.ToolCall{
+			ID:       call.ID,
+			Function: api.ToolCallFunction{Index: i, Name: call.Function.Name, Arguments: args},
+		})
+	}
+	return msg, nil
+}
+
+// doneReason maps a finish reason to the done reasons reported by Ollama.
+func doneReason(reason string) string {
+	if reason == "length" {
+		return "length"
+	}
+	return "stop"
+}
+
+func (u *usage) metrics() api.Metrics {
+	if u == nil {
+		return api.Metrics{}
+	}
+	return api.Metrics{PromptEvalCount: u.PromptTokens, EvalCount: u.CompletionTokens}
+}
+
+// dataURL encodes an image as a data URL, detecting its type from the content.
+func dataURL(image api.ImageData) string {
+	return "data:" + http.DetectContentType(image) + ";base64," + base64.StdEncoding.EncodeToString(image)
+}
+
+func floatOption(options map[string]any, name string) *float64 {
+	switch v := options[name].(type) {
+	case float64:
+		return &v
+	case float32:
+		f := float64(v)
+		return &f
+	case int:
+		f := float64(v)
+		return &f
+	}
+	return nil
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,146 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ollama/ollama/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChat_Stream(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"model":"gpt","choices":[{"delta":{"role":"assistant","content":"Let me "}}]}`,
+			`{"model":"gpt","choices":[{"delta":{"content":"look."}}]}`,
+			`{"model":"gpt","choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"read_file","arguments":"{\"pa"}}]}}]}`,
+			`{"model":"gpt","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"main.go\"}"}}]}}]}`,
+			`{"model":"gpt","choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+			`{"model":"gpt","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":5}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	req := &api.ChatRequest{
+		Model: "gpt",
+		Messages: []api.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "Read main.go"},
+		},
+		Tools:   api.Tools{{Type: "function", Function: api.ToolFunction{Name: "read_file", Description: "Read a file"}}},
+		Options: map[string]any{"temperature": 0.2, "num_ctx": 8192, "num_predict": 100},
+	}
+	var responses []api.ChatResponse
+	err := New(server.URL+"/v1/", "sk-test", nil).Chat(context.Background(), req, func(resp api.ChatResponse) error {
+		responses = append(responses, resp)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, true, got["stream"])
+	assert.Equal(t, 0.2, got["temperature"])
+	assert.Equal(t, float64(100), got["max_tokens"])
+	assert.NotContains(t, got, "num_ctx")
+	assert.Len(t, got["tools"], 1)
+
+	require.Len(t, responses, 4)
+	assert.Equal(t, "Let me ", responses[0].Message.Content)
+	assert.Equal(t, "look.", responses[1].Message.Content)
+	require.Len(t, responses[2].Message.ToolCalls, 1)
+	assert.Equal(t, "call_1", responses[2].Message.ToolCalls[0].ID)
+	assert.Equal(t, "read_file", responses[2].Message.ToolCalls[0].Function.Name)
+	assert.Equal(t, "main.go", responses[2].Message.ToolCalls[0].Function.Arguments["path"])
+	assert.True(t, responses[3].Done)
+	assert.Equal(t, 12, responses[3].Metrics.PromptEvalCount)
+	assert.Equal(t, 5, responses[3].Metrics.EvalCount)
+}
+
+func TestChat_NoStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var got map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		assert.Equal(t, false, got["stream"])
+		assert.Equal(t, map[string]any{"type": "json_object"}, got["response_format"])
+		fmt.Fprint(w, `{"model":"gpt","choices":[{"message":{"role":"assistant","content":"{}"},"finish_reason":"length"}]}`)
+	}))
+	defer server.Close()
+
+	stream := false
+	req := &api.ChatRequest{Model: "gpt", Stream: &stream, Format: json.RawMessage(`"json"`)}
+	var got api.ChatResponse
+	err := New(server.URL, "", nil).Chat(context.Background(), req, func(resp api.ChatResponse) error {
+		got = resp
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, got.Done)
+	assert.Equal(t, "length", got.DoneReason)
+	assert.Equal(t, "{}", got.Message.Content)
+}
+
+func TestChat_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"message":"The model 'gpt-9' does not exist"}}`)
+	}))
+	defer server.Close()
+
+	err := New(server.URL, "", nil).Chat(context.Background(), &api.ChatRequest{Model: "gpt-9"}, func(api.ChatResponse) error { return nil })
+	var statusErr StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, "openai: 404 The model 'gpt-9' does not exist", err.Error())
+}
+
+func TestConvertMessages_ToolCallIDs(t *testing.T) {
+	messages, err := convertMessages([]api.Message{
+		{Role: "user", Content: "look", Images: []api.ImageData{[]byte("\x89PNG\r\n\x1a\n")}},
+		{Role: "assistant", ToolCalls: []api.ToolCall{
+			{Function: api.ToolCallFunction{Name: "a", Arguments: api.ToolCallFunctionArguments{"x": 1}}},
+			{ID: "b1", Function: api.ToolCallFunction{Name: "b"}},
+		}},
+		{Role: "tool", Content: "A", ToolName: "a"},
+		{Role: "tool", Content: "A failed verification", ToolName: "a", ToolCallID: "call_1_0"},
+		{Role: "system", Content: "imports fixed"},
+		{Role: "tool", Content: "B", ToolName: "b", ToolCallID: "b1"},
+	})
+	require.NoError(t, err)
+
+	parts, ok := messages[0].Content.([]contentPart)
+	require.True(t, ok)
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgo=", parts[1].ImageURL.URL)
+
+	assert.Nil(t, messages[1].Content)
+	assert.Equal(t, "call_1_0", messages[1].ToolCalls[0].ID)
+	assert.Equal(t, `{"x":1}`, messages[1].ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "call_1_0", messages[2].ToolCallID)
+	assert.Equal(t, "b1", messages[3].ToolCallID)
+	assert.Equal(t, message{Role: "user", Content: "A failed verification"}, messages[4])
+	assert.Equal(t, message{Role: "system", Content: "imports fixed"}, messages[5])
+}
+
+func TestEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		fmt.Fprint(w, `{"model":"emb","data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer server.Close()
+
+	resp, err := New(server.URL, "", nil).Embed(context.Background(), &api.EmbedRequest{Model: "emb", Input: []string{"a", "b"}})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, resp.Embeddings)
+}
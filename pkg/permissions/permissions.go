@@ -0,0 +1,179 @@
+// Package permissions remembers the "always allow" answers of the approval
+// prompts of a project, so that trusted requests are not asked about again in
+// every session. The rules are stored in .coding-agent/permissions.json in the
+// project directory and can be committed or shared with the project.
+package permissions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DirName is the directory of the project that holds the agent's settings.
+const DirName = ".coding-agent"
+
+// FileName is the name of the rules file in DirName.
+const FileName = "permissions.json"
+
+// Kinds of requests a rule can allow.
+const (
+	KindSampling = "sampling" // sampling requests of an MCP server, Name is the server
+	KindTool     = "tool"     // calls of a tool, Name is the tool
+)
+
+// Rule always allows the requests of one kind with one name.
+type Rule struct {
+	Kind  string    `json:"kind"`
+	Name  string    `json:"name"`
+	Added time.Time `json:"added"`
+}
+
+// String returns the rule as "kind:name", the form accepted by Parse.
+func (r Rule) String() string {
+	return r.Kind + ":" + r.Name
+}
+
+// file is the JSON layout of the rules file.
+type file struct {
+	Allow []Rule `json:"allow"`
+}
+
+// Store holds the rules of one project. It is safe for concurrent use.
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	rules []Rule
+}
+
+// Path returns the rules file of the project in dir.
+func Path(dir string) string {
+	return filepath.Join(dir, DirName, FileName)
+}
+
+// Load reads the rules of the project in dir. A missing file is an empty store.
+func Load(dir string) (*Store, error) {
+	s := &Store{path: Path(dir)}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", s.path, err)
+	}
+	s.rules = f.Allow
+	return s, nil
+}
+
+// Path returns the file the store is saved to.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Allowed reports whether a rule allows the request. A nil store allows nothing.
+func (s *Store) Allowed(kind, name string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index(kind, name) >= 0
+}
+
+// Allow adds a rule for the request and saves the store.
+func (s *Store) Allow(kind, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index(kind, name) >= 0 {
+		return nil
+	}
+	s.rules = append(s.rules, Rule{Kind: kind, Name: name, Added: time.Now().UTC().Truncate(time.Second)})
+	return s.saveLocked()
+}
+
+// Revoke removes the rules matching kind and name and saves the store. An empty
+// kind matches every kind. It returns the removed rules.
+func (s *Store) Revoke(kind, name string) ([]Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept, removed []Rule
+	for _, r := range s.rules {
+		if (kind == "" || r.Kind == kind) && r.Name == name {
+			removed = append(removed, r)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	s.rules = kept
+	return removed, s.saveLocked()
+}
+
+// Rules returns the rules sorted by kind and name.
+func (s *Store) Rules() []Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rules := append([]Rule(nil), s.rules...)
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Kind != rules[j].Kind {
+			return rules[i].Kind < rules[j].Kind
+		}
+		return rules[i].Name < rules[j].Name
+	})
+	return rules
+}
+
+// Parse splits "kind:name" into its parts. Without a known kind prefix the
+// whole string is the name and the kind is empty.
+func Parse(s string) (kind, name string) {
+	for _, k := range []string{KindSampling, KindTool} {
+		if rest, ok := strings.CutPrefix(s, k+":"); ok {
+			return k, rest
+		}
+	}
+	return "", s
+}
+
+func (s *Store) index(kind, name string) int {
+	for i, r := range s.rules {
+		if r.Kind == kind && r.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveLocked writes the rules through a temporary file, so that a crash never
+// leaves a truncated file behind.
+func (s *Store) saveLocked() error {
+	rules := s.rules
+	if rules == nil {
+		rules = []Rule{}
+	}
+	data, err := json.MarshalIndent(file{Allow: rules}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
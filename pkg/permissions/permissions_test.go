@@ -0,0 +1,89 @@
+package permissions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_AllowPersists(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Load(dir)
+	require.NoError(t, err)
+	assert.False(t, s.Allowed(KindSampling, "weather"))
+	assert.NoFileExists(t, Path(dir))
+
+	require.NoError(t, s.Allow(KindSampling, "weather"))
+	require.NoError(t, s.Allow(KindSampling, "weather"))
+	require.NoError(t, s.Allow(KindTool, "bash"))
+	assert.True(t, s.Allowed(KindSampling, "weather"))
+	assert.False(t, s.Allowed(KindTool, "weather"))
+
+	loaded, err := Load(dir)
+	require.NoError(t, err)
+	rules := loaded.Rules()
+	require.Len(t, rules, 2)
+	assert.Equal(t, "sampling:weather", rules[0].String())
+	assert.Equal(t, "tool:bash", rules[1].String())
+	assert.True(t, loaded.Allowed(KindTool, "bash"))
+}
+
+func TestStore_Revoke(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Load(dir)
+	require.NoError(t, err)
+	require.NoError(t, s.Allow(KindSampling, "git"))
+	require.NoError(t, s.Allow(KindTool, "git"))
+	require.NoError(t, s.Allow(KindTool, "bash"))
+
+	removed, err := s.Revoke(KindTool, "git")
+	require.NoError(t, err)
+	assert.Len(t, removed, 1)
+	assert.True(t, s.Allowed(KindSampling, "git"))
+
+	removed, err = s.Revoke("", "git")
+	require.NoError(t, err)
+	assert.Len(t, removed, 1)
+
+	removed, err = s.Revoke("", "missing")
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+
+	loaded, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tool:bash"}, names(loaded.Rules()))
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, DirName), 0755))
+	require.NoError(t, os.WriteFile(Path(dir), []byte("{"), 0644))
+	_, err := Load(dir)
+	assert.ErrorContains(t, err, "invalid")
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	assert.False(t, s.Allowed(KindTool, "bash"))
+}
+
+func TestParse(t *testing.T) {
+	kind, name := Parse("tool:filesystem__write_file")
+	assert.Equal(t, KindTool, kind)
+	assert.Equal(t, "filesystem__write_file", name)
+
+	kind, name = Parse("weather")
+	assert.Empty(t, kind)
+	assert.Equal(t, "weather", name)
+}
+
+func names(rules []Rule) []string {
+	var result []string
+	for _, r := range rules {
+		result = append(result, r.String())
+	}
+	return result
+}
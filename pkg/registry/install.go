@@ -0,0 +1,111 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+)
+
+// invalidKeyChars matches characters that are not allowed in the server names
+// of a config, since they end up in the tool names.
+var invalidKeyChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Key suggests the name of the server in mcp.json: the last part of its
+// registry name, e.g. "context7" for "io.github.upstash/context7".
+func (s Server) Key() string {
+	name := s.Name
+	if i := strings.LastIndexAny(name, "/."); i >= 0 && i < len(name)-1 {
+		name = name[i+1:]
+	}
+	return invalidKeyChars.ReplaceAllString(name, "_")
+}
+
+// Config returns the mcp.json stanza of the first way to run the server that
+// the agent supports: a stdio package run with npx, uvx or docker, or else an
+// SSE endpoint. Environment variables are not filled in; servers inherit them
+// from the agent's environment.
+func (s Server) Config() (mcp.MCPServer, bool) {
+	for _, p := range s.Packages {
+		if server, ok := p.config(); ok {
+			return server, true
+		}
+	}
+	for _, r := range s.Remotes {
+		if r.Type == "sse" {
+			return mcp.MCPServer{Type: "sse", URL: r.URL}, true
+		}
+	}
+	return mcp.MCPServer{}, false
+}
+
+func (p Package) config() (mcp.MCPServer, bool) {
+	if p.Transport.Type != "" && p.Transport.Type != "stdio" {
+		return mcp.MCPServer{}, false
+	}
+	switch p.RegistryType {
+	case "npm":
+		return mcp.MCPServer{Command: "npx", Args: []string{"-y", p.versioned("@")}}, true
+	case "pypi":
+		return mcp.MCPServer{Command: "uvx", Args: []string{p.versioned("@")}}, true
+	case "oci":
+		image := p.Identifier
+		if !strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+			image = p.versioned(":")
+		}
+		// Registry servers usually need the network, unlike the default of docker servers.
+		return mcp.MCPServer{Type: "docker", Image: image, Network: "bridge"}, true
+	}
+	return mcp.MCPServer{}, false
+}
+
+// versioned returns the identifier pinned to the package version.
+func (p Package) versioned(sep string) string {
+	if p.Version == "" || p.Version == "latest" {
+		return p.Identifier
+	}
+	return p.Identifier + sep + p.Version
+}
+
+// Instructions describes how to run the server, one line per package or
+// remote, followed by the environment variables it needs.
+func (s Server) Instructions() []string {
+	var lines []string
+	for _, p := range s.Packages {
+		server, ok := p.config()
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("%s package %s (%s transport, not supported)", p.RegistryType, p.Identifier, p.Transport.Type))
+		case server.Type == "docker":
+			lines = append(lines, "docker run -i --rm "+server.Image)
+		default:
+			lines = append(lines, strings.Join(append([]string{server.Command}, server.Args...), " "))
+		}
+		for _, env := range p.EnvironmentVariables {
+			line := "  env " + env.Name
+			var notes []string
+			if env.IsRequired {
+				notes = append(notes, "required")
+			}
+			if env.IsSecret {
+				notes = append(notes, "secret")
+			}
+			if len(notes) > 0 {
+				line += " (" + strings.Join(notes, ", ") + ")"
+			}
+			if env.Description != "" {
+				line += ": " + env.Description
+			}
+			lines = append(lines, line)
+		}
+	}
+	for _, r := range s.Remotes {
+		if r.Type == "sse" {
+			lines = append(lines, "sse "+r.URL)
+		} else {
+			lines = append(lines, fmt.Sprintf("%s %s (not supported)", r.Type, r.URL))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,180 @@
+// Package registry searches an index of known MCP servers, such as the official
+// MCP registry, and turns its entries into server configs for mcp.json.
+//
+// The index is either the base URL of a registry that implements the
+// /v0/servers API or a local JSON file in the same format, which is useful for
+// curated company indexes and offline use.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the official MCP registry.
+const DefaultURL = "https://registry.modelcontextprotocol.io"
+
+// requestTimeout bounds a search, registries are expected to answer quickly.
+const requestTimeout = 15 * time.Second
+
+// Server is an entry of the index.
+type Server struct {
+	Name        string     `json:"name"` // reverse-DNS name, e.g. "io.github.upstash/context7"
+	Description string     `json:"description"`
+	Version     string     `json:"version"`
+	Repository  Repository `json:"repository"`
+	Packages    []Package  `json:"packages"`
+	Remotes     []Remote   `json:"remotes"`
+}
+
+// Repository is the source repository of a server.
+type Repository struct {
+	URL string `json:"url"`
+}
+
+// Package is a way to run a server locally from a package registry.
+type Package struct {
+	RegistryType         string    `json:"registryType"` // npm, pypi or oci
+	Identifier           string    `json:"identifier"`
+	Version              string    `json:"version"`
+	Transport            Transport `json:"transport"`
+	EnvironmentVariables []EnvVar  `json:"environmentVariables"`
+}
+
+// Transport is how a package talks to the client, e.g. "stdio".
+type Transport struct {
+	Type string `json:"type"`
+}
+
+// Remote is a hosted endpoint of a server.
+type Remote struct {
+	Type string `json:"type"` // sse or streamable-http
+	URL  string `json:"url"`
+}
+
+// EnvVar is an environment variable a package needs.
+type EnvVar struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsRequired  bool   `json:"isRequired"`
+	IsSecret    bool   `json:"isSecret"`
+}
+
+// listResponse is the body of GET /v0/servers. Newer registries wrap each
+// server in an object with registry metadata, older ones list them directly.
+type listResponse struct {
+	Servers []entry `json:"servers"`
+}
+
+type entry struct {
+	Server
+}
+
+func (e *entry) UnmarshalJSON(data []byte) error {
+	var wrapped struct {
+		Server *Server `json:"server"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return err
+	}
+	if wrapped.Server != nil {
+		e.Server = *wrapped.Server
+		return nil
+	}
+	return json.Unmarshal(data, &e.Server)
+}
+
+// Search returns up to limit servers of the index whose name or description
+// contains query, ignoring case. A limit <= 0 returns all matches.
+func Search(ctx context.Context, index, query string, limit int) ([]Server, error) {
+	var (
+		response listResponse
+		err      error
+	)
+	if isURL(index) {
+		err = fetch(ctx, index, query, limit, &response)
+	} else {
+		err = readFile(index, &response)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Remote registries already filter, but not necessarily on the description.
+	var servers []Server
+	seen := make(map[string]bool)
+	for _, e := range response.Servers {
+		if !matches(e.Server, query) || seen[e.Name] {
+			continue
+		}
+		seen[e.Name] = true
+		servers = append(servers, e.Server)
+		if limit > 0 && len(servers) == limit {
+			break
+		}
+	}
+	return servers, nil
+}
+
+func isURL(index string) bool {
+	return strings.HasPrefix(index, "http://") || strings.HasPrefix(index, "https://")
+}
+
+func fetch(ctx context.Context, index, query string, limit int, response *listResponse) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	params := url.Values{}
+	params.Set("search", query)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	// Only the latest version of each server is interesting for installing it.
+	params.Set("version", "latest")
+	endpoint := strings.TrimSuffix(index, "/") + "/v0/servers?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query registry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("registry returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("invalid registry response: %w", err)
+	}
+	return nil
+}
+
+func readFile(path string, response *listResponse) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("invalid index %s: %w", path, err)
+	}
+	return nil
+}
+
+func matches(s Server, query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	return query == "" ||
+		strings.Contains(strings.ToLower(s.Name), query) ||
+		strings.Contains(strings.ToLower(s.Description), query)
+}
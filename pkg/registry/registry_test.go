@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const index = `{
+  "servers": [
+    {"server": {
+      "name": "io.github.upstash/context7",
+      "description": "Up-to-date code documentation for LLMs",
+      "version": "1.0.14",
+      "packages": [{"registryType": "npm", "identifier": "@upstash/context7-mcp", "version": "1.0.14", "transport": {"type": "stdio"},
+        "environmentVariables": [{"name": "CONTEXT7_API_KEY", "description": "API key", "isSecret": true}]}]
+    }, "_meta": {"io.modelcontextprotocol.registry/official": {"isLatest": true}}},
+    {
+      "name": "io.example/weather",
+      "description": "Weather forecasts",
+      "version": "2.0.0",
+      "remotes": [{"type": "streamable-http", "url": "https://weather.example/mcp"}, {"type": "sse", "url": "https://weather.example/sse"}]
+    },
+    {
+      "name": "io.example/git",
+      "description": "Git repositories",
+      "version": "0.3.0",
+      "packages": [{"registryType": "pypi", "identifier": "mcp-server-git", "version": "0.3.0", "transport": {"type": "stdio"}}]
+    }
+  ]
+}`
+
+func TestSearch_URL(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v0/servers", r.URL.Path)
+		query = r.URL.Query().Get("search")
+		w.Write([]byte(index))
+	}))
+	defer srv.Close()
+
+	servers, err := Search(context.Background(), srv.URL+"/", "documentation", 10)
+	require.NoError(t, err)
+	assert.Equal(t, "documentation", query)
+	// Only the description matches, the other servers are filtered out.
+	require.Len(t, servers, 1)
+	assert.Equal(t, "io.github.upstash/context7", servers[0].Name)
+	assert.Equal(t, "1.0.14", servers[0].Version)
+}
+
+func TestSearch_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := Search(context.Background(), srv.URL, "git", 0)
+	assert.ErrorContains(t, err, "rate limited")
+}
+
+func TestSearch_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	require.NoError(t, os.WriteFile(path, []byte(index), 0644))
+
+	servers, err := Search(context.Background(), path, "", 2)
+	require.NoError(t, err)
+	assert.Len(t, servers, 2)
+
+	servers, err = Search(context.Background(), path, "WEATHER", 0)
+	require.NoError(t, err)
+	require.Len(t, servers, 1)
+	assert.Equal(t, "weather", servers[0].Key())
+}
+
+func TestServer_Config(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	require.NoError(t, os.WriteFile(path, []byte(index), 0644))
+	servers, err := Search(context.Background(), path, "", 0)
+	require.NoError(t, err)
+
+	config, ok := servers[0].Config()
+	require.True(t, ok)
+	assert.Equal(t, mcp.MCPServer{Command: "npx", Args: []string{"-y", "@upstash/context7-mcp@1.0.14"}}, config)
+	assert.Equal(t, []string{
+		"npx -y @upstash/context7-mcp@1.0.14",
+		"  env CONTEXT7_API_KEY (secret): API key",
+	}, servers[0].Instructions())
+
+	config, ok = servers[1].Config()
+	require.True(t, ok)
+	assert.Equal(t, mcp.MCPServer{Type: "sse", URL: "https://weather.example/sse"}, config)
+
+	config, ok = servers[2].Config()
+	require.True(t, ok)
+	assert.Equal(t, mcp.MCPServer{Command: "uvx", Args: []string{"mcp-server-git@0.3.0"}}, config)
+	assert.Equal(t, "git", servers[2].Key())
+
+	oci := Server{Packages: []Package{{RegistryType: "oci", Identifier: "ghcr.io/example/server", Version: "1.2"}}}
+	config, ok = oci.Config()
+	require.True(t, ok)
+	assert.Equal(t, "ghcr.io/example/server:1.2", config.Image)
+
+	_, ok = Server{Remotes: []Remote{{Type: "streamable-http", URL: "https://x"}}}.Config()
+	assert.False(t, ok)
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ollama/ollama/envconfig"
+)
+
+// Banner summarizes the environment an agent starts in. Empty fields are not
+// shown, so the exercises only fill in what they have.
+type Banner struct {
+	Title     string   // e.g. "Chat with Ollama"
+	Model     string   // the model answering
+	Provider  string   // e.g. "ollama" or "openai"
+	Endpoint  string   // URL of the inference server
+	Workspace string   // directory the agent works in
+	Profile   string   // MCP config profile
+	Servers   []string // connected MCP servers
+	Tools     int      // number of tools offered to the model
+	Hint      string   // how to quit or get help
+}
+
+// Banner writes the startup banner: the title in bold, one aligned line per
+// field and the hint in the note style.
+func (r *Renderer) Banner(b Banner) {
+	r.Heading(b.Title)
+
+	model := b.Model
+	if details := joinNonEmpty(", ", b.Provider, b.Endpoint); details != "" {
+		model += " " + r.Paint(Gray, "("+details+")")
+	}
+	r.bannerLine("model", model)
+	r.bannerLine("workspace", b.Workspace)
+	if len(b.Servers) > 0 || b.Profile != "" {
+		servers := strings.Join(b.Servers, ", ")
+		if len(b.Servers) == 0 {
+			servers = "none"
+		}
+		if b.Profile != "" {
+			servers += " " + r.Paint(Gray, "(profile "+b.Profile+")")
+		}
+		r.bannerLine("servers", servers)
+	}
+	if b.Tools > 0 {
+		r.bannerLine("tools", fmt.Sprint(b.Tools))
+	}
+	if b.Hint != "" {
+		r.Hint("%s", b.Hint)
+	}
+}
+
+// ExerciseBanner writes the banner of the exercise programs, which chat with a
+// model on the Ollama server configured by OLLAMA_HOST in the current
+// directory. A tools count of 0 omits the tools line.
+func (r *Renderer) ExerciseBanner(model string, tools int) {
+	workspace, _ := os.Getwd()
+	r.Banner(Banner{
+		Title:     "Chat with Ollama",
+		Model:     model,
+		Provider:  "ollama",
+		Endpoint:  envconfig.Host().String(),
+		Workspace: workspace,
+		Tools:     tools,
+		Hint:      "type 'exit' to quit",
+	})
+}
+
+func (r *Renderer) bannerLine(label, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(r.w, "  %s %s\n", r.Paint(Gray, fmt.Sprintf("%-9s", label)), value)
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, sep)
+}
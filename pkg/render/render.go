@@ -0,0 +1,143 @@
+// Package render formats the terminal output of the agents, so that assistant
+// replies, tool calls and results, errors and progress are labelled and
+// colored the same way in every program.
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Style is an ANSI SGR parameter.
+type Style string
+
+const (
+	Bold    Style = "1"
+	Gray    Style = "90" // notes and progress
+	Red     Style = "91" // errors
+	Green   Style = "92" // tool results and success
+	Yellow  Style = "93" // assistant replies and warnings
+	Blue    Style = "94" // the user
+	Magenta Style = "95" // questions and reviews
+	Cyan    Style = "96" // tool calls
+)
+
+// AssistantLabel labels the replies of the model.
+const AssistantLabel = "Ollama"
+
+// Renderer writes labelled lines such as "tool: read_file(...)" to a writer.
+type Renderer struct {
+	w     io.Writer
+	color bool
+}
+
+// New returns a Renderer writing to w, with ANSI colors if color is set.
+func New(w io.Writer, color bool) *Renderer {
+	return &Renderer{w: w, color: color}
+}
+
+// Stdout writes to standard output. Colors are disabled when NO_COLOR is set
+// (https://no-color.org) or the terminal is dumb.
+var Stdout = New(os.Stdout, os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb")
+
+// Writer returns the writer the renderer writes to.
+func (r *Renderer) Writer() io.Writer {
+	return r.w
+}
+
+// Paint wraps s in the escape codes of style.
+func (r *Renderer) Paint(style Style, s string) string {
+	if !r.color || s == "" {
+		return s
+	}
+	return "\u001b[" + string(style) + "m" + s + "\u001b[0m"
+}
+
+// Line writes "label: text" with the label in the given style.
+func (r *Renderer) Line(style Style, label, format string, args ...any) {
+	fmt.Fprintf(r.w, "%s: %s\n", r.Paint(style, label), fmt.Sprintf(format, args...))
+}
+
+// Assistant writes a reply of the model.
+func (r *Renderer) Assistant(text string) {
+	r.Line(Yellow, AssistantLabel, "%s", text)
+}
+
+// AssistantPrefix writes the label of a reply that is streamed after it.
+func (r *Renderer) AssistantPrefix() {
+	fmt.Fprintf(r.w, "%s:", r.Paint(Yellow, AssistantLabel))
+}
+
+// ToolCall writes a tool call with its arguments as JSON.
+func (r *Renderer) ToolCall(name string, args any) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		data = []byte(fmt.Sprint(args))
+	}
+	r.Line(Cyan, "tool", "%s(%s)", name, data)
+}
+
+// ToolResult writes the result of a tool call.
+func (r *Renderer) ToolResult(text string) {
+	r.Line(Green, "result", "%s", text)
+}
+
+// Error writes an error.
+func (r *Renderer) Error(err error) {
+	r.Line(Red, "error", "%s", err.Error())
+}
+
+// Errorf writes an error with a custom label, e.g. "verify".
+func (r *Renderer) Errorf(label, format string, args ...any) {
+	r.Line(Red, label, format, args...)
+}
+
+// Warning writes a warning.
+func (r *Renderer) Warning(label, format string, args ...any) {
+	r.Line(Yellow, label, format, args...)
+}
+
+// Success writes the successful outcome of an operation.
+func (r *Renderer) Success(label, format string, args ...any) {
+	r.Line(Green, label, format, args...)
+}
+
+// Note writes secondary information such as status updates.
+func (r *Renderer) Note(label, format string, args ...any) {
+	r.Line(Gray, label, format, args...)
+}
+
+// Hint writes a whole line in the note style, without a label.
+func (r *Renderer) Hint(format string, args ...any) {
+	fmt.Fprintln(r.w, r.Paint(Gray, fmt.Sprintf(format, args...)))
+}
+
+// Heading writes a section heading in bold.
+func (r *Renderer) Heading(text string) {
+	fmt.Fprintln(r.w, r.Paint(Bold, text))
+}
+
+// Progress writes the progress of a long running operation. A total <= 0 means
+// the total is unknown.
+func (r *Renderer) Progress(progress, total float64, message string) {
+	if total > 0 {
+		r.Note("progress", "%3.0f%% %s", progress/total*100, message)
+	} else {
+		r.Note("progress", "%v %s", progress, message)
+	}
+}
+
+// Status overwrites the current line with "label: text" in the note style,
+// for progress that is updated in place. The line is not terminated; write a
+// newline when the operation ends.
+func (r *Renderer) Status(label, format string, args ...any) {
+	// \u001b[K clears what is left of a longer previous status.
+	fmt.Fprintf(r.w, "\r%s: %s\u001b[K", r.Paint(Gray, label), fmt.Sprintf(format, args...))
+}
+
+// Prompt returns the label of an input prompt, e.g. "You:".
+func (r *Renderer) Prompt(label string) string {
+	return r.Paint(Blue, label) + ":"
+}
@@ -0,0 +1,89 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderer_Plain(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, false)
+
+	r.Assistant("hello")
+	r.ToolCall("read_file", map[string]any{"path": "main.go"})
+	r.ToolResult("package main")
+	r.Error(errors.New("boom"))
+	r.Progress(1, 4, "reading")
+	r.Progress(3, 0, "files")
+
+	assert.Equal(t, "Ollama: hello\n"+
+		"tool: read_file({\"path\":\"main.go\"})\n"+
+		"result: package main\n"+
+		"error: boom\n"+
+		"progress:  25% reading\n"+
+		"progress: 3 files\n", buf.String())
+}
+
+func TestRenderer_Color(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, true)
+
+	r.Warning("watchdog", "retrying %s", "qwen3")
+	assert.Equal(t, "\u001b[93mwatchdog\u001b[0m: retrying qwen3\n", buf.String())
+	assert.Equal(t, "\u001b[94mYou\u001b[0m:", r.Prompt("You"))
+	assert.Equal(t, "", r.Paint(Red, ""))
+}
+
+func TestRenderer_Status(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, false)
+
+	r.Status("pull", "pulling %s", "abc")
+	r.Status("pull", "done")
+	assert.Equal(t, "\rpull: pulling abc\u001b[K\rpull: done\u001b[K", buf.String())
+}
+
+func TestRenderer_Banner(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, false)
+
+	r.Banner(Banner{
+		Title:     "Chat with Ollama + MCP",
+		Model:     "qwen3:1.7b",
+		Provider:  "ollama",
+		Endpoint:  "http://127.0.0.1:11434",
+		Workspace: "/src/project",
+		Profile:   "dev",
+		Servers:   []string{"filesystem", "code_search"},
+		Tools:     12,
+		Hint:      "use 'ctrl-c' to quit",
+	})
+	assert.Equal(t, "Chat with Ollama + MCP\n"+
+		"  model     qwen3:1.7b (ollama, http://127.0.0.1:11434)\n"+
+		"  workspace /src/project\n"+
+		"  servers   filesystem, code_search (profile dev)\n"+
+		"  tools     12\n"+
+		"use 'ctrl-c' to quit\n", buf.String())
+
+	buf.Reset()
+	r.Banner(Banner{Title: "Chat with Ollama", Model: "llama3.1"})
+	assert.Equal(t, "Chat with Ollama\n  model     llama3.1\n", buf.String())
+}
+
+func TestRenderer_ExerciseBanner(t *testing.T) {
+	t.Setenv("OLLAMA_HOST", "http://127.0.0.1:11434")
+	dir := t.TempDir()
+	t.Chdir(dir)
+	var buf bytes.Buffer
+	r := New(&buf, false)
+
+	r.ExerciseBanner("llama3.1", 2)
+	assert.Equal(t, "Chat with Ollama\n"+
+		"  model     llama3.1 (ollama, http://127.0.0.1:11434)\n"+
+		"  workspace "+dir+"\n"+
+		"  tools     2\n"+
+		"type 'exit' to quit\n", buf.String())
+}
@@ -0,0 +1,127 @@
+// Package servers knows how to build the MCP servers shipped in this repository.
+package servers
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DefaultBinDir is where bundled servers are built, relative to the repository root.
+const DefaultBinDir = "bin"
+
+// Server is an MCP server shipped in this repository.
+type Server struct {
+	Name        string
+	Description string
+	Package     string // Go package path relative to the repository root
+	Type        string // "stdio" or "sse"
+	URL         string // Default endpoint of SSE servers
+	// ReadOnly lists the tools that do not change anything, which generated
+	// configs let the agent call without asking.
+	ReadOnly []string
+}
+
+// Bundled lists the servers under mcp_tool/.
+var Bundled = []Server{
+	{"filesystem", "文件系统 - 读写、编辑和搜索文件", "./mcp_tool/stdio/filesystem", "stdio", "",
+		[]string{"read_file", "list_directory", "get_file_info", "search_files"}},
+	{"code_search", "代码搜索 - 正则搜索、查找文件和符号定义", "./mcp_tool/stdio/code_search", "stdio", "",
+		[]string{"*"}},
+	{"web_browser", "网页浏览 - 获取网页内容、链接和截图 (SSE, 需单独启动)", "./mcp_tool/sse/web_browser", "sse", "http://localhost:9621",
+		[]string{"*"}},
+}
+
+// Resolve reports whether a server command points into mcp_tool/, either as
+// "go run <file or package>" or as a path inside a bundled server package.
+// It returns the bundled server and the remaining program arguments.
+func Resolve(command string, args []string) (Server, []string, bool) {
+	if command == "go" {
+		if len(args) < 2 || args[0] != "run" {
+			return Server{}, nil, false
+		}
+		if s, ok := find(args[1]); ok {
+			return s, args[2:], true
+		}
+		return Server{}, nil, false
+	}
+	if s, ok := find(command); ok {
+		return s, args, true
+	}
+	return Server{}, nil, false
+}
+
+// find returns the bundled server whose package contains path.
+func find(path string) (Server, bool) {
+	path = filepath.ToSlash(filepath.Clean(path))
+	if strings.HasSuffix(path, ".go") {
+		path = filepath.ToSlash(filepath.Dir(path))
+	}
+	for _, s := range Bundled {
+		if path == filepath.ToSlash(filepath.Clean(s.Package)) {
+			return s, true
+		}
+	}
+	return Server{}, false
+}
+
+// Binary returns the path of the server binary in binDir, with the .exe
+// extension Windows needs to run it.
+func Binary(s Server, binDir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(binDir, s.Name+".exe")
+	}
+	return filepath.Join(binDir, s.Name)
+}
+
+// Build compiles the server into binDir, stamping it with the repository version.
+// It must be called from the repository root and returns the binary path.
+func Build(s Server, binDir string) (string, error) {
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", binDir, err)
+	}
+
+	binary := Binary(s, binDir)
+	cmd := exec.Command("go", "build", "-ldflags", "-X main.version="+Version(), "-o", binary, s.Package)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to build %s: %w", s.Name, err)
+	}
+	return binary, nil
+}
+
+// Stale reports whether the binary is missing or older than any source file of the server.
+func Stale(s Server, binDir string) bool {
+	info, err := os.Stat(Binary(s, binDir))
+	if err != nil {
+		return true
+	}
+	built := info.ModTime()
+
+	stale := false
+	filepath.WalkDir(s.Package, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return err
+		}
+		if fi, err := d.Info(); err == nil && fi.ModTime().After(built) {
+			stale = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return stale
+}
+
+// Version describes the checked out revision, e.g. "v1.2.0-3-gabc1234-dirty".
+func Version() string {
+	out, err := exec.Command("git", "describe", "--tags", "--always", "--dirty").Output()
+	if err != nil {
+		return "dev"
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,58 @@
+package servers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name         string
+		command      string
+		args         []string
+		expectServer string
+		expectArgs   []string
+		expectOK     bool
+	}{
+		{
+			name:         "go run file",
+			command:      "go",
+			args:         []string{"run", "./mcp_tool/stdio/filesystem/filesystem.go"},
+			expectServer: "filesystem",
+			expectArgs:   []string{},
+			expectOK:     true,
+		},
+		{
+			name:         "go run package with args",
+			command:      "go",
+			args:         []string{"run", "mcp_tool/stdio/code_search", "--root", "."},
+			expectServer: "code_search",
+			expectArgs:   []string{"--root", "."},
+			expectOK:     true,
+		},
+		{
+			name:     "other go run",
+			command:  "go",
+			args:     []string{"run", "./cmd/other"},
+			expectOK: false,
+		},
+		{
+			name:     "external command",
+			command:  "npx",
+			args:     []string{"-y", "@upstash/context7-mcp@latest"},
+			expectOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, args, ok := Resolve(tt.command, tt.args)
+			assert.Equal(t, tt.expectOK, ok)
+			if tt.expectOK {
+				assert.Equal(t, tt.expectServer, server.Name)
+				assert.Equal(t, tt.expectArgs, args)
+			}
+		})
+	}
+}
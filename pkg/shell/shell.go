@@ -0,0 +1,69 @@
+// Package shell runs the commands of the bash tools with the shell available on
+// the platform: bash where it is installed (including Git Bash on Windows), and
+// otherwise sh on Unix and PowerShell or cmd on Windows.
+package shell
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Shell is a command interpreter that runs a script given as an argument.
+type Shell struct {
+	// Name is the name shown to the model, e.g. "bash" or "powershell", so that
+	// it writes commands in the right syntax.
+	Name string
+	// Path is the executable of the shell.
+	Path string
+	// Args precede the script, e.g. ["-c"].
+	Args []string
+}
+
+// Command returns a command that runs script with the shell.
+func (s Shell) Command(script string) *exec.Cmd {
+	args := append(append([]string(nil), s.Args...), script)
+	return exec.Command(s.Path, args...)
+}
+
+// CommandContext is like Command, but the shell is killed when ctx is done.
+// Output is then collected for at most another second, so that processes the
+// script started and that keep its output open do not block the caller.
+func (s Shell) CommandContext(ctx context.Context, script string) *exec.Cmd {
+	args := append(append([]string(nil), s.Args...), script)
+	cmd := exec.CommandContext(ctx, s.Path, args...)
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// candidate is a shell that is used if its executable is found.
+type candidate struct {
+	name string
+	args []string
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultShell Shell
+)
+
+// Default returns the first shell of the platform that is installed. If none
+// is found, the first candidate is returned so that running it reports a
+// meaningful error.
+func Default() Shell {
+	defaultOnce.Do(func() {
+		defaultShell = find(candidates)
+	})
+	return defaultShell
+}
+
+// find returns the first of the shells whose executable is in PATH.
+func find(shells []candidate) Shell {
+	for _, c := range shells {
+		if path, err := exec.LookPath(c.name); err == nil {
+			return Shell{Name: c.name, Path: path, Args: c.args}
+		}
+	}
+	return Shell{Name: shells[0].name, Path: shells[0].name, Args: shells[0].args}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package shell
+
+var candidates = []candidate{
+	{name: "bash", args: []string{"-c"}},
+	{name: "sh", args: []string{"-c"}},
+}
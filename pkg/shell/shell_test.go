@@ -0,0 +1,45 @@
+//go:build !windows
+
+package shell
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault_RunsScript(t *testing.T) {
+	sh := Default()
+	require.NotEmpty(t, sh.Path)
+
+	output, err := sh.Command("echo hello && echo world").CombinedOutput()
+	require.NoError(t, err)
+	assert.Equal(t, "hello\nworld", strings.TrimSpace(string(output)))
+}
+
+func TestCommandContext_StopsAtDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The background sleep keeps the output open after the shell is killed.
+	start := time.Now()
+	_, err := Default().CommandContext(ctx, "sleep 30 & sleep 30").CombinedOutput()
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestFind_FallsBack(t *testing.T) {
+	sh := find([]candidate{
+		{name: "no-such-shell-xyz", args: []string{"-x"}},
+		{name: "sh", args: []string{"-c"}},
+	})
+	assert.Equal(t, "sh", sh.Name)
+	assert.Equal(t, []string{"-c"}, sh.Args)
+
+	sh = find([]candidate{{name: "no-such-shell-xyz", args: []string{"-x"}}})
+	assert.Equal(t, Shell{Name: "no-such-shell-xyz", Path: "no-such-shell-xyz", Args: []string{"-x"}}, sh)
+}
@@ -0,0 +1,8 @@
+package shell
+
+var candidates = []candidate{
+	{name: "bash", args: []string{"-c"}},
+	{name: "pwsh", args: []string{"-NoProfile", "-NonInteractive", "-Command"}},
+	{name: "powershell", args: []string{"-NoProfile", "-NonInteractive", "-Command"}},
+	{name: "cmd", args: []string{"/C"}},
+}
@@ -0,0 +1,64 @@
+// Package stream accumulates the content of streamed model responses.
+package stream
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultLimit is the default maximum size of a streamed response in bytes.
+const DefaultLimit = 256 * 1024
+
+// ErrLimit is returned by Builder.Write once the response reached the size limit.
+// Returning it from a streaming callback stops the response.
+var ErrLimit = errors.New("response exceeded the output size limit")
+
+// Builder collects streamed chunks in amortized linear time and enforces a size limit.
+// The zero value has no limit.
+type Builder struct {
+	sb        strings.Builder
+	limit     int
+	truncated bool
+}
+
+// NewBuilder returns a Builder that keeps at most limit bytes. A limit <= 0 means no limit.
+func NewBuilder(limit int) *Builder {
+	return &Builder{limit: limit}
+}
+
+// Write appends a chunk and returns the part that was kept, so that callers can
+// display exactly what ends up in the content. Once the limit is reached the rest
+// of the chunk is dropped and ErrLimit is returned.
+func (b *Builder) Write(chunk string) (string, error) {
+	if b.truncated {
+		return "", ErrLimit
+	}
+	if b.limit > 0 && b.sb.Len()+len(chunk) > b.limit {
+		kept := chunk[:b.limit-b.sb.Len()]
+		// Do not split a multi-byte character.
+		for len(kept) > 0 && !utf8.ValidString(kept) {
+			kept = kept[:len(kept)-1]
+		}
+		b.sb.WriteString(kept)
+		b.truncated = true
+		return kept, ErrLimit
+	}
+	b.sb.WriteString(chunk)
+	return chunk, nil
+}
+
+// String returns the collected content.
+func (b *Builder) String() string {
+	return b.sb.String()
+}
+
+// Len returns the size of the collected content in bytes.
+func (b *Builder) Len() int {
+	return b.sb.Len()
+}
+
+// Truncated reports whether content was dropped because of the limit.
+func (b *Builder) Truncated() bool {
+	return b.truncated
+}
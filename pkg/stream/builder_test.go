@@ -0,0 +1,47 @@
+package stream
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder_NoLimit(t *testing.T) {
+	var b Builder
+	for range 1000 {
+		kept, err := b.Write("chunk ")
+		assert.NoError(t, err)
+		assert.Equal(t, "chunk ", kept)
+	}
+	assert.Equal(t, strings.Repeat("chunk ", 1000), b.String())
+	assert.False(t, b.Truncated())
+}
+
+func TestBuilder_Limit(t *testing.T) {
+	b := NewBuilder(10)
+
+	kept, err := b.Write("hello ")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello ", kept)
+
+	kept, err = b.Write("world!")
+	assert.ErrorIs(t, err, ErrLimit)
+	assert.Equal(t, "worl", kept)
+
+	kept, err = b.Write("more")
+	assert.ErrorIs(t, err, ErrLimit)
+	assert.Empty(t, kept)
+
+	assert.Equal(t, "hello worl", b.String())
+	assert.True(t, b.Truncated())
+}
+
+func TestBuilder_LimitKeepsCharactersWhole(t *testing.T) {
+	b := NewBuilder(4)
+
+	kept, err := b.Write("ab你好")
+	assert.ErrorIs(t, err, ErrLimit)
+	assert.Equal(t, "ab", kept)
+	assert.Equal(t, 2, b.Len())
+}
@@ -0,0 +1,52 @@
+package textenc
+
+import "strings"
+
+// LineEndings is the line ending convention of a text file.
+type LineEndings struct {
+	// CRLF is set if most lines end with "\r\n" rather than "\n".
+	CRLF bool
+	// FinalNewline is set if the last line ends with a line ending.
+	FinalNewline bool
+
+	known bool // the text had at least one line ending
+}
+
+// DetectLineEndings returns the dominant line ending of text and whether it
+// ends with one.
+func DetectLineEndings(text string) LineEndings {
+	lf := strings.Count(text, "\n")
+	if lf == 0 {
+		return LineEndings{}
+	}
+	crlf := strings.Count(text, "\r\n")
+	return LineEndings{
+		CRLF:         crlf > lf-crlf,
+		FinalNewline: strings.HasSuffix(text, "\n"),
+		known:        true,
+	}
+}
+
+// ToLF converts the "\r\n" line endings of text to "\n".
+func ToLF(text string) string {
+	return strings.ReplaceAll(text, "\r\n", "\n")
+}
+
+// Apply converts the line endings of text to the convention, and adds or
+// removes the final newline to match it. Conventions detected in text without
+// line endings only leave text unchanged.
+func (l LineEndings) Apply(text string) string {
+	if !l.known {
+		return text
+	}
+	text = ToLF(text)
+	if l.FinalNewline && text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	} else if !l.FinalNewline {
+		text = strings.TrimSuffix(text, "\n")
+	}
+	if l.CRLF {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	return text
+}
@@ -0,0 +1,28 @@
+package textenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLineEndings(t *testing.T) {
+	assert.Equal(t, LineEndings{CRLF: true, FinalNewline: true, known: true}, DetectLineEndings("a\r\nb\r\n"))
+	assert.Equal(t, LineEndings{FinalNewline: false, known: true}, DetectLineEndings("a\nb"))
+	// Mixed files use the dominant line ending.
+	assert.Equal(t, LineEndings{CRLF: true, known: true}, DetectLineEndings("a\r\nb\r\nc\nd"))
+	assert.Equal(t, LineEndings{}, DetectLineEndings("single line"))
+}
+
+func TestLineEndings_Apply(t *testing.T) {
+	crlf := DetectLineEndings("a\r\nb\r\n")
+	assert.Equal(t, "x\r\ny\r\n", crlf.Apply("x\ny"))
+	assert.Equal(t, "x\r\ny\r\n", crlf.Apply("x\r\ny\n"))
+
+	noFinal := DetectLineEndings("a\nb")
+	assert.Equal(t, "x\ny", noFinal.Apply("x\ny\n"))
+
+	// Without a detected convention the text is left as it is.
+	assert.Equal(t, "x\r\ny\n", DetectLineEndings("").Apply("x\r\ny\n"))
+	assert.Equal(t, "", crlf.Apply(""))
+}
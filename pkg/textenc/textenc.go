@@ -0,0 +1,148 @@
+// Package textenc detects the encoding and line endings of text files, so that
+// file tools can hand UTF-8 to the model and write edits back in the file's
+// original format.
+package textenc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Encoding is a text encoding that files are read and written in.
+type Encoding struct {
+	Name  string
+	bom   []byte
+	codec encoding.Encoding // nil for UTF-8
+}
+
+var (
+	UTF8    = Encoding{Name: "UTF-8"}
+	UTF8BOM = Encoding{Name: "UTF-8 with BOM", bom: []byte{0xEF, 0xBB, 0xBF}}
+	UTF16LE = Encoding{Name: "UTF-16LE", bom: []byte{0xFF, 0xFE}, codec: unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)}
+	UTF16BE = Encoding{Name: "UTF-16BE", bom: []byte{0xFE, 0xFF}, codec: unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)}
+	// GBK is decoded and encoded as GB18030, its superset.
+	GBK = Encoding{Name: "GBK", codec: simplifiedchinese.GB18030}
+	// Latin1 is decoded and encoded as Windows-1252, the superset browsers and
+	// editors use for files labelled ISO-8859-1.
+	Latin1 = Encoding{Name: "Latin-1", codec: charmap.Windows1252}
+)
+
+// IsUTF8 reports whether the encoding is UTF-8 without a BOM, i.e. whether the
+// file's content is passed on unchanged.
+func (e Encoding) IsUTF8() bool {
+	return e.codec == nil && len(e.bom) == 0
+}
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	return e.Name
+}
+
+// Detect returns the encoding of data: the one given by a byte order mark, UTF-8
+// if data is valid UTF-8, GBK if its non-ASCII bytes look like Chinese text and
+// Latin-1 otherwise.
+func Detect(data []byte) Encoding {
+	for _, enc := range []Encoding{UTF8BOM, UTF16LE, UTF16BE} {
+		if bytes.HasPrefix(data, enc.bom) {
+			return enc
+		}
+	}
+	if utf8.Valid(data) {
+		return UTF8
+	}
+	if looksLikeGBK(data) {
+		return GBK
+	}
+	return Latin1
+}
+
+// looksLikeGBK reports whether all non-ASCII bytes of data form GBK double-byte
+// characters, and most of them are in the GB2312 range of common Chinese
+// characters and punctuation. Latin-1 text rarely has two high bytes in a row.
+func looksLikeGBK(data []byte) bool {
+	pairs, common := 0, 0
+	for i := 0; i < len(data); i++ {
+		lead := data[i]
+		if lead < 0x80 {
+			continue
+		}
+		if lead == 0x80 || lead == 0xFF || i+1 == len(data) {
+			return false
+		}
+		trail := data[i+1]
+		if trail < 0x40 || trail == 0x7F || trail == 0xFF {
+			return false
+		}
+		pairs++
+		if lead >= 0xA1 && lead <= 0xF7 && trail >= 0xA1 {
+			common++
+		}
+		i++
+	}
+	return pairs > 0 && common*2 >= pairs
+}
+
+// Decode converts data in the given encoding to UTF-8, removing the byte order mark.
+func Decode(data []byte, enc Encoding) (string, error) {
+	data = bytes.TrimPrefix(data, enc.bom)
+	if enc.codec == nil {
+		return string(data), nil
+	}
+	decoded, err := enc.codec.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", enc.Name, err)
+	}
+	return string(decoded), nil
+}
+
+// Encode converts text to the given encoding, adding its byte order mark. It
+// fails if the text contains characters the encoding cannot represent.
+func Encode(text string, enc Encoding) ([]byte, error) {
+	data := []byte(text)
+	if enc.codec != nil {
+		encoded, err := enc.codec.NewEncoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("text cannot be encoded in %s: %w", enc.Name, err)
+		}
+		data = encoded
+	}
+	if len(enc.bom) == 0 {
+		return data, nil
+	}
+	return append(append([]byte(nil), enc.bom...), data...), nil
+}
+
+// ReadFile reads a text file and returns its content as UTF-8 together with the
+// detected encoding, to be passed to WriteFile when the edited text is saved.
+func ReadFile(path string) (string, Encoding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", Encoding{}, err
+	}
+	enc := Detect(data)
+	text, err := Decode(data, enc)
+	return text, enc, err
+}
+
+// Match converts text to the encoding and line endings of the existing file at
+// path, so that rewriting the file keeps its format. Text for a new file is
+// returned as UTF-8 unchanged.
+func Match(path, text string) ([]byte, error) {
+	old, enc, err := ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []byte(text), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Encode(DetectLineEndings(old).Apply(text), enc)
+}
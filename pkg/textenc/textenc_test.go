@@ -0,0 +1,86 @@
+package textenc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Encoding
+	}{
+		{"ascii", []byte("package main\n"), UTF8},
+		{"utf-8", []byte("你好，世界\n"), UTF8},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFhello"), UTF8BOM},
+		{"utf-16le", []byte("\xFF\xFEh\x00i\x00"), UTF16LE},
+		{"utf-16be", []byte("\xFE\xFF\x00h\x00i"), UTF16BE},
+		// "你好，世界" in GBK.
+		{"gbk", []byte("\xC4\xE3\xBA\xC3\xA3\xAC\xCA\xC0\xBD\xE7\n"), GBK},
+		// "café naïve" in Latin-1.
+		{"latin-1", []byte("caf\xE9 na\xEFve\n"), Latin1},
+		{"latin-1 trailing", []byte("caf\xE9"), Latin1},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want.Name, Detect(tt.data).Name, tt.name)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		enc  Encoding
+		text string
+	}{
+		{UTF8, "你好\n"},
+		{UTF8BOM, "你好\n"},
+		{UTF16LE, "你好\n"},
+		{UTF16BE, "你好\n"},
+		{GBK, "你好，世界\n"},
+		{Latin1, "café naïve\n"},
+	} {
+		data, err := Encode(tt.text, tt.enc)
+		require.NoError(t, err, tt.enc.Name)
+		assert.Equal(t, tt.enc.Name, Detect(data).Name)
+
+		text, err := Decode(data, tt.enc)
+		require.NoError(t, err, tt.enc.Name)
+		assert.Equal(t, tt.text, text, tt.enc.Name)
+	}
+}
+
+func TestEncode_Unrepresentable(t *testing.T) {
+	_, err := Encode("你好", Latin1)
+	assert.ErrorContains(t, err, "cannot be encoded in Latin-1")
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gbk.txt")
+	require.NoError(t, os.WriteFile(path, []byte("\xC4\xE3\xBA\xC3\n"), 0o644))
+
+	text, enc, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "你好\n", text)
+	assert.Equal(t, GBK.Name, enc.Name)
+	assert.False(t, enc.IsUTF8())
+}
+
+func TestMatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gbk.txt")
+	require.NoError(t, os.WriteFile(path, []byte("\xC4\xE3\r\n\xBA\xC3\r\n"), 0o644))
+
+	// The new text is written in GBK with CRLF line endings like the file.
+	data, err := Match(path, "你\n好\n世界")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\xC4\xE3\r\n\xBA\xC3\r\n\xCA\xC0\xBD\xE7\r\n"), data)
+
+	data, err = Match(filepath.Join(dir, "new.txt"), "new\n")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new\n"), data)
+}
@@ -0,0 +1,120 @@
+// Package vectorindex splits workspace files into chunks and finds the chunks
+// most similar to a query by the cosine similarity of their embeddings. The
+// index is kept in memory; embeddings come from a caller-supplied function,
+// e.g. an Ollama embedding model.
+package vectorindex
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DefaultChunkLines is the number of lines per chunk used by Split.
+const DefaultChunkLines = 40
+
+// batchSize is the number of chunks embedded per call of the EmbedFunc.
+const batchSize = 32
+
+// EmbedFunc returns one embedding per input text, in order.
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
+// Chunk is a range of lines of a file.
+type Chunk struct {
+	Path      string // relative to the workspace
+	StartLine int    // 1-based, inclusive
+	EndLine   int    // inclusive
+	Text      string
+}
+
+// String returns the location of the chunk, e.g. "main.go:41-80".
+func (c Chunk) String() string {
+	return fmt.Sprintf("%s:%d-%d", c.Path, c.StartLine, c.EndLine)
+}
+
+// Split cuts text into chunks of at most lines lines. Chunks without any
+// non-blank character are dropped.
+func Split(path, text string, lines int) []Chunk {
+	if lines <= 0 {
+		lines = DefaultChunkLines
+	}
+	all := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	var chunks []Chunk
+	for start := 0; start < len(all); start += lines {
+		end := min(start+lines, len(all))
+		body := strings.Join(all[start:end], "\n")
+		if strings.TrimSpace(body) == "" {
+			continue
+		}
+		chunks = append(chunks, Chunk{Path: path, StartLine: start + 1, EndLine: end, Text: body})
+	}
+	return chunks
+}
+
+// Index holds chunks with their embeddings.
+type Index struct {
+	chunks  []Chunk
+	vectors [][]float32
+}
+
+// Build embeds the chunks in batches and returns their index.
+func Build(ctx context.Context, chunks []Chunk, embed EmbedFunc) (*Index, error) {
+	idx := &Index{chunks: chunks, vectors: make([][]float32, 0, len(chunks))}
+	for start := 0; start < len(chunks); start += batchSize {
+		batch := chunks[start:min(start+batchSize, len(chunks))]
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			// The path helps to match questions that name a file or package.
+			texts[i] = c.Path + "\n" + c.Text
+		}
+		vectors, err := embed(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(batch) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(vectors))
+		}
+		idx.vectors = append(idx.vectors, vectors...)
+	}
+	return idx, nil
+}
+
+// Len returns the number of chunks in the index.
+func (idx *Index) Len() int {
+	return len(idx.chunks)
+}
+
+// Result is a chunk found by Search.
+type Result struct {
+	Chunk
+	Score float64
+}
+
+// Search returns the k chunks most similar to the query embedding, best first.
+// Chunks scoring at or below minScore are left out.
+func (idx *Index) Search(query []float32, k int, minScore float64) []Result {
+	results := make([]Result, 0, len(idx.chunks))
+	for i, c := range idx.chunks {
+		if score := Cosine(query, idx.vectors[i]); score > minScore {
+			results = append(results, Result{Chunk: c, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results[:min(k, len(results))]
+}
+
+// Cosine returns the cosine similarity of two vectors, 0 if either is zero.
+func Cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
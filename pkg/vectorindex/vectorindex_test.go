@@ -0,0 +1,76 @@
+package vectorindex
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	text := "a\nb\nc\n\n\n\nd\n"
+	chunks := Split("x.go", text, 3)
+	require.Len(t, chunks, 2)
+	assert.Equal(t, Chunk{Path: "x.go", StartLine: 1, EndLine: 3, Text: "a\nb\nc"}, chunks[0])
+	// Lines 4-6 are blank and dropped.
+	assert.Equal(t, "x.go:7-7", chunks[1].String())
+	assert.Equal(t, "d", chunks[1].Text)
+}
+
+// wordEmbed embeds a text as the counts of a few words.
+func wordEmbed(_ context.Context, texts []string) ([][]float32, error) {
+	words := []string{"http", "parse", "database"}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = make([]float32, len(words))
+		for j, w := range words {
+			vectors[i][j] = float32(strings.Count(text, w))
+		}
+	}
+	return vectors, nil
+}
+
+func TestIndex_Search(t *testing.T) {
+	chunks := []Chunk{
+		{Path: "server.go", Text: "http handler http"},
+		{Path: "parser.go", Text: "parse the input"},
+		{Path: "db.go", Text: "database connection"},
+		{Path: "mixed.go", Text: "http parse"},
+	}
+	idx, err := Build(context.Background(), chunks, wordEmbed)
+	require.NoError(t, err)
+	assert.Equal(t, 4, idx.Len())
+
+	query, _ := wordEmbed(context.Background(), []string{"http"})
+	results := idx.Search(query[0], 2, 0)
+	require.Len(t, results, 2)
+	assert.Equal(t, "server.go", results[0].Path)
+	assert.Equal(t, "mixed.go", results[1].Path)
+	assert.InDelta(t, 1.0, results[0].Score, 1e-9)
+
+	// db.go does not match at all and is never returned.
+	results = idx.Search(query[0], 10, 0)
+	assert.Len(t, results, 2)
+}
+
+func TestBuild_Errors(t *testing.T) {
+	chunks := []Chunk{{Path: "a", Text: "a"}}
+	_, err := Build(context.Background(), chunks, func(context.Context, []string) ([][]float32, error) {
+		return nil, errors.New("model not found")
+	})
+	assert.ErrorContains(t, err, "model not found")
+
+	_, err = Build(context.Background(), chunks, func(context.Context, []string) ([][]float32, error) {
+		return nil, nil
+	})
+	assert.ErrorContains(t, err, "expected 1 embeddings")
+}
+
+func TestCosine(t *testing.T) {
+	assert.InDelta(t, 1.0, Cosine([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, Cosine([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Equal(t, 0.0, Cosine([]float32{0, 0}, []float32{1, 1}))
+}
@@ -0,0 +1,73 @@
+package workspace
+
+import (
+	"context"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Roots caches the workspace directories that MCP clients declare through the
+// roots capability, so that a server does not ask the client for them on every
+// tool call. Set Invalidate as the server's RootsListChangedHandler to fetch
+// them again after the client changes its roots.
+type Roots struct {
+	mu    sync.Mutex
+	roots map[*mcp.ServerSession][]string
+	// gen counts invalidations, so that roots listed before a change are not cached.
+	gen int
+}
+
+// Get returns the roots of the session's client as local paths, or nil if the
+// client does not support roots or they cannot be listed.
+func (r *Roots) Get(ctx context.Context, session *mcp.ServerSession) []string {
+	if session == nil {
+		return nil
+	}
+	params := session.InitializeParams()
+	if params == nil || params.Capabilities == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	roots, ok := r.roots[session]
+	gen := r.gen
+	r.mu.Unlock()
+	if ok {
+		return roots
+	}
+
+	result, err := session.ListRoots(ctx, nil)
+	if err != nil {
+		// Not cached, the next call asks again.
+		return nil
+	}
+	roots = make([]string, 0, len(result.Roots))
+	for _, root := range result.Roots {
+		path, err := PathFromURI(root.URI)
+		if err != nil {
+			continue
+		}
+		roots = append(roots, path)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gen != gen {
+		return roots
+	}
+	if r.roots == nil {
+		r.roots = make(map[*mcp.ServerSession][]string)
+	}
+	r.roots[session] = roots
+	return roots
+}
+
+// Invalidate drops the cached roots of the session that sent the
+// roots/list_changed notification.
+func (r *Roots) Invalidate(_ context.Context, req *mcp.RootsListChangedRequest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gen++
+	delete(r.roots, req.Session)
+}
@@ -0,0 +1,78 @@
+package workspace
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connect returns a server session whose client declares the given roots, and
+// counts the roots/list requests the client receives.
+func connect(t *testing.T, roots *Roots, dirs ...string) (*mcp.Client, *mcp.ServerSession, *atomic.Int32) {
+	t.Helper()
+	ctx := context.Background()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "client"}, nil)
+	for _, dir := range dirs {
+		client.AddRoots(&mcp.Root{URI: FileURI(dir)})
+	}
+	var lists atomic.Int32
+	client.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method == "roots/list" {
+				lists.Add(1)
+			}
+			return next(ctx, method, req)
+		}
+	})
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "server"}, &mcp.ServerOptions{
+		RootsListChangedHandler: roots.Invalidate,
+	})
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	session, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		clientSession.Close()
+		session.Close()
+	})
+	return client, session, &lists
+}
+
+func TestRootsCached(t *testing.T) {
+	var roots Roots
+	dir := filepath.FromSlash("/home/me/src")
+	_, session, lists := connect(t, &roots, dir)
+
+	assert.Equal(t, []string{dir}, roots.Get(context.Background(), session))
+	assert.Equal(t, []string{dir}, roots.Get(context.Background(), session))
+	assert.Equal(t, int32(1), lists.Load())
+}
+
+func TestRootsListChanged(t *testing.T) {
+	var roots Roots
+	first, second := filepath.FromSlash("/home/me/a"), filepath.FromSlash("/home/me/b")
+	client, session, lists := connect(t, &roots, first)
+	require.Equal(t, []string{first}, roots.Get(context.Background(), session))
+
+	// AddRoots notifies the server, which drops the cached roots.
+	client.AddRoots(&mcp.Root{URI: FileURI(second)})
+	assert.Eventually(t, func() bool {
+		return len(roots.Get(context.Background(), session)) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{first, second}, roots.Get(context.Background(), session))
+	assert.GreaterOrEqual(t, lists.Load(), int32(2))
+}
+
+func TestRootsNoSession(t *testing.T) {
+	var roots Roots
+	assert.Nil(t, roots.Get(context.Background(), nil))
+}
@@ -0,0 +1,41 @@
+package workspace
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// FileURI returns the file URI of an absolute path, e.g. "file:///home/me/src"
+// or "file:///C:/Users/me/src" on Windows.
+func FileURI(path string) string {
+	slashed := filepath.ToSlash(path)
+	if !strings.HasPrefix(slashed, "/") {
+		// Drive letters follow the empty host: file:///C:/...
+		slashed = "/" + slashed
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String()
+}
+
+// PathFromURI returns the local path of a file URI, the inverse of FileURI.
+func PathFromURI(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" || u.Path == "" {
+		return "", fmt.Errorf("not a file URI: %s", uri)
+	}
+	path := u.Path
+	if isDrivePath(path) {
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path)), nil
+}
+
+// isDrivePath reports whether path starts with a Windows drive, as in "/C:/Users".
+func isDrivePath(path string) bool {
+	return len(path) >= 3 && path[0] == '/' && path[2] == ':' &&
+		('a' <= path[1] && path[1] <= 'z' || 'A' <= path[1] && path[1] <= 'Z')
+}
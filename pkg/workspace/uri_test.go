@@ -0,0 +1,28 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileURI(t *testing.T) {
+	assert.Equal(t, "file:///home/me/my%20src", FileURI("/home/me/my src"))
+	// On Windows the drive follows the empty host.
+	assert.Equal(t, "file:///C:/Users/me", FileURI("C:/Users/me"))
+}
+
+func TestPathFromURI(t *testing.T) {
+	path, err := PathFromURI("file:///home/me/my%20src")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.FromSlash("/home/me/my src"), path)
+
+	path, err = PathFromURI("file:///C:/Users/me")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.FromSlash("C:/Users/me"), path)
+
+	_, err = PathFromURI("https://example.com/src")
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+// Package workspace confines the paths used by file tools to workspace
+// directories, so that a model cannot read or write files elsewhere through
+// ".." components or symlinks.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutside is returned for paths that resolve to a location outside every root.
+var ErrOutside = errors.New("path is outside the workspace")
+
+// maxLinks bounds the symlinks followed while resolving a path, like the kernel's ELOOP limit.
+const maxLinks = 40
+
+// Resolve returns the absolute path that path refers to, with all symlinks
+// resolved. Relative paths are relative to the first root. The result must lie
+// within one of the roots, otherwise an error wrapping ErrOutside is returned.
+// Without roots, the path is resolved but not restricted.
+//
+// Paths that do not exist yet, such as a file about to be created, are resolved
+// through their deepest existing parent, so creating them cannot escape either.
+func Resolve(roots []string, path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is empty")
+	}
+
+	resolvedRoots := make([]string, 0, len(roots))
+	for _, root := range roots {
+		resolved, err := resolveExisting(root)
+		if err != nil {
+			return "", fmt.Errorf("invalid workspace root %s: %w", root, err)
+		}
+		resolvedRoots = append(resolvedRoots, resolved)
+	}
+
+	if !filepath.IsAbs(path) && len(resolvedRoots) > 0 {
+		path = filepath.Join(resolvedRoots[0], path)
+	}
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resolvedRoots) == 0 {
+		return resolved, nil
+	}
+	for _, root := range resolvedRoots {
+		if Contains(root, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s is not within %s", ErrOutside, path, strings.Join(roots, ", "))
+}
+
+// InWorkingDir resolves path within the current working directory, see Resolve.
+func InWorkingDir(path string) (string, error) {
+	return Resolve([]string{"."}, path)
+}
+
+// Contains reports whether path is root or lies below it. Both must be absolute and clean.
+func Contains(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// resolveExisting makes path absolute and resolves the symlinks of its existing
+// part; the components that do not exist yet are appended unchanged.
+func resolveExisting(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	for links := 0; ; links++ {
+		if links > maxLinks {
+			return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+		}
+
+		// Find the deepest existing ancestor.
+		existing, rest := path, ""
+		for {
+			if _, err := os.Lstat(existing); err == nil {
+				break
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+			parent := filepath.Dir(existing)
+			if parent == existing {
+				break
+			}
+			rest = filepath.Join(filepath.Base(existing), rest)
+			existing = parent
+		}
+
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		// existing is a dangling symlink: writing to it would create its target,
+		// so continue with the target instead.
+		target, err := os.Readlink(existing)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(existing), target)
+		}
+		path = filepath.Join(target, rest)
+	}
+}
@@ -0,0 +1,103 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWorkspace creates a workspace and a directory outside of it, both with
+// symlinks resolved so that paths can be compared.
+func newWorkspace(t *testing.T) (root, outside string) {
+	t.Helper()
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	root = filepath.Join(base, "workspace")
+	outside = filepath.Join(base, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src"), 0o755))
+	require.NoError(t, os.MkdirAll(outside, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o644))
+	return root, outside
+}
+
+func TestResolve_InsideWorkspace(t *testing.T) {
+	root, _ := newWorkspace(t)
+	roots := []string{root}
+
+	for _, path := range []string{
+		"src/main.go",
+		"./src/../src/main.go",
+		filepath.Join(root, "src", "main.go"),
+	} {
+		resolved, err := Resolve(roots, path)
+		require.NoError(t, err, path)
+		assert.Equal(t, filepath.Join(root, "src", "main.go"), resolved, path)
+	}
+
+	// Files that do not exist yet can be created.
+	resolved, err := Resolve(roots, "src/new/file.go")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "src", "new", "file.go"), resolved)
+
+	resolved, err = Resolve(roots, ".")
+	require.NoError(t, err)
+	assert.Equal(t, root, resolved)
+}
+
+func TestResolve_Traversal(t *testing.T) {
+	root, outside := newWorkspace(t)
+	roots := []string{root}
+
+	for _, path := range []string{
+		"../outside/secret",
+		"src/../../outside/secret",
+		"../workspace-evil/file",
+		filepath.Join(outside, "secret"),
+		"/etc/passwd",
+	} {
+		_, err := Resolve(roots, path)
+		assert.ErrorIs(t, err, ErrOutside, path)
+	}
+}
+
+func TestResolve_Symlinks(t *testing.T) {
+	root, outside := newWorkspace(t)
+	roots := []string{root}
+
+	// A symlinked directory and file pointing outside the workspace.
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(root, "secret.txt")))
+	// A dangling symlink whose target would be created outside by a write.
+	require.NoError(t, os.Symlink(filepath.Join(outside, "created"), filepath.Join(root, "dangling")))
+	// A symlink that stays inside the workspace is fine.
+	require.NoError(t, os.Symlink("src", filepath.Join(root, "code")))
+
+	for _, path := range []string{"escape/secret", "escape/new.txt", "secret.txt", "dangling"} {
+		_, err := Resolve(roots, path)
+		assert.ErrorIs(t, err, ErrOutside, path)
+	}
+
+	resolved, err := Resolve(roots, "code/main.go")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "src", "main.go"), resolved)
+}
+
+func TestResolve_SymlinkLoop(t *testing.T) {
+	root, _ := newWorkspace(t)
+	require.NoError(t, os.Symlink("b", filepath.Join(root, "a")))
+	require.NoError(t, os.Symlink("a", filepath.Join(root, "b")))
+
+	_, err := Resolve([]string{root}, "a")
+	assert.Error(t, err)
+}
+
+func TestResolve_NoRoots(t *testing.T) {
+	_, outside := newWorkspace(t)
+	resolved, err := Resolve(nil, filepath.Join(outside, "secret"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(outside, "secret"), resolved)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kiosk404/how-to-build-a-coding-agent/pkg/servers"
+)
+
+// shutdownTimeout 是停止服务时等待子进程退出的最长时间，超时后强制结束
+const shutdownTimeout = 10 * time.Second
+
+// agentServer 是 mcp_agent 的 HTTP API（mcp_agent serve），在 SSE 服务器之后启动
+var agentServer = servers.Server{Name: "mcp_agent", Package: "./mcp_agent", Type: "api", URL: "http://localhost:9622"}
+
+// child 是 serve-all 启动的一个子进程，done 在进程退出后关闭
+type child struct {
+	name string
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// portFlags 是可重复指定的 name=port 参数
+type portFlags map[string]string
+
+func (p portFlags) String() string {
+	var pairs []string
+	for name, port := range p {
+		pairs = append(pairs, name+"="+port)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p portFlags) Set(value string) error {
+	name, port, ok := strings.Cut(value, "=")
+	if !ok || name == "" || port == "" {
+		return fmt.Errorf("expected name=port, got %q", value)
+	}
+	p[name] = port
+	return nil
+}
+
+// runServeAll 编译并在同一进程组中启动所有 SSE MCP 服务器和 mcp_agent 的 HTTP API，统一输出日志，
+// 收到 SIGINT/SIGTERM 或任一进程退出时停止全部进程。"--" 之后的参数传给 mcp_agent，如 --config 和 --model
+func runServeAll(args []string) error {
+	flags := flag.NewFlagSet("serve-all", flag.ContinueOnError)
+	bind := flags.String("bind", "0.0.0.0", "Address the SSE servers and the agent API listen on")
+	ports := portFlags{}
+	flags.Var(ports, "port", "Override the port of a server, e.g. --port web_browser=9700 or --port mcp_agent=9800 (repeatable)")
+	binDir := flags.String("bin", servers.DefaultBinDir, "Directory of the server binaries; missing or stale ones are rebuilt when the sources are available")
+	noAgent := flags.Bool("no-agent", false, "Only start the SSE MCP servers, not the agent API")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var sseServers []servers.Server
+	for _, s := range servers.Bundled {
+		if s.Type == "sse" {
+			sseServers = append(sseServers, s)
+		}
+	}
+	if !*noAgent {
+		sseServers = append(sseServers, agentServer)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logs := &logMux{out: os.Stdout}
+	var children []*child
+	exited := make(chan error, len(sseServers))
+	for i, s := range sseServers {
+		binary := servers.Binary(s, *binDir)
+		if _, err := os.Stat(s.Package); err == nil && servers.Stale(s, *binDir) {
+			fmt.Printf("  🔨 Building %s%s%s -> %s\n", Bold, s.Name, ColorReset, binary)
+			if _, err := servers.Build(s, *binDir); err != nil {
+				return err
+			}
+		}
+
+		port := ports[s.Name]
+		if port == "" {
+			if u, err := url.Parse(s.URL); err == nil {
+				port = u.Port()
+			}
+		}
+
+		cmd := exec.Command(binary)
+		cmd.Env = append(os.Environ(), "MCP_HOST="+*bind, "MCP_PORT="+port)
+		if s.Name == agentServer.Name {
+			// 全局参数必须在子命令之前
+			cmd.Args = append(append(cmd.Args, flags.Args()...), "--listen", net.JoinHostPort(*bind, port), "serve")
+		}
+		cmd.Stdout = logs.writer(s.Name, i)
+		cmd.Stderr = cmd.Stdout
+		if err := cmd.Start(); err != nil {
+			terminate(children)
+			return fmt.Errorf("failed to start %s: %w", s.Name, err)
+		}
+		fmt.Printf("  🚀 Started %s%s%s on %s:%s (pid %d)\n", Bold, s.Name, ColorReset, *bind, port, cmd.Process.Pid)
+		c := &child{name: s.Name, cmd: cmd, done: make(chan struct{})}
+		children = append(children, c)
+
+		go func() {
+			err := c.cmd.Wait()
+			close(c.done)
+			exited <- fmt.Errorf("%s exited: %v", c.name, err)
+		}()
+	}
+
+	var result error
+	select {
+	case <-ctx.Done():
+		fmt.Printf("\n  🛑 Shutting down %d process(es)...\n", len(children))
+	case result = <-exited:
+		fmt.Printf("  %s❌ %v, shutting down the others%s\n", ColorRed, result, ColorReset)
+	}
+	terminate(children)
+	return result
+}
+
+// terminate 请求所有子进程退出（Unix 上发送 SIGTERM），超时后强制结束。
+// 进程是否退出由等待它的 goroutine 通过 done 告知，不在这里读取进程状态
+func terminate(children []*child) {
+	var wg sync.WaitGroup
+	for _, c := range children {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case <-c.done:
+				return
+			default:
+			}
+			interrupt(c.cmd.Process)
+			select {
+			case <-c.done:
+			case <-time.After(shutdownTimeout):
+				c.cmd.Process.Kill()
+				<-c.done
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// logMux 将多个子进程的输出按行合并到同一输出，每行带上服务器名前缀
+type logMux struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+var logColors = []string{ColorCyan, ColorPurple, ColorYellow, ColorGreen, ColorBlue}
+
+func (m *logMux) writer(name string, index int) io.Writer {
+	return &prefixWriter{mux: m, prefix: fmt.Sprintf("%s[%s]%s ", logColors[index%len(logColors)], name, ColorReset)}
+}
+
+// prefixWriter 缓冲不完整的行，只输出完整的行
+type prefixWriter struct {
+	mux    *logMux
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// 将不完整的行放回缓冲区，等待后续输出
+			rest := append([]byte(nil), line...)
+			w.buf.Reset()
+			w.buf.Write(rest)
+			return len(p), nil
+		}
+		w.mux.mu.Lock()
+		fmt.Fprintf(w.mux.out, "%s%s", w.prefix, line)
+		w.mux.mu.Unlock()
+	}
+}
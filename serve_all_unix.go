@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// interrupt 请求进程正常退出
+func interrupt(p *os.Process) {
+	p.Signal(syscall.SIGTERM)
+}
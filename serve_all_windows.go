@@ -0,0 +1,8 @@
+package main
+
+import "os"
+
+// interrupt 结束进程：Windows 不支持向其他进程发送 SIGTERM
+func interrupt(p *os.Process) {
+	p.Kill()
+}